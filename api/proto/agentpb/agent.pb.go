@@ -133,9 +133,13 @@ type RegisterRequest struct {
 	// agent_token is the pre-shared token used to authenticate the agent.
 	AgentToken string `protobuf:"bytes,1,opt,name=agent_token,json=agentToken,proto3" json:"agent_token,omitempty"`
 	// cluster_name is the unique name identifying this cluster.
-	ClusterName   string `protobuf:"bytes,2,opt,name=cluster_name,json=clusterName,proto3" json:"cluster_name,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	ClusterName string `protobuf:"bytes,2,opt,name=cluster_name,json=clusterName,proto3" json:"cluster_name,omitempty"`
+	// max_concurrent_commands is how many one-shot commands the agent runs at
+	// once. Central never has more than this many running on the agent; 0
+	// means the agent did not say.
+	MaxConcurrentCommands int32 `protobuf:"varint,4,opt,name=max_concurrent_commands,json=maxConcurrentCommands,proto3" json:"max_concurrent_commands,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
 }
 
 func (x *RegisterRequest) Reset() {
@@ -182,6 +186,13 @@ func (x *RegisterRequest) GetClusterName() string {
 	return ""
 }
 
+func (x *RegisterRequest) GetMaxConcurrentCommands() int32 {
+	if x != nil {
+		return x.MaxConcurrentCommands
+	}
+	return 0
+}
+
 // RegisterResponse is returned after an agent registration attempt.
 type RegisterResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -372,7 +383,11 @@ type CommandRequest struct {
 	TimeoutSeconds int32 `protobuf:"varint,5,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
 	// stdin is optional input to be piped to the command's standard input.
 	// Used for commands like "kubectl apply -f -" that read from stdin.
-	Stdin         []byte `protobuf:"bytes,6,opt,name=stdin,proto3" json:"stdin,omitempty"`
+	Stdin []byte `protobuf:"bytes,6,opt,name=stdin,proto3" json:"stdin,omitempty"`
+	// user and groups identify the kbridge user the command runs for. Agents
+	// with impersonation enabled act as them in the cluster (--as/--as-group).
+	User          string   `protobuf:"bytes,7,opt,name=user,proto3" json:"user,omitempty"`
+	Groups        []string `protobuf:"bytes,8,rep,name=groups,proto3" json:"groups,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -449,6 +464,20 @@ func (x *CommandRequest) GetStdin() []byte {
 	return nil
 }
 
+func (x *CommandRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
 // CommandResponse contains output from a command execution.
 // Multiple responses may be streamed as output is generated.
 type CommandResponse struct {
@@ -545,7 +574,10 @@ func (x *CommandResponse) GetErrorMessage() string {
 type GetPendingCommandsRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// agent_id is the identifier assigned during registration.
-	AgentId       string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	AgentId string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	// max_commands is how many commands the agent can start now (its idle
+	// workers). 0 means no limit.
+	MaxCommands   int32 `protobuf:"varint,2,opt,name=max_commands,json=maxCommands,proto3" json:"max_commands,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -587,13 +619,23 @@ func (x *GetPendingCommandsRequest) GetAgentId() string {
 	return ""
 }
 
+func (x *GetPendingCommandsRequest) GetMaxCommands() int32 {
+	if x != nil {
+		return x.MaxCommands
+	}
+	return 0
+}
+
 // GetPendingCommandsResponse contains any pending commands for the agent.
 type GetPendingCommandsResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// commands contains the list of pending commands to execute.
-	Commands      []*CommandRequest `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	Commands []*CommandRequest `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	// canceled_request_ids lists commands handed to the agent earlier that
+	// have since been canceled. The agent kills them if they are still running.
+	CanceledRequestIds []string `protobuf:"bytes,2,rep,name=canceled_request_ids,json=canceledRequestIds,proto3" json:"canceled_request_ids,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *GetPendingCommandsResponse) Reset() {
@@ -633,6 +675,13 @@ func (x *GetPendingCommandsResponse) GetCommands() []*CommandRequest {
 	return nil
 }
 
+func (x *GetPendingCommandsResponse) GetCanceledRequestIds() []string {
+	if x != nil {
+		return x.CanceledRequestIds
+	}
+	return nil
+}
+
 // SubmitCommandResultRequest is sent by the agent after executing a command.
 type SubmitCommandResultRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -773,6 +822,9 @@ type CentralStreamMessage struct {
 	//	*CentralStreamMessage_PfOpen
 	//	*CentralStreamMessage_PfData
 	//	*CentralStreamMessage_PfClose
+	//	*CentralStreamMessage_Command
+	//	*CentralStreamMessage_Registered
+	//	*CentralStreamMessage_CancelCommand
 	Msg           isCentralStreamMessage_Msg `protobuf_oneof:"msg"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -887,6 +939,33 @@ func (x *CentralStreamMessage) GetPfClose() *PfClose {
 	return nil
 }
 
+func (x *CentralStreamMessage) GetCommand() *CommandRequest {
+	if x != nil {
+		if x, ok := x.Msg.(*CentralStreamMessage_Command); ok {
+			return x.Command
+		}
+	}
+	return nil
+}
+
+func (x *CentralStreamMessage) GetRegistered() *StreamRegistered {
+	if x != nil {
+		if x, ok := x.Msg.(*CentralStreamMessage_Registered); ok {
+			return x.Registered
+		}
+	}
+	return nil
+}
+
+func (x *CentralStreamMessage) GetCancelCommand() *CancelCommand {
+	if x != nil {
+		if x, ok := x.Msg.(*CentralStreamMessage_CancelCommand); ok {
+			return x.CancelCommand
+		}
+	}
+	return nil
+}
+
 type isCentralStreamMessage_Msg interface {
 	isCentralStreamMessage_Msg()
 }
@@ -923,6 +1002,18 @@ type CentralStreamMessage_PfClose struct {
 	PfClose *PfClose `protobuf:"bytes,8,opt,name=pf_close,json=pfClose,proto3,oneof"`
 }
 
+type CentralStreamMessage_Command struct {
+	Command *CommandRequest `protobuf:"bytes,9,opt,name=command,proto3,oneof"`
+}
+
+type CentralStreamMessage_Registered struct {
+	Registered *StreamRegistered `protobuf:"bytes,10,opt,name=registered,proto3,oneof"`
+}
+
+type CentralStreamMessage_CancelCommand struct {
+	CancelCommand *CancelCommand `protobuf:"bytes,11,opt,name=cancel_command,json=cancelCommand,proto3,oneof"`
+}
+
 func (*CentralStreamMessage_Start) isCentralStreamMessage_Msg() {}
 
 func (*CentralStreamMessage_Cancel) isCentralStreamMessage_Msg() {}
@@ -939,21 +1030,123 @@ func (*CentralStreamMessage_PfData) isCentralStreamMessage_Msg() {}
 
 func (*CentralStreamMessage_PfClose) isCentralStreamMessage_Msg() {}
 
-type StartStream struct {
+func (*CentralStreamMessage_Command) isCentralStreamMessage_Msg() {}
+
+func (*CentralStreamMessage_Registered) isCentralStreamMessage_Msg() {}
+
+func (*CentralStreamMessage_CancelCommand) isCentralStreamMessage_Msg() {}
+
+// StreamRegistered acknowledges StreamRegister. push_commands confirms that
+// central will push CommandRequests on the stream, so the agent can stop
+// polling GetPendingCommands while the stream is open.
+type StreamRegistered struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Command       []string               `protobuf:"bytes,2,rep,name=command,proto3" json:"command,omitempty"`
-	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	Tty           bool                   `protobuf:"varint,4,opt,name=tty,proto3" json:"tty,omitempty"`
-	Rows          uint32                 `protobuf:"varint,5,opt,name=rows,proto3" json:"rows,omitempty"`
-	Cols          uint32                 `protobuf:"varint,6,opt,name=cols,proto3" json:"cols,omitempty"`
+	PushCommands  bool                   `protobuf:"varint,1,opt,name=push_commands,json=pushCommands,proto3" json:"push_commands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRegistered) Reset() {
+	*x = StreamRegistered{}
+	mi := &file_agent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRegistered) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRegistered) ProtoMessage() {}
+
+func (x *StreamRegistered) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRegistered.ProtoReflect.Descriptor instead.
+func (*StreamRegistered) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamRegistered) GetPushCommands() bool {
+	if x != nil {
+		return x.PushCommands
+	}
+	return false
+}
+
+// CancelCommand tells the agent to kill a one-shot command it was given
+// earlier, pushed or polled. A command that already finished is ignored.
+type CancelCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelCommand) Reset() {
+	*x = CancelCommand{}
+	mi := &file_agent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCommand) ProtoMessage() {}
+
+func (x *CancelCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCommand.ProtoReflect.Descriptor instead.
+func (*CancelCommand) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CancelCommand) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type StartStream struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Command   []string               `protobuf:"bytes,2,rep,name=command,proto3" json:"command,omitempty"`
+	Namespace string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Tty       bool                   `protobuf:"varint,4,opt,name=tty,proto3" json:"tty,omitempty"`
+	Rows      uint32                 `protobuf:"varint,5,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols      uint32                 `protobuf:"varint,6,opt,name=cols,proto3" json:"cols,omitempty"`
+	// user and groups are the requester, as in CommandRequest.
+	User          string   `protobuf:"bytes,7,opt,name=user,proto3" json:"user,omitempty"`
+	Groups        []string `protobuf:"bytes,8,rep,name=groups,proto3" json:"groups,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StartStream) Reset() {
 	*x = StartStream{}
-	mi := &file_agent_proto_msgTypes[11]
+	mi := &file_agent_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -965,7 +1158,7 @@ func (x *StartStream) String() string {
 func (*StartStream) ProtoMessage() {}
 
 func (x *StartStream) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[11]
+	mi := &file_agent_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -978,7 +1171,7 @@ func (x *StartStream) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StartStream.ProtoReflect.Descriptor instead.
 func (*StartStream) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{11}
+	return file_agent_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *StartStream) GetSessionId() string {
@@ -1023,6 +1216,20 @@ func (x *StartStream) GetCols() uint32 {
 	return 0
 }
 
+func (x *StartStream) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *StartStream) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
 type CancelStream struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
@@ -1032,7 +1239,7 @@ type CancelStream struct {
 
 func (x *CancelStream) Reset() {
 	*x = CancelStream{}
-	mi := &file_agent_proto_msgTypes[12]
+	mi := &file_agent_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1044,7 +1251,7 @@ func (x *CancelStream) String() string {
 func (*CancelStream) ProtoMessage() {}
 
 func (x *CancelStream) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[12]
+	mi := &file_agent_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1057,7 +1264,7 @@ func (x *CancelStream) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelStream.ProtoReflect.Descriptor instead.
 func (*CancelStream) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{12}
+	return file_agent_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *CancelStream) GetSessionId() string {
@@ -1077,7 +1284,7 @@ type StdinData struct {
 
 func (x *StdinData) Reset() {
 	*x = StdinData{}
-	mi := &file_agent_proto_msgTypes[13]
+	mi := &file_agent_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1089,7 +1296,7 @@ func (x *StdinData) String() string {
 func (*StdinData) ProtoMessage() {}
 
 func (x *StdinData) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[13]
+	mi := &file_agent_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1102,7 +1309,7 @@ func (x *StdinData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StdinData.ProtoReflect.Descriptor instead.
 func (*StdinData) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{13}
+	return file_agent_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *StdinData) GetSessionId() string {
@@ -1130,7 +1337,7 @@ type Resize struct {
 
 func (x *Resize) Reset() {
 	*x = Resize{}
-	mi := &file_agent_proto_msgTypes[14]
+	mi := &file_agent_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1142,7 +1349,7 @@ func (x *Resize) String() string {
 func (*Resize) ProtoMessage() {}
 
 func (x *Resize) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[14]
+	mi := &file_agent_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1155,7 +1362,7 @@ func (x *Resize) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Resize.ProtoReflect.Descriptor instead.
 func (*Resize) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{14}
+	return file_agent_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *Resize) GetSessionId() string {
@@ -1191,6 +1398,7 @@ type AgentStreamMessage struct {
 	//	*AgentStreamMessage_PfClose
 	//	*AgentStreamMessage_PfConnError
 	//	*AgentStreamMessage_PfSessionError
+	//	*AgentStreamMessage_CommandResult
 	Msg           isAgentStreamMessage_Msg `protobuf_oneof:"msg"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1198,7 +1406,7 @@ type AgentStreamMessage struct {
 
 func (x *AgentStreamMessage) Reset() {
 	*x = AgentStreamMessage{}
-	mi := &file_agent_proto_msgTypes[15]
+	mi := &file_agent_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1210,7 +1418,7 @@ func (x *AgentStreamMessage) String() string {
 func (*AgentStreamMessage) ProtoMessage() {}
 
 func (x *AgentStreamMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[15]
+	mi := &file_agent_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1223,7 +1431,7 @@ func (x *AgentStreamMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentStreamMessage.ProtoReflect.Descriptor instead.
 func (*AgentStreamMessage) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{15}
+	return file_agent_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *AgentStreamMessage) GetMsg() isAgentStreamMessage_Msg {
@@ -1305,6 +1513,15 @@ func (x *AgentStreamMessage) GetPfSessionError() *PfSessionError {
 	return nil
 }
 
+func (x *AgentStreamMessage) GetCommandResult() *SubmitCommandResultRequest {
+	if x != nil {
+		if x, ok := x.Msg.(*AgentStreamMessage_CommandResult); ok {
+			return x.CommandResult
+		}
+	}
+	return nil
+}
+
 type isAgentStreamMessage_Msg interface {
 	isAgentStreamMessage_Msg()
 }
@@ -1341,6 +1558,10 @@ type AgentStreamMessage_PfSessionError struct {
 	PfSessionError *PfSessionError `protobuf:"bytes,8,opt,name=pf_session_error,json=pfSessionError,proto3,oneof"`
 }
 
+type AgentStreamMessage_CommandResult struct {
+	CommandResult *SubmitCommandResultRequest `protobuf:"bytes,9,opt,name=command_result,json=commandResult,proto3,oneof"`
+}
+
 func (*AgentStreamMessage_Register) isAgentStreamMessage_Msg() {}
 
 func (*AgentStreamMessage_Output) isAgentStreamMessage_Msg() {}
@@ -1357,16 +1578,21 @@ func (*AgentStreamMessage_PfConnError) isAgentStreamMessage_Msg() {}
 
 func (*AgentStreamMessage_PfSessionError) isAgentStreamMessage_Msg() {}
 
+func (*AgentStreamMessage_CommandResult) isAgentStreamMessage_Msg() {}
+
+// StreamRegister is the first message on a stream. push_commands advertises
+// that the agent runs CommandRequests pushed on the stream.
 type StreamRegister struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	PushCommands  bool                   `protobuf:"varint,2,opt,name=push_commands,json=pushCommands,proto3" json:"push_commands,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StreamRegister) Reset() {
 	*x = StreamRegister{}
-	mi := &file_agent_proto_msgTypes[16]
+	mi := &file_agent_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1378,7 +1604,7 @@ func (x *StreamRegister) String() string {
 func (*StreamRegister) ProtoMessage() {}
 
 func (x *StreamRegister) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[16]
+	mi := &file_agent_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1391,7 +1617,7 @@ func (x *StreamRegister) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamRegister.ProtoReflect.Descriptor instead.
 func (*StreamRegister) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{16}
+	return file_agent_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *StreamRegister) GetAgentId() string {
@@ -1401,6 +1627,13 @@ func (x *StreamRegister) GetAgentId() string {
 	return ""
 }
 
+func (x *StreamRegister) GetPushCommands() bool {
+	if x != nil {
+		return x.PushCommands
+	}
+	return false
+}
+
 type StreamOutput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
@@ -1412,7 +1645,7 @@ type StreamOutput struct {
 
 func (x *StreamOutput) Reset() {
 	*x = StreamOutput{}
-	mi := &file_agent_proto_msgTypes[17]
+	mi := &file_agent_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1424,7 +1657,7 @@ func (x *StreamOutput) String() string {
 func (*StreamOutput) ProtoMessage() {}
 
 func (x *StreamOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[17]
+	mi := &file_agent_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1437,7 +1670,7 @@ func (x *StreamOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamOutput.ProtoReflect.Descriptor instead.
 func (*StreamOutput) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{17}
+	return file_agent_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *StreamOutput) GetSessionId() string {
@@ -1472,7 +1705,7 @@ type StreamExit struct {
 
 func (x *StreamExit) Reset() {
 	*x = StreamExit{}
-	mi := &file_agent_proto_msgTypes[18]
+	mi := &file_agent_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1484,7 +1717,7 @@ func (x *StreamExit) String() string {
 func (*StreamExit) ProtoMessage() {}
 
 func (x *StreamExit) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[18]
+	mi := &file_agent_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1497,7 +1730,7 @@ func (x *StreamExit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamExit.ProtoReflect.Descriptor instead.
 func (*StreamExit) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{18}
+	return file_agent_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *StreamExit) GetSessionId() string {
@@ -1522,18 +1755,21 @@ func (x *StreamExit) GetErrorMessage() string {
 }
 
 type PortForwardStart struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Pod           string                 `protobuf:"bytes,2,opt,name=pod,proto3" json:"pod,omitempty"`
-	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	Ports         []uint32               `protobuf:"varint,4,rep,packed,name=ports,proto3" json:"ports,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Pod       string                 `protobuf:"bytes,2,opt,name=pod,proto3" json:"pod,omitempty"`
+	Namespace string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Ports     []uint32               `protobuf:"varint,4,rep,packed,name=ports,proto3" json:"ports,omitempty"`
+	// user and groups are the requester, as in CommandRequest.
+	User          string   `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`
+	Groups        []string `protobuf:"bytes,6,rep,name=groups,proto3" json:"groups,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PortForwardStart) Reset() {
 	*x = PortForwardStart{}
-	mi := &file_agent_proto_msgTypes[19]
+	mi := &file_agent_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1545,7 +1781,7 @@ func (x *PortForwardStart) String() string {
 func (*PortForwardStart) ProtoMessage() {}
 
 func (x *PortForwardStart) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[19]
+	mi := &file_agent_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1558,7 +1794,7 @@ func (x *PortForwardStart) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PortForwardStart.ProtoReflect.Descriptor instead.
 func (*PortForwardStart) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{19}
+	return file_agent_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *PortForwardStart) GetSessionId() string {
@@ -1589,6 +1825,20 @@ func (x *PortForwardStart) GetPorts() []uint32 {
 	return nil
 }
 
+func (x *PortForwardStart) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *PortForwardStart) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
 type PfOpen struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
@@ -1600,7 +1850,7 @@ type PfOpen struct {
 
 func (x *PfOpen) Reset() {
 	*x = PfOpen{}
-	mi := &file_agent_proto_msgTypes[20]
+	mi := &file_agent_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1612,7 +1862,7 @@ func (x *PfOpen) String() string {
 func (*PfOpen) ProtoMessage() {}
 
 func (x *PfOpen) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[20]
+	mi := &file_agent_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1625,7 +1875,7 @@ func (x *PfOpen) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PfOpen.ProtoReflect.Descriptor instead.
 func (*PfOpen) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{20}
+	return file_agent_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *PfOpen) GetSessionId() string {
@@ -1660,7 +1910,7 @@ type PfData struct {
 
 func (x *PfData) Reset() {
 	*x = PfData{}
-	mi := &file_agent_proto_msgTypes[21]
+	mi := &file_agent_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1672,7 +1922,7 @@ func (x *PfData) String() string {
 func (*PfData) ProtoMessage() {}
 
 func (x *PfData) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[21]
+	mi := &file_agent_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1685,7 +1935,7 @@ func (x *PfData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PfData.ProtoReflect.Descriptor instead.
 func (*PfData) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{21}
+	return file_agent_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *PfData) GetSessionId() string {
@@ -1719,7 +1969,7 @@ type PfClose struct {
 
 func (x *PfClose) Reset() {
 	*x = PfClose{}
-	mi := &file_agent_proto_msgTypes[22]
+	mi := &file_agent_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1731,7 +1981,7 @@ func (x *PfClose) String() string {
 func (*PfClose) ProtoMessage() {}
 
 func (x *PfClose) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[22]
+	mi := &file_agent_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1744,7 +1994,7 @@ func (x *PfClose) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PfClose.ProtoReflect.Descriptor instead.
 func (*PfClose) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{22}
+	return file_agent_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *PfClose) GetSessionId() string {
@@ -1772,7 +2022,7 @@ type PfConnError struct {
 
 func (x *PfConnError) Reset() {
 	*x = PfConnError{}
-	mi := &file_agent_proto_msgTypes[23]
+	mi := &file_agent_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1784,7 +2034,7 @@ func (x *PfConnError) String() string {
 func (*PfConnError) ProtoMessage() {}
 
 func (x *PfConnError) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[23]
+	mi := &file_agent_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1797,7 +2047,7 @@ func (x *PfConnError) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PfConnError.ProtoReflect.Descriptor instead.
 func (*PfConnError) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{23}
+	return file_agent_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *PfConnError) GetSessionId() string {
@@ -1830,7 +2080,7 @@ type PfReady struct {
 
 func (x *PfReady) Reset() {
 	*x = PfReady{}
-	mi := &file_agent_proto_msgTypes[24]
+	mi := &file_agent_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1842,7 +2092,7 @@ func (x *PfReady) String() string {
 func (*PfReady) ProtoMessage() {}
 
 func (x *PfReady) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[24]
+	mi := &file_agent_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1855,7 +2105,7 @@ func (x *PfReady) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PfReady.ProtoReflect.Descriptor instead.
 func (*PfReady) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{24}
+	return file_agent_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *PfReady) GetSessionId() string {
@@ -1875,7 +2125,7 @@ type PfSessionError struct {
 
 func (x *PfSessionError) Reset() {
 	*x = PfSessionError{}
-	mi := &file_agent_proto_msgTypes[25]
+	mi := &file_agent_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1887,7 +2137,7 @@ func (x *PfSessionError) String() string {
 func (*PfSessionError) ProtoMessage() {}
 
 func (x *PfSessionError) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_msgTypes[25]
+	mi := &file_agent_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1900,7 +2150,7 @@ func (x *PfSessionError) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PfSessionError.ProtoReflect.Descriptor instead.
 func (*PfSessionError) Descriptor() ([]byte, []int) {
-	return file_agent_proto_rawDescGZIP(), []int{25}
+	return file_agent_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *PfSessionError) GetSessionId() string {
@@ -1921,11 +2171,12 @@ var File_agent_proto protoreflect.FileDescriptor
 
 const file_agent_proto_rawDesc = "" +
 	"\n" +
-	"\vagent.proto\x12\x10kbridge.agent.v1\"[\n" +
+	"\vagent.proto\x12\x10kbridge.agent.v1\"\x93\x01\n" +
 	"\x0fRegisterRequest\x12\x1f\n" +
 	"\vagent_token\x18\x01 \x01(\tR\n" +
 	"agentToken\x12!\n" +
-	"\fcluster_name\x18\x02 \x01(\tR\vclusterNameJ\x04\b\x03\x10\x04\"l\n" +
+	"\fcluster_name\x18\x02 \x01(\tR\vclusterName\x126\n" +
+	"\x17max_concurrent_commands\x18\x04 \x01(\x05R\x15maxConcurrentCommandsJ\x04\b\x03\x10\x04\"l\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x19\n" +
 	"\bagent_id\x18\x02 \x01(\tR\aagentId\x12#\n" +
@@ -1935,7 +2186,7 @@ const file_agent_proto_rawDesc = "" +
 	"\x06status\x18\x02 \x01(\x0e2\x1d.kbridge.agent.v1.AgentStatusR\x06status\"m\n" +
 	"\x11HeartbeatResponse\x12\"\n" +
 	"\facknowledged\x18\x01 \x01(\bR\facknowledged\x124\n" +
-	"\x16next_heartbeat_seconds\x18\x02 \x01(\x03R\x14nextHeartbeatSeconds\"\xc1\x01\n" +
+	"\x16next_heartbeat_seconds\x18\x02 \x01(\x03R\x14nextHeartbeatSeconds\"\xed\x01\n" +
 	"\x0eCommandRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x19\n" +
@@ -1943,7 +2194,9 @@ const file_agent_proto_rawDesc = "" +
 	"\acommand\x18\x03 \x03(\tR\acommand\x12\x1c\n" +
 	"\tnamespace\x18\x04 \x01(\tR\tnamespace\x12'\n" +
 	"\x0ftimeout_seconds\x18\x05 \x01(\x05R\x0etimeoutSeconds\x12\x14\n" +
-	"\x05stdin\x18\x06 \x01(\fR\x05stdin\"\xd4\x01\n" +
+	"\x05stdin\x18\x06 \x01(\fR\x05stdin\x12\x12\n" +
+	"\x04user\x18\a \x01(\tR\x04user\x12\x16\n" +
+	"\x06groups\x18\b \x03(\tR\x06groups\"\xd4\x01\n" +
 	"\x0fCommandResponse\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x120\n" +
@@ -1951,11 +2204,13 @@ const file_agent_proto_rawDesc = "" +
 	"\x04data\x18\x03 \x01(\fR\x04data\x12\x1a\n" +
 	"\bcomplete\x18\x04 \x01(\bR\bcomplete\x12\x1b\n" +
 	"\texit_code\x18\x05 \x01(\x05R\bexitCode\x12#\n" +
-	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\"6\n" +
+	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\"Y\n" +
 	"\x19GetPendingCommandsRequest\x12\x19\n" +
-	"\bagent_id\x18\x01 \x01(\tR\aagentId\"Z\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12!\n" +
+	"\fmax_commands\x18\x02 \x01(\x05R\vmaxCommands\"\x8c\x01\n" +
 	"\x1aGetPendingCommandsResponse\x12<\n" +
-	"\bcommands\x18\x01 \x03(\v2 .kbridge.agent.v1.CommandRequestR\bcommands\"\xad\x01\n" +
+	"\bcommands\x18\x01 \x03(\v2 .kbridge.agent.v1.CommandRequestR\bcommands\x120\n" +
+	"\x14canceled_request_ids\x18\x02 \x03(\tR\x12canceledRequestIds\"\xad\x01\n" +
 	"\x1aSubmitCommandResultRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x16\n" +
@@ -1964,7 +2219,7 @@ const file_agent_proto_rawDesc = "" +
 	"\texit_code\x18\x04 \x01(\x05R\bexitCode\x12#\n" +
 	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\"7\n" +
 	"\x1bSubmitCommandResultResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"\xda\x03\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"\xa8\x05\n" +
 	"\x14CentralStreamMessage\x125\n" +
 	"\x05start\x18\x01 \x01(\v2\x1d.kbridge.agent.v1.StartStreamH\x00R\x05start\x128\n" +
 	"\x06cancel\x18\x02 \x01(\v2\x1e.kbridge.agent.v1.CancelStreamH\x00R\x06cancel\x123\n" +
@@ -1973,8 +2228,19 @@ const file_agent_proto_rawDesc = "" +
 	"\bpf_start\x18\x05 \x01(\v2\".kbridge.agent.v1.PortForwardStartH\x00R\apfStart\x123\n" +
 	"\apf_open\x18\x06 \x01(\v2\x18.kbridge.agent.v1.PfOpenH\x00R\x06pfOpen\x123\n" +
 	"\apf_data\x18\a \x01(\v2\x18.kbridge.agent.v1.PfDataH\x00R\x06pfData\x126\n" +
-	"\bpf_close\x18\b \x01(\v2\x19.kbridge.agent.v1.PfCloseH\x00R\apfCloseB\x05\n" +
-	"\x03msg\"\x9e\x01\n" +
+	"\bpf_close\x18\b \x01(\v2\x19.kbridge.agent.v1.PfCloseH\x00R\apfClose\x12<\n" +
+	"\acommand\x18\t \x01(\v2 .kbridge.agent.v1.CommandRequestH\x00R\acommand\x12D\n" +
+	"\n" +
+	"registered\x18\n" +
+	" \x01(\v2\".kbridge.agent.v1.StreamRegisteredH\x00R\n" +
+	"registered\x12H\n" +
+	"\x0ecancel_command\x18\v \x01(\v2\x1f.kbridge.agent.v1.CancelCommandH\x00R\rcancelCommandB\x05\n" +
+	"\x03msg\"7\n" +
+	"\x10StreamRegistered\x12#\n" +
+	"\rpush_commands\x18\x01 \x01(\bR\fpushCommands\".\n" +
+	"\rCancelCommand\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"\xca\x01\n" +
 	"\vStartStream\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x18\n" +
@@ -1982,7 +2248,9 @@ const file_agent_proto_rawDesc = "" +
 	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x10\n" +
 	"\x03tty\x18\x04 \x01(\bR\x03tty\x12\x12\n" +
 	"\x04rows\x18\x05 \x01(\rR\x04rows\x12\x12\n" +
-	"\x04cols\x18\x06 \x01(\rR\x04cols\"-\n" +
+	"\x04cols\x18\x06 \x01(\rR\x04cols\x12\x12\n" +
+	"\x04user\x18\a \x01(\tR\x04user\x12\x16\n" +
+	"\x06groups\x18\b \x03(\tR\x06groups\"-\n" +
 	"\fCancelStream\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\">\n" +
@@ -1994,7 +2262,7 @@ const file_agent_proto_rawDesc = "" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
 	"\x04rows\x18\x02 \x01(\rR\x04rows\x12\x12\n" +
-	"\x04cols\x18\x03 \x01(\rR\x04cols\"\x81\x04\n" +
+	"\x04cols\x18\x03 \x01(\rR\x04cols\"\xd8\x04\n" +
 	"\x12AgentStreamMessage\x12>\n" +
 	"\bregister\x18\x01 \x01(\v2 .kbridge.agent.v1.StreamRegisterH\x00R\bregister\x128\n" +
 	"\x06output\x18\x02 \x01(\v2\x1e.kbridge.agent.v1.StreamOutputH\x00R\x06output\x122\n" +
@@ -2003,10 +2271,12 @@ const file_agent_proto_rawDesc = "" +
 	"\apf_data\x18\x05 \x01(\v2\x18.kbridge.agent.v1.PfDataH\x00R\x06pfData\x126\n" +
 	"\bpf_close\x18\x06 \x01(\v2\x19.kbridge.agent.v1.PfCloseH\x00R\apfClose\x12C\n" +
 	"\rpf_conn_error\x18\a \x01(\v2\x1d.kbridge.agent.v1.PfConnErrorH\x00R\vpfConnError\x12L\n" +
-	"\x10pf_session_error\x18\b \x01(\v2 .kbridge.agent.v1.PfSessionErrorH\x00R\x0epfSessionErrorB\x05\n" +
-	"\x03msg\"+\n" +
+	"\x10pf_session_error\x18\b \x01(\v2 .kbridge.agent.v1.PfSessionErrorH\x00R\x0epfSessionError\x12U\n" +
+	"\x0ecommand_result\x18\t \x01(\v2,.kbridge.agent.v1.SubmitCommandResultRequestH\x00R\rcommandResultB\x05\n" +
+	"\x03msg\"P\n" +
 	"\x0eStreamRegister\x12\x19\n" +
-	"\bagent_id\x18\x01 \x01(\tR\aagentId\"s\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12#\n" +
+	"\rpush_commands\x18\x02 \x01(\bR\fpushCommands\"s\n" +
 	"\fStreamOutput\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x120\n" +
@@ -2017,13 +2287,15 @@ const file_agent_proto_rawDesc = "" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1b\n" +
 	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12#\n" +
-	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"w\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"\xa3\x01\n" +
 	"\x10PortForwardStart\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x10\n" +
 	"\x03pod\x18\x02 \x01(\tR\x03pod\x12\x1c\n" +
 	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x14\n" +
-	"\x05ports\x18\x04 \x03(\rR\x05ports\"a\n" +
+	"\x05ports\x18\x04 \x03(\rR\x05ports\x12\x12\n" +
+	"\x04user\x18\x05 \x01(\tR\x04user\x12\x16\n" +
+	"\x06groups\x18\x06 \x03(\tR\x06groups\"a\n" +
 	"\x06PfOpen\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x17\n" +
@@ -2081,7 +2353,7 @@ func file_agent_proto_rawDescGZIP() []byte {
 }
 
 var file_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_agent_proto_goTypes = []any{
 	(AgentStatus)(0),                    // 0: kbridge.agent.v1.AgentStatus
 	(OutputType)(0),                     // 1: kbridge.agent.v1.OutputType
@@ -2096,58 +2368,64 @@ var file_agent_proto_goTypes = []any{
 	(*SubmitCommandResultRequest)(nil),  // 10: kbridge.agent.v1.SubmitCommandResultRequest
 	(*SubmitCommandResultResponse)(nil), // 11: kbridge.agent.v1.SubmitCommandResultResponse
 	(*CentralStreamMessage)(nil),        // 12: kbridge.agent.v1.CentralStreamMessage
-	(*StartStream)(nil),                 // 13: kbridge.agent.v1.StartStream
-	(*CancelStream)(nil),                // 14: kbridge.agent.v1.CancelStream
-	(*StdinData)(nil),                   // 15: kbridge.agent.v1.StdinData
-	(*Resize)(nil),                      // 16: kbridge.agent.v1.Resize
-	(*AgentStreamMessage)(nil),          // 17: kbridge.agent.v1.AgentStreamMessage
-	(*StreamRegister)(nil),              // 18: kbridge.agent.v1.StreamRegister
-	(*StreamOutput)(nil),                // 19: kbridge.agent.v1.StreamOutput
-	(*StreamExit)(nil),                  // 20: kbridge.agent.v1.StreamExit
-	(*PortForwardStart)(nil),            // 21: kbridge.agent.v1.PortForwardStart
-	(*PfOpen)(nil),                      // 22: kbridge.agent.v1.PfOpen
-	(*PfData)(nil),                      // 23: kbridge.agent.v1.PfData
-	(*PfClose)(nil),                     // 24: kbridge.agent.v1.PfClose
-	(*PfConnError)(nil),                 // 25: kbridge.agent.v1.PfConnError
-	(*PfReady)(nil),                     // 26: kbridge.agent.v1.PfReady
-	(*PfSessionError)(nil),              // 27: kbridge.agent.v1.PfSessionError
+	(*StreamRegistered)(nil),            // 13: kbridge.agent.v1.StreamRegistered
+	(*CancelCommand)(nil),               // 14: kbridge.agent.v1.CancelCommand
+	(*StartStream)(nil),                 // 15: kbridge.agent.v1.StartStream
+	(*CancelStream)(nil),                // 16: kbridge.agent.v1.CancelStream
+	(*StdinData)(nil),                   // 17: kbridge.agent.v1.StdinData
+	(*Resize)(nil),                      // 18: kbridge.agent.v1.Resize
+	(*AgentStreamMessage)(nil),          // 19: kbridge.agent.v1.AgentStreamMessage
+	(*StreamRegister)(nil),              // 20: kbridge.agent.v1.StreamRegister
+	(*StreamOutput)(nil),                // 21: kbridge.agent.v1.StreamOutput
+	(*StreamExit)(nil),                  // 22: kbridge.agent.v1.StreamExit
+	(*PortForwardStart)(nil),            // 23: kbridge.agent.v1.PortForwardStart
+	(*PfOpen)(nil),                      // 24: kbridge.agent.v1.PfOpen
+	(*PfData)(nil),                      // 25: kbridge.agent.v1.PfData
+	(*PfClose)(nil),                     // 26: kbridge.agent.v1.PfClose
+	(*PfConnError)(nil),                 // 27: kbridge.agent.v1.PfConnError
+	(*PfReady)(nil),                     // 28: kbridge.agent.v1.PfReady
+	(*PfSessionError)(nil),              // 29: kbridge.agent.v1.PfSessionError
 }
 var file_agent_proto_depIdxs = []int32{
 	0,  // 0: kbridge.agent.v1.HeartbeatRequest.status:type_name -> kbridge.agent.v1.AgentStatus
 	1,  // 1: kbridge.agent.v1.CommandResponse.type:type_name -> kbridge.agent.v1.OutputType
 	6,  // 2: kbridge.agent.v1.GetPendingCommandsResponse.commands:type_name -> kbridge.agent.v1.CommandRequest
-	13, // 3: kbridge.agent.v1.CentralStreamMessage.start:type_name -> kbridge.agent.v1.StartStream
-	14, // 4: kbridge.agent.v1.CentralStreamMessage.cancel:type_name -> kbridge.agent.v1.CancelStream
-	15, // 5: kbridge.agent.v1.CentralStreamMessage.stdin:type_name -> kbridge.agent.v1.StdinData
-	16, // 6: kbridge.agent.v1.CentralStreamMessage.resize:type_name -> kbridge.agent.v1.Resize
-	21, // 7: kbridge.agent.v1.CentralStreamMessage.pf_start:type_name -> kbridge.agent.v1.PortForwardStart
-	22, // 8: kbridge.agent.v1.CentralStreamMessage.pf_open:type_name -> kbridge.agent.v1.PfOpen
-	23, // 9: kbridge.agent.v1.CentralStreamMessage.pf_data:type_name -> kbridge.agent.v1.PfData
-	24, // 10: kbridge.agent.v1.CentralStreamMessage.pf_close:type_name -> kbridge.agent.v1.PfClose
-	18, // 11: kbridge.agent.v1.AgentStreamMessage.register:type_name -> kbridge.agent.v1.StreamRegister
-	19, // 12: kbridge.agent.v1.AgentStreamMessage.output:type_name -> kbridge.agent.v1.StreamOutput
-	20, // 13: kbridge.agent.v1.AgentStreamMessage.exit:type_name -> kbridge.agent.v1.StreamExit
-	26, // 14: kbridge.agent.v1.AgentStreamMessage.pf_ready:type_name -> kbridge.agent.v1.PfReady
-	23, // 15: kbridge.agent.v1.AgentStreamMessage.pf_data:type_name -> kbridge.agent.v1.PfData
-	24, // 16: kbridge.agent.v1.AgentStreamMessage.pf_close:type_name -> kbridge.agent.v1.PfClose
-	25, // 17: kbridge.agent.v1.AgentStreamMessage.pf_conn_error:type_name -> kbridge.agent.v1.PfConnError
-	27, // 18: kbridge.agent.v1.AgentStreamMessage.pf_session_error:type_name -> kbridge.agent.v1.PfSessionError
-	1,  // 19: kbridge.agent.v1.StreamOutput.type:type_name -> kbridge.agent.v1.OutputType
-	2,  // 20: kbridge.agent.v1.AgentService.Register:input_type -> kbridge.agent.v1.RegisterRequest
-	4,  // 21: kbridge.agent.v1.AgentService.Heartbeat:input_type -> kbridge.agent.v1.HeartbeatRequest
-	17, // 22: kbridge.agent.v1.AgentService.OpenStream:input_type -> kbridge.agent.v1.AgentStreamMessage
-	8,  // 23: kbridge.agent.v1.AgentService.GetPendingCommands:input_type -> kbridge.agent.v1.GetPendingCommandsRequest
-	10, // 24: kbridge.agent.v1.AgentService.SubmitCommandResult:input_type -> kbridge.agent.v1.SubmitCommandResultRequest
-	3,  // 25: kbridge.agent.v1.AgentService.Register:output_type -> kbridge.agent.v1.RegisterResponse
-	5,  // 26: kbridge.agent.v1.AgentService.Heartbeat:output_type -> kbridge.agent.v1.HeartbeatResponse
-	12, // 27: kbridge.agent.v1.AgentService.OpenStream:output_type -> kbridge.agent.v1.CentralStreamMessage
-	9,  // 28: kbridge.agent.v1.AgentService.GetPendingCommands:output_type -> kbridge.agent.v1.GetPendingCommandsResponse
-	11, // 29: kbridge.agent.v1.AgentService.SubmitCommandResult:output_type -> kbridge.agent.v1.SubmitCommandResultResponse
-	25, // [25:30] is the sub-list for method output_type
-	20, // [20:25] is the sub-list for method input_type
-	20, // [20:20] is the sub-list for extension type_name
-	20, // [20:20] is the sub-list for extension extendee
-	0,  // [0:20] is the sub-list for field type_name
+	15, // 3: kbridge.agent.v1.CentralStreamMessage.start:type_name -> kbridge.agent.v1.StartStream
+	16, // 4: kbridge.agent.v1.CentralStreamMessage.cancel:type_name -> kbridge.agent.v1.CancelStream
+	17, // 5: kbridge.agent.v1.CentralStreamMessage.stdin:type_name -> kbridge.agent.v1.StdinData
+	18, // 6: kbridge.agent.v1.CentralStreamMessage.resize:type_name -> kbridge.agent.v1.Resize
+	23, // 7: kbridge.agent.v1.CentralStreamMessage.pf_start:type_name -> kbridge.agent.v1.PortForwardStart
+	24, // 8: kbridge.agent.v1.CentralStreamMessage.pf_open:type_name -> kbridge.agent.v1.PfOpen
+	25, // 9: kbridge.agent.v1.CentralStreamMessage.pf_data:type_name -> kbridge.agent.v1.PfData
+	26, // 10: kbridge.agent.v1.CentralStreamMessage.pf_close:type_name -> kbridge.agent.v1.PfClose
+	6,  // 11: kbridge.agent.v1.CentralStreamMessage.command:type_name -> kbridge.agent.v1.CommandRequest
+	13, // 12: kbridge.agent.v1.CentralStreamMessage.registered:type_name -> kbridge.agent.v1.StreamRegistered
+	14, // 13: kbridge.agent.v1.CentralStreamMessage.cancel_command:type_name -> kbridge.agent.v1.CancelCommand
+	20, // 14: kbridge.agent.v1.AgentStreamMessage.register:type_name -> kbridge.agent.v1.StreamRegister
+	21, // 15: kbridge.agent.v1.AgentStreamMessage.output:type_name -> kbridge.agent.v1.StreamOutput
+	22, // 16: kbridge.agent.v1.AgentStreamMessage.exit:type_name -> kbridge.agent.v1.StreamExit
+	28, // 17: kbridge.agent.v1.AgentStreamMessage.pf_ready:type_name -> kbridge.agent.v1.PfReady
+	25, // 18: kbridge.agent.v1.AgentStreamMessage.pf_data:type_name -> kbridge.agent.v1.PfData
+	26, // 19: kbridge.agent.v1.AgentStreamMessage.pf_close:type_name -> kbridge.agent.v1.PfClose
+	27, // 20: kbridge.agent.v1.AgentStreamMessage.pf_conn_error:type_name -> kbridge.agent.v1.PfConnError
+	29, // 21: kbridge.agent.v1.AgentStreamMessage.pf_session_error:type_name -> kbridge.agent.v1.PfSessionError
+	10, // 22: kbridge.agent.v1.AgentStreamMessage.command_result:type_name -> kbridge.agent.v1.SubmitCommandResultRequest
+	1,  // 23: kbridge.agent.v1.StreamOutput.type:type_name -> kbridge.agent.v1.OutputType
+	2,  // 24: kbridge.agent.v1.AgentService.Register:input_type -> kbridge.agent.v1.RegisterRequest
+	4,  // 25: kbridge.agent.v1.AgentService.Heartbeat:input_type -> kbridge.agent.v1.HeartbeatRequest
+	19, // 26: kbridge.agent.v1.AgentService.OpenStream:input_type -> kbridge.agent.v1.AgentStreamMessage
+	8,  // 27: kbridge.agent.v1.AgentService.GetPendingCommands:input_type -> kbridge.agent.v1.GetPendingCommandsRequest
+	10, // 28: kbridge.agent.v1.AgentService.SubmitCommandResult:input_type -> kbridge.agent.v1.SubmitCommandResultRequest
+	3,  // 29: kbridge.agent.v1.AgentService.Register:output_type -> kbridge.agent.v1.RegisterResponse
+	5,  // 30: kbridge.agent.v1.AgentService.Heartbeat:output_type -> kbridge.agent.v1.HeartbeatResponse
+	12, // 31: kbridge.agent.v1.AgentService.OpenStream:output_type -> kbridge.agent.v1.CentralStreamMessage
+	9,  // 32: kbridge.agent.v1.AgentService.GetPendingCommands:output_type -> kbridge.agent.v1.GetPendingCommandsResponse
+	11, // 33: kbridge.agent.v1.AgentService.SubmitCommandResult:output_type -> kbridge.agent.v1.SubmitCommandResultResponse
+	29, // [29:34] is the sub-list for method output_type
+	24, // [24:29] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
 }
 
 func init() { file_agent_proto_init() }
@@ -2164,8 +2442,11 @@ func file_agent_proto_init() {
 		(*CentralStreamMessage_PfOpen)(nil),
 		(*CentralStreamMessage_PfData)(nil),
 		(*CentralStreamMessage_PfClose)(nil),
+		(*CentralStreamMessage_Command)(nil),
+		(*CentralStreamMessage_Registered)(nil),
+		(*CentralStreamMessage_CancelCommand)(nil),
 	}
-	file_agent_proto_msgTypes[15].OneofWrappers = []any{
+	file_agent_proto_msgTypes[17].OneofWrappers = []any{
 		(*AgentStreamMessage_Register)(nil),
 		(*AgentStreamMessage_Output)(nil),
 		(*AgentStreamMessage_Exit)(nil),
@@ -2174,6 +2455,7 @@ func file_agent_proto_init() {
 		(*AgentStreamMessage_PfClose)(nil),
 		(*AgentStreamMessage_PfConnError)(nil),
 		(*AgentStreamMessage_PfSessionError)(nil),
+		(*AgentStreamMessage_CommandResult)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -2181,7 +2463,7 @@ func file_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agent_proto_rawDesc), len(file_agent_proto_rawDesc)),
 			NumEnums:      2,
-			NumMessages:   26,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -41,10 +41,13 @@ type AgentServiceClient interface {
 	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
 	// OpenStream is opened once by the agent after Register. Central pushes
 	// streaming command/control messages down it; the agent streams output back
-	// up. Sessions are multiplexed by session_id.
+	// up. Sessions are multiplexed by session_id. Agents that set push_commands
+	// in StreamRegister also receive one-shot CommandRequests on it and return
+	// their results on it.
 	OpenStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AgentStreamMessage, CentralStreamMessage], error)
 	// GetPendingCommands is called by the agent to poll for commands to execute.
-	// Returns any pending commands for this agent.
+	// Returns any pending commands for this agent. Agents poll only while they
+	// have no open stream accepting pushed commands.
 	GetPendingCommands(ctx context.Context, in *GetPendingCommandsRequest, opts ...grpc.CallOption) (*GetPendingCommandsResponse, error)
 	// SubmitCommandResult is called by the agent after executing a command.
 	// Submits the command output and exit code back to central.
@@ -127,10 +130,13 @@ type AgentServiceServer interface {
 	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
 	// OpenStream is opened once by the agent after Register. Central pushes
 	// streaming command/control messages down it; the agent streams output back
-	// up. Sessions are multiplexed by session_id.
+	// up. Sessions are multiplexed by session_id. Agents that set push_commands
+	// in StreamRegister also receive one-shot CommandRequests on it and return
+	// their results on it.
 	OpenStream(grpc.BidiStreamingServer[AgentStreamMessage, CentralStreamMessage]) error
 	// GetPendingCommands is called by the agent to poll for commands to execute.
-	// Returns any pending commands for this agent.
+	// Returns any pending commands for this agent. Agents poll only while they
+	// have no open stream accepting pushed commands.
 	GetPendingCommands(context.Context, *GetPendingCommandsRequest) (*GetPendingCommandsResponse, error)
 	// SubmitCommandResult is called by the agent after executing a command.
 	// Submits the command output and exit code back to central.
@@ -11,6 +11,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	configPath := flag.String("config", "", "Path to config file")
 	showVersion := flag.Bool("version", false, "print version and exit")
 	flag.Parse()
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/why-xn/kbridge/internal/central"
+)
+
+const migrateUsage = `Usage: kbridge-central migrate [-config path] <status|up>
+
+  status  list every schema migration and whether it is applied
+  up      apply all pending migrations
+`
+
+// runMigrate implements `kbridge-central migrate status|up` and returns the
+// process exit code.
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (fs.Arg(0) != "status" && fs.Arg(0) != "up") {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	store, err := central.OpenStore(cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if fs.Arg(0) == "up" {
+		if err := store.Migrate(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			return 1
+		}
+	}
+
+	statuses, err := store.MigrationStatus(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read migration status: %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, st := range statuses {
+		applied := "-"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Version, st.Name, st.State, applied)
+	}
+	w.Flush()
+
+	if err := central.CheckSchema(statuses); err != nil {
+		fmt.Fprintf(os.Stderr, "\n%v\n", err)
+		return 1
+	}
+	return 0
+}
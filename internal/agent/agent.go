@@ -6,41 +6,73 @@ import (
 	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/why-xn/kbridge/api/proto/agentpb"
 	"google.golang.org/grpc"
 )
 
-// DefaultPollInterval is how often the agent polls for pending commands.
+// DefaultPollInterval is how often the agent polls for pending commands while
+// central is not pushing them on the stream.
 const DefaultPollInterval = 2 * time.Second
 
+// defaultHeartbeatInterval is used until central asks for a different one.
+const defaultHeartbeatInterval = 30 * time.Second
+
 // Agent represents the kbridge agent that connects to central service.
 type Agent struct {
 	config    *Config
 	conn      *grpc.ClientConn
 	client    agentpb.AgentServiceClient
-	executor  *KubectlExecutor
+	executor  Executor
+	pool      *workerPool
+	commands  *commandCancels
 	agentID   string
 	mu        sync.RWMutex
 	stopCh    chan struct{}
 	stoppedCh chan struct{}
+
+	// commandsPushed is set while central pushes commands on the stream;
+	// polling is skipped meanwhile.
+	commandsPushed atomic.Bool
+
+	// Health and readiness state served by the HTTP listener.
+	streamOpen        atomic.Bool
+	lastHeartbeat     atomic.Int64 // unix nanos of the last successful heartbeat
+	heartbeatInterval atomic.Int64 // nanoseconds
 }
 
 // New creates a new agent with the given configuration.
 func New(cfg *Config) *Agent {
-	return &Agent{
+	executor, err := newExecutor(cfg)
+	if err != nil {
+		log.Printf("Native executor unavailable: %v; using kubectl", err)
+	}
+	a := &Agent{
 		config:    cfg,
-		executor:  NewKubectlExecutor(),
+		executor:  executor,
+		pool:      newWorkerPool(cfg.Workers),
+		commands:  newCommandCancels(),
 		stopCh:    make(chan struct{}),
 		stoppedCh: make(chan struct{}),
 	}
+	a.heartbeatInterval.Store(int64(defaultHeartbeatInterval))
+	return a
 }
 
 // Run starts the agent, connecting to central and maintaining the connection.
 func (a *Agent) Run(ctx context.Context) error {
 	log.Printf("Agent starting for cluster: %s", a.config.Cluster.Name)
 
+	if a.config.HTTPAddr != "" {
+		srv, err := a.startHTTP(a.config.HTTPAddr)
+		if err != nil {
+			return fmt.Errorf("starting HTTP server: %w", err)
+		}
+		defer shutdownHTTP(srv)
+	}
+
 	if err := a.connect(ctx); err != nil {
 		return fmt.Errorf("connecting to central: %w", err)
 	}
@@ -118,8 +150,9 @@ func (a *Agent) register(ctx context.Context) error {
 	log.Printf("Registering with central service")
 
 	req := &agentpb.RegisterRequest{
-		AgentToken:  a.config.Central.Token,
-		ClusterName: a.config.Cluster.Name,
+		AgentToken:            a.config.Central.Token,
+		ClusterName:           a.config.Cluster.Name,
+		MaxConcurrentCommands: int32(a.pool.size()),
 	}
 
 	// Add timeout for registration
@@ -144,8 +177,8 @@ func (a *Agent) register(ctx context.Context) error {
 }
 
 func (a *Agent) runHeartbeatLoop(ctx context.Context) {
-	// Default to 30 second interval, will be updated from server response
-	interval := 30 * time.Second
+	// Start with the default interval, will be updated from server response
+	interval := defaultHeartbeatInterval
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -167,6 +200,7 @@ func (a *Agent) runHeartbeatLoop(ctx context.Context) {
 			if nextInterval > 0 && nextInterval != interval {
 				interval = nextInterval
 				ticker.Reset(interval)
+				a.heartbeatInterval.Store(int64(interval))
 			}
 
 		case <-a.stopCh:
@@ -206,8 +240,10 @@ func (a *Agent) sendHeartbeat(ctx context.Context) (time.Duration, error) {
 	return time.Duration(resp.NextHeartbeatSeconds) * time.Second, nil
 }
 
-// touchHealthFile updates the mtime of the health file, creating it if absent.
+// touchHealthFile records a successful heartbeat for /healthz and updates the
+// mtime of the health file, creating it if absent.
 func (a *Agent) touchHealthFile() {
+	a.lastHeartbeat.Store(time.Now().UnixNano())
 	if a.config.HealthFile == "" {
 		return
 	}
@@ -222,6 +258,7 @@ func (a *Agent) touchHealthFile() {
 
 func (a *Agent) reconnect(ctx context.Context) error {
 	log.Printf("Attempting to reconnect to central service")
+	reconnectsTotal.WithLabelValues("grpc").Inc()
 
 	// Close existing connection
 	a.disconnect()
@@ -286,14 +323,16 @@ func (a *Agent) runCommandPollLoop(ctx context.Context) {
 	}
 }
 
-// pollAndExecuteCommands polls for pending commands and executes them.
+// pollAndExecuteCommands polls for as many pending commands as there are idle
+// workers and starts them on the pool.
 func (a *Agent) pollAndExecuteCommands(ctx context.Context) {
 	a.mu.RLock()
 	agentID := a.agentID
 	client := a.client
 	a.mu.RUnlock()
 
-	if agentID == "" || client == nil {
+	idle := a.pool.idle()
+	if agentID == "" || client == nil || a.commandsPushed.Load() || idle == 0 {
 		return
 	}
 
@@ -302,30 +341,71 @@ func (a *Agent) pollAndExecuteCommands(ctx context.Context) {
 	defer cancel()
 
 	resp, err := client.GetPendingCommands(pollCtx, &agentpb.GetPendingCommandsRequest{
-		AgentId: agentID,
+		AgentId:     agentID,
+		MaxCommands: int32(idle),
 	})
 	if err != nil {
 		// Don't log on every poll failure - connection issues are handled by heartbeat
 		return
 	}
 
+	for _, requestID := range resp.CanceledRequestIds {
+		a.cancelCommand(requestID)
+	}
+
 	// Execute each command
 	for _, cmd := range resp.Commands {
 		log.Printf("Executing command %s: %v", cmd.RequestId, cmd.Command)
-		a.executeAndSubmit(ctx, cmd)
+		a.startCommand(ctx, cmd.RequestId, func(ctx context.Context) { a.executeAndSubmit(ctx, cmd) })
 	}
 }
 
-// executeAndSubmit executes a command and submits the result to central.
+// startCommand runs fn for a one-shot command on the worker pool. Until fn
+// returns, cancelCommand(requestID) cancels fn's context: a running command's
+// kubectl is killed, and one still waiting for a worker never starts.
+func (a *Agent) startCommand(ctx context.Context, requestID string, fn func(ctx context.Context)) {
+	cmdCtx, cancel := context.WithCancel(ctx)
+	a.commands.add(requestID, cancel)
+	a.pool.run(func() {
+		defer a.commands.cancel(requestID)
+		fn(cmdCtx)
+	})
+}
+
+// cancelCommand kills a one-shot command that central canceled.
+func (a *Agent) cancelCommand(requestID string) {
+	if a.commands.cancel(requestID) {
+		log.Printf("Command %s canceled by central", requestID)
+	}
+}
+
+// executeAndSubmit executes a command and submits the result to central. The
+// result of a canceled command is dropped: nobody waits for it any more.
 func (a *Agent) executeAndSubmit(ctx context.Context, cmd *agentpb.CommandRequest) {
+	res := a.executeCommand(ctx, cmd)
+	if ctx.Err() != nil {
+		return
+	}
+	a.submitResult(ctx, res)
+}
+
+// executeCommand runs a one-shot command and builds its result.
+func (a *Agent) executeCommand(ctx context.Context, cmd *agentpb.CommandRequest) *agentpb.SubmitCommandResultRequest {
 	// Determine timeout
 	timeout := time.Duration(cmd.TimeoutSeconds) * time.Second
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
+	args, err := a.impersonate(cmd.Command, cmd.User, cmd.Groups)
+	if err != nil {
+		return &agentpb.SubmitCommandResultRequest{RequestId: cmd.RequestId, ExitCode: -1, ErrorMessage: err.Error()}
+	}
+
 	// Execute the kubectl command with optional stdin
-	result := a.executor.ExecuteWithStdin(ctx, cmd.Command, cmd.Namespace, timeout, cmd.Stdin)
+	start := time.Now()
+	result := a.executor.ExecuteWithStdin(ctx, args, cmd.Namespace, timeout, cmd.Stdin)
+	observeKubectl(modeCommand, start, result.ExitCode)
 
 	// Build result request
 	submitReq := &agentpb.SubmitCommandResultRequest{
@@ -337,8 +417,11 @@ func (a *Agent) executeAndSubmit(ctx context.Context, cmd *agentpb.CommandReques
 	if result.Error != nil {
 		submitReq.ErrorMessage = result.Error.Error()
 	}
+	return submitReq
+}
 
-	// Submit result to central
+// submitResult sends a command result to central with SubmitCommandResult.
+func (a *Agent) submitResult(ctx context.Context, submitReq *agentpb.SubmitCommandResultRequest) {
 	a.mu.RLock()
 	client := a.client
 	a.mu.RUnlock()
@@ -348,8 +431,8 @@ func (a *Agent) executeAndSubmit(ctx context.Context, cmd *agentpb.CommandReques
 
 	_, err := client.SubmitCommandResult(submitCtx, submitReq)
 	if err != nil {
-		log.Printf("Failed to submit command result for %s: %v", cmd.RequestId, err)
+		log.Printf("Failed to submit command result for %s: %v", submitReq.RequestId, err)
 	} else {
-		log.Printf("Command %s completed with exit code %d", cmd.RequestId, result.ExitCode)
+		log.Printf("Command %s completed with exit code %d", submitReq.RequestId, submitReq.ExitCode)
 	}
 }
@@ -56,7 +56,7 @@ func (m *mockAgentService) Heartbeat(ctx context.Context, req *agentpb.Heartbeat
 	}, nil
 }
 
-func startMockServer(t *testing.T, svc *mockAgentService) (string, func()) {
+func startMockServer(t *testing.T, svc agentpb.AgentServiceServer) (string, func()) {
 	lis, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
 		t.Fatalf("failed to listen: %v", err)
@@ -4,6 +4,7 @@ package agent
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -14,6 +15,28 @@ type Config struct {
 	Central    CentralConfig `yaml:"central"`
 	Cluster    ClusterConfig `yaml:"cluster"`
 	HealthFile string        `yaml:"health_file"`
+	// HTTPAddr, when set, is the address of a listener serving /healthz,
+	// /readyz and /metrics (e.g. ":8080"). Empty disables it.
+	HTTPAddr string `yaml:"http_addr"`
+	// Workers is how many one-shot commands run at once (0 means
+	// DefaultWorkers). It is advertised to central, which never sends more.
+	Workers int `yaml:"workers"`
+	// Executor selects how commands reach the cluster: "kubectl" (the
+	// default) runs the kubectl binary, "native" calls the API server with
+	// client-go and falls back to kubectl for commands it does not cover.
+	Executor string `yaml:"executor"`
+	// Impersonation makes commands act in the cluster as the kbridge user
+	// who sent them rather than as the agent's ServiceAccount.
+	Impersonation ImpersonationConfig `yaml:"impersonation"`
+}
+
+// ImpersonationConfig controls per-user impersonation. The prefixes are
+// prepended to the user name and to every group, e.g. "kbridge:", so that
+// they cannot collide with names Kubernetes gives meaning to.
+type ImpersonationConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	UserPrefix  string `yaml:"user_prefix"`
+	GroupPrefix string `yaml:"group_prefix"`
 }
 
 // CentralConfig holds the central service connection configuration.
@@ -51,6 +74,8 @@ func DefaultConfig() *Config {
 			Name: "default",
 		},
 		HealthFile: "/tmp/kbridge-agent-healthy",
+		Workers:    DefaultWorkers,
+		Executor:   ExecutorKubectl,
 	}
 }
 
@@ -149,6 +174,22 @@ func applyEnvOverrides(cfg *Config) {
 	if name := os.Getenv("KBRIDGE_CLUSTER_NAME"); name != "" {
 		cfg.Cluster.Name = name
 	}
+	if addr := os.Getenv("KBRIDGE_AGENT_HTTP_ADDR"); addr != "" {
+		cfg.HTTPAddr = addr
+	}
+	if v := os.Getenv("KBRIDGE_AGENT_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Workers = n
+		}
+	}
+	if v := os.Getenv("KBRIDGE_AGENT_EXECUTOR"); v != "" {
+		cfg.Executor = v
+	}
+	if v := os.Getenv("KBRIDGE_AGENT_IMPERSONATE"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.Impersonation.Enabled = b
+		}
+	}
 }
 
 // Validate checks if the configuration is valid.
@@ -162,5 +203,13 @@ func (c *Config) Validate() error {
 	if c.Cluster.Name == "" {
 		return fmt.Errorf("cluster.name is required")
 	}
+	if c.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
+	switch c.Executor {
+	case "", ExecutorKubectl, ExecutorNative:
+	default:
+		return fmt.Errorf("executor must be %q or %q, got %q", ExecutorKubectl, ExecutorNative, c.Executor)
+	}
 	return nil
 }
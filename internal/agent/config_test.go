@@ -184,6 +184,33 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "negative workers",
+			config: &Config{
+				Central: CentralConfig{URL: "localhost:9090", Token: "token"},
+				Cluster: ClusterConfig{Name: "test"},
+				Workers: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "native executor",
+			config: &Config{
+				Central:  CentralConfig{URL: "localhost:9090", Token: "token"},
+				Cluster:  ClusterConfig{Name: "test"},
+				Executor: ExecutorNative,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown executor",
+			config: &Config{
+				Central:  CentralConfig{URL: "localhost:9090", Token: "token"},
+				Cluster:  ClusterConfig{Name: "test"},
+				Executor: "helm",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -265,6 +292,100 @@ cluster:
 	}
 }
 
+func TestLoadConfig_HTTPAddrAndWorkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "agent.yaml")
+	if err := os.WriteFile(configPath, []byte("http_addr: \":8080\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.HTTPAddr != ":8080" {
+		t.Errorf("expected http_addr ':8080', got %q", cfg.HTTPAddr)
+	}
+
+	t.Setenv("KBRIDGE_AGENT_HTTP_ADDR", "127.0.0.1:9100")
+	t.Setenv("KBRIDGE_AGENT_WORKERS", "8")
+	cfg, err = LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.HTTPAddr != "127.0.0.1:9100" {
+		t.Errorf("expected env to override http_addr, got %q", cfg.HTTPAddr)
+	}
+	if cfg.Workers != 8 {
+		t.Errorf("expected env to override workers, got %d", cfg.Workers)
+	}
+}
+
+func TestLoadConfig_Executor(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "agent.yaml")
+	if err := os.WriteFile(configPath, []byte("cluster:\n  name: test\n"), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Executor != ExecutorKubectl {
+		t.Errorf("expected default executor %q, got %q", ExecutorKubectl, cfg.Executor)
+	}
+
+	if err := os.WriteFile(configPath, []byte("executor: native\n"), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+	cfg, err = LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Executor != ExecutorNative {
+		t.Errorf("expected executor %q, got %q", ExecutorNative, cfg.Executor)
+	}
+
+	t.Setenv("KBRIDGE_AGENT_EXECUTOR", ExecutorKubectl)
+	cfg, err = LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Executor != ExecutorKubectl {
+		t.Errorf("expected env to override executor, got %q", cfg.Executor)
+	}
+}
+
+func TestLoadConfig_Impersonation(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "agent.yaml")
+	yaml := "impersonation:\n  user_prefix: \"kbridge:\"\n  group_prefix: \"kbridge:\"\n"
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Impersonation.Enabled {
+		t.Error("expected impersonation to be off by default")
+	}
+	if cfg.Impersonation.UserPrefix != "kbridge:" || cfg.Impersonation.GroupPrefix != "kbridge:" {
+		t.Errorf("unexpected prefixes: %+v", cfg.Impersonation)
+	}
+
+	t.Setenv("KBRIDGE_AGENT_IMPERSONATE", "true")
+	cfg, err = LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !cfg.Impersonation.Enabled {
+		t.Error("expected env to enable impersonation")
+	}
+}
+
 func TestLoadConfig_KBRIDGEAgentTokenOverridesAgentToken(t *testing.T) {
 	// KBRIDGE_AGENT_TOKEN should take precedence over AGENT_TOKEN
 	os.Setenv("AGENT_TOKEN", "legacy-token")
@@ -22,6 +22,31 @@ type CommandResult struct {
 	Error    error
 }
 
+// Executor runs the kubectl command lines an agent receives from central.
+// KubectlExecutor runs them with the kubectl binary; NativeExecutor talks to
+// the API server with client-go and hands what it does not support to
+// kubectl.
+type Executor interface {
+	// ExecuteWithStdin runs a one-shot command with optional stdin input.
+	ExecuteWithStdin(ctx context.Context, args []string, namespace string, timeout time.Duration, stdin []byte) *CommandResult
+
+	// ExecuteInteractiveNoTTY runs a command with stdin pumped from the
+	// channel, streaming stdout/stderr to onOutput, until it exits or ctx is
+	// cancelled. onOutput may be called concurrently.
+	ExecuteInteractiveNoTTY(ctx context.Context, args []string, namespace string, stdin <-chan []byte, onOutput func(bool, []byte)) (int, error)
+
+	// ExecuteInteractive runs a command on a terminal of the given size,
+	// applying resize events, until it exits or ctx is cancelled. onOutput
+	// is never called concurrently.
+	ExecuteInteractive(ctx context.Context, args []string, namespace string, rows, cols uint16, stdin <-chan []byte, resize <-chan [2]uint16, onOutput func([]byte)) (int, error)
+
+	// PortForward forwards ports of a pod to ephemeral ports on 127.0.0.1
+	// and returns the remote->local map once all are listening. The forward
+	// lasts until ctx is cancelled or it fails; done is closed then. flags
+	// are global kubectl flags for the forward, such as --as.
+	PortForward(ctx context.Context, pod, namespace string, ports []uint32, flags []string) (m map[uint16]uint16, done <-chan struct{}, err error)
+}
+
 // KubectlExecutor executes kubectl commands on the local cluster.
 type KubectlExecutor struct {
 	kubectlPath string
@@ -91,7 +116,7 @@ const streamChunkSize = 32 * 1024
 
 // ExecuteStream runs a kubectl command, invoking onChunk for each piece of
 // output as it is produced. Cancelling ctx kills the process.
-// onChunk may be called concurrently from the stdout and stderr readers;
+// onChunk may be called concurrently from the stdout and stderr copiers;
 // callers must synchronize access to any shared state touched inside onChunk.
 func (e *KubectlExecutor) ExecuteStream(ctx context.Context, args []string, namespace string, onChunk func(stdout bool, data []byte)) (int, error) {
 	cmdArgs := args
@@ -99,33 +124,18 @@ func (e *KubectlExecutor) ExecuteStream(ctx context.Context, args []string, name
 		cmdArgs = append([]string{"-n", namespace}, args...)
 	}
 	cmd := exec.CommandContext(ctx, e.kubectlPath, cmdArgs...)
-	// WaitDelay ensures pipes are force-closed after context cancellation so that
-	// any goroutines blocked on Read are unblocked even if child processes
-	// inherited and still hold the pipe handles.
+	// WaitDelay ensures the output copiers are stopped after context
+	// cancellation even if child processes inherited and still hold the pipes.
 	cmd.WaitDelay = 500 * time.Millisecond
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return -1, fmt.Errorf("stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return -1, fmt.Errorf("stderr pipe: %w", err)
-	}
+	// Let os/exec own the pipes: Wait returns only after all output has been
+	// copied, so nothing written before exit is lost.
+	cmd.Stdout = chunkWriter{stdout: true, onChunk: onChunk}
+	cmd.Stderr = chunkWriter{stdout: false, onChunk: onChunk}
 	if err := cmd.Start(); err != nil {
 		return -1, fmt.Errorf("starting kubectl: %w", err)
 	}
 
-	// waitCh carries the result of cmd.Wait so we can unblock pipe readers.
-	waitCh := make(chan error, 1)
-	go func() { waitCh <- cmd.Wait() }()
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go pumpStream(&wg, stdout, true, onChunk)
-	go pumpStream(&wg, stderr, false, onChunk)
-	wg.Wait()
-
-	waitErr := <-waitCh
+	waitErr := cmd.Wait()
 	if waitErr != nil {
 		if exitErr, ok := waitErr.(*exec.ExitError); ok {
 			return exitErr.ExitCode(), nil
@@ -135,6 +145,19 @@ func (e *KubectlExecutor) ExecuteStream(ctx context.Context, args []string, name
 	return 0, nil
 }
 
+// chunkWriter passes each write to onChunk as its own copy of the data.
+type chunkWriter struct {
+	stdout  bool
+	onChunk func(bool, []byte)
+}
+
+func (w chunkWriter) Write(p []byte) (int, error) {
+	chunk := make([]byte, len(p))
+	copy(chunk, p)
+	w.onChunk(w.stdout, chunk)
+	return len(p), nil
+}
+
 func pumpStream(wg *sync.WaitGroup, r io.Reader, stdout bool, onChunk func(bool, []byte)) {
 	defer wg.Done()
 	buf := make([]byte, streamChunkSize)
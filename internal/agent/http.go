@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/why-xn/kbridge/internal/metrics"
+)
+
+// healthStaleHeartbeats is how many heartbeat intervals may pass without a
+// successful heartbeat before /healthz reports the agent unhealthy.
+const healthStaleHeartbeats = 3
+
+// startHTTP listens on addr and serves /healthz, /readyz and /metrics until
+// the returned server is shut down.
+func (a *Agent) startHTTP(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	srv := &http.Server{Handler: a.httpHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP server error: %v", err)
+		}
+	}()
+	log.Printf("Serving health and metrics on %s", ln.Addr())
+	return srv, nil
+}
+
+func shutdownHTTP(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+}
+
+// httpHandler routes the agent's health and metrics endpoints.
+func (a *Agent) httpHandler() http.Handler {
+	live := metrics.NewRegistry()
+	live.NewGaugeFunc("kbridge_agent_info",
+		"Always 1; labels identify the cluster and the agent ID assigned by central.",
+		[]string{"cluster", "agent_id"}, func(set func(float64, ...string)) {
+			set(1, a.config.Cluster.Name, a.AgentID())
+		})
+	live.NewGaugeFunc("kbridge_agent_ready",
+		"1 when the agent is registered with central and its command stream is open.",
+		nil, func(set func(float64, ...string)) {
+			if ok, _ := a.ready(); ok {
+				set(1)
+			} else {
+				set(0)
+			}
+		})
+
+	live.NewGaugeFunc("kbridge_agent_command_workers",
+		"Workers for one-shot commands, by state (busy or idle).",
+		[]string{"state"}, func(set func(float64, ...string)) {
+			set(float64(a.pool.busy()), "busy")
+			set(float64(a.pool.idle()), "idle")
+		})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if ok, reason := a.healthy(time.Now()); !ok {
+			writeStatus(w, http.StatusServiceUnavailable, "unhealthy", reason)
+			return
+		}
+		writeStatus(w, http.StatusOK, "ok", "")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if ok, reason := a.ready(); !ok {
+			writeStatus(w, http.StatusServiceUnavailable, "not ready", reason)
+			return
+		}
+		writeStatus(w, http.StatusOK, "ready", "")
+	})
+	mux.Handle("GET /metrics", metrics.Handler(metricsRegistry, live))
+	return mux
+}
+
+func writeStatus(w http.ResponseWriter, code int, status, reason string) {
+	body := map[string]string{"status": status}
+	if reason != "" {
+		body["reason"] = reason
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// healthy reports whether the last successful heartbeat is recent. Before
+// the first heartbeat the agent is still starting and counts as healthy.
+func (a *Agent) healthy(now time.Time) (bool, string) {
+	last := a.lastHeartbeat.Load()
+	if last == 0 {
+		return true, ""
+	}
+	interval := time.Duration(a.heartbeatInterval.Load())
+	if age := now.Sub(time.Unix(0, last)); age > healthStaleHeartbeats*interval {
+		return false, fmt.Sprintf("no successful heartbeat for %s", age.Round(time.Second))
+	}
+	return true, ""
+}
+
+// ready reports whether the agent is registered with central and has its
+// command stream open.
+func (a *Agent) ready() (bool, string) {
+	if a.AgentID() == "" {
+		return false, "not registered with central"
+	}
+	if !a.streamOpen.Load() {
+		return false, "command stream not open"
+	}
+	return true, ""
+}
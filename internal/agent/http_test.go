@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgent_HTTPEndpoints(t *testing.T) {
+	a := New(&Config{Cluster: ClusterConfig{Name: "http-cluster"}})
+	h := a.httpHandler()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("healthz while starting: want 200, got %d", w.Code)
+	}
+	a.lastHeartbeat.Store(time.Now().Add(-time.Hour).UnixNano())
+	if w := get("/healthz"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "no successful heartbeat") {
+		t.Errorf("healthz with stale heartbeat: %d %s", w.Code, w.Body.String())
+	}
+	a.touchHealthFile()
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("healthz after heartbeat: want 200, got %d", w.Code)
+	}
+
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "not registered") {
+		t.Errorf("readyz before register: %d %s", w.Code, w.Body.String())
+	}
+	a.agentID = "http-agent"
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "stream not open") {
+		t.Errorf("readyz without stream: %d %s", w.Code, w.Body.String())
+	}
+	a.streamOpen.Store(true)
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("readyz: want 200, got %d %s", w.Code, w.Body.String())
+	}
+
+	observeKubectl(modeCommand, time.Now(), 3)
+	w := get("/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics: %d", w.Code)
+	}
+	for _, want := range []string{
+		`kbridge_agent_info{cluster="http-cluster",agent_id="http-agent"} 1`,
+		"kbridge_agent_ready 1",
+		`kbridge_agent_command_workers{state="idle"} 4`,
+		`kbridge_agent_kubectl_exit_codes_total{mode="command",code="3"} `,
+		`kbridge_agent_kubectl_duration_seconds_count{mode="command"} `,
+		"# TYPE kbridge_agent_reconnects_total counter",
+		"# TYPE kbridge_agent_portforward_connections_active gauge",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestAgent_StartHTTP(t *testing.T) {
+	a := New(&Config{})
+	srv, err := a.startHTTP("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdownHTTP(srv)
+	if _, err := a.startHTTP("256.0.0.1:0"); err == nil {
+		t.Error("want an error for an invalid address")
+	}
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// identityFlags are kubectl flags that pick the identity or credentials a
+// command runs with, or the server it connects to and how it is verified.
+// While impersonation is on they would let a user step outside it, or send
+// the agent's ServiceAccount token to another host, so commands carrying them
+// are refused.
+var identityFlags = map[string]bool{
+	"--as": true, "--as-group": true, "--as-uid": true, "--user": true,
+	"--context": true, "--cluster": true, "--kubeconfig": true, "--kuberc": true,
+	"--token": true, "--username": true, "--password": true,
+	"--client-certificate": true, "--client-key": true,
+	"-s": true, "--server": true, "--insecure-skip-tls-verify": true,
+	"--certificate-authority": true, "--tls-server-name": true,
+}
+
+// valueShorthands are the short kubectl flags whose value may be attached
+// ("-nprod"); letters after one of them in a bundle are its value.
+const valueShorthands = "nolckLev"
+
+// identityFlag returns the identity flag arg sets, if any. Short flags are
+// matched inside bundles and with attached values ("-shttps://x").
+func identityFlag(arg string) string {
+	if strings.HasPrefix(arg, "--") {
+		name, _, _ := strings.Cut(arg, "=")
+		if identityFlags[name] {
+			return name
+		}
+		return ""
+	}
+	if !strings.HasPrefix(arg, "-") {
+		return ""
+	}
+	for _, r := range arg[1:] {
+		short := "-" + string(r)
+		if identityFlags[short] {
+			return short
+		}
+		if strings.ContainsRune(valueShorthands, r) {
+			break
+		}
+	}
+	return ""
+}
+
+// impersonationFlags returns the kubectl flags that make a command act as
+// user and groups, or nil when impersonation is off. With it on, a command
+// is refused rather than run as the agent when central did not say who sent
+// it, when it names its own identity in args, or when the user or a group
+// would land in Kubernetes' reserved system: names.
+func (a *Agent) impersonationFlags(args []string, user string, groups []string) ([]string, error) {
+	cfg := a.config.Impersonation
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if user == "" {
+		return nil, errors.New("impersonation is enabled but the request does not name a user")
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if name := identityFlag(arg); name != "" {
+			return nil, fmt.Errorf("%s is not allowed: commands run as the requesting user", name)
+		}
+	}
+
+	names := []string{cfg.UserPrefix + user}
+	for _, g := range groups {
+		names = append(names, cfg.GroupPrefix+g)
+	}
+	flags := make([]string, len(names))
+	for i, name := range names {
+		if strings.HasPrefix(name, "system:") {
+			return nil, fmt.Errorf("refusing to impersonate %q", name)
+		}
+		flags[i] = "--as-group=" + name
+	}
+	flags[0] = "--as=" + names[0]
+	return flags, nil
+}
+
+// impersonate prefixes a command's arguments with the impersonation flags
+// for its sender.
+func (a *Agent) impersonate(args []string, user string, groups []string) ([]string, error) {
+	flags, err := a.impersonationFlags(args, user, groups)
+	if err != nil || flags == nil {
+		return args, err
+	}
+	return append(flags, args...), nil
+}
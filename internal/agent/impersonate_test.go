@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/why-xn/kbridge/api/proto/agentpb"
+)
+
+func TestAgent_ImpersonationFlags(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  ImpersonationConfig
+		args    []string
+		user    string
+		groups  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "disabled", args: []string{"get", "pods"}, user: "dev@x.com", want: nil},
+		{name: "disabled ignores identity flags", args: []string{"get", "pods", "--as=admin"}, want: nil},
+		{
+			name:   "user and groups",
+			config: ImpersonationConfig{Enabled: true},
+			args:   []string{"get", "pods"},
+			user:   "dev@x.com",
+			groups: []string{"sre", "oncall"},
+			want:   []string{"--as=dev@x.com", "--as-group=sre", "--as-group=oncall"},
+		},
+		{
+			name:   "prefixes",
+			config: ImpersonationConfig{Enabled: true, UserPrefix: "kbridge:", GroupPrefix: "kbridge:"},
+			user:   "dev@x.com",
+			groups: []string{"system:masters"},
+			want:   []string{"--as=kbridge:dev@x.com", "--as-group=kbridge:system:masters"},
+		},
+		{
+			name:   "container command may use identity flags",
+			config: ImpersonationConfig{Enabled: true},
+			args:   []string{"exec", "web", "--", "kubectl", "--as=admin"},
+			user:   "dev@x.com",
+			want:   []string{"--as=dev@x.com"},
+		},
+		{name: "no user", config: ImpersonationConfig{Enabled: true}, args: []string{"get", "pods"}, wantErr: true},
+		{name: "own --as", config: ImpersonationConfig{Enabled: true}, args: []string{"get", "pods", "--as=admin"}, user: "dev@x.com", wantErr: true},
+		{name: "own kubeconfig", config: ImpersonationConfig{Enabled: true}, args: []string{"--kubeconfig", "/tmp/k", "get", "pods"}, user: "dev@x.com", wantErr: true},
+		{name: "system group", config: ImpersonationConfig{Enabled: true}, user: "dev@x.com", groups: []string{"system:masters"}, wantErr: true},
+		{name: "system user", config: ImpersonationConfig{Enabled: true}, user: "system:admin", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := &Agent{config: &Config{Impersonation: c.config}}
+			got, err := a.impersonationFlags(c.args, c.user, c.groups)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("impersonationFlags() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("impersonationFlags() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAgent_ImpersonationFlags_RefusesOverrides(t *testing.T) {
+	a := &Agent{config: &Config{Impersonation: ImpersonationConfig{Enabled: true}}}
+	var cases [][]string
+	for _, flag := range []string{
+		"--as", "--as-group", "--as-uid", "--user", "--context", "--cluster",
+		"--kubeconfig", "--kuberc", "--token", "--username", "--password",
+		"--client-certificate", "--client-key", "-s", "--server",
+		"--certificate-authority", "--tls-server-name", "--insecure-skip-tls-verify",
+	} {
+		cases = append(cases,
+			[]string{"get", "pods", flag + "=x"},
+			[]string{"get", "pods", flag, "x"})
+	}
+	cases = append(cases,
+		[]string{"--insecure-skip-tls-verify", "get", "pods"},
+		[]string{"get", "pods", "-shttps://attacker"},
+		[]string{"exec", "-its", "https://attacker", "web", "--", "sh"})
+	for _, args := range cases {
+		if _, err := a.impersonationFlags(args, "dev@x.com", nil); err == nil {
+			t.Errorf("impersonationFlags(%q) allowed the command", args)
+		}
+	}
+
+	// Short flags whose value happens to contain an "s" are not overrides.
+	for _, args := range [][]string{
+		{"get", "pods", "-nsre"},
+		{"get", "pods", "-ojsonpath={.items}"},
+		{"exec", "-it", "web", "--", "sh", "-s"},
+	} {
+		if _, err := a.impersonationFlags(args, "dev@x.com", nil); err != nil {
+			t.Errorf("impersonationFlags(%q) error = %v", args, err)
+		}
+	}
+}
+
+func TestAgent_ExecuteCommand_Impersonates(t *testing.T) {
+	exec := &fakeExecutor{}
+	a := &Agent{config: &Config{Impersonation: ImpersonationConfig{Enabled: true}}, executor: exec}
+
+	res := a.executeCommand(context.Background(), &agentpb.CommandRequest{
+		RequestId: "req-1", Command: []string{"get", "pods"}, User: "dev@x.com", Groups: []string{"sre"},
+	})
+	if res.ErrorMessage != "" {
+		t.Fatalf("unexpected error: %s", res.ErrorMessage)
+	}
+	want := [][]string{{"--as=dev@x.com", "--as-group=sre", "get", "pods"}}
+	if !reflect.DeepEqual(exec.args, want) {
+		t.Errorf("executor ran %q, want %q", exec.args, want)
+	}
+
+	// Without a user the command is refused, not run as the agent.
+	res = a.executeCommand(context.Background(), &agentpb.CommandRequest{RequestId: "req-2", Command: []string{"get", "pods"}})
+	if res.ErrorMessage == "" || res.ExitCode != -1 {
+		t.Errorf("expected a refused command, got exit %d error %q", res.ExitCode, res.ErrorMessage)
+	}
+	if len(exec.args) != 1 {
+		t.Errorf("refused command reached the executor: %q", exec.args)
+	}
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/why-xn/kbridge/internal/metrics"
+)
+
+// Execution modes, used as the "mode" label of the kubectl metrics.
+const (
+	modeCommand     = "command"
+	modeStream      = "stream"
+	modeInteractive = "interactive"
+)
+
+// Session kinds, used as the "kind" label of kbridge_agent_stream_sessions_active.
+const (
+	sessionKindStream      = "stream"
+	sessionKindInteractive = "interactive"
+	sessionKindPortForward = "port-forward"
+)
+
+// Event metrics are process-wide; the gauges that read agent state are
+// registered per listener in serveHTTP.
+var (
+	metricsRegistry = metrics.NewRegistry()
+
+	kubectlInvocationsTotal = metricsRegistry.NewCounterVec("kbridge_agent_kubectl_invocations_total",
+		"kubectl processes run, by mode (command, stream or interactive).", "mode")
+	kubectlDurationSeconds = metricsRegistry.NewHistogramVec("kbridge_agent_kubectl_duration_seconds",
+		"Wall time of kubectl processes, by mode.",
+		[]float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300}, "mode")
+	kubectlExitCodesTotal = metricsRegistry.NewCounterVec("kbridge_agent_kubectl_exit_codes_total",
+		"kubectl exit codes by mode; -1 means kubectl could not be run or was killed.", "mode", "code")
+	streamSessionsActive = metricsRegistry.NewGaugeVec("kbridge_agent_stream_sessions_active",
+		"Open stream sessions by kind (stream, interactive or port-forward).", "kind")
+	portForwardConnsActive = metricsRegistry.NewGauge("kbridge_agent_portforward_connections_active",
+		"Open TCP connections across all port-forward sessions.")
+	portForwardConnsTotal = metricsRegistry.NewCounter("kbridge_agent_portforward_connections_total",
+		"TCP connections opened for port-forward sessions.")
+	reconnectsTotal = metricsRegistry.NewCounterVec("kbridge_agent_reconnects_total",
+		"Reconnects to central, by connection (grpc or stream).", "connection")
+)
+
+// observeKubectl records one finished kubectl process.
+func observeKubectl(mode string, start time.Time, exitCode int) {
+	kubectlInvocationsTotal.WithLabelValues(mode).Inc()
+	kubectlDurationSeconds.WithLabelValues(mode).Observe(time.Since(start).Seconds())
+	kubectlExitCodesTotal.WithLabelValues(mode, strconv.Itoa(exitCode)).Inc()
+}
@@ -0,0 +1,496 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// Executor backends, selected with the agent's executor setting.
+const (
+	ExecutorKubectl = "kubectl"
+	ExecutorNative  = "native"
+)
+
+// NativeExecutor runs common kubectl commands with client-go instead of the
+// kubectl binary: get, describe, logs, exec, port-forward and apply. A
+// command it does not support, or that uses a flag it does not know, runs on
+// the fallback executor, so no command changes behaviour by being half
+// understood.
+type NativeExecutor struct {
+	config    *rest.Config
+	namespace string // default namespace, as kubectl would pick it
+	client    kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	fallback  Executor
+}
+
+// NewNativeExecutor connects to the cluster the way kubectl does: with
+// $KUBECONFIG or ~/.kube/config when present, otherwise with the pod's
+// service account. Unsupported commands run on fallback.
+func NewNativeExecutor(fallback Executor) (*NativeExecutor, error) {
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading cluster credentials: %w", err)
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("resolving default namespace: %w", err)
+	}
+	return newNativeExecutor(cfg, namespace, fallback)
+}
+
+func newNativeExecutor(cfg *rest.Config, namespace string, fallback Executor) (*NativeExecutor, error) {
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating dynamic client: %w", err)
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	// Discovery is cached and refreshed when a type is not found, so CRDs
+	// installed after startup resolve too.
+	cached := memory.NewMemCacheClient(client.Discovery())
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cached), cached, nil)
+	return &NativeExecutor{
+		config:    cfg,
+		namespace: namespace,
+		client:    client,
+		dynamic:   dyn,
+		mapper:    mapper,
+		fallback:  fallback,
+	}, nil
+}
+
+// newExecutor returns the executor selected by cfg. If the native executor
+// cannot reach cluster credentials the agent logs why and uses kubectl.
+func newExecutor(cfg *Config) (Executor, error) {
+	kubectl := NewKubectlExecutor()
+	if cfg.Executor != ExecutorNative {
+		return kubectl, nil
+	}
+	native, err := NewNativeExecutor(kubectl)
+	if err != nil {
+		return kubectl, err
+	}
+	return native, nil
+}
+
+// parse parses a command line and reports whether the native executor runs
+// it. describe is left to kubectl for built-in kinds: kubectl prints most of
+// them with dedicated describers, and the native one matches it only for
+// custom resources.
+func (e *NativeExecutor) parse(args []string, namespace string) (*nativeCommand, bool) {
+	cmd, ok := parseNativeCommand(args, namespace)
+	if !ok {
+		return nil, false
+	}
+	if cmd.verb == "describe" {
+		// An unknown type stays native, which reports it as kubectl would.
+		if mapping, err := e.mapping(cmd.resource); err == nil && scheme.Scheme.Recognizes(mapping.GroupVersionKind) {
+			return nil, false
+		}
+	}
+	return cmd, true
+}
+
+// ExecuteWithStdin runs a one-shot command.
+func (e *NativeExecutor) ExecuteWithStdin(ctx context.Context, args []string, namespace string, timeout time.Duration, stdin []byte) *CommandResult {
+	cmd, ok := e.parse(args, namespace)
+	if !ok {
+		return e.fallback.ExecuteWithStdin(ctx, args, namespace, timeout, stdin)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	stdio := nativeIO{stdout: &stdout, stderr: &stderr}
+	if len(stdin) > 0 {
+		stdio.stdin = bytes.NewReader(stdin)
+	}
+	// Like a killed kubectl, a cancelled command exits with -1 and no error.
+	code, _ := e.run(ctx, cmd, stdio)
+	return &CommandResult{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), ExitCode: code}
+}
+
+// ExecuteInteractiveNoTTY runs a streaming command such as logs -f or exec -i.
+func (e *NativeExecutor) ExecuteInteractiveNoTTY(ctx context.Context, args []string, namespace string, stdin <-chan []byte, onOutput func(bool, []byte)) (int, error) {
+	cmd, ok := e.parse(args, namespace)
+	if !ok {
+		return e.fallback.ExecuteInteractiveNoTTY(ctx, args, namespace, stdin, onOutput)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	in := pipeStdin(ctx, stdin)
+	defer in.Close()
+	return e.run(ctx, cmd, nativeIO{
+		stdin:  in,
+		stdout: chunkWriter{stdout: true, onChunk: onOutput},
+		stderr: chunkWriter{stdout: false, onChunk: onOutput},
+	})
+}
+
+// ExecuteInteractive runs exec -it; other commands need a local terminal and
+// run on the fallback executor.
+func (e *NativeExecutor) ExecuteInteractive(ctx context.Context, args []string, namespace string, rows, cols uint16, stdin <-chan []byte, resize <-chan [2]uint16, onOutput func([]byte)) (int, error) {
+	cmd, ok := e.parse(args, namespace)
+	if !ok || cmd.verb != "exec" {
+		return e.fallback.ExecuteInteractive(ctx, args, namespace, rows, cols, stdin, resize, onOutput)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	in := pipeStdin(ctx, stdin)
+	defer in.Close()
+	// With a TTY the remote side sends everything on stdout, from a single
+	// goroutine.
+	out := chunkWriter{stdout: true, onChunk: func(_ bool, data []byte) { onOutput(data) }}
+	return e.run(ctx, cmd, nativeIO{
+		stdin:  in,
+		stdout: out,
+		stderr: out,
+		sizes:  &sizeQueue{ctx: ctx, next: &remotecommand.TerminalSize{Height: rows, Width: cols}, resize: resize},
+	})
+}
+
+// PortForward forwards ports of a pod with client-go's port forwarder.
+// Targets other than a pod, such as svc/name, run on the fallback executor.
+func (e *NativeExecutor) PortForward(ctx context.Context, pod, namespace string, ports []uint32, flags []string) (map[uint16]uint16, <-chan struct{}, error) {
+	name, ok := podName(pod)
+	user, groups, known := parseImpersonation(flags)
+	if !ok || !known {
+		return e.fallback.PortForward(ctx, pod, namespace, ports, flags)
+	}
+	if namespace == "" {
+		namespace = e.namespace
+	}
+	if user != "" {
+		var err error
+		if e, err = e.impersonating(user, groups); err != nil {
+			return nil, nil, err
+		}
+	}
+	u := e.client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(name).SubResource("portforward").URL()
+	dialer, err := e.portForwardDialer(u)
+	if err != nil {
+		return nil, nil, err
+	}
+	specs := make([]string, len(ports))
+	for i, p := range ports {
+		specs[i] = fmt.Sprintf("0:%d", p)
+	}
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, specs, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, nil, fmt.Errorf("port-forward: %w", err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- fw.ForwardPorts() }()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return nil, nil, fmt.Errorf("port-forward: %w", err)
+	case <-time.After(15 * time.Second):
+		close(stopCh)
+		return nil, nil, fmt.Errorf("port-forward establishment timed out")
+	case <-ctx.Done():
+		close(stopCh)
+		return nil, nil, fmt.Errorf("port-forward canceled during establishment")
+	}
+	forwarded, err := fw.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return nil, nil, fmt.Errorf("port-forward: %w", err)
+	}
+	m := make(map[uint16]uint16, len(forwarded))
+	for _, p := range forwarded {
+		m[p.Remote] = p.Local
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			close(stopCh)
+			<-errCh
+		case <-errCh:
+		}
+	}()
+	return m, done, nil
+}
+
+// impersonating returns a copy of e whose requests act as user and groups.
+// Discovery stays shared and keeps the agent's own identity.
+func (e *NativeExecutor) impersonating(user string, groups []string) (*NativeExecutor, error) {
+	cfg := rest.CopyConfig(e.config)
+	cfg.Impersonate = rest.ImpersonationConfig{UserName: user, Groups: groups}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating client for %s: %w", user, err)
+	}
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating dynamic client for %s: %w", user, err)
+	}
+	c := *e
+	c.config, c.client, c.dynamic = cfg, client, dyn
+	return &c, nil
+}
+
+// nativeIO carries a native command's streams. stdin is nil when there is no
+// input; sizes is set for commands on a terminal.
+type nativeIO struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	sizes  remotecommand.TerminalSizeQueue
+}
+
+// run executes a parsed command and returns its exit code. Failures are
+// written to stderr the way kubectl reports them, with exit code 1; the error
+// is only set when ctx ended the command.
+func (e *NativeExecutor) run(ctx context.Context, cmd *nativeCommand, stdio nativeIO) (int, error) {
+	if cmd.namespace == "" {
+		cmd.namespace = e.namespace
+	}
+	var err error
+	if cmd.as != "" {
+		if e, err = e.impersonating(cmd.as, cmd.asGroups); err != nil {
+			writeKubectlError(stdio.stderr, err)
+			return 1, nil
+		}
+	}
+	switch cmd.verb {
+	case "get":
+		err = e.get(ctx, cmd, stdio.stdout, stdio.stderr)
+	case "describe":
+		err = e.describe(ctx, cmd, stdio.stdout, stdio.stderr)
+	case "logs":
+		err = e.logs(ctx, cmd, stdio.stdout, stdio.stderr)
+	case "exec":
+		err = e.exec(ctx, cmd, stdio)
+	case "apply":
+		err = e.apply(ctx, cmd, stdio.stdin, stdio.stdout, stdio.stderr)
+	}
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), nil
+	}
+	writeKubectlError(stdio.stderr, err)
+	return 1, nil
+}
+
+// logs prints (or with -f follows) a pod's logs.
+func (e *NativeExecutor) logs(ctx context.Context, cmd *nativeCommand, stdout, stderr io.Writer) error {
+	pod, _ := podName(cmd.args[0])
+	container, err := e.container(ctx, cmd.namespace, pod, cmd.flags["container"], stderr)
+	if err != nil {
+		return err
+	}
+	opts := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     cmd.flags["follow"] == "true",
+		Previous:   cmd.flags["previous"] == "true",
+		Timestamps: cmd.flags["timestamps"] == "true",
+	}
+	if cmd.tail >= 0 {
+		opts.TailLines = &cmd.tail
+	}
+	rc, err := e.client.CoreV1().Pods(cmd.namespace).GetLogs(pod, opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(stdout, rc)
+	return err
+}
+
+// exec runs a command in a container, over WebSockets or, on older API
+// servers, SPDY. Like kubectl, -t only takes effect together with -i.
+func (e *NativeExecutor) exec(ctx context.Context, cmd *nativeCommand, stdio nativeIO) error {
+	pod, _ := podName(cmd.args[0])
+	container, err := e.container(ctx, cmd.namespace, pod, cmd.flags["container"], stdio.stderr)
+	if err != nil {
+		return err
+	}
+	stdin := cmd.flags["stdin"] == "true"
+	tty := stdin && cmd.flags["tty"] == "true"
+	u := e.client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(cmd.namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd.command,
+			Stdin:     stdin,
+			Stdout:    true,
+			Stderr:    !tty,
+			TTY:       tty,
+		}, scheme.ParameterCodec).URL()
+	executor, err := e.remoteExecutor(u)
+	if err != nil {
+		return err
+	}
+
+	opts := remotecommand.StreamOptions{Stdout: stdio.stdout, Tty: tty}
+	if !tty {
+		opts.Stderr = stdio.stderr
+	}
+	if stdin {
+		opts.Stdin = stdio.stdin
+		if opts.Stdin == nil {
+			opts.Stdin = bytes.NewReader(nil)
+		}
+	}
+	if tty {
+		opts.TerminalSizeQueue = stdio.sizes
+	}
+	return executor.StreamWithContext(ctx, opts)
+}
+
+// container returns the container to use in a pod: the requested one or,
+// like kubectl, the pod's default-container annotation or else its first
+// container, noting that choice on stderr when there are several.
+func (e *NativeExecutor) container(ctx context.Context, namespace, pod, requested string, stderr io.Writer) (string, error) {
+	if requested != "" {
+		return requested, nil
+	}
+	p, err := e.client.CoreV1().Pods(namespace).Get(ctx, pod, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if name := p.Annotations["kubectl.kubernetes.io/default-container"]; name != "" {
+		return name, nil
+	}
+	if len(p.Spec.Containers) == 0 {
+		return "", fmt.Errorf("pod %s has no containers", pod)
+	}
+	name := p.Spec.Containers[0].Name
+	if len(p.Spec.Containers) > 1 {
+		names := make([]string, len(p.Spec.Containers))
+		for i, c := range p.Spec.Containers {
+			names[i] = c.Name
+		}
+		fmt.Fprintf(stderr, "Defaulted container %q out of: %s\n", name, strings.Join(names, ", "))
+	}
+	return name, nil
+}
+
+// remoteExecutor streams exec over WebSockets, falling back to SPDY for API
+// servers that do not upgrade, as kubectl does.
+func (e *NativeExecutor) remoteExecutor(u *url.URL) (remotecommand.Executor, error) {
+	spdyExec, err := remotecommand.NewSPDYExecutor(e.config, http.MethodPost, u)
+	if err != nil {
+		return nil, fmt.Errorf("creating SPDY executor: %w", err)
+	}
+	wsExec, err := remotecommand.NewWebSocketExecutor(e.config, http.MethodGet, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("creating WebSocket executor: %w", err)
+	}
+	return remotecommand.NewFallbackExecutor(wsExec, spdyExec, isUpgradeFailure)
+}
+
+// portForwardDialer tunnels port-forward over WebSockets, falling back to
+// SPDY like remoteExecutor.
+func (e *NativeExecutor) portForwardDialer(u *url.URL) (httpstream.Dialer, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(e.config)
+	if err != nil {
+		return nil, fmt.Errorf("creating SPDY transport: %w", err)
+	}
+	spdyDialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, u)
+	wsDialer, err := portforward.NewSPDYOverWebsocketDialer(u, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("creating WebSocket dialer: %w", err)
+	}
+	return portforward.NewFallbackDialer(wsDialer, spdyDialer, isUpgradeFailure), nil
+}
+
+func isUpgradeFailure(err error) bool {
+	return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+}
+
+// pipeStdin turns a session's stdin channel into a reader. Closing the reader
+// stops the pump.
+func pipeStdin(ctx context.Context, stdin <-chan []byte) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data, ok := <-stdin:
+				if !ok {
+					return
+				}
+				if _, err := pw.Write(data); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return pr
+}
+
+// sizeQueue hands remotecommand the initial terminal size and then each
+// resize of the session.
+type sizeQueue struct {
+	ctx    context.Context
+	next   *remotecommand.TerminalSize
+	resize <-chan [2]uint16
+}
+
+func (q *sizeQueue) Next() *remotecommand.TerminalSize {
+	if s := q.next; s != nil {
+		q.next = nil
+		return s
+	}
+	select {
+	case <-q.ctx.Done():
+		return nil
+	case ws, ok := <-q.resize:
+		if !ok {
+			return nil
+		}
+		return &remotecommand.TerminalSize{Height: ws[0], Width: ws[1]}
+	}
+}
@@ -0,0 +1,278 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// nativeFlags lists, for each verb the native executor runs, the flags it
+// understands by long name and whether they take a value. The flags in
+// nativeGlobalFlags are accepted for every verb. A command using any other
+// flag runs with kubectl.
+var nativeFlags = map[string]map[string]bool{
+	"get":      {"all-namespaces": false, "output": true, "selector": true},
+	"describe": {"all-namespaces": false, "selector": true, "show-events": false},
+	"logs":     {"container": true, "follow": false, "previous": false, "tail": true, "timestamps": false},
+	"exec":     {"container": true, "stdin": false, "tty": false},
+	"apply":    {"filename": true, "server-side": false, "force-conflicts": false, "field-manager": true},
+}
+
+// nativeGlobalFlags take a value and may appear before or after the verb.
+// --as and --as-group are how the agent impersonates the requester.
+var nativeGlobalFlags = map[string]bool{"namespace": true, "as": true, "as-group": true}
+
+// nativeShortFlags maps each verb's one-letter flags to their long names.
+var nativeShortFlags = map[string]map[rune]string{
+	"get":      {'A': "all-namespaces", 'o': "output", 'l': "selector"},
+	"describe": {'A': "all-namespaces", 'l': "selector"},
+	"logs":     {'c': "container", 'f': "follow", 'p': "previous"},
+	"exec":     {'c': "container", 'i': "stdin", 't': "tty"},
+	"apply":    {'f': "filename"},
+}
+
+// nativeCommand is a kubectl command line the native executor can run.
+type nativeCommand struct {
+	verb      string
+	args      []string          // positional arguments after the verb
+	command   []string          // after "--", for exec
+	flags     map[string]string // by long name; "true" for set booleans
+	namespace string            // empty for the default namespace
+	as        string            // user to impersonate, if any
+	asGroups  []string
+
+	// get, describe
+	resource string
+	names    []string
+
+	// logs
+	tail int64 // -1 for all lines
+}
+
+// parseNativeCommand parses a kubectl command line and reports whether the
+// native executor supports it. namespace is the one central passed alongside
+// the command; -n on the command line overrides it, as it would for kubectl.
+func parseNativeCommand(args []string, namespace string) (*nativeCommand, bool) {
+	cmd := &nativeCommand{flags: make(map[string]string), namespace: namespace, tail: -1}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			cmd.command = args[i+1:]
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if cmd.verb == "" {
+				cmd.verb = arg
+				if nativeFlags[arg] == nil {
+					return nil, false
+				}
+			} else {
+				cmd.args = append(cmd.args, arg)
+			}
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var longs []string
+		if strings.HasPrefix(arg, "--") {
+			longs = []string{name}
+		} else {
+			// One-letter flags, possibly combined as in -it.
+			for _, r := range name {
+				long := "namespace"
+				if r != 'n' {
+					long = nativeShortFlags[cmd.verb][r]
+				}
+				if long == "" {
+					return nil, false
+				}
+				longs = append(longs, long)
+			}
+		}
+		for j, long := range longs {
+			takesValue, known := nativeFlags[cmd.verb][long]
+			if nativeGlobalFlags[long] {
+				takesValue, known = true, true
+			}
+			if !known {
+				return nil, false
+			}
+			last := j == len(longs)-1
+			switch {
+			case takesValue && !last:
+				return nil, false
+			case takesValue && !hasValue:
+				if i+1 >= len(args) {
+					return nil, false
+				}
+				i++
+				cmd.set(long, args[i])
+			case takesValue:
+				cmd.set(long, value)
+			case hasValue && last:
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, false
+				}
+				cmd.flags[long] = strconv.FormatBool(b)
+			default:
+				cmd.flags[long] = "true"
+			}
+		}
+	}
+	if cmd.verb == "" {
+		return nil, false
+	}
+	if ns, ok := cmd.flags["namespace"]; ok {
+		cmd.namespace = ns
+	}
+	return cmd, cmd.supported()
+}
+
+// set records a flag's value. --as-group may be repeated.
+func (c *nativeCommand) set(flag, value string) {
+	switch flag {
+	case "as":
+		c.as = value
+	case "as-group":
+		c.asGroups = append(c.asGroups, value)
+	default:
+		c.flags[flag] = value
+	}
+}
+
+// parseImpersonation reads the global flags passed with a port-forward. It
+// reports false for any flag other than --as and --as-group.
+func parseImpersonation(flags []string) (user string, groups []string, ok bool) {
+	for i := 0; i < len(flags); i++ {
+		name, value, hasValue := strings.Cut(flags[i], "=")
+		if !hasValue {
+			if i+1 >= len(flags) {
+				return "", nil, false
+			}
+			i++
+			value = flags[i]
+		}
+		switch name {
+		case "--as":
+			user = value
+		case "--as-group":
+			groups = append(groups, value)
+		default:
+			return "", nil, false
+		}
+	}
+	return user, groups, true
+}
+
+// supported checks the arguments of a parsed command, filling in the fields
+// its verb uses.
+func (c *nativeCommand) supported() bool {
+	if c.command != nil && c.verb != "exec" {
+		return false
+	}
+	switch c.verb {
+	case "get":
+		switch c.flags["output"] {
+		case "", "wide", "json", "yaml", "name":
+		default:
+			return false
+		}
+		return c.parseResourceArgs()
+	case "describe":
+		// Names are matched as prefixes within one namespace; across all
+		// namespaces kubectl resolves them differently.
+		if !c.parseResourceArgs() {
+			return false
+		}
+		return len(c.names) == 0 || c.flags["all-namespaces"] != "true"
+	case "logs":
+		if tail, ok := c.flags["tail"]; ok {
+			n, err := strconv.ParseInt(tail, 10, 64)
+			if err != nil {
+				return false
+			}
+			c.tail = n
+		}
+		_, ok := podName(firstArg(c.args))
+		return len(c.args) == 1 && ok
+	case "exec":
+		_, ok := podName(firstArg(c.args))
+		return len(c.args) == 1 && ok && len(c.command) > 0
+	case "apply":
+		// Manifests come from stdin only. --force-conflicts is an error
+		// without --server-side, which kubectl reports.
+		if c.flags["force-conflicts"] == "true" && c.flags["server-side"] != "true" {
+			return false
+		}
+		return c.flags["filename"] == "-" && len(c.args) == 0
+	}
+	return false
+}
+
+// parseResourceArgs accepts "TYPE [NAME...]" or "TYPE/NAME..." with a single
+// type. Lists of types are left to kubectl.
+func (c *nativeCommand) parseResourceArgs() bool {
+	if len(c.args) == 0 {
+		return false
+	}
+	if !strings.Contains(c.args[0], "/") {
+		c.resource = c.args[0]
+		for _, n := range c.args[1:] {
+			if strings.Contains(n, "/") {
+				return false
+			}
+			c.names = append(c.names, n)
+		}
+	} else {
+		for _, a := range c.args {
+			typ, name, _ := strings.Cut(a, "/")
+			if c.resource != "" && typ != c.resource || name == "" {
+				return false
+			}
+			c.resource = typ
+			c.names = append(c.names, name)
+		}
+	}
+	return c.resource != "" && !strings.Contains(c.resource, ",")
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// podName returns the pod named by "NAME" or "pod/NAME"; other resource
+// types are not resolved natively.
+func podName(arg string) (string, bool) {
+	typ, name, found := strings.Cut(arg, "/")
+	if !found {
+		return arg, arg != ""
+	}
+	switch typ {
+	case "po", "pod", "pods":
+		return name, name != ""
+	}
+	return "", false
+}
+
+// writeKubectlError reports err on stderr the way kubectl does.
+func writeKubectlError(w io.Writer, err error) {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		s := status.Status()
+		if s.Reason != "" {
+			fmt.Fprintf(w, "Error from server (%s): %s\n", s.Reason, s.Message)
+		} else {
+			fmt.Fprintf(w, "Error from server: %s\n", s.Message)
+		}
+		return
+	}
+	fmt.Fprintf(w, "error: %v\n", err)
+}
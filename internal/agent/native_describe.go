@@ -0,0 +1,378 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/dynamic"
+)
+
+// maxAnnotationLen is where kubectl describe wraps and cuts annotation values.
+const maxAnnotationLen = 140
+
+// describe prints resources in the layout kubectl uses for types without a
+// dedicated describer, such as custom resources: name, namespace, labels and
+// annotations, every other field of the object, then its events. A name that
+// matches no object describes the objects it prefixes, as in kubectl.
+// Built-in kinds run with kubectl instead; see parse.
+func (e *NativeExecutor) describe(ctx context.Context, cmd *nativeCommand, stdout, stderr io.Writer) error {
+	mapping, err := e.mapping(cmd.resource)
+	if err != nil {
+		return err
+	}
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	namespace := cmd.namespace
+	if cmd.flags["all-namespaces"] == "true" || !namespaced {
+		namespace = ""
+	}
+	var ri dynamic.ResourceInterface = e.dynamic.Resource(mapping.Resource)
+	if namespaced {
+		ri = e.dynamic.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	var objs []unstructured.Unstructured
+	if len(cmd.names) == 0 {
+		list, err := ri.List(ctx, metav1.ListOptions{LabelSelector: cmd.flags["selector"]})
+		if err != nil {
+			return err
+		}
+		if len(list.Items) == 0 {
+			if namespace != "" {
+				fmt.Fprintf(stderr, "No resources found in %s namespace.\n", namespace)
+			} else {
+				fmt.Fprintln(stderr, "No resources found")
+			}
+			return nil
+		}
+		objs = list.Items
+	}
+	failed := false
+	for _, name := range cmd.names {
+		obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if matches := prefixMatches(ctx, ri, name); len(matches) > 0 {
+				objs = append(objs, matches...)
+				continue
+			}
+		}
+		if err != nil {
+			if len(cmd.names) == 1 {
+				return err
+			}
+			writeKubectlError(stderr, err)
+			failed = true
+			continue
+		}
+		objs = append(objs, *obj)
+	}
+
+	showEvents := cmd.flags["show-events"] != "false"
+	for i := range objs {
+		if i > 0 {
+			fmt.Fprint(stdout, "\n\n")
+		}
+		var events *corev1.EventList
+		if showEvents {
+			events = e.events(ctx, &objs[i])
+		}
+		if err := describeObject(stdout, &objs[i], events); err != nil {
+			return err
+		}
+	}
+	if failed {
+		return errSomeFailed
+	}
+	return nil
+}
+
+// prefixMatches lists the objects whose name starts with prefix. Errors are
+// dropped: the caller then reports the original not-found error.
+func prefixMatches(ctx context.Context, ri dynamic.ResourceInterface, prefix string) []unstructured.Unstructured {
+	list, err := ri.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+	var matches []unstructured.Unstructured
+	for _, obj := range list.Items {
+		if strings.HasPrefix(obj.GetName(), prefix) {
+			matches = append(matches, obj)
+		}
+	}
+	return matches
+}
+
+// events returns the events about obj, or nil when they cannot be listed;
+// kubectl then leaves the Events section out.
+func (e *NativeExecutor) events(ctx context.Context, obj *unstructured.Unstructured) *corev1.EventList {
+	selector := fields.Set{
+		"involvedObject.name":      obj.GetName(),
+		"involvedObject.namespace": obj.GetNamespace(),
+		"involvedObject.kind":      obj.GetKind(),
+		"involvedObject.uid":       string(obj.GetUID()),
+	}.AsSelector().String()
+	events, err := e.client.CoreV1().Events(obj.GetNamespace()).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil
+	}
+	return events
+}
+
+// prefixWriter indents each line by level, two spaces a level, into a
+// tabwriter that aligns the label and value columns.
+type prefixWriter struct {
+	tw *tabwriter.Writer
+}
+
+func (w prefixWriter) write(level int, format string, a ...interface{}) {
+	fmt.Fprintf(w.tw, strings.Repeat("  ", level)+format, a...)
+}
+
+func describeObject(out io.Writer, obj *unstructured.Unstructured, events *corev1.EventList) error {
+	w := prefixWriter{tw: tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)}
+	w.write(0, "Name:\t%s\n", obj.GetName())
+	w.write(0, "Namespace:\t%s\n", obj.GetNamespace())
+	describeLabels(w, obj.GetLabels())
+	describeAnnotations(w, obj.GetAnnotations())
+	describeContent(w, 0, dataSizes(obj), "",
+		".metadata.managedFields", ".metadata.name", ".metadata.namespace", ".metadata.labels", ".metadata.annotations")
+	if events != nil {
+		describeEvents(w, events)
+	}
+	return w.tw.Flush()
+}
+
+// dataSizes returns the content of obj with the values of a Secret's data, or
+// a ConfigMap's binaryData, replaced by their sizes, as kubectl shows them.
+func dataSizes(obj *unstructured.Unstructured) map[string]interface{} {
+	var field string
+	switch gvk := obj.GroupVersionKind(); {
+	case gvk.Group == "" && gvk.Kind == "Secret":
+		field = "data"
+	case gvk.Group == "" && gvk.Kind == "ConfigMap":
+		field = "binaryData"
+	default:
+		return obj.UnstructuredContent()
+	}
+	data, ok := obj.Object[field].(map[string]interface{})
+	if !ok {
+		return obj.UnstructuredContent()
+	}
+	sizes := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		s, _ := v.(string)
+		b, _ := base64.StdEncoding.DecodeString(s)
+		sizes[k] = fmt.Sprintf("%d bytes", len(b))
+	}
+	content := maps.Clone(obj.UnstructuredContent())
+	content[field] = sizes
+	return content
+}
+
+func describeLabels(w prefixWriter, labels map[string]string) {
+	w.write(0, "Labels:\t")
+	if len(labels) == 0 {
+		w.write(0, "<none>\n")
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i != 0 {
+			w.write(0, "\t")
+		}
+		w.write(0, "%s=%s\n", k, labels[k])
+	}
+}
+
+// describeAnnotations prints annotations one per line, except kubectl's own
+// last-applied configuration. Long or multi-line values go on their own
+// lines, cut at maxAnnotationLen.
+func describeAnnotations(w prefixWriter, annotations map[string]string) {
+	w.write(0, "Annotations:\t")
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		if k != corev1.LastAppliedConfigAnnotation {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		w.write(0, "<none>\n")
+		return
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i != 0 {
+			w.write(0, "\t")
+		}
+		value := strings.TrimSuffix(annotations[k], "\n")
+		if len(value)+len(k)+2 > maxAnnotationLen || strings.Contains(value, "\n") {
+			w.write(0, "%s:\n", k)
+			for _, line := range strings.Split(value, "\n") {
+				if len(line) > maxAnnotationLen-2 {
+					line = line[:maxAnnotationLen-2] + "..."
+				}
+				w.write(0, "\t  %s\n", line)
+			}
+		} else {
+			w.write(0, "%s: %s\n", k, value)
+		}
+	}
+}
+
+// describeContent prints the fields of an object, sorted, as labelled
+// nested sections, leaving out the paths in skip.
+func describeContent(w prefixWriter, level int, content map[string]interface{}, prefix string, skip ...string) {
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := prefix + "." + k
+		if slices.Contains(skip, path) {
+			continue
+		}
+		switch v := content[k].(type) {
+		case map[string]interface{}:
+			w.write(level, "%s:\n", describeLabel(k))
+			describeContent(w, level+1, v, path, skip...)
+		case []interface{}:
+			w.write(level, "%s:\n", describeLabel(k))
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					describeContent(w, level+1, m, path, skip...)
+				} else {
+					w.write(level+1, "%v\n", item)
+				}
+			}
+		default:
+			w.write(level, "%s:\t%v\n", describeLabel(k), v)
+		}
+	}
+}
+
+// describeEvents prints the events table, oldest first, aligned on its own.
+func describeEvents(w prefixWriter, events *corev1.EventList) {
+	if len(events.Items) == 0 {
+		w.write(0, "Events:\t<none>\n")
+		return
+	}
+	w.tw.Flush()
+	items := append([]corev1.Event(nil), events.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].LastTimestamp.Time.Before(items[j].LastTimestamp.Time)
+	})
+	w.write(0, "Events:\n  Type\tReason\tAge\tFrom\tMessage\n")
+	w.write(1, "----\t------\t----\t----\t-------\n")
+	for _, ev := range items {
+		first := since(ev.EventTime.Time)
+		if ev.EventTime.IsZero() {
+			first = since(ev.FirstTimestamp.Time)
+		}
+		age := first
+		switch {
+		case ev.Series != nil:
+			age = fmt.Sprintf("%s (x%d over %s)", since(ev.Series.LastObservedTime.Time), ev.Series.Count, first)
+		case ev.Count > 1:
+			age = fmt.Sprintf("%s (x%d over %s)", since(ev.LastTimestamp.Time), ev.Count, first)
+		}
+		source := ev.Source.Component
+		if source == "" {
+			source = ev.ReportingController
+		}
+		w.write(1, "%v\t%v\t%s\t%v\t%v\n", ev.Type, ev.Reason, age, source, strings.TrimSpace(ev.Message))
+	}
+}
+
+// since is how long ago t was, as kubectl prints ages.
+func since(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t))
+}
+
+// describeLabel turns a field name into kubectl's section label:
+// "apiVersion" becomes "API Version". Names with characters other than
+// letters and '-' are kept as they are.
+func describeLabel(field string) string {
+	if strings.IndexFunc(field, func(r rune) bool { return !unicode.IsLetter(r) && r != '-' }) != -1 {
+		return field
+	}
+	var parts []string
+	for _, part := range splitCamelCase(field) {
+		if part == "_" {
+			continue
+		}
+		switch upper := strings.ToUpper(part); upper {
+		case "API", "URL", "UID", "OSB", "GUID":
+			part = upper
+		default:
+			r, size := utf8.DecodeRuneInString(part)
+			part = string(unicode.ToUpper(r)) + part[size:]
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// splitCamelCase splits s where its characters change between lower case,
+// upper case, digits and anything else. The last letter of an upper-case run
+// starts the lower-case run after it: "podIP" → "pod", "IP" and
+// "HTTPServer" → "HTTP", "Server".
+func splitCamelCase(s string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLower(r):
+			return 1
+		case unicode.IsUpper(r):
+			return 2
+		case unicode.IsDigit(r):
+			return 3
+		}
+		return 4
+	}
+	var runs [][]rune
+	last := 0
+	for _, r := range s {
+		if c := class(r); c == last {
+			runs[len(runs)-1] = append(runs[len(runs)-1], r)
+		} else {
+			runs = append(runs, []rune{r})
+			last = c
+		}
+	}
+	for i := 0; i < len(runs)-1; i++ {
+		if unicode.IsUpper(runs[i][0]) && unicode.IsLower(runs[i+1][0]) {
+			runs[i+1] = append([]rune{runs[i][len(runs[i])-1]}, runs[i+1]...)
+			runs[i] = runs[i][:len(runs[i])-1]
+		}
+	}
+	parts := make([]string, 0, len(runs))
+	for _, r := range runs {
+		if len(r) > 0 {
+			parts = append(parts, string(r))
+		}
+	}
+	return parts
+}
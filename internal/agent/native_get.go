@@ -0,0 +1,467 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	utilexec "k8s.io/client-go/util/exec"
+	"sigs.k8s.io/yaml"
+)
+
+// tableAccept asks the API server to render a list as the table kubectl
+// prints, falling back to plain JSON.
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io,application/json"
+
+// errSomeFailed ends a command that printed its own errors for some objects.
+var errSomeFailed = utilexec.CodeExitError{Err: errors.New("some objects could not be retrieved"), Code: 1}
+
+// get prints resources as a table (optionally wide), JSON, YAML or names.
+func (e *NativeExecutor) get(ctx context.Context, cmd *nativeCommand, stdout, stderr io.Writer) error {
+	mapping, err := e.mapping(cmd.resource)
+	if err != nil {
+		return err
+	}
+	allNamespaces := cmd.flags["all-namespaces"] == "true"
+	if allNamespaces && len(cmd.names) > 0 {
+		return errors.New("a resource cannot be retrieved by name across all namespaces")
+	}
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	namespace := cmd.namespace
+	if allNamespaces || !namespaced {
+		namespace = ""
+	}
+
+	switch output := cmd.flags["output"]; output {
+	case "json", "yaml", "name":
+		return e.getObjects(ctx, cmd, mapping, namespace, output, stdout, stderr)
+	default:
+		return e.getTable(ctx, cmd, mapping, namespace, output == "wide", stdout, stderr)
+	}
+}
+
+// getTable prints the table the API server renders for the resources.
+func (e *NativeExecutor) getTable(ctx context.Context, cmd *nativeCommand, mapping *meta.RESTMapping, namespace string, wide bool, stdout, stderr io.Writer) error {
+	paths := []string{resourcePath(mapping, namespace, "")}
+	if len(cmd.names) > 0 {
+		paths = paths[:0]
+		for _, name := range cmd.names {
+			paths = append(paths, resourcePath(mapping, namespace, name))
+		}
+	}
+
+	var table *metav1.Table
+	failed := false
+	for _, path := range paths {
+		t, err := e.fetchTable(ctx, path, cmd.flags["selector"])
+		if err != nil {
+			if len(paths) == 1 {
+				return err
+			}
+			writeKubectlError(stderr, err)
+			failed = true
+			continue
+		}
+		if table == nil {
+			table = t
+		} else {
+			table.Rows = append(table.Rows, t.Rows...)
+		}
+	}
+
+	switch {
+	case table != nil && len(table.Rows) > 0:
+		withNamespace := namespace == "" && mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		if err := printTable(stdout, table, wide, withNamespace); err != nil {
+			return err
+		}
+	case !failed && namespace != "":
+		fmt.Fprintf(stderr, "No resources found in %s namespace.\n", namespace)
+	case !failed:
+		fmt.Fprintln(stderr, "No resources found")
+	}
+	if failed {
+		return errSomeFailed
+	}
+	return nil
+}
+
+func (e *NativeExecutor) fetchTable(ctx context.Context, path, selector string) (*metav1.Table, error) {
+	req := e.client.CoreV1().RESTClient().Get().AbsPath(path).
+		SetHeader("Accept", tableAccept).
+		Param("includeObject", "Metadata")
+	if selector != "" {
+		req = req.Param("labelSelector", selector)
+	}
+	body, err := req.DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Keep numbers as written: float64 would print large counts as 1e+06.
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	table := &metav1.Table{}
+	if err := dec.Decode(table); err != nil {
+		return nil, fmt.Errorf("decoding table: %w", err)
+	}
+	if table.Kind != "Table" {
+		return nil, fmt.Errorf("the server did not return a table for %s", path)
+	}
+	return table, nil
+}
+
+// printTable writes a table with kubectl's layout: upper-case headers,
+// priority-0 columns unless wide, and a NAMESPACE column across namespaces.
+func printTable(w io.Writer, table *metav1.Table, wide, withNamespace bool) error {
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	var cols []int
+	var header []string
+	if withNamespace {
+		header = append(header, "NAMESPACE")
+	}
+	for i, col := range table.ColumnDefinitions {
+		if col.Priority == 0 || wide {
+			cols = append(cols, i)
+			header = append(header, strings.ToUpper(col.Name))
+		}
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range table.Rows {
+		var cells []string
+		if withNamespace {
+			var obj metav1.PartialObjectMetadata
+			_ = json.Unmarshal(row.Object.Raw, &obj)
+			cells = append(cells, obj.Namespace)
+		}
+		for _, i := range cols {
+			cell := "<none>"
+			if i < len(row.Cells) && row.Cells[i] != nil {
+				cell = fmt.Sprint(row.Cells[i])
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// getObjects prints the resources as JSON, YAML or type/name lines. Several
+// objects are printed as a List, as kubectl does.
+func (e *NativeExecutor) getObjects(ctx context.Context, cmd *nativeCommand, mapping *meta.RESTMapping, namespace, output string, stdout, stderr io.Writer) error {
+	var ri dynamic.ResourceInterface = e.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = e.dynamic.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	var items []unstructured.Unstructured
+	failed := false
+	if len(cmd.names) == 0 {
+		list, err := ri.List(ctx, metav1.ListOptions{LabelSelector: cmd.flags["selector"]})
+		if err != nil {
+			return err
+		}
+		items = list.Items
+	}
+	for _, name := range cmd.names {
+		obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if len(cmd.names) == 1 {
+				return err
+			}
+			writeKubectlError(stderr, err)
+			failed = true
+			continue
+		}
+		items = append(items, *obj)
+	}
+
+	if output == "name" {
+		for _, obj := range items {
+			fmt.Fprintf(stdout, "%s/%s\n", kindName(mapping.GroupVersionKind.GroupKind()), obj.GetName())
+		}
+	} else {
+		var doc interface{}
+		if len(cmd.names) == 1 {
+			doc = items[0].Object
+		} else {
+			list := make([]interface{}, len(items))
+			for i, obj := range items {
+				list[i] = obj.Object
+			}
+			doc = map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "List",
+				"items":      list,
+				"metadata":   map[string]interface{}{"resourceVersion": ""},
+			}
+		}
+		var out []byte
+		var err error
+		if output == "json" {
+			out, err = json.MarshalIndent(doc, "", "    ")
+			out = append(out, '\n')
+		} else {
+			out, err = yaml.Marshal(doc)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := stdout.Write(out); err != nil {
+			return err
+		}
+	}
+	if failed {
+		return errSomeFailed
+	}
+	return nil
+}
+
+// apply applies every object of the manifest read from stdin, server-side
+// with --server-side and otherwise the client-side way kubectl does, and
+// prints one line per object.
+func (e *NativeExecutor) apply(ctx context.Context, cmd *nativeCommand, stdin io.Reader, stdout, stderr io.Writer) error {
+	var objs []*unstructured.Unstructured
+	if stdin != nil {
+		var err error
+		if objs, err = decodeManifest(stdin); err != nil {
+			return err
+		}
+	}
+	if len(objs) == 0 {
+		return errors.New("no objects passed to apply")
+	}
+	serverSide := cmd.flags["server-side"] == "true"
+	manager := cmd.flags["field-manager"]
+	switch {
+	case manager != "":
+	case serverSide:
+		manager = "kubectl"
+	default:
+		manager = "kubectl-client-side-apply"
+	}
+	force := cmd.flags["force-conflicts"] == "true"
+
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		mapping, err := e.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				return fmt.Errorf("resource mapping not found for name: %q: no matches for kind %q in version %q",
+					obj.GetName(), gvk.Kind, gvk.GroupVersion())
+			}
+			return err
+		}
+		var ri dynamic.ResourceInterface = e.dynamic.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			switch ns := obj.GetNamespace(); {
+			case ns == "":
+				obj.SetNamespace(cmd.namespace)
+			case cmd.flags["namespace"] != "" && ns != cmd.namespace:
+				return fmt.Errorf("the namespace from the provided object %q does not match the namespace %q. You must pass '--namespace=%s' to perform this operation",
+					ns, cmd.namespace, ns)
+			}
+			ri = e.dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+		}
+		if !serverSide {
+			result, err := clientSideApply(ctx, ri, mapping, obj, manager, stderr)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "%s/%s %s\n", kindName(gvk.GroupKind()), obj.GetName(), result)
+			continue
+		}
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if _, err := ri.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: manager,
+			Force:        &force,
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s/%s serverside-applied\n", kindName(gvk.GroupKind()), obj.GetName())
+	}
+	return nil
+}
+
+// clientSideApply creates obj, or patches it with the three-way merge kubectl
+// apply computes between the configuration last applied (kept in an
+// annotation), obj and the live object. Built-in types get a strategic merge
+// patch, others a JSON merge patch. It returns what happened: created,
+// configured or unchanged.
+func clientSideApply(ctx context.Context, ri dynamic.ResourceInterface, mapping *meta.RESTMapping, obj *unstructured.Unstructured, manager string, stderr io.Writer) (string, error) {
+	modified, err := lastAppliedConfiguration(obj)
+	if err != nil {
+		return "", err
+	}
+	current, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := ri.Create(ctx, obj, metav1.CreateOptions{FieldManager: manager}); err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var original []byte
+	if last := current.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; last != "" {
+		original = []byte(last)
+	} else {
+		name := mapping.Resource.Resource
+		if mapping.Resource.Group != "" {
+			name += "." + mapping.Resource.Group
+		}
+		fmt.Fprintf(stderr, "Warning: resource %s/%s is missing the %s annotation which is required by kubectl apply.  "+
+			"kubectl apply should only be used on resources created declaratively by either kubectl create --save-config or kubectl apply.  "+
+			"The missing annotation will be patched automatically.\n", name, obj.GetName(), corev1.LastAppliedConfigAnnotation)
+	}
+	live, err := current.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+
+	var patch []byte
+	patchType := types.StrategicMergePatchType
+	versioned, err := scheme.Scheme.New(mapping.GroupVersionKind)
+	switch {
+	case runtime.IsNotRegisteredError(err):
+		patchType = types.MergePatchType
+		patch, err = jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, live,
+			mergepatch.RequireKeyUnchanged("apiVersion"), mergepatch.RequireKeyUnchanged("kind"),
+			mergepatch.RequireMetadataKeyUnchanged("name"))
+		if mergepatch.IsPreconditionFailed(err) {
+			return "", errors.New("At least one of apiVersion, kind and name was changed")
+		}
+	case err != nil:
+		return "", err
+	default:
+		var lookup strategicpatch.LookupPatchMeta
+		if lookup, err = strategicpatch.NewPatchMetaFromStruct(versioned); err == nil {
+			patch, err = strategicpatch.CreateThreeWayMergePatch(original, modified, live, lookup, true)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("creating patch for %s: %w", obj.GetName(), err)
+	}
+	if string(patch) == "{}" {
+		return "unchanged", nil
+	}
+	if _, err := ri.Patch(ctx, obj.GetName(), patchType, patch, metav1.PatchOptions{FieldManager: manager}); err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// lastAppliedConfiguration records obj, without any previous record, in its
+// last-applied-configuration annotation and returns obj's JSON including it,
+// the way kubectl apply does.
+func lastAppliedConfiguration(obj *unstructured.Unstructured) ([]byte, error) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	obj.SetAnnotations(annotations)
+	applied, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return nil, err
+	}
+	annotations[corev1.LastAppliedConfigAnnotation] = string(applied)
+	obj.SetAnnotations(annotations)
+	return runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+}
+
+// decodeManifest reads the YAML or JSON documents of a manifest, expanding
+// List kinds into their items.
+func decodeManifest(r io.Reader) ([]*unstructured.Unstructured, error) {
+	dec := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	var objs []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := dec.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				return objs, nil
+			}
+			return nil, fmt.Errorf("decoding manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if !obj.IsList() {
+			objs = append(objs, obj)
+			continue
+		}
+		if err := obj.EachListItem(func(item runtime.Object) error {
+			objs = append(objs, item.(*unstructured.Unstructured))
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("decoding manifest: %w", err)
+		}
+	}
+}
+
+// mapping resolves a resource type as typed on the command line, including
+// short names (po) and group-qualified names (deployments.apps).
+func (e *NativeExecutor) mapping(resource string) (*meta.RESTMapping, error) {
+	gvr, err := e.mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("the server doesn't have a resource type %q", resource)
+		}
+		return nil, err
+	}
+	gvk, err := e.mapper.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+	return e.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// resourcePath is the API path of a resource collection, or of one object
+// when name is set.
+func resourcePath(mapping *meta.RESTMapping, namespace, name string) string {
+	gvr := mapping.Resource
+	path := "/apis/" + gvr.Group + "/" + gvr.Version
+	if gvr.Group == "" {
+		path = "/api/" + gvr.Version
+	}
+	if namespace != "" && mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		path += "/namespaces/" + namespace
+	}
+	path += "/" + gvr.Resource
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+// kindName is how kubectl names a type in output: pod, deployment.apps.
+func kindName(gk schema.GroupKind) string {
+	name := strings.ToLower(gk.Kind)
+	if gk.Group != "" {
+		name += "." + gk.Group
+	}
+	return name
+}
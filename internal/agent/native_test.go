@@ -0,0 +1,491 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+func TestParseNativeCommand(t *testing.T) {
+	cases := []struct {
+		name      string
+		args      []string
+		ok        bool
+		namespace string
+		flags     map[string]string
+		resource  string
+		names     []string
+		command   []string
+	}{
+		{name: "get list", args: []string{"get", "pods"}, ok: true, namespace: "default", flags: map[string]string{}, resource: "pods"},
+		{name: "get names", args: []string{"get", "po", "a", "b", "-o", "yaml"}, ok: true, namespace: "default",
+			flags: map[string]string{"output": "yaml"}, resource: "po", names: []string{"a", "b"}},
+		{name: "get type/name", args: []string{"get", "pod/a", "pod/b"}, ok: true, namespace: "default",
+			flags: map[string]string{}, resource: "pod", names: []string{"a", "b"}},
+		{name: "namespace override", args: []string{"-n", "kube-system", "get", "pods", "-l=app=web"}, ok: true, namespace: "kube-system",
+			flags: map[string]string{"namespace": "kube-system", "selector": "app=web"}, resource: "pods"},
+		{name: "all namespaces", args: []string{"get", "pods", "-A", "--output=wide"}, ok: true, namespace: "default",
+			flags: map[string]string{"all-namespaces": "true", "output": "wide"}, resource: "pods"},
+		{name: "exec combined flags", args: []string{"exec", "-it", "web", "--", "sh", "-c", "ls -l"}, ok: true, namespace: "default",
+			flags: map[string]string{"stdin": "true", "tty": "true"}, command: []string{"sh", "-c", "ls -l"}},
+		{name: "logs", args: []string{"logs", "pod/web", "-f", "--tail", "10", "--timestamps=false"}, ok: true, namespace: "default",
+			flags: map[string]string{"follow": "true", "tail": "10", "timestamps": "false"}},
+		{name: "server-side apply", args: []string{"apply", "--server-side", "-f", "-"}, ok: true, namespace: "default",
+			flags: map[string]string{"server-side": "true", "filename": "-"}},
+		{name: "client-side apply", args: []string{"apply", "-f", "-"}, ok: true, namespace: "default",
+			flags: map[string]string{"filename": "-"}},
+		{name: "describe", args: []string{"describe", "pod", "web", "--show-events=false"}, ok: true, namespace: "default",
+			flags: map[string]string{"show-events": "false"}, resource: "pod", names: []string{"web"}},
+		{name: "describe all namespaces", args: []string{"describe", "pods", "-A", "-l", "app=web"}, ok: true, namespace: "default",
+			flags: map[string]string{"all-namespaces": "true", "selector": "app=web"}, resource: "pods"},
+
+		{name: "describe name across namespaces", args: []string{"describe", "pods", "web", "-A"}},
+		{name: "force conflicts client-side", args: []string{"apply", "--force-conflicts", "-f", "-"}},
+		{name: "unknown flag", args: []string{"get", "pods", "--show-labels"}},
+		{name: "unknown output", args: []string{"get", "pods", "-o", "jsonpath={.items}"}},
+		{name: "several types", args: []string{"get", "pods,svc"}},
+		{name: "no resource", args: []string{"get"}},
+		{name: "value flag combined", args: []string{"get", "pods", "-oA", "wide"}},
+		{name: "missing flag value", args: []string{"get", "pods", "-o"}},
+		{name: "exec without command", args: []string{"exec", "web"}},
+		{name: "exec in deployment", args: []string{"exec", "deploy/web", "--", "ls"}},
+		{name: "apply from file", args: []string{"apply", "--server-side", "-f", "app.yaml"}},
+		{name: "global flag", args: []string{"--context", "prod", "get", "pods"}},
+		{name: "as-uid", args: []string{"--as=dev", "--as-uid=1", "get", "pods"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cmd, ok := parseNativeCommand(c.args, "default")
+			if ok != c.ok {
+				t.Fatalf("parseNativeCommand(%q) ok=%v, want %v", c.args, ok, c.ok)
+			}
+			if !ok {
+				return
+			}
+			if cmd.namespace != c.namespace {
+				t.Errorf("namespace = %q, want %q", cmd.namespace, c.namespace)
+			}
+			if !reflect.DeepEqual(cmd.flags, c.flags) {
+				t.Errorf("flags = %v, want %v", cmd.flags, c.flags)
+			}
+			if cmd.resource != c.resource || !reflect.DeepEqual(cmd.names, c.names) {
+				t.Errorf("resource = %q %q, want %q %q", cmd.resource, cmd.names, c.resource, c.names)
+			}
+			if !reflect.DeepEqual(cmd.command, c.command) {
+				t.Errorf("command = %q, want %q", cmd.command, c.command)
+			}
+		})
+	}
+}
+
+// fakeExecutor records the commands handed to it as a fallback.
+type fakeExecutor struct {
+	args [][]string
+}
+
+func (f *fakeExecutor) ExecuteWithStdin(ctx context.Context, args []string, namespace string, timeout time.Duration, stdin []byte) *CommandResult {
+	f.args = append(f.args, args)
+	return &CommandResult{Stdout: []byte("from kubectl\n")}
+}
+
+func (f *fakeExecutor) ExecuteInteractiveNoTTY(ctx context.Context, args []string, namespace string, stdin <-chan []byte, onOutput func(bool, []byte)) (int, error) {
+	f.args = append(f.args, args)
+	return 0, nil
+}
+
+func (f *fakeExecutor) ExecuteInteractive(ctx context.Context, args []string, namespace string, rows, cols uint16, stdin <-chan []byte, resize <-chan [2]uint16, onOutput func([]byte)) (int, error) {
+	f.args = append(f.args, args)
+	return 0, nil
+}
+
+func (f *fakeExecutor) PortForward(ctx context.Context, pod, namespace string, ports []uint32, flags []string) (map[uint16]uint16, <-chan struct{}, error) {
+	f.args = append(f.args, append(flags, "port-forward", pod))
+	return nil, nil, nil
+}
+
+// webPod is a two-container pod as the API server returns it.
+const webPod = `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"web","namespace":"default","uid":"1234",
+	"labels":{"tier":"front","app":"web"},
+	"annotations":{"note":"hello","kubectl.kubernetes.io/last-applied-configuration":"{}"},
+	"managedFields":[{"manager":"kubectl"}]},
+	"spec":{"containers":[{"name":"app"},{"name":"sidecar"}],"nodeName":"n1"}}`
+
+// webCertificate is a custom resource as the API server returns it.
+const webCertificate = `{"kind":"Certificate","apiVersion":"cert-manager.io/v1","metadata":{"name":"web","namespace":"default","uid":"1234",
+	"labels":{"tier":"front","app":"web"},
+	"annotations":{"note":"hello","kubectl.kubernetes.io/last-applied-configuration":"{}"},
+	"managedFields":[{"manager":"kubectl"}]},
+	"spec":{"dnsNames":["web.example.com"],"secretName":"web-tls"}}`
+
+// fakeAPIServer serves discovery for core/v1 pods and secrets and for
+// cert-manager.io/v1 certificates, a pod list as a table, a two-container pod
+// "web", its logs, a certificate "web" and the events.
+func fakeAPIServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	routes := map[string]string{
+		"/api": `{"kind":"APIVersions","versions":["v1"]}`,
+		"/apis": `{"kind":"APIGroupList","apiVersion":"v1","groups":[{"name":"cert-manager.io",
+			"versions":[{"groupVersion":"cert-manager.io/v1","version":"v1"}],
+			"preferredVersion":{"groupVersion":"cert-manager.io/v1","version":"v1"}}]}`,
+		"/apis/cert-manager.io/v1": `{"kind":"APIResourceList","groupVersion":"cert-manager.io/v1","resources":[
+			{"name":"certificates","singularName":"certificate","namespaced":true,"kind":"Certificate","verbs":["get","list"]}]}`,
+		"/apis/cert-manager.io/v1/namespaces/default/certificates": `{"kind":"CertificateList","apiVersion":"cert-manager.io/v1",
+			"metadata":{},"items":[` + webCertificate + `]}`,
+		"/apis/cert-manager.io/v1/namespaces/default/certificates/web": webCertificate,
+		"/apis/cert-manager.io/v1/namespaces/default/certificates/missing": `{"kind":"Status","apiVersion":"v1","status":"Failure",
+			"message":"certificates.cert-manager.io \"missing\" not found","reason":"NotFound","code":404}`,
+		"/api/v1": `{"kind":"APIResourceList","groupVersion":"v1","resources":[
+			{"name":"pods","singularName":"pod","namespaced":true,"kind":"Pod","shortNames":["po"],"verbs":["get","list","patch"]},
+			{"name":"pods/log","singularName":"","namespaced":true,"kind":"Pod","verbs":["get"]},
+			{"name":"secrets","singularName":"secret","namespaced":true,"kind":"Secret","verbs":["get","list"]}]}`,
+		"/api/v1/namespaces/default/pods": `{"kind":"Table","apiVersion":"meta.k8s.io/v1","metadata":{},
+			"columnDefinitions":[
+				{"name":"Name","type":"string","priority":0},
+				{"name":"Ready","type":"string","priority":0},
+				{"name":"Restarts","type":"integer","priority":0},
+				{"name":"IP","type":"string","priority":1},
+				{"name":"Nominated Node","type":"string","priority":1}],
+			"rows":[{"cells":["web","2/2",1000000,"10.0.0.7",null],
+				"object":{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"web","namespace":"default"}}}]}`,
+		"/api/v1/namespaces/default/pods/web": webPod,
+		"/api/v1/namespaces/default/events": `{"kind":"EventList","apiVersion":"v1","metadata":{},"items":[
+			{"metadata":{"name":"web.1","namespace":"default"},"involvedObject":{"kind":"Certificate","name":"web"},
+				"type":"Normal","reason":"Scheduled","message":"assigned ","source":{"component":"sched"}}]}`,
+		"/api/v1/namespaces/default/pods/missing": `{"kind":"Status","apiVersion":"v1","status":"Failure",
+			"message":"pods \"missing\" not found","reason":"NotFound","code":404}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/namespaces/default/pods/web/log" {
+			w.Header().Set("Content-Type", "text/plain")
+			line := "container=" + r.URL.Query().Get("container") + " tail=" + r.URL.Query().Get("tailLines")
+			if user := r.Header.Get("Impersonate-User"); user != "" {
+				line += " as=" + user + " groups=" + strings.Join(r.Header.Values("Impersonate-Group"), ",")
+			}
+			w.Write([]byte(line + "\n"))
+			return
+		}
+		body, ok := routes[r.URL.Path]
+		if r.URL.Path == "/api/v1/namespaces/default/pods" && !strings.Contains(r.Header.Get("Accept"), "as=Table") {
+			body = `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[` + webPod + `]}`
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestNativeExecutor_Execute(t *testing.T) {
+	srv := fakeAPIServer(t)
+	defer srv.Close()
+	fallback := &fakeExecutor{}
+	e, err := newNativeExecutor(&rest.Config{Host: srv.URL}, "", fallback)
+	if err != nil {
+		t.Fatalf("newNativeExecutor: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		exitCode int
+		stdout   string
+		stderr   string
+	}{
+		{
+			name:   "table",
+			args:   []string{"get", "po"},
+			stdout: "NAME   READY   RESTARTS\nweb    2/2     1000000\n",
+		},
+		{
+			name:   "wide table",
+			args:   []string{"get", "pods", "-o", "wide"},
+			stdout: "NAME   READY   RESTARTS   IP         NOMINATED NODE\nweb    2/2     1000000    10.0.0.7   <none>\n",
+		},
+		{
+			name:   "names",
+			args:   []string{"get", "pods", "web", "-o", "name"},
+			stdout: "pod/web\n",
+		},
+		{
+			name:     "not found",
+			args:     []string{"get", "pod", "missing", "-o", "json"},
+			exitCode: 1,
+			stderr:   "Error from server (NotFound): pods \"missing\" not found\n",
+		},
+		{
+			name:     "unknown type",
+			args:     []string{"get", "widgets"},
+			exitCode: 1,
+			stderr:   "error: the server doesn't have a resource type \"widgets\"\n",
+		},
+		{
+			name:   "logs default container",
+			args:   []string{"logs", "web", "--tail=5"},
+			stdout: "container=app tail=5\n",
+			stderr: "Defaulted container \"app\" out of: app, sidecar\n",
+		},
+		{
+			name:   "logs container",
+			args:   []string{"logs", "pod/web", "-c", "sidecar"},
+			stdout: "container=sidecar tail=\n",
+		},
+		{
+			name:   "impersonated",
+			args:   []string{"--as=dev@x.com", "--as-group=sre", "--as-group", "ops", "logs", "web", "-c", "app"},
+			stdout: "container=app tail= as=dev@x.com groups=sre,ops\n",
+		},
+		{
+			name: "describe",
+			args: []string{"describe", "certificate", "web"},
+			stdout: "Name:         web\n" +
+				"Namespace:    default\n" +
+				"Labels:       app=web\n" +
+				"              tier=front\n" +
+				"Annotations:  note: hello\n" +
+				"API Version:  cert-manager.io/v1\n" +
+				"Kind:         Certificate\n" +
+				"Metadata:\n" +
+				"  UID:  1234\n" +
+				"Spec:\n" +
+				"  Dns Names:\n" +
+				"    web.example.com\n" +
+				"  Secret Name:  web-tls\n" +
+				"Events:\n" +
+				"  Type    Reason     Age        From   Message\n" +
+				"  ----    ------     ----       ----   -------\n" +
+				"  Normal  Scheduled  <unknown>  sched  assigned\n",
+		},
+		{
+			name: "describe by prefix without events",
+			args: []string{"describe", "certificates.cert-manager.io", "we", "--show-events=false"},
+			stdout: "Name:         web\n" +
+				"Namespace:    default\n" +
+				"Labels:       app=web\n" +
+				"              tier=front\n" +
+				"Annotations:  note: hello\n" +
+				"API Version:  cert-manager.io/v1\n" +
+				"Kind:         Certificate\n" +
+				"Metadata:\n" +
+				"  UID:  1234\n" +
+				"Spec:\n" +
+				"  Dns Names:\n" +
+				"    web.example.com\n" +
+				"  Secret Name:  web-tls\n",
+		},
+		{
+			name:     "describe missing",
+			args:     []string{"describe", "certificate", "missing"},
+			exitCode: 1,
+			stderr:   "Error from server (NotFound): certificates.cert-manager.io \"missing\" not found\n",
+		},
+		{
+			name:   "describe built-in kind",
+			args:   []string{"describe", "pod", "web"},
+			stdout: "from kubectl\n",
+		},
+		{
+			name:   "describe secret",
+			args:   []string{"describe", "secret", "db"},
+			stdout: "from kubectl\n",
+		},
+		{
+			name:   "fallback",
+			args:   []string{"rollout", "status", "deploy/web"},
+			stdout: "from kubectl\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := e.ExecuteWithStdin(context.Background(), c.args, "", 5*time.Second, nil)
+			if result.ExitCode != c.exitCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", result.ExitCode, c.exitCode, result.Stderr)
+			}
+			if string(result.Stdout) != c.stdout {
+				t.Errorf("stdout = %q, want %q", result.Stdout, c.stdout)
+			}
+			if string(result.Stderr) != c.stderr {
+				t.Errorf("stderr = %q, want %q", result.Stderr, c.stderr)
+			}
+		})
+	}
+
+	want := [][]string{{"describe", "pod", "web"}, {"describe", "secret", "db"}, {"rollout", "status", "deploy/web"}}
+	if !reflect.DeepEqual(fallback.args, want) {
+		t.Errorf("fallback ran %q, want %q", fallback.args, want)
+	}
+}
+
+func TestDescribeLabel(t *testing.T) {
+	cases := map[string]string{
+		"apiVersion":        "API Version",
+		"creationTimestamp": "Creation Timestamp",
+		"podIP":             "Pod IP",
+		"uid":               "UID",
+		"HTTPServer":        "HTTP Server",
+		"x-request":         "X - Request",
+		"v1beta1":           "v1beta1",
+		"app.kubernetes.io": "app.kubernetes.io",
+	}
+	for in, want := range cases {
+		if got := describeLabel(in); got != want {
+			t.Errorf("describeLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDescribeObject_DataSizes(t *testing.T) {
+	password := base64.StdEncoding.EncodeToString([]byte("hunter2"))
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1", "kind": "Secret", "type": "Opaque",
+		"metadata": map[string]interface{}{"name": "db", "namespace": "default"},
+		"data":     map[string]interface{}{"password": password},
+	}}
+	configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "blob", "namespace": "default"},
+		"binaryData": map[string]interface{}{"key": password},
+	}}
+	for _, obj := range []*unstructured.Unstructured{secret, configMap} {
+		var out bytes.Buffer
+		if err := describeObject(&out, obj, nil); err != nil {
+			t.Fatalf("describeObject: %v", err)
+		}
+		if strings.Contains(out.String(), password) || strings.Contains(out.String(), "hunter2") {
+			t.Errorf("%s: output shows the value:\n%s", obj.GetKind(), out.String())
+		}
+		if !strings.Contains(out.String(), "7 bytes") {
+			t.Errorf("%s: output lacks the value's size:\n%s", obj.GetKind(), out.String())
+		}
+	}
+	if secret.Object["data"].(map[string]interface{})["password"] != password {
+		t.Error("describeObject modified the object")
+	}
+}
+
+// appliedRequest is a write the fake API server received.
+type appliedRequest struct {
+	method, path, contentType, body string
+}
+
+func TestNativeExecutor_ClientSideApply(t *testing.T) {
+	// The annotation kubectl would have written for configmap "same".
+	same := `{"apiVersion":"v1","data":{"k":"v"},"kind":"ConfigMap","metadata":{"annotations":{},"name":"same","namespace":"default"}}` + "\n"
+	live := map[string]string{
+		"/api/v1/namespaces/default/configmaps/same": `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"same","namespace":"default",
+			"annotations":{"kubectl.kubernetes.io/last-applied-configuration":` + strconv.Quote(same) + `}},"data":{"k":"v"}}`,
+		"/api/v1/namespaces/default/configmaps/old": `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"old","namespace":"default"},"data":{"k":"old"}}`,
+		"/apis/example.com/v1/namespaces/default/widgets/w1": `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w1","namespace":"default",
+			"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"example.com/v1\",\"kind\":\"Widget\",\"metadata\":{\"name\":\"w1\",\"namespace\":\"default\"},\"spec\":{\"color\":\"red\",\"size\":1}}\n"}},
+			"spec":{"color":"red","size":1}}`,
+	}
+	discovery := map[string]string{
+		"/api": `{"kind":"APIVersions","versions":["v1"]}`,
+		"/apis": `{"kind":"APIGroupList","apiVersion":"v1","groups":[{"name":"example.com",
+			"versions":[{"groupVersion":"example.com/v1","version":"v1"}],"preferredVersion":{"groupVersion":"example.com/v1","version":"v1"}}]}`,
+		"/api/v1": `{"kind":"APIResourceList","groupVersion":"v1","resources":[
+			{"name":"configmaps","singularName":"configmap","namespaced":true,"kind":"ConfigMap","verbs":["get","create","patch"]}]}`,
+		"/apis/example.com/v1": `{"kind":"APIResourceList","groupVersion":"example.com/v1","resources":[
+			{"name":"widgets","singularName":"widget","namespaced":true,"kind":"Widget","verbs":["get","create","patch"]}]}`,
+	}
+	var mu sync.Mutex
+	var writes []appliedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if body, ok := discovery[r.URL.Path]; ok {
+			w.Write([]byte(body))
+			return
+		}
+		if r.Method != http.MethodGet {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			writes = append(writes, appliedRequest{r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(body)})
+			mu.Unlock()
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+				w.Write(body)
+				return
+			}
+			w.Write([]byte(live[r.URL.Path]))
+			return
+		}
+		body, ok := live[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	e, err := newNativeExecutor(&rest.Config{Host: srv.URL}, "", &fakeExecutor{})
+	if err != nil {
+		t.Fatalf("newNativeExecutor: %v", err)
+	}
+
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata: {name: new}
+data: {k: v}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: same}
+data: {k: v}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: old}
+data: {k: v}
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata: {name: w1}
+spec: {size: 2}
+`
+	result := e.ExecuteWithStdin(context.Background(), []string{"apply", "-f", "-"}, "", 5*time.Second, []byte(manifest))
+	if result.ExitCode != 0 {
+		t.Fatalf("exit code = %d, stderr %q", result.ExitCode, result.Stderr)
+	}
+	wantOut := "configmap/new created\nconfigmap/same unchanged\nconfigmap/old configured\nwidget.example.com/w1 configured\n"
+	if string(result.Stdout) != wantOut {
+		t.Errorf("stdout = %q, want %q", result.Stdout, wantOut)
+	}
+	if !strings.HasPrefix(string(result.Stderr), "Warning: resource configmaps/old is missing the kubectl.kubernetes.io/last-applied-configuration annotation") {
+		t.Errorf("stderr = %q, want the missing-annotation warning", result.Stderr)
+	}
+
+	if len(writes) != 3 {
+		t.Fatalf("writes = %+v, want create, patch, patch", writes)
+	}
+	created, old, widget := writes[0], writes[1], writes[2]
+	if created.method != http.MethodPost || created.path != "/api/v1/namespaces/default/configmaps" ||
+		!strings.Contains(created.body, `"kubectl.kubernetes.io/last-applied-configuration"`) {
+		t.Errorf("create = %+v", created)
+	}
+	if old.method != http.MethodPatch || old.contentType != "application/strategic-merge-patch+json" ||
+		!strings.Contains(old.body, `"data":{"k":"v"}`) || !strings.Contains(old.body, "last-applied-configuration") {
+		t.Errorf("configmap patch = %+v", old)
+	}
+	// Dropping spec.color from the manifest removes it from the live object.
+	if widget.contentType != "application/merge-patch+json" ||
+		!strings.Contains(widget.body, `"spec":{"color":null,"size":2}`) {
+		t.Errorf("widget patch = %+v", widget)
+	}
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultWorkers is how many one-shot commands an agent runs at once unless
+// configured otherwise.
+const DefaultWorkers = 4
+
+// workerPool bounds how many one-shot commands run at once. Central hands an
+// agent no more commands than it has workers, so tasks rarely wait for a slot.
+type workerPool struct {
+	slots chan struct{}
+}
+
+func newWorkerPool(workers int) *workerPool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &workerPool{slots: make(chan struct{}, workers)}
+}
+
+// size returns the number of workers.
+func (p *workerPool) size() int { return cap(p.slots) }
+
+// busy returns the number of workers running a task.
+func (p *workerPool) busy() int { return len(p.slots) }
+
+// idle returns the number of workers free to start a task.
+func (p *workerPool) idle() int { return p.size() - p.busy() }
+
+// run starts fn on the next free worker without blocking the caller.
+func (p *workerPool) run(fn func()) {
+	go func() {
+		p.slots <- struct{}{}
+		defer func() { <-p.slots }()
+		fn()
+	}()
+}
+
+// commandCancels tracks the one-shot commands an agent has received and not
+// yet finished, so central can cancel them by request ID.
+type commandCancels struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newCommandCancels() *commandCancels {
+	return &commandCancels{cancels: make(map[string]context.CancelFunc)}
+}
+
+func (c *commandCancels) add(requestID string, cancel context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancels[requestID] = cancel
+}
+
+// cancel cancels and forgets a command and reports whether it was tracked; it
+// is a no-op for a command that already finished.
+func (c *commandCancels) cancel(requestID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cancel, ok := c.cancels[requestID]
+	if ok {
+		cancel()
+		delete(c.cancels, requestID)
+	}
+	return ok
+}
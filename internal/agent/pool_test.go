@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/why-xn/kbridge/api/proto/agentpb"
+)
+
+func TestWorkerPool_BoundsConcurrency(t *testing.T) {
+	p := newWorkerPool(2)
+	if p.size() != 2 || p.idle() != 2 {
+		t.Fatalf("new pool: size %d idle %d", p.size(), p.idle())
+	}
+
+	release := make(chan struct{})
+	var running, peak, done atomic.Int32
+	for i := 0; i < 5; i++ {
+		p.run(func() {
+			n := running.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			done.Add(1)
+		})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for p.busy() != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if p.busy() != 2 || p.idle() != 0 {
+		t.Fatalf("expected a full pool, got busy %d idle %d", p.busy(), p.idle())
+	}
+
+	close(release)
+	for done.Load() != 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if done.Load() != 5 {
+		t.Fatalf("expected all tasks to finish, got %d", done.Load())
+	}
+	if peak.Load() != 2 {
+		t.Errorf("expected at most 2 tasks at once, got %d", peak.Load())
+	}
+}
+
+func TestNewWorkerPool_Default(t *testing.T) {
+	if got := newWorkerPool(0).size(); got != DefaultWorkers {
+		t.Errorf("expected %d workers, got %d", DefaultWorkers, got)
+	}
+}
+
+func TestAgent_CancelCommand(t *testing.T) {
+	a := New(&Config{Workers: 1})
+	a.executor = &KubectlExecutor{kubectlPath: "sleep"}
+	ctx := context.Background()
+
+	results := make(chan *agentpb.SubmitCommandResultRequest, 2)
+	run := func(requestID string) {
+		cmd := &agentpb.CommandRequest{RequestId: requestID, Command: []string{"30"}, TimeoutSeconds: 60}
+		a.startCommand(ctx, requestID, func(ctx context.Context) { results <- a.executeCommand(ctx, cmd) })
+	}
+	run("req-running")
+	run("req-waiting") // no free worker
+
+	// Canceled while waiting for a worker: it never runs.
+	a.cancelCommand("req-waiting")
+	deadline := time.Now().Add(2 * time.Second)
+	for a.pool.busy() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond) // let sleep start
+	a.cancelCommand("req-running")
+
+	for i := 0; i < 2; i++ {
+		select {
+		case res := <-results:
+			if res.GetExitCode() == 0 {
+				t.Errorf("%s: expected a killed command, got exit code 0", res.GetRequestId())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("canceled command still running")
+		}
+	}
+	if a.commands.cancel("req-running") {
+		t.Error("expected finished command to be forgotten")
+	}
+}
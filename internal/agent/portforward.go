@@ -76,6 +76,8 @@ func (s *pfSession) open(connID uint32, remotePort uint16) {
 	s.mu.Lock()
 	s.conns[connID] = pc
 	s.mu.Unlock()
+	portForwardConnsTotal.Inc()
+	portForwardConnsActive.Inc()
 
 	// writer goroutine: drains pc.in so data() never blocks the recv loop.
 	go func() {
@@ -133,6 +135,7 @@ func (s *pfSession) closeConn(connID uint32) {
 	if pc != nil {
 		close(pc.in)
 		_ = pc.conn.Close()
+		portForwardConnsActive.Dec()
 	}
 }
 
@@ -144,14 +147,15 @@ func (s *pfSession) shutdown() {
 	for _, pc := range conns {
 		close(pc.in)
 		_ = pc.conn.Close()
+		portForwardConnsActive.Dec()
 	}
 }
 
-// startKubectlPortForward spawns kubectl, parses the Forwarding lines into a
-// remote->local map, and returns it once all ports are mapped. The caller emits
-// PfReady. On early kubectl failure it returns an error (-> PfSessionError).
-func (e *KubectlExecutor) startKubectlPortForward(ctx context.Context, pod, namespace string, ports []uint32) (map[uint16]uint16, *exec.Cmd, error) {
-	args := []string{"port-forward"}
+// PortForward spawns kubectl, parses the Forwarding lines into a remote->local
+// map, and returns it once all ports are mapped. The caller emits PfReady. On
+// early kubectl failure it returns an error (-> PfSessionError).
+func (e *KubectlExecutor) PortForward(ctx context.Context, pod, namespace string, ports []uint32, flags []string) (map[uint16]uint16, <-chan struct{}, error) {
+	args := append(append([]string{}, flags...), "port-forward")
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
@@ -197,7 +201,10 @@ func (e *KubectlExecutor) startKubectlPortForward(ctx context.Context, pod, name
 			_ = cmd.Wait()
 			return nil, nil, res.err
 		}
-		return res.m, cmd, nil
+		// Cancelling ctx kills kubectl.
+		done := make(chan struct{})
+		go func() { _ = cmd.Wait(); close(done) }()
+		return res.m, done, nil
 	case <-time.After(15 * time.Second):
 		_ = cmd.Process.Kill()
 		_ = cmd.Wait()
@@ -29,6 +29,7 @@ func (a *Agent) runStream(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			}
+			reconnectsTotal.WithLabelValues("stream").Inc()
 		}
 	}
 }
@@ -42,11 +43,15 @@ func (a *Agent) openAndServeStream(ctx context.Context) error {
 	agentID := a.agentID
 	a.mu.RUnlock()
 	if err := stream.Send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Register{
-		Register: &agentpb.StreamRegister{AgentId: agentID},
+		Register: &agentpb.StreamRegister{AgentId: agentID, PushCommands: true},
 	}}); err != nil {
 		return err
 	}
 	log.Printf("Opened command stream to central")
+	a.streamOpen.Store(true)
+	defer a.streamOpen.Store(false)
+	// Fall back to polling as soon as the stream is gone.
+	defer a.commandsPushed.Store(false)
 
 	var mu sync.Mutex // guards stream.Send across session goroutines
 	sessions := newSessionCancels()
@@ -61,6 +66,16 @@ func (a *Agent) openAndServeStream(ctx context.Context) error {
 			return err
 		}
 		switch v := msg.GetMsg().(type) {
+		case *agentpb.CentralStreamMessage_Registered:
+			if v.Registered.GetPushCommands() {
+				log.Printf("Central pushes commands on the stream; polling paused")
+				a.commandsPushed.Store(true)
+			}
+		case *agentpb.CentralStreamMessage_Command:
+			cmd := v.Command
+			a.startCommand(ctx, cmd.RequestId, func(ctx context.Context) { a.runPushedCommand(ctx, &mu, stream, cmd) })
+		case *agentpb.CentralStreamMessage_CancelCommand:
+			a.cancelCommand(v.CancelCommand.GetRequestId())
 		case *agentpb.CentralStreamMessage_Start:
 			sctx, cancel := context.WithCancel(ctx)
 			sid := v.Start.GetSessionId()
@@ -110,6 +125,26 @@ func (a *Agent) openAndServeStream(ctx context.Context) error {
 	}
 }
 
+// runPushedCommand runs a one-shot command received on the stream and returns
+// its result on the stream, or with SubmitCommandResult if the stream has
+// failed meanwhile. Like executeAndSubmit it drops the result of a canceled
+// command.
+func (a *Agent) runPushedCommand(ctx context.Context, mu *sync.Mutex, stream agentpb.AgentService_OpenStreamClient, cmd *agentpb.CommandRequest) {
+	log.Printf("Executing command %s: %v", cmd.RequestId, cmd.Command)
+	res := a.executeCommand(ctx, cmd)
+	if ctx.Err() != nil {
+		return
+	}
+	mu.Lock()
+	err := stream.Send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_CommandResult{CommandResult: res}})
+	mu.Unlock()
+	if err != nil {
+		a.submitResult(ctx, res)
+		return
+	}
+	log.Printf("Command %s completed with exit code %d", res.RequestId, res.ExitCode)
+}
+
 func (a *Agent) runPortForwardSession(ctx context.Context, mu *sync.Mutex, stream agentpb.AgentService_OpenStreamClient, start *agentpb.PortForwardStart, sessions *sessionCancels) {
 	sid := start.GetSessionId()
 	send := func(m *agentpb.AgentStreamMessage) {
@@ -117,7 +152,14 @@ func (a *Agent) runPortForwardSession(ctx context.Context, mu *sync.Mutex, strea
 		defer mu.Unlock()
 		_ = stream.Send(m)
 	}
-	m, cmd, err := a.executor.startKubectlPortForward(ctx, start.GetPod(), start.GetNamespace(), start.GetPorts())
+	streamSessionsActive.WithLabelValues(sessionKindPortForward).Inc()
+	defer streamSessionsActive.WithLabelValues(sessionKindPortForward).Dec()
+	flags, err := a.impersonationFlags([]string{start.GetPod()}, start.GetUser(), start.GetGroups())
+	var m map[uint16]uint16
+	var done <-chan struct{}
+	if err == nil {
+		m, done, err = a.executor.PortForward(ctx, start.GetPod(), start.GetNamespace(), start.GetPorts(), flags)
+	}
 	if err != nil {
 		send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_PfSessionError{
 			PfSessionError: &agentpb.PfSessionError{SessionId: sid, Error: err.Error()},
@@ -130,13 +172,10 @@ func (a *Agent) runPortForwardSession(ctx context.Context, mu *sync.Mutex, strea
 
 	send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_PfReady{PfReady: &agentpb.PfReady{SessionId: sid}}})
 
-	// Block until ctx cancel (session end / stream drop) or kubectl exits.
-	waitCh := make(chan struct{})
-	go func() { _ = cmd.Wait(); close(waitCh) }()
+	// Block until ctx cancel (session end / stream drop) or the forward ends.
 	select {
 	case <-ctx.Done():
-		_ = cmd.Process.Kill()
-	case <-waitCh:
+	case <-done:
 	}
 }
 
@@ -240,7 +279,15 @@ func (a *Agent) runStreamSession(ctx context.Context, mu *sync.Mutex, stream age
 		defer mu.Unlock()
 		_ = stream.Send(m)
 	}
-	code, err := a.executor.ExecuteInteractiveNoTTY(ctx, start.GetCommand(), start.GetNamespace(), stdin,
+	streamSessionsActive.WithLabelValues(sessionKindStream).Inc()
+	defer streamSessionsActive.WithLabelValues(sessionKindStream).Dec()
+	args, err := a.impersonate(start.GetCommand(), start.GetUser(), start.GetGroups())
+	if err != nil {
+		sendRefused(send, sid, err)
+		return
+	}
+	started := time.Now()
+	code, err := a.executor.ExecuteInteractiveNoTTY(ctx, args, start.GetNamespace(), stdin,
 		func(stdout bool, data []byte) {
 			send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Output{
 				Output: &agentpb.StreamOutput{SessionId: sid, Type: outputTypeFor(stdout), Data: data},
@@ -251,6 +298,7 @@ func (a *Agent) runStreamSession(ctx context.Context, mu *sync.Mutex, stream age
 		errMsg = err.Error()
 		code = -1
 	}
+	observeKubectl(modeStream, started, code)
 	send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Exit{
 		Exit: &agentpb.StreamExit{SessionId: sid, ExitCode: int32(code), ErrorMessage: errMsg},
 	}})
@@ -263,7 +311,15 @@ func (a *Agent) runInteractiveSession(ctx context.Context, mu *sync.Mutex, strea
 		defer mu.Unlock()
 		_ = stream.Send(m)
 	}
-	code, err := a.executor.ExecuteInteractive(ctx, start.GetCommand(), start.GetNamespace(),
+	streamSessionsActive.WithLabelValues(sessionKindInteractive).Inc()
+	defer streamSessionsActive.WithLabelValues(sessionKindInteractive).Dec()
+	args, err := a.impersonate(start.GetCommand(), start.GetUser(), start.GetGroups())
+	if err != nil {
+		sendRefused(send, sid, err)
+		return
+	}
+	started := time.Now()
+	code, err := a.executor.ExecuteInteractive(ctx, args, start.GetNamespace(),
 		uint16(start.GetRows()), uint16(start.GetCols()), stdin, resize,
 		func(data []byte) {
 			send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Output{
@@ -275,7 +331,15 @@ func (a *Agent) runInteractiveSession(ctx context.Context, mu *sync.Mutex, strea
 		errMsg = err.Error()
 		code = -1
 	}
+	observeKubectl(modeInteractive, started, code)
 	send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Exit{
 		Exit: &agentpb.StreamExit{SessionId: sid, ExitCode: int32(code), ErrorMessage: errMsg},
 	}})
 }
+
+// sendRefused ends a session that was refused before anything ran.
+func sendRefused(send func(*agentpb.AgentStreamMessage), sid string, err error) {
+	send(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Exit{
+		Exit: &agentpb.StreamExit{SessionId: sid, ExitCode: -1, ErrorMessage: err.Error()},
+	}})
+}
@@ -3,10 +3,98 @@ package agent
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/why-xn/kbridge/api/proto/agentpb"
 )
 
+// pushStreamService accepts one stream, confirms pushed commands, pushes
+// cmd and reports the result the agent sends back.
+type pushStreamService struct {
+	agentpb.UnimplementedAgentServiceServer
+	cmd      *agentpb.CommandRequest
+	register chan *agentpb.StreamRegister
+	results  chan *agentpb.SubmitCommandResultRequest
+	release  chan struct{}
+}
+
+func (s *pushStreamService) OpenStream(stream agentpb.AgentService_OpenStreamServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.register <- first.GetRegister()
+	if err := stream.Send(&agentpb.CentralStreamMessage{Msg: &agentpb.CentralStreamMessage_Registered{
+		Registered: &agentpb.StreamRegistered{PushCommands: true},
+	}}); err != nil {
+		return err
+	}
+	if err := stream.Send(&agentpb.CentralStreamMessage{Msg: &agentpb.CentralStreamMessage_Command{Command: s.cmd}}); err != nil {
+		return err
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.results <- msg.GetCommandResult()
+	<-s.release
+	return nil
+}
+
+func TestAgent_StreamPushedCommand(t *testing.T) {
+	svc := &pushStreamService{
+		cmd:      &agentpb.CommandRequest{RequestId: "req-push", Command: []string{"hello", "push"}, TimeoutSeconds: 5},
+		register: make(chan *agentpb.StreamRegister, 1),
+		results:  make(chan *agentpb.SubmitCommandResultRequest, 1),
+		release:  make(chan struct{}),
+	}
+	addr, stop := startMockServer(t, svc)
+	defer stop()
+
+	a := New(&Config{Central: CentralConfig{URL: addr}})
+	a.executor = &KubectlExecutor{kubectlPath: "echo"}
+	a.agentID = "agent-push"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := a.connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a.disconnect()
+
+	done := make(chan error, 1)
+	go func() { done <- a.openAndServeStream(ctx) }()
+
+	select {
+	case reg := <-svc.register:
+		if reg.GetAgentId() != "agent-push" || !reg.GetPushCommands() {
+			t.Errorf("unexpected registration %v", reg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not registered")
+	}
+	select {
+	case res := <-svc.results:
+		if res.GetRequestId() != "req-push" || string(res.GetStdout()) != "hello push\n" || res.GetExitCode() != 0 {
+			t.Errorf("unexpected result %v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result on the stream")
+	}
+	if !a.commandsPushed.Load() {
+		t.Error("expected polling to be paused while central pushes commands")
+	}
+
+	close(svc.release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not close")
+	}
+	if a.commandsPushed.Load() {
+		t.Error("expected polling to resume once the stream closes")
+	}
+}
+
 func TestOutputTypeFor(t *testing.T) {
 	if outputTypeFor(true) != agentpb.OutputType_OUTPUT_TYPE_STDOUT {
 		t.Error("stdout mismatch")
@@ -16,6 +16,8 @@ type UserClaims struct {
 	Email   string `json:"email"`
 	Name    string `json:"name"`
 	IsAdmin bool   `json:"is_admin"`
+	// Groups lists the user's group memberships, e.g. from an SSO provider.
+	Groups []string `json:"groups,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,12 +26,19 @@ const ClusterStatusPending = "pending"
 type AdminHandlers struct {
 	store  Store
 	pepper string
+	chain  *AuditChain
 }
 
 // NewAdminHandlers creates a new AdminHandlers instance. pepper is the
 // server-side secret used to HMAC agent tokens at rest.
 func NewAdminHandlers(store Store, pepper string) *AdminHandlers {
-	return &AdminHandlers{store: store, pepper: pepper}
+	return &AdminHandlers{store: store, pepper: pepper, chain: NewAuditChain(nil)}
+}
+
+// SetAuditChain sets the hasher used to verify the audit chain. It must match
+// the one given to the store.
+func (h *AdminHandlers) SetAuditChain(chain *AuditChain) {
+	h.chain = chain
 }
 
 type createAgentTokenRequest struct {
@@ -145,16 +153,18 @@ func (h *AdminHandlers) HandleListAgentTokens(c *gin.Context) {
 }
 
 type createUserRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Name     string `json:"name" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	IsActive *bool  `json:"is_active"`
+	Email    string   `json:"email" binding:"required,email"`
+	Name     string   `json:"name" binding:"required"`
+	Password string   `json:"password" binding:"required"`
+	IsActive *bool    `json:"is_active"`
+	Groups   []string `json:"groups"`
 }
 
 type updateUserRequest struct {
-	Name     *string `json:"name"`
-	IsActive *bool   `json:"is_active"`
-	Password *string `json:"password"`
+	Name     *string   `json:"name"`
+	IsActive *bool     `json:"is_active"`
+	Password *string   `json:"password"`
+	Groups   *[]string `json:"groups"` // replaces the full list; [] clears it
 }
 
 // HandleListUsers lists all users.
@@ -192,6 +202,7 @@ func (h *AdminHandlers) HandleCreateUser(c *gin.Context) {
 		Name:         req.Name,
 		PasswordHash: hash,
 		IsActive:     req.IsActive == nil || *req.IsActive,
+		Groups:       normalizeGroups(req.Groups),
 	}
 	if err := h.store.CreateUser(ctx, user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
@@ -200,7 +211,8 @@ func (h *AdminHandlers) HandleCreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, user)
 }
 
-// HandleUpdateUser updates a user's name, active state, and/or password.
+// HandleUpdateUser updates a user's name, active state, password, and/or
+// groups.
 func (h *AdminHandlers) HandleUpdateUser(c *gin.Context) {
 	var req updateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -225,6 +237,9 @@ func (h *AdminHandlers) HandleUpdateUser(c *gin.Context) {
 	if req.IsActive != nil {
 		user.IsActive = *req.IsActive
 	}
+	if req.Groups != nil {
+		user.Groups = normalizeGroups(*req.Groups)
+	}
 	if req.Password != nil {
 		hash, err := auth.HashPassword(*req.Password)
 		if err != nil {
@@ -256,40 +271,209 @@ const (
 	maxAuditPerPage     = 200
 )
 
-// HandleListAuditLogs returns audit logs filtered by query parameters:
-// user, cluster, status, from, to (RFC3339), page, per_page.
+// HandleListAuditLogs returns audit logs filtered by query parameters (see
+// auditFilterFromQuery). By default it pages with page and per_page and
+// reports the total; with a cursor parameter (empty for the first page) it
+// pages by keyset from the entry the cursor names and skips the count, which
+// stays fast on large tables. Both modes return next_cursor while there are
+// more entries.
 func (h *AdminHandlers) HandleListAuditLogs(c *gin.Context) {
+	filter, ok := auditFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	filter.Page = atoiDefault(c.Query("page"), 1)
+	filter.PerPage = clampPerPage(atoiDefault(c.Query("per_page"), defaultAuditPerPage))
+
+	if cursor, keyset := c.GetQuery("cursor"); keyset {
+		after, err := parseAuditCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		if filter.PerPage < 1 {
+			filter.PerPage = defaultAuditPerPage
+		}
+		logs, err := h.store.ListAuditLogsAfter(c.Request.Context(), filter, after, filter.PerPage+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		next := ""
+		if len(logs) > filter.PerPage {
+			logs = logs[:filter.PerPage]
+			next = auditCursorAt(logs[len(logs)-1])
+		}
+		if logs == nil {
+			logs = []*AuditLog{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"logs":        logs,
+			"per_page":    filter.PerPage,
+			"next_cursor": next,
+		})
+		return
+	}
+
+	logs, total, err := h.store.ListAuditLogs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	next := ""
+	if len(logs) > 0 && filter.PerPage > 0 && filter.Page*filter.PerPage < total {
+		next = auditCursorAt(logs[len(logs)-1])
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"logs":        logs,
+		"total":       total,
+		"page":        filter.Page,
+		"per_page":    filter.PerPage,
+		"next_cursor": next,
+	})
+}
+
+// auditFilterFromQuery reads the audit filters shared by listing and export:
+// user, cluster, status, namespace, verb, resource, client_ip, q (command
+// substring), from and to (RFC3339) and order (asc or desc). It answers 400
+// and returns false for invalid values.
+func auditFilterFromQuery(c *gin.Context) (AuditLogFilter, bool) {
 	filter := AuditLogFilter{
 		UserEmail:   c.Query("user"),
 		ClusterName: c.Query("cluster"),
 		Status:      c.Query("status"),
-		Page:        atoiDefault(c.Query("page"), 1),
-		PerPage:     clampPerPage(atoiDefault(c.Query("per_page"), defaultAuditPerPage)),
+		Namespace:   c.Query("namespace"),
+		Verb:        c.Query("verb"),
+		Resource:    c.Query("resource"),
+		ClientIP:    c.Query("client_ip"),
+		Search:      c.Query("q"),
 	}
 
 	from, err := parseTimeParam(c.Query("from"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' timestamp (use RFC3339)"})
-		return
+		return filter, false
 	}
 	to, err := parseTimeParam(c.Query("to"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' timestamp (use RFC3339)"})
-		return
+		return filter, false
 	}
 	filter.From, filter.To = from, to
 
-	logs, total, err := h.store.ListAuditLogs(c.Request.Context(), filter)
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return filter, false
+	}
+	return filter, true
+}
+
+// HandleVerifyAuditChain walks the audit hash chain and reports gaps and
+// edited entries. A broken chain is still a 200; check "ok".
+func (h *AdminHandlers) HandleVerifyAuditChain(c *gin.Context) {
+	report, err := h.chain.Verify(c.Request.Context(), h.store)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"logs":     logs,
-		"total":    total,
-		"page":     filter.Page,
-		"per_page": filter.PerPage,
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetAuditLog returns one audit entry and the output captured for it,
+// if any.
+func (h *AdminHandlers) HandleGetAuditLog(c *gin.Context) {
+	entry, err := h.store.GetAuditLog(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audit log not found"})
+		return
+	}
+	output, err := h.store.GetAuditOutput(c.Request.Context(), entry.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"log": entry, "output": output})
+}
+
+const (
+	defaultSessionListLimit = 50
+	maxSessionListLimit     = 500
+)
+
+// HandleListSessionRecordings lists recorded exec sessions, newest first,
+// filtered by the query parameters user, cluster and limit.
+func (h *AdminHandlers) HandleListSessionRecordings(c *gin.Context) {
+	limit := atoiDefault(c.Query("limit"), defaultSessionListLimit)
+	if limit < 1 || limit > maxSessionListLimit {
+		limit = defaultSessionListLimit
+	}
+	recs, err := h.store.ListSessionRecordings(c.Request.Context(), SessionRecordingFilter{
+		UserEmail:   c.Query("user"),
+		ClusterName: c.Query("cluster"),
+		Limit:       limit,
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if recs == nil {
+		recs = []*SessionRecording{}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": recs})
+}
+
+// HandleGetSessionRecording returns a recording's metadata.
+func (h *AdminHandlers) HandleGetSessionRecording(c *gin.Context) {
+	rec, ok := h.sessionRecording(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// HandleDownloadSessionRecording returns a recording as an asciicast v2 file.
+func (h *AdminHandlers) HandleDownloadSessionRecording(c *gin.Context) {
+	rec, ok := h.sessionRecording(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.cast"`, rec.ID))
+	c.Data(http.StatusOK, CastContentType, rec.Data)
+}
+
+func (h *AdminHandlers) sessionRecording(c *gin.Context) (*SessionRecording, bool) {
+	rec, err := h.store.GetSessionRecording(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return nil, false
+	}
+	if rec == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session recording not found"})
+		return nil, false
+	}
+	return rec, true
+}
+
+// normalizeGroups trims group names and drops blanks and duplicates.
+func normalizeGroups(groups []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		g = strings.TrimSpace(g)
+		if g != "" && !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	return out
 }
 
 func atoiDefault(s string, def int) int {
@@ -276,6 +276,22 @@ func TestAdminHandler_UpdateUser(t *testing.T) {
 		}
 	})
 
+	t.Run("replaces groups", func(t *testing.T) {
+		groups := []string{" sre ", "dev", "sre", ""}
+		body, _ := json.Marshal(updateUserRequest{Groups: &groups})
+		w := doRequest(t, "PUT", "/api/v1/admin/users/:id", ah.HandleUpdateUser, "PUT", "/api/v1/admin/users/"+u.ID, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		got, _ := store.GetUserByID(context.Background(), u.ID)
+		if len(got.Groups) != 2 || got.Groups[0] != "sre" || got.Groups[1] != "dev" {
+			t.Errorf("groups = %v, want normalized [sre dev]", got.Groups)
+		}
+		if got.Name != "New Name" {
+			t.Error("omitted fields must be left unchanged")
+		}
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		body, _ := json.Marshal(updateUserRequest{Name: ptr("x")})
 		w := doRequest(t, "PUT", "/api/v1/admin/users/:id", ah.HandleUpdateUser, "PUT", "/api/v1/admin/users/nope", body)
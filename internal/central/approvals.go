@@ -0,0 +1,212 @@
+package central
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command approval states.
+const (
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusDenied   = "denied"
+	ApprovalStatusExpired  = "expired"
+	ApprovalStatusCanceled = "canceled"
+)
+
+// DefaultApprovalTimeout is how long a command waits for approval when
+// rbac.approval_timeout is not set.
+const DefaultApprovalTimeout = 15 * time.Minute
+
+var (
+	// ErrApprovalNotFound is returned for an unknown or already consumed
+	// approval ID.
+	ErrApprovalNotFound = errors.New("command approval not found")
+	// ErrApprovalNotPending is returned when deciding an approval that was
+	// already decided, expired or withdrawn.
+	ErrApprovalNotPending = errors.New("command approval is no longer pending")
+	// ErrApprovalSelf is returned when the requester tries to decide their own
+	// command.
+	ErrApprovalSelf = errors.New("cannot approve your own command")
+)
+
+// CommandApproval is an exec request parked until a second user approves it.
+// Only the requester's waiting connection runs the command, so an approval is
+// used at most once.
+type CommandApproval struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"-"`
+	UserEmail   string    `json:"user_email"`
+	ClusterName string    `json:"cluster_name"`
+	Command     []string  `json:"command"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Status      string    `json:"status"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	req     ExecRequest
+	decided chan struct{} // closed when Status leaves pending
+}
+
+// ApprovalQueue holds commands waiting for approval. Like CommandQueue it is
+// in memory: the requester's connection waits on the replica that parked the
+// command.
+type ApprovalQueue struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	items   map[string]*CommandApproval
+}
+
+// NewApprovalQueue creates a queue whose approvals expire after timeout.
+func NewApprovalQueue(timeout time.Duration) *ApprovalQueue {
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	return &ApprovalQueue{timeout: timeout, items: make(map[string]*CommandApproval)}
+}
+
+// Park stores req on behalf of the requester and returns the pending
+// approval. It expires, and is dropped, after the queue's timeout unless it
+// was decided by then.
+func (q *ApprovalQueue) Park(userID, email, cluster string, req ExecRequest) (*CommandApproval, error) {
+	id, err := generateRequestID()
+	if err != nil {
+		return nil, fmt.Errorf("generating approval ID: %w", err)
+	}
+	now := time.Now().UTC()
+	a := &CommandApproval{
+		ID:          "apr-" + strings.TrimPrefix(id, "req-"),
+		UserID:      userID,
+		UserEmail:   email,
+		ClusterName: cluster,
+		Command:     req.Command,
+		Namespace:   req.Namespace,
+		Status:      ApprovalStatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(q.timeout),
+		req:         req,
+		decided:     make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	q.items[a.ID] = a
+	snap := *a
+	q.mu.Unlock()
+
+	time.AfterFunc(q.timeout, func() { q.expire(a.ID) })
+	return &snap, nil
+}
+
+// expire marks a still-pending approval expired and drops it from the queue.
+// A decided approval is left for its requester to collect.
+func (q *ApprovalQueue) expire(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.items[id]
+	if !ok || !q.settle(a, ApprovalStatusExpired, "") {
+		return
+	}
+	delete(q.items, id)
+}
+
+// drop removes a decided approval its requester never collected.
+func (q *ApprovalQueue) drop(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if a, ok := q.items[id]; ok && a.Status != ApprovalStatusPending {
+		delete(q.items, id)
+	}
+}
+
+// settle moves a pending approval to status. Callers hold q.mu.
+func (q *ApprovalQueue) settle(a *CommandApproval, status, decidedBy string) bool {
+	if a.Status != ApprovalStatusPending {
+		return false
+	}
+	a.Status = status
+	a.DecidedBy = decidedBy
+	close(a.decided)
+	return true
+}
+
+// Get returns a copy of an approval, including the parked request.
+func (q *ApprovalQueue) Get(id string) (*CommandApproval, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.items[id]
+	if !ok {
+		return nil, false
+	}
+	snap := *a
+	return &snap, true
+}
+
+// Pending returns copies of the pending approvals, oldest first.
+func (q *ApprovalQueue) Pending() []*CommandApproval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []*CommandApproval
+	for _, a := range q.items {
+		if a.Status == ApprovalStatusPending {
+			snap := *a
+			out = append(out, &snap)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Decide approves or denies a pending approval on behalf of approver, who must
+// not be the requester. Whether approver may decide it at all is up to the
+// caller. The requester has the queue's timeout to collect the decision.
+func (q *ApprovalQueue) Decide(id, approver string, approve bool) (*CommandApproval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.items[id]
+	if !ok {
+		return nil, ErrApprovalNotFound
+	}
+	if strings.EqualFold(a.UserEmail, approver) {
+		return nil, ErrApprovalSelf
+	}
+	status := ApprovalStatusDenied
+	if approve {
+		status = ApprovalStatusApproved
+	}
+	if !q.settle(a, status, approver) {
+		return nil, ErrApprovalNotPending
+	}
+	time.AfterFunc(q.timeout, func() { q.drop(id) })
+	snap := *a
+	return &snap, nil
+}
+
+// Wait blocks until the approval is decided or expires and returns its final
+// state, consuming it. If ctx ends first the approval is withdrawn and
+// returned as canceled, since nobody would receive the command's output.
+func (q *ApprovalQueue) Wait(ctx context.Context, id string) (*CommandApproval, error) {
+	q.mu.Lock()
+	a, ok := q.items[id]
+	q.mu.Unlock()
+	if !ok {
+		return nil, ErrApprovalNotFound
+	}
+
+	select {
+	case <-a.decided:
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.settle(a, ApprovalStatusCanceled, "")
+	delete(q.items, id)
+	snap := *a
+	return &snap, nil
+}
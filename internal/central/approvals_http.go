@@ -0,0 +1,194 @@
+package central
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+// SetApprovalQueue replaces the queue that holds commands awaiting approval,
+// e.g. to apply rbac.approval_timeout.
+func (s *HTTPServer) SetApprovalQueue(q *ApprovalQueue) {
+	s.approvals = q
+}
+
+// parkForApproval holds an exec request that a require_approval rule matched
+// and answers 202 with the pending approval. The requester then waits on
+// POST /approvals/:id/wait, which runs the command once it is approved.
+func (s *HTTPServer) parkForApproval(c *gin.Context, clusterName string, req ExecRequest) {
+	claims := auth.GetUserFromContext(c)
+	a, err := s.approvals.Park(claims.UserID, claims.Email, clusterName, req)
+	if err != nil {
+		log.Printf("Failed to park command for approval: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	log.Printf("Command %s on cluster %s by %s awaits approval: %v", a.ID, clusterName, claims.Email, req.Command)
+	s.recordExecAudit(c, clusterName, req, AuditStatusApprovalRequested, nil, nil, "awaiting approval "+a.ID)
+	c.JSON(http.StatusAccepted, gin.H{"approval": a})
+}
+
+// canApproveCommand reports whether subject may decide a: admins can, as can
+// anyone the policy itself allows to run the command. The requester never can.
+func (s *HTTPServer) canApproveCommand(claims *auth.UserClaims, a *CommandApproval) bool {
+	if strings.EqualFold(claims.Email, a.UserEmail) {
+		return false
+	}
+	if claims.IsAdmin {
+		return true
+	}
+	accesses, err := execAccessRequests(a.ClusterName, a.req)
+	if err != nil {
+		return false
+	}
+	subject := subjectFromClaims(claims)
+	for _, access := range accesses {
+		if !s.policy.Allows(subject, access) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleListApprovals lists pending commands the caller may decide, plus the
+// caller's own, oldest first.
+func (s *HTTPServer) handleListApprovals(c *gin.Context) {
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	out := []*CommandApproval{}
+	for _, a := range s.approvals.Pending() {
+		if strings.EqualFold(a.UserEmail, claims.Email) || s.canApproveCommand(claims, a) {
+			out = append(out, a)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"approvals": out})
+}
+
+// handleApproveCommand approves a pending command; it then runs on the
+// requester's waiting connection.
+func (s *HTTPServer) handleApproveCommand(c *gin.Context) {
+	s.decideCommand(c, true)
+}
+
+// handleDenyCommand denies a pending command.
+func (s *HTTPServer) handleDenyCommand(c *gin.Context) {
+	s.decideCommand(c, false)
+}
+
+func (s *HTTPServer) decideCommand(c *gin.Context, approve bool) {
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	a, ok := s.approvals.Get(c.Param("id"))
+	if !ok {
+		s.writeApprovalError(c, ErrApprovalNotFound)
+		return
+	}
+	if strings.EqualFold(a.UserEmail, claims.Email) {
+		s.writeApprovalError(c, ErrApprovalSelf)
+		return
+	}
+	if !s.canApproveCommand(claims, a) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not allowed to decide this command"})
+		return
+	}
+	a, err := s.approvals.Decide(a.ID, claims.Email, approve)
+	if err != nil {
+		s.writeApprovalError(c, err)
+		return
+	}
+
+	status, verb := AuditStatusApprovalDenied, "denied"
+	if approve {
+		status, verb = AuditStatusApprovalApproved, "approved"
+	}
+	command := fmt.Sprintf("command approval %s %s: %s (requested by %s)", a.ID, verb, strings.Join(a.Command, " "), a.UserEmail)
+	log.Printf("approvals: %s by %s", command, claims.Email)
+	if s.audit != nil {
+		s.audit.Record(&AuditLog{
+			UserID:      claims.UserID,
+			UserEmail:   claims.Email,
+			ClusterName: a.ClusterName,
+			Command:     command,
+			Namespace:   a.Namespace,
+			Status:      status,
+			ClientIP:    c.ClientIP(),
+		})
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// handleWaitApproval blocks the requester until their parked command is
+// decided. An approved command is checked against the policy again and, if
+// still allowed, runs immediately with the usual ExecResponse. A denied
+// command returns 403 and an expired one 410. Disconnecting before a decision
+// withdraws the request.
+func (s *HTTPServer) handleWaitApproval(c *gin.Context) {
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	a, ok := s.approvals.Get(c.Param("id"))
+	if !ok || !strings.EqualFold(a.UserEmail, claims.Email) {
+		s.writeApprovalError(c, ErrApprovalNotFound)
+		return
+	}
+
+	a, err := s.approvals.Wait(c.Request.Context(), a.ID)
+	if err != nil {
+		s.writeApprovalError(c, err)
+		return
+	}
+	switch a.Status {
+	case ApprovalStatusApproved:
+		// The policy may have changed while the command waited: a revoked
+		// grant or a new deny rule applies even after approval.
+		if allowed, _ := s.checkExec(c, a.ClusterName, a.req); !allowed {
+			return
+		}
+		agent, exists := s.agentStore.GetByClusterName(a.ClusterName)
+		if !exists || agent.Status != AgentStatusConnected {
+			s.recordExecAudit(c, a.ClusterName, a.req, AuditStatusFailed, nil, nil, "cluster agent is disconnected")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "cluster agent is disconnected"})
+			return
+		}
+		log.Printf("Command %s approved by %s; running on cluster %s", a.ID, a.DecidedBy, a.ClusterName)
+		s.executeCommand(c, agent.ID, a.ClusterName, a.req)
+	case ApprovalStatusDenied:
+		msg := "command denied by " + a.DecidedBy
+		s.recordExecAudit(c, a.ClusterName, a.req, AuditStatusDenied, nil, nil, msg)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	case ApprovalStatusExpired:
+		s.recordExecAudit(c, a.ClusterName, a.req, AuditStatusDenied, nil, nil, "approval expired")
+		c.JSON(http.StatusGone, gin.H{"error": "approval expired before anyone approved the command"})
+	default:
+		// The requester went away; there is nobody to answer.
+		s.recordExecAudit(c, a.ClusterName, a.req, AuditStatusCanceled, nil, nil, "approval withdrawn")
+	}
+}
+
+// writeApprovalError maps ApprovalQueue errors to HTTP responses.
+func (s *HTTPServer) writeApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrApprovalSelf):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrApprovalNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("approvals: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,200 @@
+package central
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+func TestCommandApprovalAPI(t *testing.T) {
+	store := newTestStore(t)
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	eng := &PolicyEngine{}
+	eng.current.Store(mustParse(t, `
+default: viewer
+roles:
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+  - name: operator
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+      - clusters: ["prod"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["delete"]
+        require_approval: true
+bindings:
+  - subject: group:ops
+    roles: ["operator"]
+`))
+	agents := NewAgentStore()
+	agents.Register(&AgentInfo{ID: "a1", ClusterName: "prod"})
+	queue := NewCommandQueue()
+	srv := NewHTTPServer(agents, queue, NewAuthHandlers(store, jm, time.Hour),
+		NewAdminHandlers(store, testPepper), eng, NewAuditRecorder(store), NewSessionManager(10), jm)
+
+	token := func(email string, groups ...string) string {
+		u := &User{Email: email, Name: email, PasswordHash: "x", IsActive: true}
+		if err := store.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+		tok, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: u.ID, Email: email, Groups: groups})
+		return tok
+	}
+	dev := token("dev@x.com", "ops")
+	peer := token("peer@x.com", "ops")
+	viewer := token("viewer@x.com")
+
+	// Stand in for the agent: complete every queued command successfully.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			for _, cmd := range queue.GetPendingForAgent("a1") {
+				queue.Complete(cmd.RequestID, &CommandResult{RequestID: cmd.RequestID, Stdout: []byte("pod \"web\" deleted\n")})
+			}
+		}
+	}()
+
+	park := func(t *testing.T) string {
+		t.Helper()
+		w := execRequest(t, srv, dev, []string{"delete", "pod", "web"})
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("exec code = %d, want 202: %s", w.Code, w.Body.String())
+		}
+		var resp struct{ Approval CommandApproval }
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Approval.ID == "" || resp.Approval.Status != ApprovalStatusPending {
+			t.Fatalf("approval = %+v", resp.Approval)
+		}
+		return resp.Approval.ID
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"commands without require_approval run directly", func(t *testing.T) {
+			if w := execRequest(t, srv, dev, []string{"get", "pods"}); w.Code != http.StatusOK {
+				t.Errorf("code = %d, want 200", w.Code)
+			}
+		}},
+		{"approved command runs and streams back to the requester", func(t *testing.T) {
+			id := park(t)
+			if len(queue.GetPendingForAgent("a1")) != 0 {
+				t.Fatal("parked command must not be queued")
+			}
+
+			done := make(chan *ExecResponse, 1)
+			go func() {
+				w := authzRequest(t, srv, dev, "POST", "/api/v1/approvals/"+id+"/wait", nil)
+				var resp ExecResponse
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				if w.Code != http.StatusOK {
+					resp.Error = w.Body.String()
+				}
+				done <- &resp
+			}()
+
+			if w := authzRequest(t, srv, dev, "POST", "/api/v1/approvals/"+id+"/approve", nil); w.Code != http.StatusForbidden {
+				t.Errorf("self-approve code = %d, want 403", w.Code)
+			}
+			if w := authzRequest(t, srv, viewer, "POST", "/api/v1/approvals/"+id+"/approve", nil); w.Code != http.StatusForbidden {
+				t.Errorf("viewer approve code = %d, want 403", w.Code)
+			}
+			w := authzRequest(t, srv, peer, "GET", "/api/v1/approvals", nil)
+			var list struct{ Approvals []CommandApproval }
+			json.Unmarshal(w.Body.Bytes(), &list)
+			if len(list.Approvals) != 1 || list.Approvals[0].ID != id {
+				t.Errorf("peer list = %+v", list.Approvals)
+			}
+			if w := authzRequest(t, srv, peer, "POST", "/api/v1/approvals/"+id+"/approve", nil); w.Code != http.StatusOK {
+				t.Fatalf("approve code = %d: %s", w.Code, w.Body.String())
+			}
+
+			select {
+			case resp := <-done:
+				if resp.Output != "pod \"web\" deleted\n" || resp.Error != "" {
+					t.Errorf("wait response = %+v", resp)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("wait did not return")
+			}
+
+			for _, status := range []string{AuditStatusApprovalRequested, AuditStatusApprovalApproved} {
+				logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{Status: status})
+				if len(logs) != 1 {
+					t.Errorf("%s audit = %+v", status, logs)
+				}
+			}
+		}},
+		{"denied command does not run", func(t *testing.T) {
+			id := park(t)
+			if w := authzRequest(t, srv, peer, "POST", "/api/v1/approvals/"+id+"/deny", nil); w.Code != http.StatusOK {
+				t.Fatalf("deny code = %d", w.Code)
+			}
+			w := authzRequest(t, srv, dev, "POST", "/api/v1/approvals/"+id+"/wait", nil)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("wait code = %d, want 403", w.Code)
+			}
+		}},
+		{"approval does not outlive a policy change", func(t *testing.T) {
+			id := park(t)
+			if w := authzRequest(t, srv, peer, "POST", "/api/v1/approvals/"+id+"/approve", nil); w.Code != http.StatusOK {
+				t.Fatalf("approve code = %d", w.Code)
+			}
+			// The operator grant is revoked before the requester collects.
+			prev := eng.current.Load()
+			defer eng.current.Store(prev)
+			eng.current.Store(mustParse(t, `
+default: viewer
+roles:
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+`))
+			w := authzRequest(t, srv, dev, "POST", "/api/v1/approvals/"+id+"/wait", nil)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("wait code = %d, want 403: %s", w.Code, w.Body.String())
+			}
+			logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{Status: AuditStatusDenied})
+			if len(logs) == 0 || logs[0].ErrorMessage != "permission denied" {
+				t.Errorf("denied audit = %+v", logs)
+			}
+		}},
+		{"only the requester can wait", func(t *testing.T) {
+			id := park(t)
+			if w := authzRequest(t, srv, peer, "POST", "/api/v1/approvals/"+id+"/wait", nil); w.Code != http.StatusNotFound {
+				t.Errorf("code = %d, want 404", w.Code)
+			}
+		}},
+		{"streaming commands cannot wait for approval", func(t *testing.T) {
+			w := authzRequest(t, srv, dev, "POST", "/api/v1/clusters/prod/stream", ExecRequest{Command: []string{"delete", "pod", "web"}})
+			if w.Code != http.StatusForbidden {
+				t.Errorf("code = %d, want 403", w.Code)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
@@ -0,0 +1,108 @@
+package central
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApprovalQueue(t *testing.T) {
+	req := ExecRequest{Command: []string{"delete", "pod", "web"}, Namespace: "app"}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"approve wakes the waiter once", func(t *testing.T) {
+			q := NewApprovalQueue(time.Minute)
+			a, _ := q.Park("u1", "dev@x.com", "prod", req)
+			if a.Status != ApprovalStatusPending || len(q.Pending()) != 1 {
+				t.Fatalf("parked = %+v", a)
+			}
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				if _, err := q.Decide(a.ID, "lead@x.com", true); err != nil {
+					t.Error(err)
+				}
+			}()
+			got, err := q.Wait(context.Background(), a.ID)
+			if err != nil || got.Status != ApprovalStatusApproved || got.DecidedBy != "lead@x.com" {
+				t.Fatalf("wait = %+v, %v", got, err)
+			}
+			if got.req.Namespace != "app" {
+				t.Errorf("parked request lost: %+v", got.req)
+			}
+			if _, err := q.Wait(context.Background(), a.ID); !errors.Is(err, ErrApprovalNotFound) {
+				t.Errorf("second wait err = %v, want ErrApprovalNotFound", err)
+			}
+		}},
+		{"requester cannot decide and decisions are final", func(t *testing.T) {
+			q := NewApprovalQueue(time.Minute)
+			a, _ := q.Park("u1", "dev@x.com", "prod", req)
+			if _, err := q.Decide(a.ID, "DEV@x.com", true); !errors.Is(err, ErrApprovalSelf) {
+				t.Errorf("err = %v, want ErrApprovalSelf", err)
+			}
+			if _, err := q.Decide(a.ID, "lead@x.com", false); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.Decide(a.ID, "lead@x.com", true); !errors.Is(err, ErrApprovalNotPending) {
+				t.Errorf("err = %v, want ErrApprovalNotPending", err)
+			}
+		}},
+		{"leaving withdraws the approval", func(t *testing.T) {
+			q := NewApprovalQueue(time.Minute)
+			a, _ := q.Park("u1", "dev@x.com", "prod", req)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			got, err := q.Wait(ctx, a.ID)
+			if err != nil || got.Status != ApprovalStatusCanceled {
+				t.Fatalf("wait = %+v, %v", got, err)
+			}
+			if _, err := q.Decide(a.ID, "lead@x.com", true); !errors.Is(err, ErrApprovalNotFound) {
+				t.Errorf("err = %v, want ErrApprovalNotFound", err)
+			}
+		}},
+		{"expires after the timeout", func(t *testing.T) {
+			q := NewApprovalQueue(20 * time.Millisecond)
+			a, _ := q.Park("u1", "dev@x.com", "prod", req)
+			got, err := q.Wait(context.Background(), a.ID)
+			if err != nil || got.Status != ApprovalStatusExpired {
+				t.Fatalf("wait = %+v, %v", got, err)
+			}
+			if len(q.Pending()) != 0 {
+				t.Error("expired approval still pending")
+			}
+		}},
+		{"expiry keeps a decided approval until collected", func(t *testing.T) {
+			q := NewApprovalQueue(time.Minute)
+			a, _ := q.Park("u1", "dev@x.com", "prod", req)
+			if _, err := q.Decide(a.ID, "lead@x.com", true); err != nil {
+				t.Fatal(err)
+			}
+			q.expire(a.ID)
+			got, err := q.Wait(context.Background(), a.ID)
+			if err != nil || got.Status != ApprovalStatusApproved {
+				t.Fatalf("wait = %+v, %v", got, err)
+			}
+		}},
+		{"uncollected decisions are dropped", func(t *testing.T) {
+			q := NewApprovalQueue(time.Minute)
+			a, _ := q.Park("u1", "dev@x.com", "prod", req)
+			q.drop(a.ID)
+			if _, ok := q.Get(a.ID); !ok {
+				t.Fatal("pending approval dropped")
+			}
+			if _, err := q.Decide(a.ID, "lead@x.com", true); err != nil {
+				t.Fatal(err)
+			}
+			q.drop(a.ID)
+			if _, err := q.Wait(context.Background(), a.ID); !errors.Is(err, ErrApprovalNotFound) {
+				t.Errorf("err = %v, want ErrApprovalNotFound", err)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
@@ -8,29 +8,57 @@ import (
 
 // Audit status values for a recorded command.
 const (
-	AuditStatusSuccess = "success"
-	AuditStatusFailed  = "failed"
-	AuditStatusDenied  = "denied"
-	AuditStatusTimeout = "timeout"
+	AuditStatusSuccess  = "success"
+	AuditStatusFailed   = "failed"
+	AuditStatusDenied   = "denied"
+	AuditStatusTimeout  = "timeout"
 	AuditStatusCanceled = "canceled"
+	// AuditStatusPolicyChange marks an RBAC policy update or rollback.
+	AuditStatusPolicyChange = "policy_change"
+	// JIT access request lifecycle events.
+	AuditStatusJITRequested = "jit_requested"
+	AuditStatusJITApproved  = "jit_approved"
+	AuditStatusJITDenied    = "jit_denied"
+	// AuditStatusBreakglass marks a break-glass activation and every command
+	// run while the user's break-glass window on that cluster is open.
+	AuditStatusBreakglass = "breakglass"
+	// Command approval lifecycle events. The command itself is audited with
+	// its outcome once it runs.
+	AuditStatusApprovalRequested = "approval_requested"
+	AuditStatusApprovalApproved  = "approval_approved"
+	AuditStatusApprovalDenied    = "approval_denied"
 )
 
+// isExecOutcome reports whether status records how a command that was let
+// through ended, as opposed to a denial or a lifecycle event.
+func isExecOutcome(status string) bool {
+	switch status {
+	case AuditStatusSuccess, AuditStatusFailed, AuditStatusTimeout, AuditStatusCanceled:
+		return true
+	}
+	return false
+}
+
 // auditWriteTimeout bounds how long an audit insert may take.
 const auditWriteTimeout = 5 * time.Second
 
-// AuditRecorder persists audit log entries. Recording never blocks on the
-// caller's request context and never fails the caller: write errors are logged.
+// AuditRecorder persists audit log entries and forwards them to any
+// configured sinks. Recording never blocks on the caller's request context and
+// never fails the caller: write errors are logged.
 type AuditRecorder struct {
 	store Store
+	sinks []AuditSink
 }
 
-// NewAuditRecorder creates an AuditRecorder backed by the given store.
-func NewAuditRecorder(store Store) *AuditRecorder {
-	return &AuditRecorder{store: store}
+// NewAuditRecorder creates an AuditRecorder backed by the given store that
+// also forwards every entry to sinks.
+func NewAuditRecorder(store Store, sinks ...AuditSink) *AuditRecorder {
+	return &AuditRecorder{store: store, sinks: sinks}
 }
 
 // Record persists a single audit entry. It uses a background context so a
-// cancelled request still produces an audit trail.
+// cancelled request still produces an audit trail. The entry is forwarded to
+// the sinks even if the database write fails.
 func (r *AuditRecorder) Record(entry *AuditLog) {
 	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
 	defer cancel()
@@ -38,4 +66,39 @@ func (r *AuditRecorder) Record(entry *AuditLog) {
 		log.Printf("audit: failed to record %q on %s by %s: %v",
 			entry.Command, entry.ClusterName, entry.UserEmail, err)
 	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	for _, s := range r.sinks {
+		s.Write(entry)
+	}
+}
+
+// SaveRecording stores a session recording for an audit entry that Record
+// has already written. Failures are logged.
+func (r *AuditRecorder) SaveRecording(rec *SessionRecording) {
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	if err := r.store.CreateSessionRecording(ctx, rec); err != nil {
+		log.Printf("audit: failed to save session recording for %s: %v", rec.AuditLogID, err)
+	}
+}
+
+// SaveOutput stores the captured output of a command whose audit entry Record
+// has already written. Failures are logged.
+func (r *AuditRecorder) SaveOutput(o *AuditOutput) {
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	if err := r.store.CreateAuditOutput(ctx, o); err != nil {
+		log.Printf("audit: failed to save output for %s: %v", o.AuditLogID, err)
+	}
+}
+
+// Close flushes and closes the sinks.
+func (r *AuditRecorder) Close() {
+	for _, s := range r.sinks {
+		if err := s.Close(); err != nil {
+			log.Printf("audit: closing sink: %v", err)
+		}
+	}
 }
@@ -0,0 +1,215 @@
+package central
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+)
+
+const (
+	// auditVerifyPageSize is how many entries VerifyAuditChain reads at once.
+	auditVerifyPageSize = 500
+	// maxAuditChainProblems caps the problems listed in one report.
+	maxAuditChainProblems = 100
+	// auditChainLockID is the PostgreSQL advisory lock key held while
+	// appending to the chain or checkpointing it.
+	auditChainLockID = 7317064302
+)
+
+// Kinds of AuditChainProblem.
+const (
+	AuditProblemGap        = "gap"        // entries missing from the chain
+	AuditProblemModified   = "modified"   // an entry no longer matches its hash
+	AuditProblemRelinked   = "relinked"   // prev_hash does not match the entry before
+	AuditProblemCheckpoint = "checkpoint" // the checkpoint signature is invalid
+)
+
+// AuditChain computes the hashes that link audit entries into a
+// tamper-evident chain: each entry's hash covers its contents, its sequence
+// number and the previous entry's hash, so deleting or editing a row breaks
+// every link after it. With a key the hashes are HMAC-SHA256, so someone with
+// database access but without the key cannot rewrite the chain to hide a
+// change; without one they are plain SHA-256 and only catch careless edits.
+type AuditChain struct {
+	key []byte
+}
+
+// NewAuditChain creates an AuditChain keyed with key, which may be empty.
+func NewAuditChain(key []byte) *AuditChain {
+	return &AuditChain{key: key}
+}
+
+func (c *AuditChain) mac() hash.Hash {
+	if len(c.key) == 0 {
+		return sha256.New()
+	}
+	return hmac.New(sha256.New, c.key)
+}
+
+// auditChainRecord is the canonical form of an entry that Hash covers. Field
+// order is fixed by the struct, so the encoding is stable. user_id and
+// cluster_id are left out: the database clears them when the user or cluster
+// is deleted, and the entry still names both by email and cluster name.
+type auditChainRecord struct {
+	Seq          int64  `json:"seq"`
+	PrevHash     string `json:"prev_hash"`
+	ID           string `json:"id"`
+	UserEmail    string `json:"user_email"`
+	ClusterName  string `json:"cluster_name"`
+	Command      string `json:"command"`
+	Namespace    string `json:"namespace"`
+	Status       string `json:"status"`
+	ExitCode     *int32 `json:"exit_code"`
+	DurationMs   *int64 `json:"duration_ms"`
+	ErrorMessage string `json:"error_message"`
+	ClientIP     string `json:"client_ip"`
+	CreatedAt    string `json:"created_at"`
+	// Added after the chain was introduced; omitted when empty so older
+	// entries keep their hashes.
+	Verb     string `json:"verb,omitempty"`
+	Resource string `json:"resource,omitempty"`
+}
+
+// Hash returns the chain hash of l, which must have Seq, PrevHash and
+// CreatedAt set as stored.
+func (c *AuditChain) Hash(l *AuditLog) string {
+	b, _ := json.Marshal(auditChainRecord{
+		Seq:          l.Seq,
+		PrevHash:     l.PrevHash,
+		ID:           l.ID,
+		UserEmail:    l.UserEmail,
+		ClusterName:  l.ClusterName,
+		Command:      l.Command,
+		Namespace:    l.Namespace,
+		Status:       l.Status,
+		ExitCode:     l.ExitCode,
+		DurationMs:   l.DurationMs,
+		ErrorMessage: l.ErrorMessage,
+		ClientIP:     l.ClientIP,
+		CreatedAt:    l.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Verb:         l.Verb,
+		Resource:     l.Resource,
+	})
+	h := c.mac()
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// link sets l's position in the chain after the entry (headSeq, headHash) and
+// computes its hash.
+func (c *AuditChain) link(l *AuditLog, headSeq int64, headHash string) {
+	l.Seq = headSeq + 1
+	l.PrevHash = headHash
+	l.Hash = c.Hash(l)
+}
+
+// Sign returns the signature of a checkpoint.
+func (c *AuditChain) Sign(cp *AuditCheckpoint) string {
+	h := c.mac()
+	fmt.Fprintf(h, "checkpoint\n%s\n%d\n%s\n%d\n%s",
+		cp.ID, cp.Seq, cp.Hash, cp.Deleted, cp.CreatedAt.UTC().Format(time.RFC3339Nano))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditChainProblem is one inconsistency found by VerifyAuditChain.
+type AuditChainProblem struct {
+	Kind   string `json:"kind"`
+	Seq    int64  `json:"seq"`
+	ID     string `json:"id,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// AuditVerifyReport is the result of walking the audit chain.
+type AuditVerifyReport struct {
+	OK         bool                `json:"ok"`
+	Checked    int                 `json:"checked"`
+	FirstSeq   int64               `json:"first_seq,omitempty"`
+	LastSeq    int64               `json:"last_seq,omitempty"`
+	HeadHash   string              `json:"head_hash,omitempty"`
+	Keyed      bool                `json:"keyed"`
+	Checkpoint *AuditCheckpoint    `json:"checkpoint,omitempty"`
+	Problems   []AuditChainProblem `json:"problems,omitempty"`
+	Truncated  bool                `json:"truncated,omitempty"`
+}
+
+func (r *AuditVerifyReport) problem(p AuditChainProblem) {
+	r.OK = false
+	if len(r.Problems) >= maxAuditChainProblems {
+		r.Truncated = true
+		return
+	}
+	r.Problems = append(r.Problems, p)
+}
+
+// Verify walks the chain in store from the latest checkpoint (or the
+// beginning) and reports gaps, edited entries and broken links. Each entry is
+// checked against its stored hash, so one edited row is reported once rather
+// than breaking every later link. Deleting the newest entries cannot be
+// detected from the chain alone; compare HeadHash with a copy kept elsewhere,
+// such as an audit sink.
+func (c *AuditChain) Verify(ctx context.Context, store Store) (*AuditVerifyReport, error) {
+	report := &AuditVerifyReport{OK: true, Keyed: len(c.key) > 0}
+	var prevSeq int64
+	var prevHash string
+
+	cp, err := store.GetLatestAuditCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cp != nil {
+		report.Checkpoint = cp
+		if !hmac.Equal([]byte(cp.Signature), []byte(c.Sign(cp))) {
+			report.problem(AuditChainProblem{Kind: AuditProblemCheckpoint, Seq: cp.Seq, ID: cp.ID,
+				Detail: "checkpoint signature does not match; it was altered or written with another key"})
+		}
+		prevSeq, prevHash = cp.Seq, cp.Hash
+	}
+
+	after := prevSeq
+	for {
+		page, err := store.ListAuditChain(ctx, after, auditVerifyPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range page {
+			if report.Checked == 0 {
+				report.FirstSeq = l.Seq
+			}
+			report.Checked++
+			switch {
+			case l.Seq != prevSeq+1:
+				report.problem(AuditChainProblem{Kind: AuditProblemGap, Seq: l.Seq, ID: l.ID,
+					Detail: fmt.Sprintf("entries %s missing before this one", seqRange(prevSeq+1, l.Seq-1))})
+			case l.PrevHash != prevHash:
+				report.problem(AuditChainProblem{Kind: AuditProblemRelinked, Seq: l.Seq, ID: l.ID,
+					Detail: "prev_hash does not match the preceding entry"})
+			}
+			if !hmac.Equal([]byte(l.Hash), []byte(c.Hash(l))) {
+				report.problem(AuditChainProblem{Kind: AuditProblemModified, Seq: l.Seq, ID: l.ID,
+					Detail: "contents do not match the stored hash"})
+			}
+			prevSeq, prevHash = l.Seq, l.Hash
+		}
+		if len(page) < auditVerifyPageSize {
+			break
+		}
+		after = prevSeq
+	}
+	if report.Checked > 0 {
+		report.LastSeq, report.HeadHash = prevSeq, prevHash
+	}
+	return report, nil
+}
+
+func seqRange(from, to int64) string {
+	if from == to {
+		return strconv.FormatInt(from, 10)
+	}
+	return fmt.Sprintf("%d-%d", from, to)
+}
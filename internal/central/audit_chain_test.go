@@ -0,0 +1,183 @@
+package central
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAuditChain_Hash(t *testing.T) {
+	l := &AuditLog{ID: "a", UserEmail: "u@test.com", Command: "get pods", Status: AuditStatusSuccess,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	unkeyed := NewAuditChain(nil)
+	unkeyed.link(l, 4, "prev")
+	if l.Seq != 5 || l.PrevHash != "prev" || l.Hash == "" {
+		t.Fatalf("link set seq=%d prev=%q hash=%q", l.Seq, l.PrevHash, l.Hash)
+	}
+	if got := NewAuditChain([]byte("k")).Hash(l); got == l.Hash {
+		t.Error("keyed and unkeyed hashes should differ")
+	}
+	edited := *l
+	edited.Command = "delete pods"
+	if unkeyed.Hash(&edited) == l.Hash {
+		t.Error("hash should cover the command")
+	}
+}
+
+func TestAuditChain_Verify(t *testing.T) {
+	chain := NewAuditChain([]byte("chain-secret"))
+	seed := func(t *testing.T, store *testBackend, n int) {
+		t.Helper()
+		store.SetAuditChain(chain)
+		for i := 0; i < n; i++ {
+			if err := store.CreateAuditLog(context.Background(), &AuditLog{
+				UserEmail: "u@test.com", ClusterName: "prod", Command: "get pods", Status: AuditStatusSuccess,
+			}); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+		}
+	}
+	verify := func(t *testing.T, store *testBackend) *AuditVerifyReport {
+		t.Helper()
+		r, err := chain.Verify(context.Background(), store)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		return r
+	}
+	wantProblem := func(t *testing.T, r *AuditVerifyReport, kind string, seq int64) {
+		t.Helper()
+		if r.OK {
+			t.Fatalf("want a %s problem at #%d, chain reported OK", kind, seq)
+		}
+		for _, p := range r.Problems {
+			if p.Kind == kind && p.Seq == seq {
+				return
+			}
+		}
+		t.Fatalf("want a %s problem at #%d, got %+v", kind, seq, r.Problems)
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store *testBackend)
+	}{
+		{"intact chain verifies", func(t *testing.T, store *testBackend) {
+			seed(t, store, 3)
+			r := verify(t, store)
+			if !r.OK || r.Checked != 3 || r.FirstSeq != 1 || r.LastSeq != 3 || !r.Keyed {
+				t.Fatalf("unexpected report: %+v", r)
+			}
+			logs, err := store.ListAuditChain(context.Background(), 0, 10)
+			if err != nil {
+				t.Fatalf("list chain: %v", err)
+			}
+			if logs[1].PrevHash != logs[0].Hash || logs[2].PrevHash != logs[1].Hash {
+				t.Error("entries are not linked to their predecessors")
+			}
+			if r.HeadHash != logs[2].Hash {
+				t.Errorf("head hash = %q, want the last entry's", r.HeadHash)
+			}
+		}},
+		{"edited entry is reported", func(t *testing.T, store *testBackend) {
+			seed(t, store, 3)
+			store.exec(t, `UPDATE audit_logs SET command = ? WHERE seq = ?`, "get secrets", 2)
+			r := verify(t, store)
+			wantProblem(t, r, AuditProblemModified, 2)
+			if len(r.Problems) != 1 {
+				t.Errorf("one edit should be one problem, got %+v", r.Problems)
+			}
+		}},
+		{"deleting the user and cluster keeps the chain valid", func(t *testing.T, store *testBackend) {
+			ctx := context.Background()
+			user := &User{Email: "u@test.com", PasswordHash: "hash", IsActive: true}
+			if err := store.CreateUser(ctx, user); err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+			cluster := &Cluster{Name: "prod", Status: "connected"}
+			if err := store.CreateCluster(ctx, cluster); err != nil {
+				t.Fatalf("create cluster: %v", err)
+			}
+			store.SetAuditChain(chain)
+			for i := 0; i < 2; i++ {
+				if err := store.CreateAuditLog(ctx, &AuditLog{UserID: user.ID, UserEmail: user.Email,
+					ClusterID: cluster.ID, ClusterName: cluster.Name, Command: "get pods", Status: AuditStatusSuccess,
+				}); err != nil {
+					t.Fatalf("create: %v", err)
+				}
+			}
+			if err := store.DeleteUser(ctx, user.ID); err != nil {
+				t.Fatalf("delete user: %v", err)
+			}
+			if err := store.DeleteCluster(ctx, cluster.ID); err != nil {
+				t.Fatalf("delete cluster: %v", err)
+			}
+			if r := verify(t, store); !r.OK || r.Checked != 2 {
+				t.Fatalf("chain after deleting the user and cluster: %+v", r)
+			}
+		}},
+		{"deleted entry is a gap", func(t *testing.T, store *testBackend) {
+			seed(t, store, 4)
+			store.exec(t, `DELETE FROM audit_logs WHERE seq = ?`, 2)
+			wantProblem(t, verify(t, store), AuditProblemGap, 3)
+		}},
+		{"deleted first entry is a gap", func(t *testing.T, store *testBackend) {
+			seed(t, store, 2)
+			store.exec(t, `DELETE FROM audit_logs WHERE seq = ?`, 1)
+			wantProblem(t, verify(t, store), AuditProblemGap, 2)
+		}},
+		{"a wrong key fails every entry", func(t *testing.T, store *testBackend) {
+			seed(t, store, 2)
+			r, err := NewAuditChain([]byte("other")).Verify(context.Background(), store)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			wantProblem(t, r, AuditProblemModified, 1)
+			wantProblem(t, r, AuditProblemModified, 2)
+		}},
+		{"cleanup checkpoints the chain", func(t *testing.T, store *testBackend) {
+			ctx := context.Background()
+			seed(t, store, 4)
+			store.exec(t, `UPDATE audit_logs SET created_at = ? WHERE seq <= ?`, time.Now().Add(-48*time.Hour), 2)
+			n, err := store.CleanupOldAuditLogs(ctx, time.Now().Add(-24*time.Hour))
+			if err != nil || n != 2 {
+				t.Fatalf("cleanup = %d, %v; want 2", n, err)
+			}
+			cp, err := store.GetLatestAuditCheckpoint(ctx)
+			if err != nil || cp == nil || cp.Seq != 2 || cp.Deleted != 2 {
+				t.Fatalf("checkpoint = %+v, %v", cp, err)
+			}
+			r := verify(t, store)
+			if !r.OK || r.Checked != 2 || r.FirstSeq != 3 {
+				t.Fatalf("chain after cleanup: %+v", r)
+			}
+
+			// Once every entry is gone, new ones continue from the checkpoint.
+			store.exec(t, `UPDATE audit_logs SET created_at = ?`, time.Now().Add(-48*time.Hour))
+			if _, err := store.CleanupOldAuditLogs(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+				t.Fatalf("cleanup: %v", err)
+			}
+			seed(t, store, 1)
+			if r := verify(t, store); !r.OK || r.FirstSeq != 5 {
+				t.Fatalf("chain after full cleanup: %+v", r)
+			}
+		}},
+		{"forged checkpoint is reported", func(t *testing.T, store *testBackend) {
+			ctx := context.Background()
+			seed(t, store, 3)
+			store.exec(t, `UPDATE audit_logs SET created_at = ? WHERE seq = ?`, time.Now().Add(-48*time.Hour), 1)
+			if _, err := store.CleanupOldAuditLogs(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+				t.Fatalf("cleanup: %v", err)
+			}
+			// Hide entry 2 by moving the checkpoint past it.
+			store.exec(t, `DELETE FROM audit_logs WHERE seq = ?`, 2)
+			store.exec(t, `UPDATE audit_checkpoints SET seq = ?`, 2)
+			wantProblem(t, verify(t, store), AuditProblemCheckpoint, 2)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachStore(t, tt.fn)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package central
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditExportPageSize is how many entries an export reads per query. Pages
+// are written and flushed one at a time, so memory use does not grow with the
+// size of the export and the database is not held for its whole duration.
+const auditExportPageSize = 500
+
+// Audit export formats.
+const (
+	AuditExportCSV   = "csv"
+	AuditExportJSONL = "jsonl"
+)
+
+// auditCSVHeader names the columns of a CSV export.
+var auditCSVHeader = []string{
+	"id", "seq", "created_at", "user_email", "cluster_name", "namespace", "verb", "resource",
+	"command", "status", "exit_code", "duration_ms", "error_message", "client_ip", "hash",
+}
+
+// auditCursorAt returns the opaque cursor that continues a listing after l.
+func auditCursorAt(l *AuditLog) string {
+	raw := fmt.Sprintf("%s|%d|%s", l.CreatedAt.UTC().Format(time.RFC3339Nano), l.Seq, l.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseAuditCursor decodes a cursor from auditCursorAt. The empty cursor
+// means the start of the listing and returns nil.
+func parseAuditCursor(s string) (*AuditCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	seq, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditCursor{CreatedAt: t, Seq: seq, ID: parts[2]}, nil
+}
+
+// HandleExportAuditLogs streams every audit entry matching the query filters
+// (see auditFilterFromQuery) as CSV or JSON lines, oldest first unless
+// order=desc. Errors after the first row can no longer change the status, so
+// they end the stream early and are logged.
+func (h *AdminHandlers) HandleExportAuditLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", AuditExportJSONL)
+	contentType := "application/x-ndjson"
+	switch format {
+	case AuditExportJSONL:
+	case AuditExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or jsonl"})
+		return
+	}
+	filter, ok := auditFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	if c.Query("order") == "" {
+		filter.Ascending = true
+	}
+
+	ctx := c.Request.Context()
+	// Fetch the first page before committing to a 200.
+	page, err := h.store.ListAuditLogsAfter(ctx, filter, nil, auditExportPageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.%s"`,
+		time.Now().UTC().Format("20060102T150405Z"), format))
+	c.Status(http.StatusOK)
+
+	w := newAuditExportWriter(c.Writer, format)
+	exported := 0
+	for {
+		for _, l := range page {
+			if err := w.write(l); err != nil {
+				log.Printf("audit export: writing: %v", err)
+				return
+			}
+		}
+		exported += len(page)
+		if err := w.flush(); err != nil {
+			log.Printf("audit export: writing: %v", err)
+			return
+		}
+		c.Writer.Flush()
+		if len(page) < auditExportPageSize || ctx.Err() != nil {
+			break
+		}
+		page, err = h.store.ListAuditLogsAfter(ctx, filter, auditCursorFor(page[len(page)-1]), auditExportPageSize)
+		if err != nil {
+			log.Printf("audit export: stopped after %d entries: %v", exported, err)
+			return
+		}
+	}
+}
+
+// auditExportWriter encodes audit entries in one export format.
+type auditExportWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newAuditExportWriter(w io.Writer, format string) *auditExportWriter {
+	if format == AuditExportCSV {
+		cw := csv.NewWriter(w)
+		_ = cw.Write(auditCSVHeader)
+		return &auditExportWriter{csv: cw}
+	}
+	return &auditExportWriter{json: json.NewEncoder(w)}
+}
+
+func (w *auditExportWriter) write(l *AuditLog) error {
+	if w.json != nil {
+		return w.json.Encode(l)
+	}
+	seq, exit, dur := "", "", ""
+	if l.Seq > 0 {
+		seq = strconv.FormatInt(l.Seq, 10)
+	}
+	if l.ExitCode != nil {
+		exit = strconv.Itoa(int(*l.ExitCode))
+	}
+	if l.DurationMs != nil {
+		dur = strconv.FormatInt(*l.DurationMs, 10)
+	}
+	return w.csv.Write([]string{
+		l.ID, seq, l.CreatedAt.UTC().Format(time.RFC3339Nano), l.UserEmail, l.ClusterName, l.Namespace,
+		l.Verb, l.Resource, l.Command, l.Status, exit, dur, l.ErrorMessage, l.ClientIP, l.Hash,
+	})
+}
+
+func (w *auditExportWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
@@ -0,0 +1,114 @@
+package central
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// File sink defaults.
+const (
+	defaultFileSinkMaxSizeMB  = 100
+	defaultFileSinkMaxBackups = 5
+)
+
+// fileSink appends each audit entry as one JSON line. When the file would
+// grow past its size limit it is rotated: path becomes path.1, path.1 becomes
+// path.2 and so on, keeping at most maxBackups old files.
+type fileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	queue *auditQueue
+	f     *os.File // owned by the queue goroutine
+	size  int64
+}
+
+func newFileSink(cfg AuditSinkConfig) (*fileSink, error) {
+	s := &fileSink{path: cfg.Path, maxBytes: int64(cfg.MaxSizeMB) << 20, maxBackups: cfg.MaxBackups}
+	if s.maxBytes == 0 {
+		s.maxBytes = defaultFileSinkMaxSizeMB << 20
+	}
+	if s.maxBackups == 0 {
+		s.maxBackups = defaultFileSinkMaxBackups
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	s.queue = newAuditQueue("file "+cfg.Path, s.deliver)
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", s.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("opening %s: %w", s.path, err)
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+// Write encodes entry and queues it.
+func (s *fileSink) Write(entry *AuditLog) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit file: encoding entry: %v", err)
+		return
+	}
+	if !s.queue.push(append(line, '\n')) {
+		log.Printf("audit file: queue full or closed, dropping entry")
+	}
+}
+
+// Close flushes the queue and closes the file.
+func (s *fileSink) Close() error {
+	s.queue.close()
+	if s.f != nil {
+		return s.f.Close()
+	}
+	return nil
+}
+
+func (s *fileSink) deliver(line []byte) {
+	if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			log.Printf("audit file: rotating %s: %v", s.path, err)
+		}
+	}
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			log.Printf("audit file: %v", err)
+			return
+		}
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		log.Printf("audit file: writing %s: %v", s.path, err)
+	}
+}
+
+// rotate shifts the backups up by one, dropping the oldest, and moves the
+// current file to path.1. The next write reopens path.
+func (s *fileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	s.f = nil
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	return os.Rename(s.path, s.path+".1")
+}
@@ -0,0 +1,144 @@
+package central
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Output capture modes for audit.output.mode.
+const (
+	OutputCaptureOff    = "off"    // store no output
+	OutputCaptureStderr = "stderr" // store stderr only
+	OutputCaptureFull   = "full"   // store stdout and stderr
+)
+
+// DefaultOutputCaptureMaxKB caps each captured stream when audit.output.max_kb
+// is not set.
+const DefaultOutputCaptureMaxKB = 64
+
+// redactedText replaces a redacted secret.
+const redactedText = "[REDACTED]"
+
+// defaultRedactPatterns match common secrets in kubectl output. When a pattern
+// has a capture group, the text it matches is kept and only the rest of the
+// match is replaced, so "password: hunter2" becomes "password: [REDACTED]".
+var defaultRedactPatterns = []string{
+	// PEM private keys.
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	// Bearer tokens in headers or kubeconfigs.
+	`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`,
+	// JWTs, including service account tokens.
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	// key: value and key=value pairs whose key names a secret.
+	`(?i)((?:password|passwd|secret|token|api[_-]?key|access[_-]?key|client[_-]?secret)[\w.-]*["']?\s*[:=]\s*["']?)[^\s"',]+`,
+	// The data of a Secret printed with -o yaml or -o json: long base64 values.
+	`(?m)(^[ \t]*"?[\w.-]+"?[ \t]*:[ \t]*"?)[A-Za-z0-9+/]{20,}={0,2}("?,?[ \t]*$)`,
+	// AWS access key IDs.
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+}
+
+// OutputCaptureConfig controls capture of one-shot command output into the
+// audit trail.
+type OutputCaptureConfig struct {
+	// Mode is off (the default), stderr or full.
+	Mode string `yaml:"mode"`
+	// MaxKB caps each stream; the rest is dropped. Zero means
+	// DefaultOutputCaptureMaxKB.
+	MaxKB int `yaml:"max_kb"`
+	// Redact adds regular expressions to the built-in secret patterns.
+	Redact []string `yaml:"redact"`
+}
+
+func (c OutputCaptureConfig) enabled() bool {
+	return c.Mode != "" && c.Mode != OutputCaptureOff
+}
+
+// OutputCapture redacts and truncates command output for storage with its
+// audit entry.
+type OutputCapture struct {
+	stdout bool
+	limit  int
+	redact []*regexp.Regexp
+}
+
+// NewOutputCapture compiles cfg's redaction patterns.
+func NewOutputCapture(cfg OutputCaptureConfig) (*OutputCapture, error) {
+	c := &OutputCapture{stdout: cfg.Mode == OutputCaptureFull, limit: cfg.MaxKB * 1024}
+	if c.limit <= 0 {
+		c.limit = DefaultOutputCaptureMaxKB * 1024
+	}
+	for _, p := range append(append([]string{}, defaultRedactPatterns...), cfg.Redact...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		c.redact = append(c.redact, re)
+	}
+	return c, nil
+}
+
+// Capture returns the output to store for an audit entry, or nil when the
+// mode keeps none of it. The redaction patterns cannot recognize a bare
+// secret value (get secret -o jsonpath={.data.password}), so the stdout of a
+// command that may read secrets is never stored; only its size is.
+func (c *OutputCapture) Capture(auditLogID string, stdout, stderr []byte, readsSecrets bool) *AuditOutput {
+	o := &AuditOutput{AuditLogID: auditLogID, StderrBytes: int64(len(stderr))}
+	var n int
+	o.Stderr, o.Truncated, n = c.clean(stderr)
+	o.Redactions += n
+	if c.stdout {
+		o.StdoutBytes = int64(len(stdout))
+	}
+	if c.stdout && !readsSecrets {
+		var truncated bool
+		o.Stdout, truncated, n = c.clean(stdout)
+		o.Truncated = o.Truncated || truncated
+		o.Redactions += n
+	}
+	if o.Stdout == "" && o.Stderr == "" {
+		return nil
+	}
+	return o
+}
+
+// readsSecrets reports whether a command whose resource types are resources
+// (comma-separated, as recorded in the audit log) may print Secret data:
+// it names secrets, or its resources cannot be determined from its arguments.
+func readsSecrets(resources string) bool {
+	for _, r := range strings.Split(resources, ",") {
+		if r == "secrets" || r == anyResource {
+			return true
+		}
+	}
+	return false
+}
+
+// clean makes b storable as text, redacts secrets and truncates the result to
+// the limit. Redaction comes first so a cut never leaves half a secret.
+func (c *OutputCapture) clean(b []byte) (string, bool, int) {
+	s := strings.ToValidUTF8(strings.ReplaceAll(string(b), "\x00", ""), "�")
+	redactions := 0
+	for _, re := range c.redact {
+		repl := redactedText
+		if re.NumSubexp() > 0 {
+			repl = "${1}" + redactedText
+			if re.NumSubexp() > 1 {
+				repl += "${2}"
+			}
+		}
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			redactions++
+			return re.ReplaceAllString(m, repl)
+		})
+	}
+	if len(s) <= c.limit {
+		return s, false, redactions
+	}
+	cut := c.limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true, redactions
+}
@@ -0,0 +1,108 @@
+package central
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// auditQueueSize is how many entries a sink buffers before it overflows.
+	auditQueueSize = 1024
+	// auditSinkCloseTimeout bounds how long Close waits for a sink to flush.
+	auditSinkCloseTimeout = 5 * time.Second
+)
+
+// AuditSink forwards audit entries to an external system. Write must not
+// block: sinks encode the entry immediately and deliver it in the background.
+type AuditSink interface {
+	Write(entry *AuditLog)
+	Close() error
+}
+
+// NewAuditSinks creates the sinks described by cfgs. On error, sinks created
+// so far are closed.
+func NewAuditSinks(cfgs []AuditSinkConfig) ([]AuditSink, error) {
+	var sinks []AuditSink
+	for i, cfg := range cfgs {
+		var (
+			s   AuditSink
+			err error
+		)
+		switch cfg.Type {
+		case AuditSinkWebhook:
+			s, err = newWebhookSink(cfg)
+		case AuditSinkSyslog:
+			s, err = newSyslogSink(cfg)
+		case AuditSinkFile:
+			s, err = newFileSink(cfg)
+		default:
+			err = fmt.Errorf("unknown type %q", cfg.Type)
+		}
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, fmt.Errorf("audit sink %d (%s): %w", i, cfg.Type, err)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// auditQueue buffers encoded entries for one sink and hands them to deliver on
+// a dedicated goroutine, so a slow or unreachable sink never holds up Record.
+type auditQueue struct {
+	name string
+	ch   chan []byte
+	done chan struct{}
+
+	// mu guards closed, so that push never sends on the closed channel when
+	// an entry is recorded while central shuts down.
+	mu     sync.Mutex
+	closed bool
+}
+
+func newAuditQueue(name string, deliver func([]byte)) *auditQueue {
+	q := &auditQueue{name: name, ch: make(chan []byte, auditQueueSize), done: make(chan struct{})}
+	go func() {
+		defer close(q.done)
+		for b := range q.ch {
+			deliver(b)
+		}
+	}()
+	return q
+}
+
+// push enqueues b and reports false when the buffer is full or the queue has
+// been closed.
+func (q *auditQueue) push(b []byte) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.ch <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops accepting entries and waits, up to auditSinkCloseTimeout, for
+// the buffered ones to be delivered.
+func (q *auditQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mu.Unlock()
+	select {
+	case <-q.done:
+	case <-time.After(auditSinkCloseTimeout):
+		log.Printf("audit sink %s: %d entries not delivered at shutdown", q.name, len(q.ch))
+	}
+}
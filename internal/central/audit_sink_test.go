@@ -0,0 +1,309 @@
+package central
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testAuditEntry(command, status string) *AuditLog {
+	return &AuditLog{
+		ID:          "log-1",
+		UserEmail:   "u@example.com",
+		ClusterName: "dev-1",
+		Command:     command,
+		Status:      status,
+		CreatedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// webhookRecorder is an httptest handler that answers with status and keeps
+// the commands of the entries it accepted.
+type webhookRecorder struct {
+	mu       sync.Mutex
+	status   int
+	commands []string
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (h *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.status != http.StatusOK {
+		w.WriteHeader(h.status)
+		return
+	}
+	var e AuditLog
+	json.Unmarshal(body, &e)
+	h.commands = append(h.commands, e.Command)
+	h.headers = append(h.headers, r.Header.Clone())
+	h.bodies = append(h.bodies, body)
+}
+
+func (h *webhookRecorder) setStatus(status int) {
+	h.mu.Lock()
+	h.status = status
+	h.mu.Unlock()
+}
+
+func (h *webhookRecorder) received() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.commands...)
+}
+
+func TestWebhookSink(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"signs deliveries with the secret", func(t *testing.T) {
+			h := &webhookRecorder{status: http.StatusOK}
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			s, err := newWebhookSink(AuditSinkConfig{Type: AuditSinkWebhook, URL: srv.URL, Secret: "s3cret"})
+			if err != nil {
+				t.Fatalf("newWebhookSink: %v", err)
+			}
+			s.Write(testAuditEntry("get pods", AuditStatusSuccess))
+			s.Close()
+
+			if got := h.received(); len(got) != 1 || got[0] != "get pods" {
+				t.Fatalf("received %v", got)
+			}
+			ts := h.headers[0].Get(webhookTimestampHeader)
+			if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+				t.Fatalf("bad timestamp header %q", ts)
+			}
+			if got, want := h.headers[0].Get(webhookSignatureHeader), signWebhook([]byte("s3cret"), ts, h.bodies[0]); got != want {
+				t.Errorf("signature = %q, want %q", got, want)
+			}
+		}},
+		{"retries until the endpoint accepts", func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+			}))
+			defer srv.Close()
+			s, err := newWebhookSink(AuditSinkConfig{Type: AuditSinkWebhook, URL: srv.URL, MaxRetries: 3})
+			if err != nil {
+				t.Fatalf("newWebhookSink: %v", err)
+			}
+			s.backoff = time.Millisecond
+			s.Write(testAuditEntry("get pods", AuditStatusSuccess))
+			waitFor(t, func() bool { return calls.Load() >= 3 })
+			s.Close()
+			if n := calls.Load(); n != 3 {
+				t.Errorf("want 3 attempts, got %d", n)
+			}
+		}},
+		{"spools while the endpoint is down and redelivers in order", func(t *testing.T) {
+			h := &webhookRecorder{status: http.StatusServiceUnavailable}
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			dir := t.TempDir()
+			s, err := newWebhookSink(AuditSinkConfig{Type: AuditSinkWebhook, URL: srv.URL, MaxRetries: 1, SpoolDir: dir})
+			if err != nil {
+				t.Fatalf("newWebhookSink: %v", err)
+			}
+			defer s.Close()
+			s.backoff = time.Millisecond
+			s.Write(testAuditEntry("first", AuditStatusSuccess))
+			s.Write(testAuditEntry("second", AuditStatusSuccess))
+			waitFor(t, func() bool { return s.spool.len() == 2 })
+
+			h.setStatus(http.StatusOK)
+			s.drain()
+			if got := h.received(); strings.Join(got, ",") != "first,second" {
+				t.Errorf("received %v, want [first second]", got)
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("spool not emptied: %d files left", len(files))
+			}
+		}},
+		{"drops entries beyond the spool limit", func(t *testing.T) {
+			sp, err := openAuditSpool(t.TempDir(), 10)
+			if err != nil {
+				t.Fatalf("openAuditSpool: %v", err)
+			}
+			if err := sp.add([]byte("12345678")); err != nil {
+				t.Fatalf("add: %v", err)
+			}
+			if err := sp.add([]byte("123")); err != errSpoolFull {
+				t.Errorf("want errSpoolFull, got %v", err)
+			}
+		}},
+		{"reopens an existing spool", func(t *testing.T) {
+			dir := t.TempDir()
+			sp, _ := openAuditSpool(dir, 1<<20)
+			sp.add([]byte(`{"command":"a"}`))
+			sp.add([]byte(`{"command":"b"}`))
+
+			sp2, err := openAuditSpool(dir, 1<<20)
+			if err != nil {
+				t.Fatalf("openAuditSpool: %v", err)
+			}
+			if sp2.len() != 2 || sp2.size != sp.size {
+				t.Fatalf("reopened spool has %d entries, %d bytes", sp2.len(), sp2.size)
+			}
+			sp2.add([]byte(`{"command":"c"}`))
+			names := sp2.names()
+			if last, _ := sp2.read(names[len(names)-1]); string(last) != `{"command":"c"}` {
+				t.Errorf("new entry not ordered last: %s", last)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.fn)
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	msgs := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			lenStr, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(lenStr))
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			msgs <- string(buf)
+		}
+	}()
+
+	s, err := newSyslogSink(AuditSinkConfig{Type: AuditSinkSyslog, Address: ln.Addr().String(), Facility: "local3"})
+	if err != nil {
+		t.Fatalf("newSyslogSink: %v", err)
+	}
+	defer s.Close()
+	s.Write(testAuditEntry("get pods", AuditStatusSuccess))
+	s.Write(testAuditEntry("delete pod x", AuditStatusDenied))
+
+	for _, want := range []struct{ pri, command string }{{"<158>1 ", "get pods"}, {"<156>1 ", "delete pod x"}} {
+		select {
+		case msg := <-msgs:
+			if !strings.HasPrefix(msg, want.pri+"2026-01-02T03:04:05.000000Z ") {
+				t.Errorf("unexpected header: %q", msg)
+			}
+			if !strings.Contains(msg, " kbridge ") || !strings.Contains(msg, " audit - {") {
+				t.Errorf("missing app name or msgid: %q", msg)
+			}
+			var e AuditLog
+			if err := json.Unmarshal([]byte(msg[strings.Index(msg, "{"):]), &e); err != nil || e.Command != want.command {
+				t.Errorf("body = %q (%v), want command %q", msg, err, want.command)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for syslog message")
+		}
+	}
+}
+
+func TestFileSink_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	s, err := newFileSink(AuditSinkConfig{Type: AuditSinkFile, Path: path, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("newFileSink: %v", err)
+	}
+	s.maxBytes = 300 // roughly two entries per file
+	for i := 0; i < 8; i++ {
+		s.Write(testAuditEntry("get pods "+strconv.Itoa(i), AuditStatusSuccess))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if int64(len(data)) > s.maxBytes {
+			t.Errorf("%s is %d bytes, over the limit", name, len(data))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var e AuditLog
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Errorf("%s: invalid line %q: %v", name, line, err)
+			}
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, found %s.3", path)
+	}
+	last, _ := os.ReadFile(path)
+	if !strings.Contains(string(last), "get pods 7") {
+		t.Errorf("newest entry not in the live file: %s", last)
+	}
+}
+
+func TestAuditRecorder_ForwardsToSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sinks, err := NewAuditSinks([]AuditSinkConfig{{Type: AuditSinkFile, Path: path}})
+	if err != nil {
+		t.Fatalf("NewAuditSinks: %v", err)
+	}
+	r := NewAuditRecorder(newTestStore(t), sinks...)
+	r.Record(&AuditLog{UserEmail: "u@example.com", ClusterName: "dev-1", Command: "get pods", Status: AuditStatusSuccess})
+	r.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var e AuditLog
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatalf("decode %q: %v", data, err)
+	}
+	if e.Command != "get pods" || e.ID == "" || e.CreatedAt.IsZero() {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
+
+func TestAuditRecorder_RecordAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sinks, err := NewAuditSinks([]AuditSinkConfig{{Type: AuditSinkFile, Path: path}})
+	if err != nil {
+		t.Fatalf("NewAuditSinks: %v", err)
+	}
+	r := NewAuditRecorder(newTestStore(t), sinks...)
+	r.Close()
+	// A stream handler finishing after shutdown still records its entry; the
+	// sinks drop it instead of sending on their closed queue.
+	r.Record(&AuditLog{UserEmail: "u@example.com", ClusterName: "dev-1", Command: "get pods", Status: AuditStatusSuccess})
+
+	q := newAuditQueue("test", func([]byte) {})
+	q.close()
+	if q.push([]byte("x")) {
+		t.Error("push on a closed queue should report false")
+	}
+}
@@ -0,0 +1,160 @@
+package central
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// syslogFacilities maps the facility names accepted in audit.sinks[].facility
+// to their RFC 5424 codes.
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Syslog sink defaults and RFC 5424 severities.
+const (
+	defaultSyslogFacility = "authpriv"
+	defaultSyslogAppName  = "kbridge"
+	syslogDialTimeout     = 5 * time.Second
+	syslogWriteTimeout    = 5 * time.Second
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+	syslogMsgID           = "audit"
+	syslogTimestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// syslogSink sends each audit entry as an RFC 5424 message whose body is the
+// entry's JSON, over TCP or TLS with octet-counting framing (RFC 6587).
+type syslogSink struct {
+	address   string
+	tlsConfig *tls.Config // nil for plain TCP
+	facility  int
+	appName   string
+	hostname  string
+	procID    string
+
+	queue *auditQueue
+	conn  net.Conn // owned by the queue goroutine
+}
+
+func newSyslogSink(cfg AuditSinkConfig) (*syslogSink, error) {
+	name := cfg.Facility
+	if name == "" {
+		name = defaultSyslogFacility
+	}
+	facility, ok := syslogFacilities[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", name)
+	}
+	s := &syslogSink{
+		address:  cfg.Address,
+		facility: facility,
+		appName:  cfg.AppName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+	if s.appName == "" {
+		s.appName = defaultSyslogAppName
+	}
+	if s.hostname, _ = os.Hostname(); s.hostname == "" {
+		s.hostname = "-"
+	}
+	if cfg.TLS {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", cfg.Address, err)
+		}
+		s.tlsConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading ca_file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+			s.tlsConfig.RootCAs = pool
+		}
+	}
+	s.queue = newAuditQueue("syslog "+cfg.Address, s.deliver)
+	return s, nil
+}
+
+// Write formats entry and queues it for delivery.
+func (s *syslogSink) Write(entry *AuditLog) {
+	msg, err := s.format(entry)
+	if err != nil {
+		log.Printf("audit syslog: encoding entry: %v", err)
+		return
+	}
+	if !s.queue.push(msg) {
+		log.Printf("audit syslog: queue full or closed, dropping entry")
+	}
+}
+
+// Close flushes the queue and closes the connection.
+func (s *syslogSink) Close() error {
+	s.queue.close()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// format returns the framed RFC 5424 message for entry. Denials and
+// break-glass use severity warning, everything else info.
+func (s *syslogSink) format(entry *AuditLog) ([]byte, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	severity := syslogSeverityInfo
+	if entry.Status == AuditStatusDenied || entry.Status == AuditStatusBreakglass {
+		severity = syslogSeverityWarning
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s %s - %s",
+		s.facility*8+severity, entry.CreatedAt.UTC().Format(syslogTimestampLayout),
+		s.hostname, s.appName, s.procID, syslogMsgID, body)
+	return []byte(strconv.Itoa(len(msg)) + " " + msg), nil
+}
+
+// deliver writes one framed message, reconnecting once if the connection has
+// gone away.
+func (s *syslogSink) deliver(msg []byte) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			conn, err := s.dial()
+			if err != nil {
+				log.Printf("audit syslog: connecting to %s: %v", s.address, err)
+				return
+			}
+			s.conn = conn
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if _, err := s.conn.Write(msg); err == nil {
+			return
+		} else if attempt == 1 {
+			log.Printf("audit syslog: writing to %s: %v", s.address, err)
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *syslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if s.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	}
+	return dialer.Dial("tcp", s.address)
+}
@@ -2,8 +2,12 @@ package central
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,6 +88,95 @@ func TestAdminHandler_ListAuditLogs(t *testing.T) {
 	}
 }
 
+func TestAdminHandler_ListAuditLogs_Cursor(t *testing.T) {
+	ah, store := newTestAdminHandlers(t)
+	for i := 0; i < 5; i++ {
+		if err := store.CreateAuditLog(context.Background(), &AuditLog{
+			UserEmail: "a@x.com", ClusterName: "dev", Command: fmt.Sprintf("get pods %d", i), Status: AuditStatusSuccess,
+		}); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	var ids []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		w := doRequest(t, "GET", "/api/v1/admin/audit", ah.HandleListAuditLogs,
+			"GET", "/api/v1/admin/audit?per_page=2&cursor="+cursor, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var r struct {
+			Logs       []AuditLog `json:"logs"`
+			Total      *int       `json:"total"`
+			NextCursor string     `json:"next_cursor"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &r)
+		if r.Total != nil {
+			t.Error("cursor pages should not count the total")
+		}
+		for _, l := range r.Logs {
+			ids = append(ids, l.ID)
+		}
+		if r.NextCursor == "" {
+			break
+		}
+		cursor = r.NextCursor
+	}
+	if len(ids) != 5 {
+		t.Fatalf("paged through %d entries, want 5", len(ids))
+	}
+
+	w := doRequest(t, "GET", "/api/v1/admin/audit", ah.HandleListAuditLogs,
+		"GET", "/api/v1/admin/audit?cursor=!!!", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad cursor: want 400, got %d", w.Code)
+	}
+}
+
+func TestAdminHandler_ExportAuditLogs(t *testing.T) {
+	ah, store := newTestAdminHandlers(t)
+	for i := 0; i < auditExportPageSize+2; i++ {
+		if err := store.CreateAuditLog(context.Background(), &AuditLog{
+			UserEmail: "a@x.com", ClusterName: "dev", Command: fmt.Sprintf("get pods %d", i), Status: AuditStatusSuccess,
+		}); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	export := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		return doRequest(t, "GET", "/api/v1/admin/audit/export", ah.HandleExportAuditLogs,
+			"GET", "/api/v1/admin/audit/export"+query, nil)
+	}
+
+	w := export("?format=jsonl")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("jsonl: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != auditExportPageSize+2 {
+		t.Fatalf("jsonl: %d lines, want %d", len(lines), auditExportPageSize+2)
+	}
+	var first AuditLog
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.Seq != 1 {
+		t.Errorf("jsonl should start with the oldest entry, got %+v (%v)", first, err)
+	}
+
+	w = export("?format=csv&q=pods%201")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	// "get pods 1", "get pods 1x" and "get pods 1xx": 1 + 10 + 100 entries.
+	if len(records) != 1+111 || records[0][0] != "id" || records[1][8] != "get pods 1" {
+		t.Errorf("csv: %d records, first rows %v", len(records), records[:2])
+	}
+
+	if w := export("?format=xml"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad format: want 400, got %d", w.Code)
+	}
+}
+
 func TestAdminHandler_ListAuditLogs_BadTimestamp(t *testing.T) {
 	ah, _ := newTestAdminHandlers(t)
 	w := doRequest(t, "GET", "/api/v1/admin/audit", ah.HandleListAuditLogs,
@@ -93,6 +186,35 @@ func TestAdminHandler_ListAuditLogs_BadTimestamp(t *testing.T) {
 	}
 }
 
+func TestAdminHandler_VerifyAuditChain(t *testing.T) {
+	ah, store := newTestAdminHandlers(t)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		store.CreateAuditLog(ctx, &AuditLog{UserEmail: "a@x.com", ClusterName: "dev", Command: "get pods", Status: AuditStatusSuccess})
+	}
+	verify := func() AuditVerifyReport {
+		t.Helper()
+		w := doRequest(t, "GET", "/api/v1/admin/audit/verify", ah.HandleVerifyAuditChain,
+			"GET", "/api/v1/admin/audit/verify", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("want 200, got %d", w.Code)
+		}
+		var r AuditVerifyReport
+		json.Unmarshal(w.Body.Bytes(), &r)
+		return r
+	}
+
+	if r := verify(); !r.OK || r.Checked != 3 {
+		t.Fatalf("intact chain: %+v", r)
+	}
+	if _, err := store.db.Exec(`UPDATE audit_logs SET status = 'denied' WHERE seq = 2`); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if r := verify(); r.OK || len(r.Problems) != 1 || r.Problems[0].Kind != AuditProblemModified {
+		t.Errorf("tampered chain: %+v", r)
+	}
+}
+
 func TestExecHandler_RecordsDeniedAudit(t *testing.T) {
 	store := newTestStore(t)
 	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
@@ -129,4 +251,7 @@ roles:
 	if logs[0].Status != AuditStatusDenied || logs[0].UserEmail != "dev@x.com" || logs[0].Command != "delete pods x" {
 		t.Errorf("unexpected denied audit entry: %+v", logs[0])
 	}
+	if logs[0].Verb != "delete" || logs[0].Resource != "pods" {
+		t.Errorf("verb/resource = %q/%q, want delete/pods", logs[0].Verb, logs[0].Resource)
+	}
 }
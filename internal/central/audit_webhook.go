@@ -0,0 +1,330 @@
+package central
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook sink defaults, used when the corresponding setting is zero.
+const (
+	defaultWebhookTimeout       = 5 * time.Second
+	defaultWebhookMaxRetries    = 5
+	defaultWebhookSpoolMaxBytes = 64 << 20
+	webhookBackoffBase          = 500 * time.Millisecond
+	webhookBackoffMax           = 30 * time.Second
+	webhookSpoolRetryInterval   = 30 * time.Second
+)
+
+// Headers set on every webhook delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	webhookTimestampHeader = "X-Kbridge-Timestamp"
+	webhookSignatureHeader = "X-Kbridge-Signature"
+)
+
+// errSpoolFull is returned when an entry does not fit in the spool.
+var errSpoolFull = errors.New("spool is full")
+
+// webhookSink POSTs each audit entry as JSON. Deliveries are retried with
+// exponential backoff; entries that still fail are spooled to disk, when a
+// spool_dir is configured, and redelivered in order once the endpoint recovers.
+type webhookSink struct {
+	url        string
+	secret     []byte
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	queue *auditQueue
+	spool *auditSpool // nil without spool_dir
+
+	sendMu sync.Mutex // serialises live deliveries and spool drains
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newWebhookSink(cfg AuditSinkConfig) (*webhookSink, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	retries := cfg.MaxRetries
+	if retries == 0 {
+		retries = defaultWebhookMaxRetries
+	}
+	s := &webhookSink{
+		url:        cfg.URL,
+		secret:     []byte(cfg.Secret),
+		client:     &http.Client{Timeout: timeout},
+		maxRetries: retries,
+		backoff:    webhookBackoffBase,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if cfg.SpoolDir != "" {
+		limit := cfg.SpoolMaxBytes
+		if limit == 0 {
+			limit = defaultWebhookSpoolMaxBytes
+		}
+		spool, err := openAuditSpool(cfg.SpoolDir, limit)
+		if err != nil {
+			return nil, err
+		}
+		s.spool = spool
+	}
+	s.queue = newAuditQueue("webhook "+cfg.URL, s.deliver)
+	go s.drainLoop(webhookSpoolRetryInterval)
+	return s, nil
+}
+
+// Write queues entry for delivery. If the queue is full, or already closed at
+// shutdown, the entry goes straight to the spool.
+func (s *webhookSink) Write(entry *AuditLog) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit webhook: encoding entry: %v", err)
+		return
+	}
+	if !s.queue.push(body) {
+		s.park(body, "queue full or closed")
+	}
+}
+
+// Close flushes the queue, trying each remaining entry once, and stops
+// redelivering the spool. Spooled entries are kept for the next start.
+func (s *webhookSink) Close() error {
+	close(s.stop)
+	s.queue.close()
+	<-s.done
+	return nil
+}
+
+// deliver sends one queued entry, retrying with backoff. While older entries
+// wait in the spool, new ones join them so delivery stays in order.
+func (s *webhookSink) deliver(body []byte) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.spool != nil && s.spool.len() > 0 {
+		s.park(body, "spool not yet drained")
+		return
+	}
+	var err error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(s.backoffFor(attempt)):
+			case <-s.stop:
+				s.park(body, "shutting down")
+				return
+			}
+		}
+		if err = s.send(body); err == nil {
+			return
+		}
+	}
+	s.park(body, err.Error())
+}
+
+// backoffFor returns the wait before retry attempt n (n >= 1).
+func (s *webhookSink) backoffFor(n int) time.Duration {
+	d := s.backoff << (n - 1)
+	if d <= 0 || d > webhookBackoffMax {
+		d = webhookBackoffMax
+	}
+	return d
+}
+
+// park spools body, or drops it with a log line when there is no spool or it
+// is full.
+func (s *webhookSink) park(body []byte, reason string) {
+	if s.spool == nil {
+		log.Printf("audit webhook: dropping entry (%s)", reason)
+		return
+	}
+	if err := s.spool.add(body); err != nil {
+		log.Printf("audit webhook: dropping entry (%s): %v", reason, err)
+	}
+}
+
+// send POSTs body once and fails on any non-2xx response.
+func (s *webhookSink) send(body []byte) error {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	ctx, cancel := context.WithTimeout(context.Background(), s.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookTimestampHeader, ts)
+	if len(s.secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, signWebhook(s.secret, ts, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", s.url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posting to %s: status %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the X-Kbridge-Signature value for body sent at ts.
+func signWebhook(secret []byte, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// drainLoop redelivers spooled entries every interval until Close.
+func (s *webhookSink) drainLoop(interval time.Duration) {
+	defer close(s.done)
+	if s.spool == nil {
+		<-s.stop
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.drain()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// drain delivers spooled entries oldest first, stopping at the first failure.
+func (s *webhookSink) drain() {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	for _, name := range s.spool.names() {
+		body, err := s.spool.read(name)
+		if err != nil {
+			log.Printf("audit webhook: reading spooled entry %s: %v", name, err)
+			s.spool.remove(name)
+			continue
+		}
+		if err := s.send(body); err != nil {
+			return
+		}
+		s.spool.remove(name)
+	}
+}
+
+// auditSpool is a bounded directory of pending webhook deliveries, one file
+// per entry named by a zero-padded sequence number so lexical order is
+// delivery order.
+type auditSpool struct {
+	dir   string
+	limit int64
+
+	mu    sync.Mutex
+	size  int64
+	sizes map[string]int64
+	seq   uint64
+}
+
+func openAuditSpool(dir string, limit int64) (*auditSpool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating spool directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading spool directory: %w", err)
+	}
+	sp := &auditSpool{dir: dir, limit: limit, sizes: make(map[string]int64)}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		sp.sizes[name] = info.Size()
+		sp.size += info.Size()
+		sp.seq = max(sp.seq, seq)
+	}
+	return sp, nil
+}
+
+// add writes body as the newest entry.
+func (sp *auditSpool) add(body []byte) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.size+int64(len(body)) > sp.limit {
+		return errSpoolFull
+	}
+	sp.seq++
+	name := fmt.Sprintf("%020d.json", sp.seq)
+	tmp := filepath.Join(sp.dir, name+".tmp")
+	if err := os.WriteFile(tmp, body, 0o600); err != nil {
+		return fmt.Errorf("writing spool entry: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(sp.dir, name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing spool entry: %w", err)
+	}
+	sp.sizes[name] = int64(len(body))
+	sp.size += int64(len(body))
+	return nil
+}
+
+// names returns the spooled entries, oldest first.
+func (sp *auditSpool) names() []string {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	out := make([]string, 0, len(sp.sizes))
+	for name := range sp.sizes {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (sp *auditSpool) len() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return len(sp.sizes)
+}
+
+func (sp *auditSpool) read(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(sp.dir, name))
+}
+
+func (sp *auditSpool) remove(name string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if err := os.Remove(filepath.Join(sp.dir, name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("audit webhook: removing spooled entry %s: %v", name, err)
+	}
+	sp.size -= sp.sizes[name]
+	delete(sp.sizes, name)
+}
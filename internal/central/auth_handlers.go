@@ -16,6 +16,7 @@ type AuthHandlers struct {
 	store         Store
 	jwtManager    *auth.JWTManager
 	refreshExpiry time.Duration
+	oidc          *OIDCProvider // nil when SSO is disabled
 }
 
 // NewAuthHandlers creates a new AuthHandlers instance.
@@ -61,21 +62,24 @@ func (h *AuthHandlers) HandleLogin(c *gin.Context) {
 		return
 	}
 	if user == nil {
+		loginFailuresTotal.WithLabelValues("password", "unknown_user").Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+		loginFailuresTotal.WithLabelValues("password", "bad_password").Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
 	if !user.IsActive {
+		loginFailuresTotal.WithLabelValues("password", "disabled").Inc()
 		c.JSON(http.StatusForbidden, gin.H{"error": "account is disabled"})
 		return
 	}
 
-	h.issueTokens(c, user)
+	h.issueTokens(c, user, nil, time.Now().Add(h.refreshExpiry))
 }
 
 // HandleRefresh exchanges a refresh token for new access and refresh tokens.
@@ -111,8 +115,19 @@ func (h *AuthHandlers) HandleRefresh(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is disabled"})
+		return
+	}
 
-	h.issueTokens(c, user)
+	// Refreshing extends a password login, but not an SSO login carrying IdP
+	// groups: those are only as fresh as the login, so its end is kept and
+	// the user then signs in with the IdP again.
+	expiresAt := time.Now().Add(h.refreshExpiry)
+	if len(rt.Groups) > 0 {
+		expiresAt = rt.ExpiresAt
+	}
+	h.issueTokens(c, user, rt.Groups, expiresAt)
 }
 
 // HandleLogout invalidates a refresh token.
@@ -172,12 +187,18 @@ func (h *AuthHandlers) HandleChangePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
 }
 
-func (h *AuthHandlers) issueTokens(c *gin.Context, user *User) {
+// issueTokens responds with a fresh access/refresh token pair for user, the
+// refresh token expiring at expiresAt. idpGroups are the group memberships
+// from an SSO login; they are carried on the refresh token so refreshes keep
+// them. The access token lists them together with the user's kbridge-managed
+// groups, which are re-read on every refresh.
+func (h *AuthHandlers) issueTokens(c *gin.Context, user *User, idpGroups []string, expiresAt time.Time) {
 	claims := &auth.UserClaims{
 		UserID:  user.ID,
 		Email:   user.Email,
 		Name:    user.Name,
 		IsAdmin: user.IsAdmin,
+		Groups:  mergeGroups(user.Groups, idpGroups),
 	}
 	accessToken, err := h.jwtManager.GenerateAccessToken(claims)
 	if err != nil {
@@ -193,7 +214,8 @@ func (h *AuthHandlers) issueTokens(c *gin.Context, user *User) {
 		ID:        uuid.New().String(),
 		UserID:    user.ID,
 		TokenHash: refreshHash,
-		ExpiresAt: time.Now().Add(h.refreshExpiry),
+		ExpiresAt: expiresAt,
+		Groups:    idpGroups,
 	}
 	if err := h.store.CreateRefreshToken(c.Request.Context(), rt); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
@@ -206,6 +228,22 @@ func (h *AuthHandlers) issueTokens(c *gin.Context, user *User) {
 	})
 }
 
+// mergeGroups returns the union of a and b, keeping first-seen order.
+func mergeGroups(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	seen := make(map[string]bool, len(a)+len(b))
+	var out []string
+	for _, g := range append(append([]string{}, a...), b...) {
+		if !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
 func hashToken(token string) string {
 	h := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(h[:])
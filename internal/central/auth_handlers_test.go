@@ -117,6 +117,34 @@ func TestAuthHandler_Login(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_LoginIncludesUserGroups(t *testing.T) {
+	ah, store := newTestAuthHandlers(t)
+	user := seedTestUser(t, store)
+	user.Groups = []string{"platform-oncall"}
+	store.UpdateUser(context.Background(), user)
+
+	body, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.POST("/auth/login", ah.HandleLogin)
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp tokenResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	claims, err := ah.jwtManager.ValidateAccessToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if len(claims.Groups) != 1 || claims.Groups[0] != "platform-oncall" {
+		t.Errorf("groups = %v, want [platform-oncall]", claims.Groups)
+	}
+}
+
 func TestAuthHandler_Refresh(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,183 @@
+package central
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+// authzCheckRequest asks whether a command, or a single verb on a resource,
+// would be allowed on a cluster. Exactly one of Command or Verb is set. As and
+// AsGroups evaluate the request for another subject and are admin-only.
+type authzCheckRequest struct {
+	Cluster   string   `json:"cluster" binding:"required"`
+	Namespace string   `json:"namespace,omitempty"`
+	Command   []string `json:"command,omitempty"`
+	Stdin     string   `json:"stdin,omitempty"`
+	Verb      string   `json:"verb,omitempty"`
+	Resource  string   `json:"resource,omitempty"` // type or type/name
+	As        string   `json:"as,omitempty"`
+	AsGroups  []string `json:"as_groups,omitempty"`
+}
+
+// authzSubject is the identity a check was evaluated for.
+type authzSubject struct {
+	Email  string   `json:"email"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// authzCheckResult is the decision for one AccessRequest of a check.
+type authzCheckResult struct {
+	Verb      string   `json:"verb"`
+	Resource  string   `json:"resource"`
+	Namespace string   `json:"namespace"`
+	Names     []string `json:"names,omitempty"`
+	Decision
+}
+
+// authzCheckResponse reports the overall decision and one result per request.
+// Enforced is false when RBAC is disabled, in which case everything is allowed.
+type authzCheckResponse struct {
+	Allowed  bool               `json:"allowed"`
+	Enforced bool               `json:"enforced"`
+	Subject  authzSubject       `json:"subject"`
+	Checks   []authzCheckResult `json:"checks"`
+}
+
+// whoamiResponse describes the caller and the roles the policy grants them.
+type whoamiResponse struct {
+	UserID   string      `json:"user_id"`
+	Email    string      `json:"email"`
+	IsAdmin  bool        `json:"is_admin"`
+	Groups   []string    `json:"groups,omitempty"`
+	Enforced bool        `json:"enforced"`
+	Roles    []RoleGrant `json:"roles"`
+}
+
+// handleAuthzCheck evaluates a command or verb/resource pair against the
+// policy without running anything, and explains the decision.
+func (s *HTTPServer) handleAuthzCheck(c *gin.Context) {
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req authzCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if (len(req.Command) == 0) == (req.Verb == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of command or verb is required"})
+		return
+	}
+	if (req.As != "" || len(req.AsGroups) > 0) && !claims.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin role required to check another subject"})
+		return
+	}
+
+	subject, err := s.authzSubject(c, claims, req.As, req.AsGroups)
+	if err != nil {
+		log.Printf("authz check: resolving subject %q: %v", req.As, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	accesses, err := authzCheckAccessRequests(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := authzCheckResponse{
+		Allowed:  true,
+		Enforced: s.policy != nil,
+		Subject:  authzSubject{Email: subject.Email, Groups: subject.Groups},
+	}
+	for _, a := range accesses {
+		d := Decision{Allowed: true}
+		if s.policy != nil {
+			d = s.policy.Decide(subject, a)
+		}
+		resp.Allowed = resp.Allowed && d.Allowed
+		resp.Checks = append(resp.Checks, authzCheckResult{
+			Verb: a.Verb, Resource: a.Resource, Namespace: a.Namespace, Names: a.Names, Decision: d,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleWhoami returns the caller's identity and the roles granted to them.
+func (s *HTTPServer) handleWhoami(c *gin.Context) {
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	resp := whoamiResponse{
+		UserID:   claims.UserID,
+		Email:    claims.Email,
+		IsAdmin:  claims.IsAdmin,
+		Groups:   claims.Groups,
+		Enforced: s.policy != nil,
+		Roles:    []RoleGrant{},
+	}
+	if s.policy != nil {
+		resp.Roles = append(resp.Roles, s.policy.Grants(subjectFromClaims(claims))...)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// authzSubject returns the subject a check is evaluated for: the caller, or
+// the named user with their kbridge-managed groups plus asGroups. IdP groups
+// only exist in a user's own tokens, so they cannot be included for --as.
+func (s *HTTPServer) authzSubject(c *gin.Context, claims *auth.UserClaims, as string, asGroups []string) (Subject, error) {
+	if as == "" && len(asGroups) == 0 {
+		return subjectFromClaims(claims), nil
+	}
+	subject := Subject{Email: as, Groups: asGroups}
+	if as == "" {
+		subject.Email = claims.Email
+	}
+	if as != "" && s.authHandlers != nil {
+		user, err := s.authHandlers.store.GetUserByEmail(c.Request.Context(), as)
+		if err != nil {
+			return Subject{}, err
+		}
+		if user != nil {
+			subject.Groups = mergeGroups(user.Groups, asGroups)
+		}
+	}
+	return subject, nil
+}
+
+// authzCheckAccessRequests builds the access requests for a check: those of
+// the command, or a single request for verb on resource ("type" or
+// "type/name").
+func authzCheckAccessRequests(req authzCheckRequest) ([]AccessRequest, error) {
+	if len(req.Command) > 0 {
+		accesses, err := execAccessRequests(req.Cluster, ExecRequest{
+			Command: req.Command, Namespace: req.Namespace, Stdin: req.Stdin,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		return accesses, nil
+	}
+
+	a := AccessRequest{Cluster: req.Cluster, Namespace: req.Namespace, Verb: strings.TrimSpace(req.Verb)}
+	if a.Namespace == "" {
+		a.Namespace = "default"
+	}
+	typ, name, ok := strings.Cut(req.Resource, "/")
+	a.Resource = normalizeResource(typ)
+	if ok {
+		a.Names = []string{name}
+	}
+	return []AccessRequest{a}, nil
+}
@@ -0,0 +1,208 @@
+package central
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+const authzTestPolicy = `
+default: viewer
+roles:
+  - name: developer
+    rules:
+      - clusters: ["dev-*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+      - effect: deny
+        clusters: ["dev-*"]
+        namespaces: ["*"]
+        resources: ["secrets"]
+        verbs: ["delete"]
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get", "list"]
+bindings:
+  - subject: group:devs
+    roles: ["developer"]
+`
+
+func authzRequest(t *testing.T, srv *HTTPServer, token, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthzCheck(t *testing.T) {
+	srv, jm := newRBACTestServer(t, authzTestPolicy)
+	token := func(claims *auth.UserClaims) string {
+		tok, err := jm.GenerateAccessToken(claims)
+		if err != nil {
+			t.Fatalf("token: %v", err)
+		}
+		return tok
+	}
+	dev := token(&auth.UserClaims{UserID: "u1", Email: "dev@x.com", Groups: []string{"devs"}})
+	plain := token(&auth.UserClaims{UserID: "u2", Email: "someone@x.com"})
+	admin := token(&auth.UserClaims{UserID: "u3", Email: "admin@x.com", IsAdmin: true})
+
+	check := func(t *testing.T, tok string, body authzCheckRequest) (int, authzCheckResponse) {
+		t.Helper()
+		w := authzRequest(t, srv, tok, "POST", "/api/v1/authz/check", body)
+		var resp authzCheckResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"verb and resource allowed, with deciding rule", func(t *testing.T) {
+			code, resp := check(t, dev, authzCheckRequest{Cluster: "dev-1", Verb: "delete", Resource: "po"})
+			if code != http.StatusOK || !resp.Allowed || !resp.Enforced {
+				t.Fatalf("code=%d resp=%+v", code, resp)
+			}
+			c := resp.Checks[0]
+			if c.Resource != "pods" || c.Role != "developer" || c.Rule != 1 || c.Effect != RuleEffectAllow {
+				t.Errorf("check = %+v, want pods allowed by developer rule 1", c)
+			}
+		}},
+		{"deny rule is reported", func(t *testing.T) {
+			_, resp := check(t, dev, authzCheckRequest{Cluster: "dev-1", Verb: "delete", Resource: "secret/db"})
+			c := resp.Checks[0]
+			if resp.Allowed || c.Role != "developer" || c.Rule != 2 || c.Effect != RuleEffectDeny {
+				t.Errorf("resp = %+v, want denied by developer rule 2", resp)
+			}
+			if len(c.Names) != 1 || c.Names[0] != "db" {
+				t.Errorf("names = %v, want [db]", c.Names)
+			}
+		}},
+		{"no matching rule", func(t *testing.T) {
+			_, resp := check(t, plain, authzCheckRequest{Cluster: "prod", Verb: "delete", Resource: "pods"})
+			if resp.Allowed || resp.Checks[0].Role != "" {
+				t.Errorf("resp = %+v, want denied with no rule", resp)
+			}
+		}},
+		{"command is checked per resource", func(t *testing.T) {
+			_, resp := check(t, plain, authzCheckRequest{Cluster: "prod", Command: []string{"get", "pods,secrets", "-n", "app"}})
+			if !resp.Allowed || len(resp.Checks) != 2 || resp.Checks[1].Namespace != "app" {
+				t.Errorf("resp = %+v, want two allowed checks in app", resp)
+			}
+		}},
+		{"command with stdin manifest", func(t *testing.T) {
+			_, resp := check(t, dev, authzCheckRequest{Cluster: "dev-1", Command: []string{"delete", "-f", "-"},
+				Stdin: "kind: Secret\nmetadata: {name: db}\n"})
+			if resp.Allowed || resp.Checks[0].Resource != "secrets" {
+				t.Errorf("resp = %+v, want secret delete denied", resp)
+			}
+		}},
+		{"command and verb are exclusive", func(t *testing.T) {
+			code, _ := check(t, dev, authzCheckRequest{Cluster: "dev-1", Verb: "get", Command: []string{"get", "pods"}})
+			if code != http.StatusBadRequest {
+				t.Errorf("code = %d, want 400", code)
+			}
+		}},
+		{"cluster is required", func(t *testing.T) {
+			code, _ := check(t, dev, authzCheckRequest{Verb: "get", Resource: "pods"})
+			if code != http.StatusBadRequest {
+				t.Errorf("code = %d, want 400", code)
+			}
+		}},
+		{"non-admin cannot check another subject", func(t *testing.T) {
+			code, _ := check(t, plain, authzCheckRequest{Cluster: "dev-1", Verb: "get", Resource: "pods", As: "dev@x.com"})
+			if code != http.StatusForbidden {
+				t.Errorf("code = %d, want 403", code)
+			}
+		}},
+		{"admin checks another user with their stored groups", func(t *testing.T) {
+			u := &User{Email: "stored@x.com", Name: "Stored", PasswordHash: "x", IsActive: true, Groups: []string{"devs"}}
+			if err := srv.authHandlers.store.CreateUser(context.Background(), u); err != nil {
+				t.Fatal(err)
+			}
+			_, resp := check(t, admin, authzCheckRequest{Cluster: "dev-1", Verb: "delete", Resource: "pods", As: "stored@x.com"})
+			if !resp.Allowed || resp.Subject.Email != "stored@x.com" || resp.Checks[0].Role != "developer" {
+				t.Errorf("resp = %+v, want allowed for stored@x.com via developer", resp)
+			}
+		}},
+		{"admin checks with explicit groups", func(t *testing.T) {
+			_, resp := check(t, admin, authzCheckRequest{Cluster: "dev-1", Verb: "delete", Resource: "pods",
+				As: "unknown@x.com", AsGroups: []string{"devs"}})
+			if !resp.Allowed {
+				t.Errorf("resp = %+v, want allowed via devs group", resp)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
+
+func TestAuthzWhoami(t *testing.T) {
+	srv, jm := newRBACTestServer(t, authzTestPolicy)
+	tok, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com", Groups: []string{"devs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := authzRequest(t, srv, tok, "GET", "/api/v1/authz/whoami", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d: %s", w.Code, w.Body.String())
+	}
+	var resp whoamiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []RoleGrant{{Role: "developer", Source: "group:devs"}, {Role: "viewer", Source: "default"}}
+	if resp.Email != "dev@x.com" || !resp.Enforced || len(resp.Roles) != len(want) {
+		t.Fatalf("resp = %+v", resp)
+	}
+	for i := range want {
+		if resp.Roles[i] != want[i] {
+			t.Errorf("roles[%d] = %+v, want %+v", i, resp.Roles[i], want[i])
+		}
+	}
+}
+
+func TestAuthzCheck_RBACDisabled(t *testing.T) {
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	srv := NewHTTPServer(NewAgentStore(), NewCommandQueue(), nil, nil, nil, nil, nil, jm)
+	tok, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := authzRequest(t, srv, tok, "POST", "/api/v1/authz/check",
+		authzCheckRequest{Cluster: "prod", Command: []string{"delete", "ns", "kube-system"}})
+	var resp authzCheckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v (%s)", err, w.Body.String())
+	}
+	if !resp.Allowed || resp.Enforced {
+		t.Errorf("resp = %+v, want allowed and not enforced", resp)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/why-xn/kbridge/api/proto/agentpb"
 )
 
 // CommandStatus represents the state of a command execution.
@@ -18,13 +21,23 @@ const (
 	CommandStatusCompleted CommandStatus = "completed"
 	CommandStatusFailed    CommandStatus = "failed"
 	CommandStatusTimeout   CommandStatus = "timeout"
+	CommandStatusCanceled  CommandStatus = "canceled"
 )
 
+// Identity is the authenticated user a command or session runs for. Agents
+// with impersonation enabled act as this user and groups in the cluster.
+type Identity struct {
+	User   string
+	Groups []string
+}
+
 // PendingCommand represents a command waiting to be executed by an agent.
 type PendingCommand struct {
 	RequestID      string
 	AgentID        string
 	ClusterName    string
+	User           string   // requester, for fair scheduling; empty if unknown
+	Groups         []string // requester's groups, for impersonation
 	Command        []string
 	Namespace      string
 	TimeoutSeconds int32
@@ -46,21 +59,79 @@ type CommandResult struct {
 	Completed    bool
 }
 
-// CommandQueue manages pending commands for agents.
+// request converts the command to the message sent to its agent.
+func (cmd *PendingCommand) request() *agentpb.CommandRequest {
+	return &agentpb.CommandRequest{
+		RequestId:      cmd.RequestID,
+		AgentId:        cmd.AgentID,
+		Command:        cmd.Command,
+		Namespace:      cmd.Namespace,
+		TimeoutSeconds: cmd.TimeoutSeconds,
+		Stdin:          cmd.Stdin,
+		User:           cmd.User,
+		Groups:         cmd.Groups,
+	}
+}
+
+// commandPusher delivers a command, or the cancellation of one, to its agent
+// over the agent's stream. It fails when the agent has no open stream that
+// accepts pushed commands.
+type commandPusher interface {
+	PushCommand(agentID string, req *agentpb.CommandRequest) error
+	CancelCommand(agentID, requestID string) error
+}
+
+// agentLimits reports how many commands an agent runs at once; 0 means it
+// did not say and is not limited.
+type agentLimits interface {
+	MaxCommands(agentID string) int
+}
+
+// CommandQueue manages pending commands for agents. Each agent runs at most
+// the number of commands it advertised; the rest wait and are handed out
+// fairly across users (see ClaimForAgent).
 type CommandQueue struct {
 	mu       sync.RWMutex
-	commands map[string]*PendingCommand // keyed by request ID
+	commands map[string]*PendingCommand            // keyed by request ID
+	byAgent  map[string]map[string]*PendingCommand // agent ID -> request ID
+	canceled map[string]map[string]time.Time       // agent ID -> request IDs to kill on its next poll
+	pusher   commandPusher
+	limits   agentLimits
 }
 
 // NewCommandQueue creates a new command queue.
 func NewCommandQueue() *CommandQueue {
 	return &CommandQueue{
 		commands: make(map[string]*PendingCommand),
+		byAgent:  make(map[string]map[string]*PendingCommand),
+		canceled: make(map[string]map[string]time.Time),
 	}
 }
 
+// SetPusher makes the queue push new commands to agents whose stream accepts
+// them. Commands that cannot be pushed stay pending for GetPendingForAgent.
+func (q *CommandQueue) SetPusher(p commandPusher) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pusher = p
+}
+
+// SetAgentLimits caps the commands running on each agent at the concurrency
+// reported by l.
+func (q *CommandQueue) SetAgentLimits(l agentLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limits = l
+}
+
 // Enqueue adds a new command to the queue and returns its request ID.
 func (q *CommandQueue) Enqueue(agentID, clusterName string, command []string, namespace string, timeoutSeconds int32, stdin []byte) (string, error) {
+	return q.EnqueueForUser(Identity{}, agentID, clusterName, command, namespace, timeoutSeconds, stdin)
+}
+
+// EnqueueForUser is Enqueue for a command requested by id, who then shares
+// the agent fairly with other users and is passed on for impersonation.
+func (q *CommandQueue) EnqueueForUser(id Identity, agentID, clusterName string, command []string, namespace string, timeoutSeconds int32, stdin []byte) (string, error) {
 	requestID, err := generateRequestID()
 	if err != nil {
 		return "", fmt.Errorf("generating request ID: %w", err)
@@ -70,6 +141,8 @@ func (q *CommandQueue) Enqueue(agentID, clusterName string, command []string, na
 		RequestID:      requestID,
 		AgentID:        agentID,
 		ClusterName:    clusterName,
+		User:           id.User,
+		Groups:         id.Groups,
 		Command:        command,
 		Namespace:      namespace,
 		TimeoutSeconds: timeoutSeconds,
@@ -81,11 +154,129 @@ func (q *CommandQueue) Enqueue(agentID, clusterName string, command []string, na
 
 	q.mu.Lock()
 	q.commands[requestID] = cmd
+	if q.byAgent[agentID] == nil {
+		q.byAgent[agentID] = make(map[string]*PendingCommand)
+	}
+	q.byAgent[agentID][requestID] = cmd
 	q.mu.Unlock()
 
+	q.DispatchPending(agentID)
 	return requestID, nil
 }
 
+// DispatchPending pushes an agent's pending commands to its stream, in fair
+// order, until none are left or the agent is at its limit. It runs when a
+// command is queued or finishes, and when the agent opens a stream that
+// accepts pushed commands. A command that cannot be pushed goes back to
+// pending so a later poll or stream can pick it up.
+func (q *CommandQueue) DispatchPending(agentID string) {
+	q.mu.RLock()
+	pusher := q.pusher
+	q.mu.RUnlock()
+	if pusher == nil {
+		return
+	}
+	for {
+		claimed := q.claim(agentID, 1)
+		if len(claimed) == 0 {
+			return
+		}
+		cmd := claimed[0]
+		if err := pusher.PushCommand(agentID, cmd.request()); err != nil {
+			q.mu.Lock()
+			if cmd.Status == CommandStatusRunning {
+				cmd.Status = CommandStatusPending
+			}
+			q.mu.Unlock()
+			return
+		}
+		observeQueueWait(cmd)
+	}
+}
+
+// ClaimForAgent marks up to max of an agent's pending commands running and
+// returns them, for an agent that polls; max <= 0 means as many as the
+// agent's limit allows. Each command is taken from the user with the fewest
+// commands running on the agent, oldest first, so one user's backlog cannot
+// starve the others.
+func (q *CommandQueue) ClaimForAgent(agentID string, max int) []*PendingCommand {
+	claimed := q.claim(agentID, max)
+	for _, cmd := range claimed {
+		observeQueueWait(cmd)
+	}
+	return claimed
+}
+
+func (q *CommandQueue) claim(agentID string, max int) []*PendingCommand {
+	q.mu.RLock()
+	limits := q.limits
+	q.mu.RUnlock()
+	limit := 0
+	if limits != nil {
+		limit = limits.MaxCommands(agentID)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limit > 0 {
+		free := limit - q.runningLocked(agentID)
+		if free <= 0 {
+			return nil
+		}
+		if max <= 0 || max > free {
+			max = free
+		}
+	}
+	var claimed []*PendingCommand
+	for max <= 0 || len(claimed) < max {
+		cmd := q.nextLocked(agentID)
+		if cmd == nil {
+			break
+		}
+		cmd.Status = CommandStatusRunning
+		claimed = append(claimed, cmd)
+	}
+	return claimed
+}
+
+// nextLocked returns the agent's pending command to run next: the oldest one
+// of the user with the fewest commands running on the agent.
+func (q *CommandQueue) nextLocked(agentID string) *PendingCommand {
+	running := make(map[string]int)
+	var pending []*PendingCommand
+	for _, cmd := range q.byAgent[agentID] {
+		switch cmd.Status {
+		case CommandStatusRunning:
+			running[cmd.User]++
+		case CommandStatusPending:
+			pending = append(pending, cmd)
+		}
+	}
+	var next *PendingCommand
+	for _, cmd := range pending {
+		if next == nil || running[cmd.User] < running[next.User] ||
+			running[cmd.User] == running[next.User] && cmd.CreatedAt.Before(next.CreatedAt) {
+			next = cmd
+		}
+	}
+	return next
+}
+
+func (q *CommandQueue) runningLocked(agentID string) int {
+	n := 0
+	for _, cmd := range q.byAgent[agentID] {
+		if cmd.Status == CommandStatusRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// observeQueueWait records how long cmd waited before reaching its agent.
+func observeQueueWait(cmd *PendingCommand) {
+	commandQueueWaitSeconds.WithLabelValues(cmd.ClusterName).Observe(time.Since(cmd.CreatedAt).Seconds())
+}
+
 // Get retrieves a pending command by request ID.
 func (q *CommandQueue) Get(requestID string) (*PendingCommand, bool) {
 	q.mu.RLock()
@@ -94,20 +285,38 @@ func (q *CommandQueue) Get(requestID string) (*PendingCommand, bool) {
 	return cmd, exists
 }
 
-// GetPendingForAgent returns pending commands for a specific agent.
+// GetPendingForAgent returns pending commands for a specific agent, oldest
+// first.
 func (q *CommandQueue) GetPendingForAgent(agentID string) []*PendingCommand {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
 	var pending []*PendingCommand
-	for _, cmd := range q.commands {
-		if cmd.AgentID == agentID && cmd.Status == CommandStatusPending {
+	for _, cmd := range q.byAgent[agentID] {
+		if cmd.Status == CommandStatusPending {
 			pending = append(pending, cmd)
 		}
 	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
 	return pending
 }
 
+// Claim marks a pending command as running and reports whether it was still
+// pending, so a command handed to an agent by polling and by push at the same
+// time only runs once.
+func (q *CommandQueue) Claim(requestID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cmd, exists := q.commands[requestID]
+	if !exists || cmd.Status != CommandStatusPending {
+		return false
+	}
+	observeQueueWait(cmd)
+	cmd.Status = CommandStatusRunning
+	return true
+}
+
 // MarkRunning marks a command as currently running.
 func (q *CommandQueue) MarkRunning(requestID string) bool {
 	q.mu.Lock()
@@ -117,10 +326,32 @@ func (q *CommandQueue) MarkRunning(requestID string) bool {
 	if !exists {
 		return false
 	}
+	if cmd.Status == CommandStatusPending {
+		observeQueueWait(cmd)
+	}
 	cmd.Status = CommandStatusRunning
 	return true
 }
 
+// queueKey groups queued commands for the depth gauge.
+type queueKey struct {
+	cluster string
+	status  CommandStatus
+}
+
+// depth counts the pending and running commands per cluster.
+func (q *CommandQueue) depth() map[queueKey]int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	counts := make(map[queueKey]int)
+	for _, cmd := range q.commands {
+		if cmd.Status == CommandStatusPending || cmd.Status == CommandStatusRunning {
+			counts[queueKey{cmd.ClusterName, cmd.Status}]++
+		}
+	}
+	return counts
+}
+
 // Complete marks a command as completed and stores the result.
 func (q *CommandQueue) Complete(requestID string, result *CommandResult) bool {
 	q.mu.Lock()
@@ -140,6 +371,8 @@ func (q *CommandQueue) Complete(requestID string, result *CommandResult) bool {
 	default:
 	}
 
+	// The agent has a free slot again.
+	q.DispatchPending(cmd.AgentID)
 	return true
 }
 
@@ -168,6 +401,7 @@ func (q *CommandQueue) Fail(requestID string, errorMessage string) bool {
 	default:
 	}
 
+	q.DispatchPending(cmd.AgentID)
 	return true
 }
 
@@ -185,21 +419,84 @@ func (q *CommandQueue) WaitForResult(ctx context.Context, requestID string) (*Co
 	case result := <-cmd.resultCh:
 		return result, nil
 	case <-ctx.Done():
-		// Mark as timeout
+		// Nobody waits for the result any more: stop the command.
+		status := CommandStatusTimeout
+		if ctx.Err() == context.Canceled {
+			status = CommandStatusCanceled
+		}
+		q.stop(requestID, status)
+		return nil, ctx.Err()
+	}
+}
+
+// Cancel stops a command that is still pending or running and reports
+// whether it was. A pending command never reaches its agent; a running one is
+// killed on the agent, over its stream when possible and otherwise on the
+// agent's next poll (see TakeCanceled).
+func (q *CommandQueue) Cancel(requestID string) bool {
+	return q.stop(requestID, CommandStatusCanceled)
+}
+
+func (q *CommandQueue) stop(requestID string, status CommandStatus) bool {
+	q.mu.Lock()
+	cmd, exists := q.commands[requestID]
+	if !exists || cmd.Status != CommandStatusPending && cmd.Status != CommandStatusRunning {
+		q.mu.Unlock()
+		return false
+	}
+	running := cmd.Status == CommandStatusRunning
+	cmd.Status = status
+	pusher := q.pusher
+	q.mu.Unlock()
+
+	if running && (pusher == nil || pusher.CancelCommand(cmd.AgentID, requestID) != nil) {
 		q.mu.Lock()
-		if cmd.Status == CommandStatusPending || cmd.Status == CommandStatusRunning {
-			cmd.Status = CommandStatusTimeout
+		if q.canceled[cmd.AgentID] == nil {
+			q.canceled[cmd.AgentID] = make(map[string]time.Time)
 		}
+		q.canceled[cmd.AgentID][requestID] = time.Now()
 		q.mu.Unlock()
-		return nil, ctx.Err()
 	}
+
+	// The agent has a free slot again.
+	q.DispatchPending(cmd.AgentID)
+	return true
+}
+
+// TakeCanceled returns the agent's running commands that were canceled while
+// its stream was unavailable, and forgets them. A polling agent
+// receives them with its next GetPendingCommands.
+func (q *CommandQueue) TakeCanceled(agentID string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var ids []string
+	for id := range q.canceled[agentID] {
+		ids = append(ids, id)
+	}
+	delete(q.canceled, agentID)
+	sort.Strings(ids)
+	return ids
 }
 
 // Remove removes a command from the queue.
 func (q *CommandQueue) Remove(requestID string) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	q.removeLocked(requestID)
+}
+
+func (q *CommandQueue) removeLocked(requestID string) {
+	cmd, exists := q.commands[requestID]
+	if !exists {
+		return
+	}
 	delete(q.commands, requestID)
+	if agentCmds := q.byAgent[cmd.AgentID]; agentCmds != nil {
+		delete(agentCmds, requestID)
+		if len(agentCmds) == 0 {
+			delete(q.byAgent, cmd.AgentID)
+		}
+	}
 }
 
 // CleanupOld removes commands older than the specified duration.
@@ -212,10 +509,21 @@ func (q *CommandQueue) CleanupOld(maxAge time.Duration) int {
 
 	for id, cmd := range q.commands {
 		if cmd.CreatedAt.Before(cutoff) {
-			delete(q.commands, id)
+			q.removeLocked(id)
 			removed++
 		}
 	}
+	// Cancellations for agents that never polled again.
+	for agentID, ids := range q.canceled {
+		for id, at := range ids {
+			if at.Before(cutoff) {
+				delete(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			delete(q.canceled, agentID)
+		}
+	}
 
 	return removed
 }
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/why-xn/kbridge/api/proto/agentpb"
 )
 
 func TestNewCommandQueue(t *testing.T) {
@@ -290,6 +292,232 @@ func TestCommandQueue_Concurrent(t *testing.T) {
 	}
 }
 
+func TestCommandQueue_Claim(t *testing.T) {
+	q := NewCommandQueue()
+	requestID, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "pods"}, "", 30, nil)
+
+	if !q.Claim(requestID) {
+		t.Fatal("expected first claim to succeed")
+	}
+	if q.Claim(requestID) {
+		t.Error("expected second claim to fail")
+	}
+	if q.Claim("req-unknown") {
+		t.Error("expected claim of unknown command to fail")
+	}
+	if pending := q.GetPendingForAgent("agent-1"); len(pending) != 0 {
+		t.Errorf("expected no pending commands after claim, got %d", len(pending))
+	}
+}
+
+// fakePusher records pushed commands and cancellations, or fails every push
+// when err is set.
+type fakePusher struct {
+	mu       sync.Mutex
+	err      error
+	pushed   []*agentpb.CommandRequest
+	canceled []string
+}
+
+func (p *fakePusher) PushCommand(agentID string, req *agentpb.CommandRequest) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.pushed = append(p.pushed, req)
+	return nil
+}
+
+func (p *fakePusher) CancelCommand(agentID, requestID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.canceled = append(p.canceled, requestID)
+	return nil
+}
+
+func TestCommandQueue_Push(t *testing.T) {
+	q := NewCommandQueue()
+	p := &fakePusher{err: ErrNoAgentStream}
+	q.SetPusher(p)
+
+	// A failed push leaves the command pending for polling.
+	first, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "pods"}, "default", 30, []byte("in"))
+	if pending := q.GetPendingForAgent("agent-1"); len(pending) != 1 {
+		t.Fatalf("expected 1 pending command after failed push, got %d", len(pending))
+	}
+
+	// Once the agent accepts pushes, pending commands are dispatched oldest
+	// first and new ones are pushed on enqueue.
+	p.err = nil
+	q.DispatchPending("agent-1")
+	second, _ := q.EnqueueForUser(Identity{User: "dev@x.com", Groups: []string{"sre"}},
+		"agent-1", "cluster-1", []string{"get", "nodes"}, "", 30, nil)
+	if len(p.pushed) != 2 || p.pushed[0].RequestId != first || p.pushed[1].RequestId != second {
+		t.Fatalf("unexpected pushes: %v", p.pushed)
+	}
+	if got := p.pushed[0]; got.Namespace != "default" || string(got.Stdin) != "in" || got.AgentId != "agent-1" {
+		t.Errorf("pushed request lost fields: %v", got)
+	}
+	if got := p.pushed[1]; got.User != "dev@x.com" || len(got.Groups) != 1 || got.Groups[0] != "sre" {
+		t.Errorf("pushed request lost the requester: %v", got)
+	}
+	for _, id := range []string{first, second} {
+		if cmd, _ := q.Get(id); cmd.Status != CommandStatusRunning {
+			t.Errorf("%s: expected running, got %s", id, cmd.Status)
+		}
+	}
+	if pending := q.GetPendingForAgent("agent-1"); len(pending) != 0 {
+		t.Errorf("expected no pending commands, got %d", len(pending))
+	}
+}
+
+// fixedLimits gives every agent the same concurrency limit.
+type fixedLimits int
+
+func (l fixedLimits) MaxCommands(string) int { return int(l) }
+
+func TestCommandQueue_ClaimForAgent_Fair(t *testing.T) {
+	q := NewCommandQueue()
+	q.SetAgentLimits(fixedLimits(2))
+	enqueue := func(user string) string {
+		t.Helper()
+		id, err := q.EnqueueForUser(Identity{User: user}, "agent-1", "cluster-1", []string{"get", "pods"}, "", 30, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond) // distinct CreatedAt
+		return id
+	}
+	a1, a2, a3 := enqueue("alice"), enqueue("alice"), enqueue("alice")
+	b1 := enqueue("bob")
+
+	// Bob's single command is not stuck behind Alice's backlog.
+	claimed := q.ClaimForAgent("agent-1", 0)
+	if len(claimed) != 2 || claimed[0].RequestID != a1 || claimed[1].RequestID != b1 {
+		t.Fatalf("expected [%s %s], got %v", a1, b1, requestIDs(claimed))
+	}
+	if got := q.ClaimForAgent("agent-1", 0); len(got) != 0 {
+		t.Errorf("agent at its limit: expected nothing, got %v", requestIDs(got))
+	}
+
+	q.Complete(a1, &CommandResult{RequestID: a1})
+	if got := q.ClaimForAgent("agent-1", 5); len(got) != 1 || got[0].RequestID != a2 {
+		t.Errorf("expected [%s] after a slot frees, got %v", a2, requestIDs(got))
+	}
+	if cmd, _ := q.Get(a3); cmd.Status != CommandStatusPending {
+		t.Errorf("expected %s still pending, got %s", a3, cmd.Status)
+	}
+}
+
+func TestCommandQueue_ClaimForAgent_Max(t *testing.T) {
+	q := NewCommandQueue()
+	for i := 0; i < 3; i++ {
+		q.Enqueue("agent-1", "cluster-1", []string{"get", "pods"}, "", 30, nil) //nolint:errcheck
+	}
+	if got := q.ClaimForAgent("agent-1", 2); len(got) != 2 {
+		t.Errorf("expected 2 claimed, got %d", len(got))
+	}
+	// No advertised limit and no max: everything left.
+	if got := q.ClaimForAgent("agent-1", 0); len(got) != 1 {
+		t.Errorf("expected 1 claimed, got %d", len(got))
+	}
+}
+
+func TestCommandQueue_PushRespectsLimit(t *testing.T) {
+	q := NewCommandQueue()
+	p := &fakePusher{}
+	q.SetPusher(p)
+	q.SetAgentLimits(fixedLimits(1))
+
+	first, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "pods"}, "", 30, nil)
+	second, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "nodes"}, "", 30, nil)
+	if len(p.pushed) != 1 || p.pushed[0].RequestId != first {
+		t.Fatalf("expected only %s pushed, got %v", first, p.pushed)
+	}
+	q.Fail(first, "boom")
+	if len(p.pushed) != 2 || p.pushed[1].RequestId != second {
+		t.Fatalf("expected %s pushed once the agent is free, got %v", second, p.pushed)
+	}
+}
+
+func TestCommandQueue_Cancel(t *testing.T) {
+	q := NewCommandQueue()
+	p := &fakePusher{}
+	q.SetPusher(p)
+	q.SetAgentLimits(fixedLimits(1))
+
+	running, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "pods"}, "", 30, nil)
+	pending, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "nodes"}, "", 30, nil)
+	queued, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "svc"}, "", 30, nil)
+
+	// A pending command is dropped without involving the agent.
+	if !q.Cancel(pending) {
+		t.Fatal("expected cancel of pending command to succeed")
+	}
+	if len(p.canceled) != 0 {
+		t.Errorf("expected no cancel sent for a pending command, got %v", p.canceled)
+	}
+
+	// A running command is canceled on the agent, and its slot is reused.
+	if !q.Cancel(running) {
+		t.Fatal("expected cancel of running command to succeed")
+	}
+	if len(p.canceled) != 1 || p.canceled[0] != running {
+		t.Errorf("expected cancel of %s sent, got %v", running, p.canceled)
+	}
+	if len(p.pushed) != 2 || p.pushed[1].RequestId != queued {
+		t.Errorf("expected %s pushed after the cancel, got %v", queued, p.pushed)
+	}
+	for _, id := range []string{running, pending} {
+		if cmd, _ := q.Get(id); cmd.Status != CommandStatusCanceled {
+			t.Errorf("%s: expected canceled, got %s", id, cmd.Status)
+		}
+	}
+	if q.Cancel(running) {
+		t.Error("expected second cancel to report nothing to cancel")
+	}
+
+	// Without a stream the cancellation waits for the agent's next poll.
+	p.err = ErrNoAgentStream
+	q.Cancel(queued)
+	if got := q.TakeCanceled("agent-1"); len(got) != 1 || got[0] != queued {
+		t.Errorf("expected [%s] for the next poll, got %v", queued, got)
+	}
+	if got := q.TakeCanceled("agent-1"); len(got) != 0 {
+		t.Errorf("expected cancellations delivered once, got %v", got)
+	}
+}
+
+func TestCommandQueue_WaitForResult_Canceled(t *testing.T) {
+	q := NewCommandQueue()
+	requestID, _ := q.Enqueue("agent-1", "cluster-1", []string{"get", "pods"}, "", 30, nil)
+	q.ClaimForAgent("agent-1", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.WaitForResult(ctx, requestID); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if cmd, _ := q.Get(requestID); cmd.Status != CommandStatusCanceled {
+		t.Errorf("expected status %q, got %q", CommandStatusCanceled, cmd.Status)
+	}
+	if got := q.TakeCanceled("agent-1"); len(got) != 1 || got[0] != requestID {
+		t.Errorf("expected polling agent told to kill %s, got %v", requestID, got)
+	}
+}
+
+func requestIDs(cmds []*PendingCommand) []string {
+	ids := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		ids[i] = cmd.RequestID
+	}
+	return ids
+}
+
 func TestGenerateRequestID(t *testing.T) {
 	ids := make(map[string]bool)
 	for i := 0; i < 100; i++ {
@@ -24,6 +24,7 @@ type Config struct {
 	RBAC      RBACConfig      `yaml:"rbac"`
 	TLS       TLSConfig       `yaml:"tls"`
 	Streams   StreamsConfig   `yaml:"streams"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
 }
 
 // TLSConfig configures TLS for the central HTTP and gRPC servers. When enabled,
@@ -39,10 +40,57 @@ type StreamsConfig struct {
 	MaxConcurrent int `yaml:"max_concurrent"`
 }
 
-// RBACConfig configures policy-file-based access control. When PolicyFile is
-// empty, RBAC enforcement is disabled (all authenticated users are allowed).
+// MetricsConfig configures the Prometheus endpoint, /metrics on the HTTP port.
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token, when set, must be sent as a bearer token to scrape /metrics.
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+}
+
+// RBAC policy sources.
+const (
+	RBACSourceFile     = "file"
+	RBACSourceDatabase = "database"
+)
+
+// RBACConfig configures access control. With the "file" source the policy is
+// read from PolicyFile, and RBAC enforcement is disabled (all authenticated
+// users are allowed) when PolicyFile is empty. With the "database" source the
+// policy is versioned in the database and edited through the admin API; a
+// PolicyFile, if set, seeds the first version. Every replica re-reads the
+// latest version each PollInterval. ApprovalTimeout is how long a command
+// matched by a require_approval rule waits for an approver.
 type RBACConfig struct {
-	PolicyFile string `yaml:"policy_file"`
+	Source             string        `yaml:"source"`
+	PolicyFile         string        `yaml:"policy_file"`
+	PollIntervalStr    string        `yaml:"poll_interval"`
+	PollInterval       time.Duration `yaml:"-"`
+	ApprovalTimeoutStr string        `yaml:"approval_timeout"`
+	ApprovalTimeout    time.Duration `yaml:"-"`
+	JIT                JITConfig     `yaml:"jit"`
+}
+
+// JITConfig configures just-in-time access requests. Approvers are binding
+// subjects ("user:<email>", "group:<name>", wildcards allowed); admins can
+// always approve. Roles, when set, limits which policy roles may be requested.
+type JITConfig struct {
+	MaxDurationStr string           `yaml:"max_duration"`
+	MaxDuration    time.Duration    `yaml:"-"`
+	Approvers      []string         `yaml:"approvers"`
+	Roles          []string         `yaml:"roles"`
+	Breakglass     BreakglassConfig `yaml:"breakglass"`
+}
+
+// BreakglassConfig configures emergency self-service access. When Role is
+// set, the Subjects may grant themselves Role on a cluster for Duration
+// without approval; NotifyURL, if set, receives a JSON POST for every use.
+type BreakglassConfig struct {
+	Role        string        `yaml:"role"`
+	DurationStr string        `yaml:"duration"`
+	Duration    time.Duration `yaml:"-"`
+	Subjects    []string      `yaml:"subjects"`
+	NotifyURL   string        `yaml:"notify_url"`
 }
 
 // BootstrapConfig optionally seeds an agent token on startup for development
@@ -61,8 +109,13 @@ type ServerConfig struct {
 
 // DatabaseConfig holds the database connection configuration.
 type DatabaseConfig struct {
-	Driver string `yaml:"driver"` // "sqlite" or "postgres"
-	Path   string `yaml:"path"`   // SQLite file path
+	Driver  string `yaml:"driver"`   // "sqlite" or "postgres"
+	Path    string `yaml:"path"`     // SQLite file path
+	DSN     string `yaml:"dsn"`      // PostgreSQL connection string (URL or key=value)
+	DSNFile string `yaml:"dsn_file"` // file containing the DSN, for credentials kept out of YAML
+	// AutoMigrate applies pending schema migrations on startup. When false,
+	// central refuses to start until `kbridge-central migrate up` has run.
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
 
 // AuthConfig holds the authentication configuration.
@@ -79,13 +132,93 @@ type AuthConfig struct {
 	AdminPassword         string        `yaml:"admin_password"`
 	AdminPasswordFile     string        `yaml:"admin_password_file"`
 	AdminName             string        `yaml:"admin_name"`
+	OIDC                  OIDCConfig    `yaml:"oidc"`
 }
 
-// AuditConfig holds the audit log configuration.
+// OIDCConfig configures single sign-on against an external OpenID Connect
+// provider. Central acts as the relying party; the kb CLI only ever talks to
+// central.
+type OIDCConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	IssuerURL        string   `yaml:"issuer_url"`
+	ClientID         string   `yaml:"client_id"`
+	ClientSecret     string   `yaml:"client_secret"`
+	ClientSecretFile string   `yaml:"client_secret_file"`
+	Scopes           []string `yaml:"scopes"`
+	// GroupsClaim names the ID token (or userinfo) claim holding the user's
+	// group memberships.
+	GroupsClaim string `yaml:"groups_claim"`
+	// AutoProvision creates a kbridge user on first SSO login. When false,
+	// only users an admin has already created can sign in.
+	AutoProvision bool `yaml:"auto_provision"`
+	// SessionLifetime caps how long an SSO login lasts, refreshes included.
+	// Its IdP groups are only re-read from the IdP at the next SSO login.
+	SessionLifetimeStr string        `yaml:"session_lifetime"`
+	SessionLifetime    time.Duration `yaml:"-"`
+}
+
+// AuditConfig holds the audit log configuration. Sinks forward every entry
+// to external systems in addition to the database.
 type AuditConfig struct {
-	RetentionDays      int           `yaml:"retention_days"`
-	CleanupIntervalStr string        `yaml:"cleanup_interval"`
-	CleanupInterval    time.Duration `yaml:"-"`
+	RetentionDays      int               `yaml:"retention_days"`
+	CleanupIntervalStr string            `yaml:"cleanup_interval"`
+	CleanupInterval    time.Duration     `yaml:"-"`
+	Sinks              []AuditSinkConfig `yaml:"sinks"`
+	// ChainSecret keys the HMAC that chains audit entries. When empty the
+	// chain uses plain SHA-256, which detects edits but not a rewrite by
+	// someone with database access.
+	ChainSecret     string `yaml:"chain_secret"`
+	ChainSecretFile string `yaml:"chain_secret_file"`
+	// Recording captures interactive exec sessions for replay.
+	Recording RecordingConfig `yaml:"recording"`
+	// Output captures what one-shot commands printed.
+	Output OutputCaptureConfig `yaml:"output"`
+}
+
+// RecordingConfig controls session recording of interactive exec.
+type RecordingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxBytes caps one recording; the rest of the session is not recorded.
+	// Zero means DefaultRecordingMaxBytes.
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// Audit sink types.
+const (
+	AuditSinkWebhook = "webhook"
+	AuditSinkSyslog  = "syslog"
+	AuditSinkFile    = "file"
+)
+
+// AuditSinkConfig configures one outbound audit sink. Which fields apply
+// depends on Type; zero values get the defaults documented in
+// docs/configuration.md.
+type AuditSinkConfig struct {
+	Type string `yaml:"type"`
+
+	// webhook: POST each entry as JSON to URL, signed with Secret (HMAC-SHA256)
+	// when set. Failed deliveries are retried MaxRetries times with backoff,
+	// then kept in SpoolDir (up to SpoolMaxBytes) until the endpoint recovers.
+	URL           string        `yaml:"url"`
+	Secret        string        `yaml:"secret"`
+	TimeoutStr    string        `yaml:"timeout"`
+	Timeout       time.Duration `yaml:"-"`
+	MaxRetries    int           `yaml:"max_retries"`
+	SpoolDir      string        `yaml:"spool_dir"`
+	SpoolMaxBytes int64         `yaml:"spool_max_bytes"`
+
+	// syslog: RFC 5424 messages over TCP (or TLS) to Address.
+	Address  string `yaml:"address"`
+	TLS      bool   `yaml:"tls"`
+	CAFile   string `yaml:"ca_file"`
+	Facility string `yaml:"facility"`
+	AppName  string `yaml:"app_name"`
+
+	// file: one JSON object per line in Path, rotated at MaxSizeMB keeping
+	// MaxBackups old files.
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
 }
 
 // DefaultConfig returns a Config with sensible default values.
@@ -96,21 +229,45 @@ func DefaultConfig() *Config {
 			GRPCPort: 9090,
 		},
 		Database: DatabaseConfig{
-			Driver: "sqlite",
-			Path:   "kbridge.db",
+			Driver:      "sqlite",
+			Path:        "kbridge.db",
+			AutoMigrate: true,
 		},
 		Auth: AuthConfig{
 			AccessTokenExpiryStr:  "1h",
 			AccessTokenExpiry:     time.Hour,
 			RefreshTokenExpiryStr: "168h",
 			RefreshTokenExpiry:    168 * time.Hour,
+			OIDC: OIDCConfig{
+				Scopes:             []string{"openid", "email", "profile"},
+				GroupsClaim:        "groups",
+				AutoProvision:      true,
+				SessionLifetimeStr: "24h",
+				SessionLifetime:    24 * time.Hour,
+			},
 		},
 		Audit: AuditConfig{
 			RetentionDays:      90,
 			CleanupIntervalStr: "24h",
 			CleanupInterval:    24 * time.Hour,
 		},
+		RBAC: RBACConfig{
+			Source:             RBACSourceFile,
+			PollIntervalStr:    "10s",
+			PollInterval:       10 * time.Second,
+			ApprovalTimeoutStr: "15m",
+			ApprovalTimeout:    DefaultApprovalTimeout,
+			JIT: JITConfig{
+				MaxDurationStr: "8h",
+				MaxDuration:    8 * time.Hour,
+				Breakglass: BreakglassConfig{
+					DurationStr: "1h",
+					Duration:    time.Hour,
+				},
+			},
+		},
 		Streams: StreamsConfig{MaxConcurrent: 50},
+		Metrics: MetricsConfig{Enabled: true},
 	}
 }
 
@@ -163,12 +320,51 @@ func (c *Config) parseDurations() error {
 			return fmt.Errorf("invalid refresh_token_expiry %q: %w", c.Auth.RefreshTokenExpiryStr, err)
 		}
 	}
+	if c.Auth.OIDC.SessionLifetimeStr != "" {
+		c.Auth.OIDC.SessionLifetime, err = time.ParseDuration(c.Auth.OIDC.SessionLifetimeStr)
+		if err != nil {
+			return fmt.Errorf("invalid oidc session_lifetime %q: %w", c.Auth.OIDC.SessionLifetimeStr, err)
+		}
+	}
 	if c.Audit.CleanupIntervalStr != "" {
 		c.Audit.CleanupInterval, err = time.ParseDuration(c.Audit.CleanupIntervalStr)
 		if err != nil {
 			return fmt.Errorf("invalid cleanup_interval %q: %w", c.Audit.CleanupIntervalStr, err)
 		}
 	}
+	for i := range c.Audit.Sinks {
+		sink := &c.Audit.Sinks[i]
+		if sink.TimeoutStr != "" {
+			sink.Timeout, err = time.ParseDuration(sink.TimeoutStr)
+			if err != nil {
+				return fmt.Errorf("invalid audit.sinks[%d].timeout %q: %w", i, sink.TimeoutStr, err)
+			}
+		}
+	}
+	if c.RBAC.PollIntervalStr != "" {
+		c.RBAC.PollInterval, err = time.ParseDuration(c.RBAC.PollIntervalStr)
+		if err != nil {
+			return fmt.Errorf("invalid rbac.poll_interval %q: %w", c.RBAC.PollIntervalStr, err)
+		}
+	}
+	if c.RBAC.ApprovalTimeoutStr != "" {
+		c.RBAC.ApprovalTimeout, err = time.ParseDuration(c.RBAC.ApprovalTimeoutStr)
+		if err != nil {
+			return fmt.Errorf("invalid rbac.approval_timeout %q: %w", c.RBAC.ApprovalTimeoutStr, err)
+		}
+	}
+	if c.RBAC.JIT.MaxDurationStr != "" {
+		c.RBAC.JIT.MaxDuration, err = time.ParseDuration(c.RBAC.JIT.MaxDurationStr)
+		if err != nil {
+			return fmt.Errorf("invalid rbac.jit.max_duration %q: %w", c.RBAC.JIT.MaxDurationStr, err)
+		}
+	}
+	if bg := &c.RBAC.JIT.Breakglass; bg.DurationStr != "" {
+		bg.Duration, err = time.ParseDuration(bg.DurationStr)
+		if err != nil {
+			return fmt.Errorf("invalid rbac.jit.breakglass.duration %q: %w", bg.DurationStr, err)
+		}
+	}
 	return nil
 }
 
@@ -183,9 +379,105 @@ func (c *Config) Validate() error {
 	if err := c.validateAuth(); err != nil {
 		return err
 	}
+	if err := c.validateRBAC(); err != nil {
+		return err
+	}
+	if err := c.validateAuditSinks(); err != nil {
+		return err
+	}
+	if c.Audit.Recording.MaxBytes < 0 {
+		return fmt.Errorf("audit.recording.max_bytes must not be negative")
+	}
+	switch c.Audit.Output.Mode {
+	case "", OutputCaptureOff, OutputCaptureStderr, OutputCaptureFull:
+	default:
+		return fmt.Errorf("audit.output.mode must be %s, %s or %s", OutputCaptureOff, OutputCaptureStderr, OutputCaptureFull)
+	}
+	if c.Audit.Output.MaxKB < 0 {
+		return fmt.Errorf("audit.output.max_kb must not be negative")
+	}
+	if _, err := NewOutputCapture(c.Audit.Output); err != nil {
+		return fmt.Errorf("audit.output.redact: %w", err)
+	}
 	return c.validateTLS()
 }
 
+func (c *Config) validateAuditSinks() error {
+	for i, s := range c.Audit.Sinks {
+		key := fmt.Sprintf("audit.sinks[%d]", i)
+		switch s.Type {
+		case AuditSinkWebhook:
+			if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+				return fmt.Errorf("%s.url must be an http or https URL", key)
+			}
+			if s.MaxRetries < 0 || s.SpoolMaxBytes < 0 || s.Timeout < 0 {
+				return fmt.Errorf("%s: max_retries, spool_max_bytes and timeout must not be negative", key)
+			}
+		case AuditSinkSyslog:
+			if s.Address == "" {
+				return fmt.Errorf("%s.address is required for a syslog sink", key)
+			}
+			if _, ok := syslogFacilities[s.Facility]; s.Facility != "" && !ok {
+				return fmt.Errorf("%s: unknown syslog facility %q", key, s.Facility)
+			}
+		case AuditSinkFile:
+			if s.Path == "" {
+				return fmt.Errorf("%s.path is required for a file sink", key)
+			}
+			if s.MaxSizeMB < 0 || s.MaxBackups < 0 {
+				return fmt.Errorf("%s: max_size_mb and max_backups must not be negative", key)
+			}
+		default:
+			return fmt.Errorf("%s: unknown type %q (want webhook, syslog or file)", key, s.Type)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateRBAC() error {
+	switch c.RBAC.Source {
+	case "", RBACSourceFile:
+	case RBACSourceDatabase:
+		if c.RBAC.PollInterval <= 0 {
+			return fmt.Errorf("rbac.poll_interval must be greater than zero")
+		}
+	default:
+		return fmt.Errorf("invalid rbac.source %q: must be file or database", c.RBAC.Source)
+	}
+	if c.RBAC.ApprovalTimeout < 0 {
+		return fmt.Errorf("rbac.approval_timeout must not be negative")
+	}
+	if c.RBAC.JIT.MaxDuration < 0 {
+		return fmt.Errorf("rbac.jit.max_duration must not be negative")
+	}
+	if err := validateSubjects("rbac.jit.approvers", c.RBAC.JIT.Approvers); err != nil {
+		return err
+	}
+	if bg := c.RBAC.JIT.Breakglass; bg.Role != "" {
+		if len(bg.Subjects) == 0 {
+			return fmt.Errorf("rbac.jit.breakglass.subjects is required when a breakglass role is set")
+		}
+		if bg.Duration <= 0 {
+			return fmt.Errorf("rbac.jit.breakglass.duration must be greater than zero")
+		}
+		if err := validateSubjects("rbac.jit.breakglass.subjects", bg.Subjects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSubjects rejects empty subjects and bare "user:"/"group:" prefixes.
+func validateSubjects(key string, subjects []string) error {
+	for _, s := range subjects {
+		switch s {
+		case "", groupSubjectPrefix, "user:":
+			return fmt.Errorf("%s: subject %q has no name", key, s)
+		}
+	}
+	return nil
+}
+
 func (c *Config) validateTLS() error {
 	if !c.TLS.Enabled {
 		return nil
@@ -213,6 +505,9 @@ func (c *Config) validateDatabase() error {
 	if c.Database.Driver != "sqlite" && c.Database.Driver != "postgres" {
 		return fmt.Errorf("invalid database driver %q: must be sqlite or postgres", c.Database.Driver)
 	}
+	if c.Database.Driver == "postgres" && c.Database.DSN == "" {
+		return fmt.Errorf("database.dsn must be set when driver is postgres")
+	}
 	return nil
 }
 
@@ -226,6 +521,23 @@ func (c *Config) validateAuth() error {
 	if c.Auth.RefreshTokenExpiry <= 0 {
 		return fmt.Errorf("refresh_token_expiry must be greater than zero")
 	}
+	return c.validateOIDC()
+}
+
+func (c *Config) validateOIDC() error {
+	o := c.Auth.OIDC
+	if !o.Enabled {
+		return nil
+	}
+	if o.IssuerURL == "" || o.ClientID == "" {
+		return fmt.Errorf("auth.oidc.issuer_url and auth.oidc.client_id are required when oidc is enabled")
+	}
+	if !strings.HasPrefix(o.IssuerURL, "https://") && !strings.HasPrefix(o.IssuerURL, "http://") {
+		return fmt.Errorf("auth.oidc.issuer_url must be an http(s) URL")
+	}
+	if o.SessionLifetime <= 0 {
+		return fmt.Errorf("auth.oidc.session_lifetime must be greater than zero")
+	}
 	return nil
 }
 
@@ -285,6 +597,18 @@ func (c *Config) resolveSecrets() error {
 	if c.Auth.AdminPassword, err = resolveSecret(c.Auth.AdminPassword, c.Auth.AdminPasswordFile, "KBRIDGE_ADMIN_PASSWORD"); err != nil {
 		return err
 	}
+	if c.Database.DSN, err = resolveSecret(c.Database.DSN, c.Database.DSNFile, "KBRIDGE_DATABASE_DSN"); err != nil {
+		return err
+	}
+	if c.Auth.OIDC.ClientSecret, err = resolveSecret(c.Auth.OIDC.ClientSecret, c.Auth.OIDC.ClientSecretFile, "KBRIDGE_OIDC_CLIENT_SECRET"); err != nil {
+		return err
+	}
+	if c.Audit.ChainSecret, err = resolveSecret(c.Audit.ChainSecret, c.Audit.ChainSecretFile, "KBRIDGE_AUDIT_CHAIN_SECRET"); err != nil {
+		return err
+	}
+	if c.Metrics.Token, err = resolveSecret(c.Metrics.Token, c.Metrics.TokenFile, "KBRIDGE_METRICS_TOKEN"); err != nil {
+		return err
+	}
 	return nil
 }
 
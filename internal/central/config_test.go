@@ -59,6 +59,16 @@ func TestDefaultConfig_Streams(t *testing.T) {
 	}
 }
 
+func TestConfig_Metrics(t *testing.T) {
+	if !DefaultConfig().Metrics.Enabled {
+		t.Error("metrics should be enabled by default")
+	}
+	t.Setenv("KBRIDGE_METRICS_TOKEN", "from-env")
+	if cfg := DefaultConfigWithEnv(); cfg.Metrics.Token != "from-env" {
+		t.Errorf("metrics token = %q, want from-env", cfg.Metrics.Token)
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -404,6 +414,100 @@ func TestConfig_Validate(t *testing.T) {
 			modify:  func(c *Config) { c.Server.GRPCPort = 8080 },
 			wantErr: true,
 		},
+		{
+			name: "database rbac source",
+			modify: func(c *Config) {
+				c.RBAC = RBACConfig{Source: RBACSourceDatabase, PollInterval: time.Second}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "database rbac source without poll interval",
+			modify:  func(c *Config) { c.RBAC = RBACConfig{Source: RBACSourceDatabase} },
+			wantErr: true,
+		},
+		{
+			name:    "unknown rbac source",
+			modify:  func(c *Config) { c.RBAC.Source = "etcd" },
+			wantErr: true,
+		},
+		{
+			name: "breakglass role with subjects",
+			modify: func(c *Config) {
+				c.RBAC.JIT.Breakglass = BreakglassConfig{Role: "admin", Duration: time.Hour, Subjects: []string{"group:oncall"}}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "breakglass role without subjects",
+			modify:  func(c *Config) { c.RBAC.JIT.Breakglass = BreakglassConfig{Role: "admin", Duration: time.Hour} },
+			wantErr: true,
+		},
+		{
+			name: "breakglass subject without a name",
+			modify: func(c *Config) {
+				c.RBAC.JIT.Breakglass = BreakglassConfig{Role: "admin", Duration: time.Hour, Subjects: []string{"group:"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "audit sinks",
+			modify: func(c *Config) {
+				c.Audit.Sinks = []AuditSinkConfig{
+					{Type: AuditSinkWebhook, URL: "https://siem.example.com/hook"},
+					{Type: AuditSinkSyslog, Address: "syslog:6514", TLS: true, Facility: "local3"},
+					{Type: AuditSinkFile, Path: "/var/log/kbridge/audit.jsonl"},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown audit sink type",
+			modify:  func(c *Config) { c.Audit.Sinks = []AuditSinkConfig{{Type: "kafka"}} },
+			wantErr: true,
+		},
+		{
+			name:    "webhook sink without url",
+			modify:  func(c *Config) { c.Audit.Sinks = []AuditSinkConfig{{Type: AuditSinkWebhook}} },
+			wantErr: true,
+		},
+		{
+			name:    "syslog sink without address",
+			modify:  func(c *Config) { c.Audit.Sinks = []AuditSinkConfig{{Type: AuditSinkSyslog}} },
+			wantErr: true,
+		},
+		{
+			name: "syslog sink with unknown facility",
+			modify: func(c *Config) {
+				c.Audit.Sinks = []AuditSinkConfig{{Type: AuditSinkSyslog, Address: "syslog:514", Facility: "local9"}}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "file sink without path",
+			modify:  func(c *Config) { c.Audit.Sinks = []AuditSinkConfig{{Type: AuditSinkFile}} },
+			wantErr: true,
+		},
+		{
+			name:    "negative recording max_bytes",
+			modify:  func(c *Config) { c.Audit.Recording = RecordingConfig{Enabled: true, MaxBytes: -1} },
+			wantErr: true,
+		},
+		{
+			name:    "unknown output capture mode",
+			modify:  func(c *Config) { c.Audit.Output.Mode = "stdout" },
+			wantErr: true,
+		},
+		{
+			name:    "invalid output redact pattern",
+			modify:  func(c *Config) { c.Audit.Output = OutputCaptureConfig{Mode: OutputCaptureFull, Redact: []string{"["}} },
+			wantErr: true,
+		},
+		{
+			name:    "stderr output capture",
+			modify:  func(c *Config) { c.Audit.Output = OutputCaptureConfig{Mode: OutputCaptureStderr, MaxKB: 16} },
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -443,10 +547,18 @@ func TestConfig_Validate_Database(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "postgres driver is valid",
-			modify:  func(c *Config) { c.Database.Driver = "postgres" },
+			name: "postgres driver is valid",
+			modify: func(c *Config) {
+				c.Database.Driver = "postgres"
+				c.Database.DSN = "postgres://kbridge@localhost/kbridge"
+			},
 			wantErr: false,
 		},
+		{
+			name:    "postgres driver without dsn",
+			modify:  func(c *Config) { c.Database.Driver = "postgres" },
+			wantErr: true,
+		},
 		{
 			name:    "empty jwt secret",
 			modify:  func(c *Config) { c.Auth.JWTSecret = "" },
@@ -472,6 +584,32 @@ func TestConfig_Validate_Database(t *testing.T) {
 			modify:  func(c *Config) { c.Auth.RefreshTokenExpiry = -1 * time.Hour },
 			wantErr: true,
 		},
+		{
+			name: "oidc enabled with issuer and client",
+			modify: func(c *Config) {
+				c.Auth.OIDC = OIDCConfig{Enabled: true, IssuerURL: "https://idp.example.com", ClientID: "kbridge", SessionLifetime: time.Hour}
+			},
+			wantErr: false,
+		},
+		{
+			name: "oidc enabled without session lifetime",
+			modify: func(c *Config) {
+				c.Auth.OIDC = OIDCConfig{Enabled: true, IssuerURL: "https://idp.example.com", ClientID: "kbridge"}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "oidc enabled without issuer",
+			modify:  func(c *Config) { c.Auth.OIDC = OIDCConfig{Enabled: true, ClientID: "kbridge"} },
+			wantErr: true,
+		},
+		{
+			name: "oidc issuer is not a url",
+			modify: func(c *Config) {
+				c.Auth.OIDC = OIDCConfig{Enabled: true, IssuerURL: "idp.example.com", ClientID: "kbridge"}
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
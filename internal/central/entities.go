@@ -3,14 +3,17 @@ package central
 import "time"
 
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Name         string    `json:"name"`
-	IsActive     bool      `json:"is_active"`
-	IsAdmin      bool      `json:"is_admin"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string `json:"id"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+	Name         string `json:"name"`
+	IsActive     bool   `json:"is_active"`
+	IsAdmin      bool   `json:"is_admin"`
+	// Groups are kbridge-managed group memberships, matched by `group:`
+	// policy bindings alongside any groups asserted by an SSO provider.
+	Groups    []string  `json:"groups,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Cluster struct {
@@ -50,22 +53,159 @@ type AuditLog struct {
 	ErrorMessage string    `json:"error_message,omitempty"`
 	ClientIP     string    `json:"client_ip,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
+	// Verb and Resource are parsed from an exec command for filtering;
+	// Resource lists every resource type the command touches, comma-separated.
+	Verb     string `json:"verb,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// Seq, PrevHash and Hash chain the entry to its predecessor; see
+	// AuditChain. Entries written before the chain existed have none.
+	Seq      int64  `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
-type RefreshToken struct {
+// AuditCheckpoint is written when retention cleanup deletes the start of the
+// audit chain. It records the last deleted entry so the chain can still be
+// verified from the first remaining one.
+type AuditCheckpoint struct {
 	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	TokenHash string    `json:"-"`
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	Deleted   int       `json:"deleted"`
+	CreatedAt time.Time `json:"created_at"`
+	Signature string    `json:"signature"`
+}
+
+type RefreshToken struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	TokenHash string `json:"-"`
+	// Groups are the IdP groups captured at SSO login, carried over to every
+	// access token minted from this refresh token. Empty for password logins.
+	Groups    []string  `json:"groups,omitempty"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// PolicyVersion is one revision of the database-backed RBAC policy. History is
+// append-only: a rollback is recorded as a new version holding an old document.
+type PolicyVersion struct {
+	Version   int       `json:"version"`
+	Document  string    `json:"document,omitempty"`
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JIT request states. An approved request grants its role until ExpiresAt.
+const (
+	JITStatusPending  = "pending"
+	JITStatusApproved = "approved"
+	JITStatusDenied   = "denied"
+)
+
+// JITRequest is a just-in-time request for a policy role on one cluster for a
+// limited time. The grant window starts when the request is approved. A
+// break-glass request is approved by its own requester when it is created.
+type JITRequest struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id,omitempty"`
+	UserEmail   string     `json:"user_email"`
+	ClusterName string     `json:"cluster_name"`
+	Role        string     `json:"role"`
+	Reason      string     `json:"reason"`
+	DurationSec int64      `json:"duration_seconds"`
+	Status      string     `json:"status"`
+	Breakglass  bool       `json:"breakglass,omitempty"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Duration returns the requested grant duration.
+func (r *JITRequest) Duration() time.Duration {
+	return time.Duration(r.DurationSec) * time.Second
+}
+
+// Active reports whether the request currently grants its role.
+func (r *JITRequest) Active(now time.Time) bool {
+	return r.Status == JITStatusApproved && r.ExpiresAt != nil && now.Before(*r.ExpiresAt)
+}
+
+// SessionRecording is an asciicast v2 capture of an interactive exec session,
+// linked to the session's audit entry. Data is only loaded by
+// GetSessionRecording.
+type SessionRecording struct {
+	ID          string    `json:"id"`
+	AuditLogID  string    `json:"audit_log_id"`
+	UserEmail   string    `json:"user_email"`
+	ClusterName string    `json:"cluster_name"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Command     string    `json:"command"`
+	DurationMs  int64     `json:"duration_ms"`
+	Size        int64     `json:"size"`
+	Truncated   bool      `json:"truncated,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Data        []byte    `json:"-"`
+}
+
+// AuditOutput is the captured, redacted output of a one-shot command, stored
+// next to its audit entry. StdoutBytes and StderrBytes are the sizes the
+// command produced, before truncation.
+type AuditOutput struct {
+	AuditLogID  string    `json:"audit_log_id"`
+	Stdout      string    `json:"stdout,omitempty"`
+	Stderr      string    `json:"stderr,omitempty"`
+	StdoutBytes int64     `json:"stdout_bytes"`
+	StderrBytes int64     `json:"stderr_bytes"`
+	Truncated   bool      `json:"truncated,omitempty"`
+	Redactions  int       `json:"redactions,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SessionRecordingFilter narrows ListSessionRecordings. Zero fields match
+// everything.
+type SessionRecordingFilter struct {
+	UserEmail   string
+	ClusterName string
+	Limit       int
+}
+
+// JITRequestFilter narrows ListJITRequests. Zero fields match everything.
+type JITRequestFilter struct {
+	UserEmail string
+	Status    string
+	Limit     int
+}
+
+// AuditLogFilter narrows ListAuditLogs and ListAuditLogsAfter. Zero fields
+// match everything.
 type AuditLogFilter struct {
 	UserEmail   string
 	ClusterName string
 	Status      string
+	Namespace   string
+	Verb        string
+	Resource    string // one resource type, e.g. "pods"
+	ClientIP    string
+	Search      string // case-insensitive substring of the command
 	From        *time.Time
 	To          *time.Time
+	Ascending   bool // oldest first; the default is newest first
 	Page        int
 	PerPage     int
 }
+
+// AuditCursor marks a position in a listing of audit logs for keyset
+// pagination: the sort key of the last entry returned.
+type AuditCursor struct {
+	CreatedAt time.Time
+	Seq       int64
+	ID        string
+}
+
+// auditCursorFor returns the cursor positioned at l.
+func auditCursorFor(l *AuditLog) *AuditCursor {
+	return &AuditCursor{CreatedAt: l.CreatedAt, Seq: l.Seq, ID: l.ID}
+}
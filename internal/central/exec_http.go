@@ -4,20 +4,30 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/why-xn/kbridge/api/proto/agentpb"
+	"github.com/why-xn/kbridge/internal/auth"
 	"github.com/why-xn/kbridge/internal/execframe"
 )
 
+// SetSessionRecording enables asciicast recording of interactive exec
+// sessions. Recordings are stored with the session's audit entry, so auditing
+// must be enabled too.
+func (s *HTTPServer) SetSessionRecording(cfg RecordingConfig) {
+	s.recording = cfg
+}
+
 // runExecBridge relays an interactive session between the CLI (upstream frames
 // in, downstream frames out) and the agent (via the SessionManager). It is
-// transport-agnostic so it can be unit-tested over pipes.
+// transport-agnostic so it can be unit-tested over pipes. Stdin, output and
+// resizes are also fed to rec, which may be nil.
 //
 // It returns (exitCode, errMsg) from the session so the caller does not need
 // to call sess.Wait() again — every return path closes the session exactly once.
-func runExecBridge(ctx context.Context, upstream io.Reader, downstream io.Writer, sess *Session, sm *SessionManager, flush func()) (int32, string) {
+func runExecBridge(ctx context.Context, upstream io.Reader, downstream io.Writer, sess *Session, sm *SessionManager, rec *sessionRecorder, flush func()) (int32, string) {
 	// The upstream goroutine only forwards stdin. On any read error (including
 	// io.EOF for a clean half-close and post-EXIT body-close errors) it simply
 	// stops forwarding. It must NOT call sm.Cancel: true client disconnect is
@@ -30,9 +40,11 @@ func runExecBridge(ctx context.Context, upstream io.Reader, downstream io.Writer
 			}
 			switch t {
 			case execframe.Stdin:
+				rec.Input(payload)
 				_ = sm.SendStdin(sess.ID, payload)
 			case execframe.Resize:
 				if rows, cols, e := execframe.DecodeResize(payload); e == nil {
+					rec.Resize(rows, cols)
 					_ = sm.SendResize(sess.ID, uint32(rows), uint32(cols))
 				}
 			}
@@ -56,6 +68,7 @@ func runExecBridge(ctx context.Context, upstream io.Reader, downstream io.Writer
 			if chunk.Type == agentpb.OutputType_OUTPUT_TYPE_STDERR {
 				ft = execframe.Stderr
 			}
+			rec.Output(chunk.Data)
 			_ = execframe.Encode(downstream, ft, chunk.Data)
 			flush()
 		}
@@ -128,9 +141,9 @@ func (s *HTTPServer) handleExecAttach(c *gin.Context) {
 	var sess *Session
 	var err error
 	if tty {
-		sess, err = s.sessions.StartInteractive(agent.ID, args, namespace, clampDim(rows), clampDim(cols))
+		sess, err = s.sessions.StartInteractive(agent.ID, requestIdentity(c), args, namespace, clampDim(rows), clampDim(cols))
 	} else {
-		sess, err = s.sessions.StartWithStdin(agent.ID, args, namespace)
+		sess, err = s.sessions.StartWithStdin(agent.ID, requestIdentity(c), args, namespace)
 	}
 	if err != nil {
 		if err == ErrTooManyStreams {
@@ -155,7 +168,22 @@ func (s *HTTPServer) handleExecAttach(c *gin.Context) {
 	flush()
 	start := time.Now()
 
-	exitCode, errMsg := runExecBridge(c.Request.Context(), c.Request.Body, c.Writer, sess, s.sessions, flush)
+	// I6: audit the logical command (exec <pod> -- <cmd>) rather than the raw
+	// kubectl args which include transport flags (-i/-t). Use req for authz
+	// (unchanged); build a separate auditReq for the final audit record.
+	auditCmd := append([]string{"exec", pod}, append([]string{"--"}, command...)...)
+	auditReq := ExecRequest{Command: auditCmd, Namespace: namespace}
+
+	var rec *sessionRecorder
+	if s.recording.Enabled && s.audit != nil {
+		title := clusterName
+		if claims := auth.GetUserFromContext(c); claims != nil {
+			title = claims.Email + "@" + clusterName
+		}
+		rec = newSessionRecorder(int(clampDim(cols)), int(clampDim(rows)), strings.Join(auditCmd, " "), title, s.recording.MaxBytes)
+	}
+
+	exitCode, errMsg := runExecBridge(c.Request.Context(), c.Request.Body, c.Writer, sess, s.sessions, rec, flush)
 
 	// I3: distinguish canceled (slow-client via Route→Cancel or ctx cancel) from
 	// failed (non-zero exit). Cancel closes the session with errMsg "canceled";
@@ -168,11 +196,17 @@ func (s *HTTPServer) handleExecAttach(c *gin.Context) {
 	}
 	dur := time.Since(start).Milliseconds()
 	ec := exitCode
-
-	// I6: audit the logical command (exec <pod> -- <cmd>) rather than the raw
-	// kubectl args which include transport flags (-i/-t). Use req for authz
-	// (unchanged); build a separate auditReq for the final audit record.
-	auditCmd := append([]string{"exec", pod}, append([]string{"--"}, command...)...)
-	auditReq := ExecRequest{Command: auditCmd, Namespace: namespace}
-	s.recordExecAudit(c, clusterName, auditReq, status, &ec, &dur, errMsg)
+	entry := s.recordExecAudit(c, clusterName, auditReq, status, &ec, &dur, errMsg)
+	if rec != nil && entry != nil && entry.ID != "" {
+		s.audit.SaveRecording(&SessionRecording{
+			AuditLogID:  entry.ID,
+			UserEmail:   entry.UserEmail,
+			ClusterName: clusterName,
+			Namespace:   namespace,
+			Command:     entry.Command,
+			DurationMs:  dur,
+			Truncated:   rec.Truncated(),
+			Data:        rec.Bytes(),
+		})
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,16 +16,17 @@ func TestRunExecBridge_RelaysBothDirections(t *testing.T) {
 	m := NewSessionManager(10)
 	rs := &recordingSender{}
 	m.RegisterAgentStream("a1", rs)
-	sess, _ := m.StartInteractive("a1", []string{"exec", "-i", "-t", "p", "--", "sh"}, "ns", 24, 80)
+	sess, _ := m.StartInteractive("a1", Identity{}, []string{"exec", "-i", "-t", "p", "--", "sh"}, "ns", 24, 80)
 
-	upR, upW := io.Pipe()       // CLI -> central (stdin frames)
-	var down bytes.Buffer       // central -> CLI (output frames)
+	upR, upW := io.Pipe() // CLI -> central (stdin frames)
+	var down bytes.Buffer // central -> CLI (output frames)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	rec := newSessionRecorder(80, 24, "exec p -- sh", "", 0)
 	done := make(chan struct{})
 	go func() {
-		runExecBridge(ctx, upR, &down, sess, m, func() {})
+		runExecBridge(ctx, upR, &down, sess, m, rec, func() {})
 		close(done)
 	}()
 
@@ -55,6 +57,11 @@ func TestRunExecBridge_RelaysBothDirections(t *testing.T) {
 	if t1 != execframe.Stdout || string(d1) != "hi" || t2 != execframe.Exit {
 		t.Fatalf("downstream frames wrong: %v %q %v", t1, d1, t2)
 	}
+
+	cast := string(rec.Bytes())
+	if !strings.Contains(cast, `"i","ls\n"]`) || !strings.Contains(cast, `"o","hi"]`) {
+		t.Fatalf("session not recorded:\n%s", cast)
+	}
 }
 
 func waitFor(t *testing.T, cond func() bool) {
@@ -30,8 +30,13 @@ type GRPCServer struct {
 // NewGRPCServer creates a new gRPC server. agents tracks live agent state for
 // command routing; authn validates registration tokens against the persistent
 // store and resolves their bound cluster. sessions manages bidi streaming
-// sessions for agents that have opened a persistent stream.
+// sessions for agents that have opened a persistent stream, and cmdQueue pushes
+// one-shot commands through it to agents that accept them.
 func NewGRPCServer(agents *AgentStore, cmdQueue *CommandQueue, authn *AgentAuthenticator, sessions *SessionManager) *GRPCServer {
+	cmdQueue.SetAgentLimits(agents)
+	if sessions != nil {
+		cmdQueue.SetPusher(sessions)
+	}
 	return &GRPCServer{
 		agents:   agents,
 		cmdQueue: cmdQueue,
@@ -79,13 +84,14 @@ func (s *GRPCServer) Register(ctx context.Context, req *agentpb.RegisterRequest)
 		ID:          agentID,
 		ClusterName: cluster.Name,
 		Token:       req.GetAgentToken(),
+		MaxCommands: int(req.GetMaxConcurrentCommands()),
 	}
 
 	// Persist the cluster's connected state, then track the agent in memory
 	// for live command routing.
 	s.markClusterConnected(ctx, cluster, agentID, info)
 	s.agents.Register(info)
-	log.Printf("Agent registered: id=%s, cluster=%s", agentID, cluster.Name)
+	log.Printf("Agent registered: id=%s, cluster=%s, max_concurrent_commands=%d", agentID, cluster.Name, info.MaxCommands)
 
 	return &agentpb.RegisterResponse{
 		Success: true,
@@ -155,23 +161,14 @@ func (s *GRPCServer) GetPendingCommands(ctx context.Context, req *agentpb.GetPen
 		return nil, status.Error(codes.NotFound, "agent not registered")
 	}
 
-	// Get pending commands for this agent
-	pending := s.cmdQueue.GetPendingForAgent(agentID)
+	// Claim as many pending commands as the agent has room for, so they are
+	// not returned again nor pushed on a stream opened meanwhile
+	claimed := s.cmdQueue.ClaimForAgent(agentID, int(req.GetMaxCommands()))
 
 	// Convert to protobuf format
-	commands := make([]*agentpb.CommandRequest, 0, len(pending))
-	for _, cmd := range pending {
-		// Mark as running so it's not returned again
-		s.cmdQueue.MarkRunning(cmd.RequestID)
-
-		commands = append(commands, &agentpb.CommandRequest{
-			RequestId:      cmd.RequestID,
-			AgentId:        cmd.AgentID,
-			Command:        cmd.Command,
-			Namespace:      cmd.Namespace,
-			TimeoutSeconds: cmd.TimeoutSeconds,
-			Stdin:          cmd.Stdin,
-		})
+	commands := make([]*agentpb.CommandRequest, 0, len(claimed))
+	for _, cmd := range claimed {
+		commands = append(commands, cmd.request())
 	}
 
 	if len(commands) > 0 {
@@ -179,17 +176,27 @@ func (s *GRPCServer) GetPendingCommands(ctx context.Context, req *agentpb.GetPen
 	}
 
 	return &agentpb.GetPendingCommandsResponse{
-		Commands: commands,
+		Commands:           commands,
+		CanceledRequestIds: s.cmdQueue.TakeCanceled(agentID),
 	}, nil
 }
 
 // SubmitCommandResult receives the result of a command execution from an agent.
 func (s *GRPCServer) SubmitCommandResult(ctx context.Context, req *agentpb.SubmitCommandResultRequest) (*agentpb.SubmitCommandResultResponse, error) {
-	requestID := req.GetRequestId()
-	if requestID == "" {
+	if req.GetRequestId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "request_id is required")
 	}
+	s.completeCommand(req)
 
+	return &agentpb.SubmitCommandResultResponse{
+		Success: true,
+	}, nil
+}
+
+// completeCommand hands a command result, submitted by RPC or on the stream,
+// to the request waiting for it.
+func (s *GRPCServer) completeCommand(req *agentpb.SubmitCommandResultRequest) {
+	requestID := req.GetRequestId()
 	log.Printf("Received command result: request_id=%s, exit_code=%d", requestID, req.GetExitCode())
 
 	result := &CommandResult{
@@ -207,10 +214,6 @@ func (s *GRPCServer) SubmitCommandResult(ctx context.Context, req *agentpb.Submi
 		// Command completed (success or non-zero exit)
 		s.cmdQueue.Complete(requestID, result)
 	}
-
-	return &agentpb.SubmitCommandResultResponse{
-		Success: true,
-	}, nil
 }
 
 // OpenStream is the persistent bidi channel an agent opens after Register.
@@ -227,15 +230,38 @@ func (s *GRPCServer) OpenStream(stream agentpb.AgentService_OpenStreamServer) er
 		return status.Error(codes.NotFound, "agent not registered")
 	}
 
-	s.sessions.RegisterAgentStream(reg.GetAgentId(), stream)
-	defer s.sessions.UnregisterAgentStream(reg.GetAgentId())
-	log.Printf("Agent stream opened: id=%s", reg.GetAgentId())
+	agentID := reg.GetAgentId()
+	if reg.GetPushCommands() {
+		// Confirm before anything else can send on the stream; the agent stops
+		// polling once it sees this.
+		if err := stream.Send(&agentpb.CentralStreamMessage{Msg: &agentpb.CentralStreamMessage_Registered{
+			Registered: &agentpb.StreamRegistered{PushCommands: true},
+		}}); err != nil {
+			return err
+		}
+	}
+	s.sessions.RegisterAgentStream(agentID, stream)
+	defer s.sessions.UnregisterAgentStream(agentID)
+	log.Printf("Agent stream opened: id=%s, push_commands=%t", agentID, reg.GetPushCommands())
+	if reg.GetPushCommands() {
+		// Commands queued while the agent had no stream are sent now.
+		s.sessions.AcceptCommands(agentID)
+		s.cmdQueue.DispatchPending(agentID)
+	}
 
 	for {
 		msg, err := stream.Recv()
 		if err != nil {
 			return err
 		}
+		if res := msg.GetCommandResult(); res != nil {
+			if cmd, ok := s.cmdQueue.Get(res.GetRequestId()); ok && cmd.AgentID != agentID {
+				log.Printf("Ignoring result for %s from agent %s: command belongs to %s", res.GetRequestId(), agentID, cmd.AgentID)
+				continue
+			}
+			s.completeCommand(res)
+			continue
+		}
 		s.sessions.Route(msg)
 	}
 }
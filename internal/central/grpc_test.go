@@ -373,6 +373,64 @@ func TestGRPCServer_GetPendingCommands_Success(t *testing.T) {
 	}
 }
 
+func TestGRPCServer_GetPendingCommands_RespectsConcurrency(t *testing.T) {
+	srv, store, cmdQueue := newTestGRPCServer(t)
+	ctx := context.Background()
+
+	regResp, _ := srv.Register(ctx, &agentpb.RegisterRequest{
+		AgentToken:            testAgentToken,
+		ClusterName:           testClusterName,
+		MaxConcurrentCommands: 2,
+	})
+	agentID := regResp.AgentId
+	if got := store.MaxCommands(agentID); got != 2 {
+		t.Fatalf("expected advertised concurrency 2, got %d", got)
+	}
+	for i := 0; i < 3; i++ {
+		cmdQueue.Enqueue(agentID, testClusterName, []string{"get", "pods"}, "", 30, nil) //nolint:errcheck
+	}
+
+	// The agent asks for one; then only one more fits under its limit.
+	resp, _ := srv.GetPendingCommands(ctx, &agentpb.GetPendingCommandsRequest{AgentId: agentID, MaxCommands: 1})
+	if len(resp.Commands) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(resp.Commands))
+	}
+	resp, _ = srv.GetPendingCommands(ctx, &agentpb.GetPendingCommandsRequest{AgentId: agentID, MaxCommands: 4})
+	if len(resp.Commands) != 1 {
+		t.Fatalf("expected 1 command under the limit, got %d", len(resp.Commands))
+	}
+	resp, _ = srv.GetPendingCommands(ctx, &agentpb.GetPendingCommandsRequest{AgentId: agentID, MaxCommands: 4})
+	if len(resp.Commands) != 0 {
+		t.Errorf("expected no commands at the limit, got %d", len(resp.Commands))
+	}
+}
+
+func TestGRPCServer_GetPendingCommands_ReturnsCanceled(t *testing.T) {
+	srv, _, cmdQueue := newTestGRPCServer(t)
+	ctx := context.Background()
+
+	regResp, _ := srv.Register(ctx, &agentpb.RegisterRequest{
+		AgentToken:  testAgentToken,
+		ClusterName: testClusterName,
+	})
+	agentID := regResp.AgentId
+	requestID, _ := cmdQueue.Enqueue(agentID, testClusterName, []string{"get", "pods"}, "", 30, nil)
+	srv.GetPendingCommands(ctx, &agentpb.GetPendingCommandsRequest{AgentId: agentID}) //nolint:errcheck
+
+	// The agent has no stream, so it learns of the cancel on its next poll.
+	cmdQueue.Cancel(requestID)
+	resp, err := srv.GetPendingCommands(ctx, &agentpb.GetPendingCommandsRequest{AgentId: agentID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.GetCanceledRequestIds(); len(got) != 1 || got[0] != requestID {
+		t.Errorf("expected canceled [%s], got %v", requestID, got)
+	}
+	if len(resp.Commands) != 0 {
+		t.Errorf("expected no commands, got %d", len(resp.Commands))
+	}
+}
+
 func TestGRPCServer_GetPendingCommands_MissingAgentID(t *testing.T) {
 	srv, _, _ := newTestGRPCServer(t)
 	ctx := context.Background()
@@ -519,11 +577,82 @@ func TestGRPCServer_OpenStream_RegistersAndRoutes(t *testing.T) {
 	fs.waitRegistered(t)
 
 	// Once registered, the manager can start a session for this agent.
-	if _, err := sm.Start(resp.AgentId, []string{"logs", "-f"}, ""); err != nil {
+	if _, err := sm.Start(resp.AgentId, Identity{}, []string{"logs", "-f"}, ""); err != nil {
 		t.Fatalf("start after OpenStream: %v", err)
 	}
 }
 
+func TestGRPCServer_OpenStream_PushesCommands(t *testing.T) {
+	srv, agents, queue := newTestGRPCServer(t)
+	agents.Register(&AgentInfo{ID: "push-a1", ClusterName: testClusterName})
+	agents.Register(&AgentInfo{ID: "push-a2", ClusterName: testClusterName})
+
+	// Queued before the stream opens: dispatched as soon as it does.
+	queued, _ := queue.Enqueue("push-a1", testClusterName, []string{"get", "pods"}, "", 30, nil)
+
+	fs := newFakeOpenStream("push-a1")
+	<-fs.incoming
+	fs.incoming <- &agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Register{
+		Register: &agentpb.StreamRegister{AgentId: "push-a1", PushCommands: true},
+	}}
+	go func() { _ = srv.OpenStream(fs) }()
+
+	select {
+	case m := <-fs.sent:
+		if !m.GetRegistered().GetPushCommands() {
+			t.Fatalf("expected push to be confirmed first, got %v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream registration not confirmed")
+	}
+	recvCommand := func() *agentpb.CommandRequest {
+		t.Helper()
+		select {
+		case m := <-fs.sent:
+			if m.GetCommand() == nil {
+				t.Fatalf("expected a command, got %v", m)
+			}
+			return m.GetCommand()
+		case <-time.After(2 * time.Second):
+			t.Fatal("no command pushed")
+			return nil
+		}
+	}
+	if got := recvCommand(); got.RequestId != queued {
+		t.Errorf("expected queued command %s, got %s", queued, got.RequestId)
+	}
+	pushed, _ := queue.Enqueue("push-a1", testClusterName, []string{"get", "nodes"}, "", 30, nil)
+	if got := recvCommand(); got.RequestId != pushed {
+		t.Errorf("expected new command %s, got %s", pushed, got.RequestId)
+	}
+	resp, _ := srv.GetPendingCommands(context.Background(), &agentpb.GetPendingCommandsRequest{AgentId: "push-a1"})
+	if len(resp.GetCommands()) != 0 {
+		t.Errorf("pushed commands must not be returned by polling, got %d", len(resp.GetCommands()))
+	}
+
+	// A result for another agent's command is ignored; the agent's own result
+	// completes the command.
+	other, _ := queue.Enqueue("push-a2", testClusterName, []string{"get", "ns"}, "", 30, nil)
+	fs.incoming <- &agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_CommandResult{
+		CommandResult: &agentpb.SubmitCommandResultRequest{RequestId: other, Stdout: []byte("forged")},
+	}}
+	fs.incoming <- &agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_CommandResult{
+		CommandResult: &agentpb.SubmitCommandResultRequest{RequestId: pushed, Stdout: []byte("node-1"), ExitCode: 0},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	result, err := queue.WaitForResult(ctx, pushed)
+	if err != nil {
+		t.Fatalf("waiting for pushed result: %v", err)
+	}
+	if string(result.Stdout) != "node-1" {
+		t.Errorf("unexpected stdout %q", result.Stdout)
+	}
+	if cmd, _ := queue.Get(other); cmd.Status != CommandStatusPending {
+		t.Errorf("result from the wrong agent changed command status to %s", cmd.Status)
+	}
+}
+
 type fakeOpenStream struct {
 	agentpb.AgentService_OpenStreamServer
 	agentID  string
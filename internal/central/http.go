@@ -2,9 +2,11 @@ package central
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -47,7 +49,11 @@ type HTTPServer struct {
 	authHandlers  *AuthHandlers
 	adminHandlers *AdminHandlers
 	policy        *PolicyEngine
+	jit           *JITManager
+	approvals     *ApprovalQueue
 	audit         *AuditRecorder
+	recording     RecordingConfig
+	output        *OutputCapture
 	sessions      *SessionManager
 	jwtManager    *auth.JWTManager
 	loginLimiter  *loginLimiter
@@ -74,6 +80,7 @@ func NewHTTPServer(agentStore *AgentStore, cmdQueue *CommandQueue, ah *AuthHandl
 		sessions:      sessions,
 		jwtManager:    jm,
 		loginLimiter:  newLoginLimiter(5.0/60.0, 5),
+		approvals:     NewApprovalQueue(DefaultApprovalTimeout),
 	}
 	s.setupRoutes()
 	return s
@@ -96,6 +103,19 @@ func (s *HTTPServer) setupRoutes() {
 			authGroup.POST("/login", s.authHandlers.HandleLogin)
 			authGroup.POST("/refresh", s.authHandlers.HandleRefresh)
 		}
+
+		// SSO routes sit outside the login limiter: the device flow polls
+		// the token endpoint every few seconds, and codes are single-use.
+		if s.authHandlers.OIDCEnabled() {
+			oidcGroup := s.router.Group("/auth/oidc")
+			oidcGroup.Use(bodyLimitMiddleware(1 << 20))
+			{
+				oidcGroup.POST("/authorize", s.authHandlers.HandleOIDCAuthorize)
+				oidcGroup.POST("/token", s.authHandlers.HandleOIDCToken)
+				oidcGroup.POST("/device", loginRateLimitMiddleware(s.loginLimiter), s.authHandlers.HandleOIDCDeviceStart)
+				oidcGroup.POST("/device/token", s.authHandlers.HandleOIDCDeviceToken)
+			}
+		}
 	}
 
 	// Protected API routes
@@ -112,6 +132,30 @@ func (s *HTTPServer) setupRoutes() {
 			api.POST("/clusters/:name/port-forward", s.handlePortForward)
 		}
 
+		// Policy dry-run: explains decisions without running anything.
+		api.POST("/authz/check", bodyLimitMiddleware(1<<20), s.handleAuthzCheck)
+		api.GET("/authz/whoami", s.handleWhoami)
+
+		// Just-in-time access requests layer on the RBAC policy.
+		if s.policy != nil {
+			access := api.Group("/access/requests")
+			access.Use(bodyLimitMiddleware(1 << 20))
+			{
+				access.POST("", s.handleCreateJITRequest)
+				access.GET("", s.handleListJITRequests)
+				access.GET("/:id", s.handleGetJITRequest)
+				access.POST("/:id/approve", s.handleApproveJITRequest)
+				access.POST("/:id/deny", s.handleDenyJITRequest)
+			}
+			api.POST("/breakglass", bodyLimitMiddleware(1<<20), s.handleBreakglass)
+
+			// Commands held by require_approval rules.
+			api.GET("/approvals", s.handleListApprovals)
+			api.POST("/approvals/:id/approve", s.handleApproveCommand)
+			api.POST("/approvals/:id/deny", s.handleDenyCommand)
+			api.POST("/approvals/:id/wait", s.handleWaitApproval)
+		}
+
 		// Auth routes that require authentication
 		if s.authHandlers != nil {
 			api.POST("/auth/logout", s.authHandlers.HandleLogout)
@@ -136,6 +180,21 @@ func (s *HTTPServer) setupRoutes() {
 				admin.DELETE("/users/:id", s.adminHandlers.HandleDeleteUser)
 
 				admin.GET("/audit", s.adminHandlers.HandleListAuditLogs)
+				admin.GET("/audit/verify", s.adminHandlers.HandleVerifyAuditChain)
+				admin.GET("/audit/export", s.adminHandlers.HandleExportAuditLogs)
+				admin.GET("/audit/:id", s.adminHandlers.HandleGetAuditLog)
+
+				admin.GET("/sessions", s.adminHandlers.HandleListSessionRecordings)
+				admin.GET("/sessions/:id", s.adminHandlers.HandleGetSessionRecording)
+				admin.GET("/sessions/:id/cast", s.adminHandlers.HandleDownloadSessionRecording)
+
+				if s.policy != nil {
+					admin.GET("/policy", s.handleGetPolicy)
+					admin.PUT("/policy", s.handleUpdatePolicy)
+					admin.GET("/policy/versions", s.handleListPolicyVersions)
+					admin.GET("/policy/versions/:version", s.handleGetPolicyVersion)
+					admin.POST("/policy/rollback", s.handleRollbackPolicy)
+				}
 			}
 		}
 	}
@@ -202,11 +261,34 @@ func (s *HTTPServer) handleExecCommand(c *gin.Context) {
 		return
 	}
 
-	// Enforce RBAC before routing the command to the agent.
-	if !s.authorizeExec(c, clusterName, req) {
+	// Enforce RBAC before routing the command to the agent. Commands that
+	// need approval are parked instead; the requester waits for the decision
+	// on /approvals/:id/wait.
+	allowed, needsApproval := s.checkExec(c, clusterName, req)
+	if !allowed {
 		return
 	}
+	if needsApproval {
+		s.parkForApproval(c, clusterName, req)
+		return
+	}
+
+	s.executeCommand(c, agent.ID, clusterName, req)
+}
 
+// requestIdentity is the authenticated user a request runs for, passed to the
+// agent for impersonation.
+func requestIdentity(c *gin.Context) Identity {
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		return Identity{}
+	}
+	return Identity{User: claims.Email, Groups: claims.Groups}
+}
+
+// executeCommand queues an authorized command for the agent, waits for the
+// result and writes the ExecResponse.
+func (s *HTTPServer) executeCommand(c *gin.Context, agentID, clusterName string, req ExecRequest) {
 	// Determine timeout
 	timeout := DefaultExecTimeout
 	if req.Timeout > 0 {
@@ -217,8 +299,9 @@ func (s *HTTPServer) handleExecCommand(c *gin.Context) {
 	}
 
 	// Queue the command
-	requestID, err := s.commandQueue.Enqueue(
-		agent.ID,
+	requestID, err := s.commandQueue.EnqueueForUser(
+		requestIdentity(c),
+		agentID,
 		clusterName,
 		req.Command,
 		req.Namespace,
@@ -245,6 +328,16 @@ func (s *HTTPServer) handleExecCommand(c *gin.Context) {
 	// Clean up the command from queue
 	defer s.commandQueue.Remove(requestID)
 
+	if err != nil && c.Request.Context().Err() != nil {
+		// The client went away (e.g. Ctrl-C); WaitForResult has already
+		// canceled the command on the agent.
+		log.Printf("Command %s canceled by client", requestID)
+		dur := time.Since(start).Milliseconds()
+		s.recordExecAudit(c, clusterName, req, AuditStatusCanceled, nil, &dur, "canceled by client")
+		// 499 Client Closed Request: nobody reads it, but logs and metrics do.
+		c.AbortWithStatus(499)
+		return
+	}
 	if err != nil {
 		log.Printf("Command %s timed out or failed: %v", requestID, err)
 		dur := time.Since(start).Milliseconds()
@@ -280,40 +373,94 @@ func (s *HTTPServer) handleExecCommand(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// authorizeExec checks the requesting user's RBAC permissions for the command.
-// It writes the appropriate error response and returns false when the request
-// must be rejected. When no authorizer is configured it allows the request.
+// authorizeExec checks the requesting user's RBAC permissions for the command,
+// including every object of a manifest passed on stdin. It writes the
+// appropriate error response and returns false when the request must be
+// rejected. When no authorizer is configured it allows the request.
+// Commands that require approval are rejected: only one-shot exec can wait
+// for an approver.
 func (s *HTTPServer) authorizeExec(c *gin.Context, clusterName string, req ExecRequest) bool {
+	allowed, needsApproval := s.checkExec(c, clusterName, req)
+	if allowed && needsApproval {
+		s.recordExecAudit(c, clusterName, req, AuditStatusDenied, nil, nil, "command requires approval")
+		c.JSON(http.StatusForbidden, gin.H{"error": "command requires approval, which is not supported for streaming or interactive commands"})
+		return false
+	}
+	return allowed
+}
+
+// checkExec evaluates the command against the policy like authorizeExec, but
+// reports commands matched by a require_approval rule instead of rejecting
+// them. It writes the error response when the command is not allowed.
+// Commands overriding the server, TLS settings or credentials kubectl uses on
+// the agent are refused whether or not a policy is configured.
+func (s *HTTPServer) checkExec(c *gin.Context, clusterName string, req ExecRequest) (allowed, needsApproval bool) {
+	if flags := parseKubectlArgs(req.Command).connection; len(flags) > 0 {
+		msg := fmt.Sprintf("flag %s is not allowed: the agent's connection and credentials cannot be overridden", flags[0])
+		s.recordExecAudit(c, clusterName, req, AuditStatusDenied, nil, nil, msg)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false, false
+	}
 	if s.policy == nil {
-		return true
+		return true, false
 	}
 
 	claims := auth.GetUserFromContext(c)
 	if claims == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
-		return false
+		return false, false
 	}
 
-	access := parseAccessRequest(clusterName, req.Command, req.Namespace)
-	if !s.policy.Allows(claims.Email, access) {
-		log.Printf("RBAC denied: user=%s cluster=%s verb=%s resource=%s namespace=%s",
-			claims.Email, clusterName, access.Verb, access.Resource, access.Namespace)
+	accesses, err := execAccessRequests(clusterName, req)
+	if err != nil {
+		msg := fmt.Sprintf("invalid manifest: %v", err)
+		s.recordExecAudit(c, clusterName, req, AuditStatusDenied, nil, nil, msg)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false, false
+	}
+
+	subject := subjectFromClaims(claims)
+	for _, access := range accesses {
+		d := s.policy.Decide(subject, access)
+		if d.Allowed {
+			needsApproval = needsApproval || d.RequireApproval
+			continue
+		}
+		log.Printf("RBAC denied: user=%s cluster=%s verb=%s resource=%s names=%s namespace=%s",
+			claims.Email, clusterName, access.Verb, access.Resource, strings.Join(access.Names, ","), access.Namespace)
+		rbacDenialsTotal.WithLabelValues(clusterName).Inc()
 		s.recordExecAudit(c, clusterName, req, AuditStatusDenied, nil, nil, "permission denied")
 		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
-		return false
+		return false, false
 	}
-	return true
+	return true, needsApproval
 }
 
 // recordExecAudit writes an audit entry for an exec attempt, attributing it to
-// the authenticated user and client IP. A no-op when auditing is disabled.
-func (s *HTTPServer) recordExecAudit(c *gin.Context, cluster string, req ExecRequest, status string, exitCode *int32, durationMs *int64, errMsg string) {
+// the authenticated user and client IP, and returns it; nil when auditing is
+// disabled.
+// Commands that were let through while the user has break-glass access to the
+// cluster are recorded as AuditStatusBreakglass; the exit code and error
+// message still carry the outcome.
+func (s *HTTPServer) recordExecAudit(c *gin.Context, cluster string, req ExecRequest, status string, exitCode *int32, durationMs *int64, errMsg string) *AuditLog {
+	observeExec(cluster, status, durationMs)
 	if s.audit == nil {
-		return
+		return nil
 	}
+	claims := auth.GetUserFromContext(c)
+	if isExecOutcome(status) && claims != nil && s.policy != nil &&
+		s.policy.BreakglassActive(subjectFromClaims(claims), cluster) {
+		if status != AuditStatusSuccess && errMsg == "" {
+			errMsg = status
+		}
+		status = AuditStatusBreakglass
+	}
+	verb, resource := auditCommandScope(cluster, req)
 	entry := &AuditLog{
 		ClusterName:  cluster,
 		Command:      strings.Join(req.Command, " "),
+		Verb:         verb,
+		Resource:     resource,
 		Namespace:    req.Namespace,
 		Status:       status,
 		ExitCode:     exitCode,
@@ -321,11 +468,25 @@ func (s *HTTPServer) recordExecAudit(c *gin.Context, cluster string, req ExecReq
 		ErrorMessage: errMsg,
 		ClientIP:     c.ClientIP(),
 	}
-	if claims := auth.GetUserFromContext(c); claims != nil {
+	if claims != nil {
 		entry.UserID = claims.UserID
 		entry.UserEmail = claims.Email
 	}
 	s.audit.Record(entry)
+	return entry
+}
+
+// auditCommandScope returns the verb and the comma-separated resource types of
+// an exec command, as RBAC parses them, for filtering the audit log.
+func auditCommandScope(cluster string, req ExecRequest) (verb, resource string) {
+	var resources []string
+	for _, r := range parseAccessRequests(cluster, req.Command, req.Namespace) {
+		verb = r.Verb
+		if r.Resource != "" && !slices.Contains(resources, r.Resource) {
+			resources = append(resources, r.Resource)
+		}
+	}
+	return verb, strings.Join(resources, ",")
 }
 
 // recordExecResult audits a completed command, deriving success/failed from the
@@ -336,7 +497,18 @@ func (s *HTTPServer) recordExecResult(c *gin.Context, cluster string, req ExecRe
 		status = AuditStatusFailed
 	}
 	exitCode := result.ExitCode
-	s.recordExecAudit(c, cluster, req, status, &exitCode, &durationMs, result.ErrorMessage)
+	entry := s.recordExecAudit(c, cluster, req, status, &exitCode, &durationMs, result.ErrorMessage)
+	if s.output != nil && entry != nil && entry.ID != "" {
+		if o := s.output.Capture(entry.ID, result.Stdout, result.Stderr, readsSecrets(entry.Resource)); o != nil {
+			s.audit.SaveOutput(o)
+		}
+	}
+}
+
+// SetOutputCapture stores the (redacted, truncated) output of one-shot
+// commands with their audit entries.
+func (s *HTTPServer) SetOutputCapture(c *OutputCapture) {
+	s.output = c
 }
 
 // handleStreamCommand streams a long-running kubectl command (logs -f, get -w)
@@ -366,7 +538,7 @@ func (s *HTTPServer) handleStreamCommand(c *gin.Context) {
 		return
 	}
 
-	sess, err := s.sessions.Start(agent.ID, req.Command, req.Namespace)
+	sess, err := s.sessions.Start(agent.ID, requestIdentity(c), req.Command, req.Namespace)
 	if err != nil {
 		if err == ErrTooManyStreams {
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many concurrent streams"})
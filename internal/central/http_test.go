@@ -2,11 +2,13 @@ package central
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -193,6 +195,100 @@ func TestHTTPServer_ExecCommand_InvalidRequest(t *testing.T) {
 	}
 }
 
+func TestHTTPServer_ExecCommand_CanceledByClient(t *testing.T) {
+	store := NewAgentStore()
+	cmdQueue := NewCommandQueue()
+	pusher := &fakePusher{}
+	cmdQueue.SetPusher(pusher)
+	auditStore := newTestStore(t)
+	srv := NewHTTPServer(store, cmdQueue, nil, nil, nil, NewAuditRecorder(auditStore), nil, nil)
+	store.Register(&AgentInfo{ID: "agent-1", ClusterName: "test-cluster"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	body, _ := json.Marshal(ExecRequest{Command: []string{"get", "pods", "-A"}})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/clusters/test-cluster/exec", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		srv.Handler().ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	// Once the command reaches the agent, the client hangs up.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pusher.mu.Lock()
+		pushed := len(pusher.pushed)
+		pusher.mu.Unlock()
+		if pushed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("command was not pushed to the agent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if rec.Code != 499 {
+		t.Errorf("expected status 499, got %d", rec.Code)
+	}
+	pusher.mu.Lock()
+	if len(pusher.canceled) != 1 || pusher.canceled[0] != pusher.pushed[0].RequestId {
+		t.Errorf("expected the agent told to cancel %s, got %v", pusher.pushed[0].RequestId, pusher.canceled)
+	}
+	pusher.mu.Unlock()
+	logs, _, err := auditStore.ListAuditLogs(context.Background(), AuditLogFilter{})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Status != AuditStatusCanceled {
+		t.Errorf("expected one %q audit entry, got %+v", AuditStatusCanceled, logs)
+	}
+}
+
+func TestHTTPServer_ExecCommand_ConnectionFlags(t *testing.T) {
+	srv, store, cmdQueue := newTestHTTPServer()
+	pusher := &fakePusher{}
+	cmdQueue.SetPusher(pusher)
+	store.Register(&AgentInfo{ID: "agent-1", ClusterName: "test-cluster"})
+
+	var commands [][]string
+	for _, flag := range []string{
+		"-s", "--server", "--certificate-authority", "--tls-server-name",
+		"--kubeconfig", "--kuberc", "--context", "--cluster", "--user", "--token",
+		"--username", "--password", "--client-certificate", "--client-key",
+		"--as", "--as-group", "--as-uid", "--insecure-skip-tls-verify",
+	} {
+		commands = append(commands,
+			[]string{"get", "pods", flag + "=https://attacker"},
+			[]string{"get", "pods", flag, "https://attacker"})
+	}
+	commands = append(commands,
+		[]string{"--insecure-skip-tls-verify", "get", "pods"},
+		[]string{"get", "pods", "-shttps://attacker"},
+		[]string{"get", "-As", "https://attacker", "pods"},
+		[]string{"get", "-Ashttps://attacker", "pods"},
+		[]string{"get", "pods", "-Aserver"})
+
+	for _, command := range commands {
+		body, _ := json.Marshal(ExecRequest{Command: command})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/clusters/test-cluster/exec", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d", command, http.StatusBadRequest, rec.Code)
+		}
+	}
+	if len(pusher.pushed) != 0 {
+		t.Errorf("expected no command pushed to the agent, got %d", len(pusher.pushed))
+	}
+}
+
 func TestHTTPServer_NotFound(t *testing.T) {
 	srv, _, _ := newTestHTTPServer()
 	req := httptest.NewRequest(http.MethodGet, "/nonexistent", nil)
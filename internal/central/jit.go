@@ -0,0 +1,267 @@
+package central
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// jitRefreshTimeout bounds a single reload of the active JIT grants.
+const jitRefreshTimeout = 5 * time.Second
+
+var (
+	// ErrInvalidJITRequest wraps validation errors of a new JIT request.
+	ErrInvalidJITRequest = errors.New("invalid access request")
+	// ErrJITNotFound is returned for an unknown request ID.
+	ErrJITNotFound = errors.New("access request not found")
+	// ErrJITNotPending is returned when deciding a request that was already
+	// approved or denied.
+	ErrJITNotPending = errors.New("access request is no longer pending")
+	// ErrJITNotApprover is returned when the caller may not decide requests.
+	ErrJITNotApprover = errors.New("not an approver for access requests")
+	// ErrJITSelfApproval is returned when a requester tries to approve their
+	// own request.
+	ErrJITSelfApproval = errors.New("cannot approve your own access request")
+	// ErrBreakglassDisabled is returned when no break-glass role is configured.
+	ErrBreakglassDisabled = errors.New("break-glass access is not enabled")
+	// ErrBreakglassNotAllowed is returned when the caller is not one of the
+	// configured break-glass subjects.
+	ErrBreakglassNotAllowed = errors.New("not allowed to use break-glass access")
+)
+
+// breakglassDecider is recorded as DecidedBy on break-glass requests, which
+// are approved without an approver.
+const breakglassDecider = "breakglass"
+
+// JITManager handles just-in-time access requests: users request a policy
+// role on one cluster for a limited time, an approver approves or denies it,
+// and approved requests are layered onto the PolicyEngine until they expire.
+type JITManager struct {
+	store      Store
+	policy     *PolicyEngine
+	cfg        JITConfig
+	approvers  []PolicyBinding
+	breakglass []PolicyBinding
+	notifier   Notifier
+}
+
+// NewJITManager creates a JITManager that grants roles through policy.
+func NewJITManager(store Store, policy *PolicyEngine, cfg JITConfig) *JITManager {
+	m := &JITManager{store: store, policy: policy, cfg: cfg}
+	for _, a := range cfg.Approvers {
+		m.approvers = append(m.approvers, PolicyBinding{Subject: a})
+	}
+	for _, s := range cfg.Breakglass.Subjects {
+		m.breakglass = append(m.breakglass, PolicyBinding{Subject: s})
+	}
+	if cfg.Breakglass.NotifyURL != "" {
+		m.notifier = NewWebhookNotifier(cfg.Breakglass.NotifyURL)
+	}
+	return m
+}
+
+// CanApprove reports whether subject may approve or deny requests. Admins
+// always can.
+func (m *JITManager) CanApprove(subject Subject, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+	for _, b := range m.approvers {
+		if b.matches(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// Request validates r and stores it as a pending request.
+func (m *JITManager) Request(ctx context.Context, r *JITRequest) error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if err := m.validate(r); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJITRequest, err)
+	}
+	r.Status = JITStatusPending
+	return m.store.CreateJITRequest(ctx, r)
+}
+
+func (m *JITManager) validate(r *JITRequest) error {
+	switch {
+	case r.ClusterName == "":
+		return fmt.Errorf("cluster is required")
+	case r.Role == "":
+		return fmt.Errorf("role is required")
+	case r.Reason == "":
+		return fmt.Errorf("reason is required")
+	case r.DurationSec <= 0:
+		return fmt.Errorf("duration must be positive")
+	case m.cfg.MaxDuration > 0 && r.Duration() > m.cfg.MaxDuration:
+		return fmt.Errorf("duration %s exceeds the maximum of %s", r.Duration(), m.cfg.MaxDuration)
+	case !m.policy.HasRole(r.Role):
+		return fmt.Errorf("role %q is not defined in the policy", r.Role)
+	case len(m.cfg.Roles) > 0 && !slices.Contains(m.cfg.Roles, r.Role):
+		return fmt.Errorf("role %q cannot be requested (requestable: %s)", r.Role, strings.Join(m.cfg.Roles, ", "))
+	}
+	return nil
+}
+
+// Approve approves a pending request on behalf of approver and activates the
+// grant immediately. The grant window starts now.
+func (m *JITManager) Approve(ctx context.Context, id string, approver Subject, isAdmin bool) (*JITRequest, error) {
+	return m.decide(ctx, id, approver, isAdmin, JITStatusApproved)
+}
+
+// Deny denies a pending request on behalf of approver.
+func (m *JITManager) Deny(ctx context.Context, id string, approver Subject, isAdmin bool) (*JITRequest, error) {
+	return m.decide(ctx, id, approver, isAdmin, JITStatusDenied)
+}
+
+func (m *JITManager) decide(ctx context.Context, id string, approver Subject, isAdmin bool, status string) (*JITRequest, error) {
+	if !m.CanApprove(approver, isAdmin) {
+		return nil, ErrJITNotApprover
+	}
+	r, err := m.store.GetJITRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrJITNotFound
+	}
+	if r.Status != JITStatusPending {
+		return nil, ErrJITNotPending
+	}
+	if status == JITStatusApproved && strings.EqualFold(r.UserEmail, approver.Email) {
+		return nil, ErrJITSelfApproval
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	r.Status = status
+	r.DecidedBy = approver.Email
+	r.DecidedAt = &now
+	if status == JITStatusApproved {
+		expires := now.Add(r.Duration())
+		r.ExpiresAt = &expires
+	}
+	ok, err := m.store.DecideJITRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJITNotPending
+	}
+	if status == JITStatusApproved {
+		if err := m.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Breakglass grants the configured break-glass role to the requester of r on
+// r.ClusterName immediately, without approval, and fires the notification
+// hook. r needs the requester, cluster and reason; the rest is filled in.
+func (m *JITManager) Breakglass(ctx context.Context, r *JITRequest, subject Subject) error {
+	bg := m.cfg.Breakglass
+	if bg.Role == "" {
+		return ErrBreakglassDisabled
+	}
+	if !slices.ContainsFunc(m.breakglass, func(b PolicyBinding) bool { return b.matches(subject) }) {
+		return ErrBreakglassNotAllowed
+	}
+	r.Reason = strings.TrimSpace(r.Reason)
+	switch {
+	case r.ClusterName == "":
+		return fmt.Errorf("%w: cluster is required", ErrInvalidJITRequest)
+	case r.Reason == "":
+		return fmt.Errorf("%w: reason is required", ErrInvalidJITRequest)
+	case !m.policy.HasRole(bg.Role):
+		return fmt.Errorf("break-glass role %q is not defined in the policy", bg.Role)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expires := now.Add(bg.Duration)
+	r.Role = bg.Role
+	r.DurationSec = int64(bg.Duration / time.Second)
+	r.Status = JITStatusApproved
+	r.Breakglass = true
+	r.DecidedBy = breakglassDecider
+	r.DecidedAt = &now
+	r.ExpiresAt = &expires
+	if err := m.store.CreateJITRequest(ctx, r); err != nil {
+		return err
+	}
+	if err := m.Refresh(ctx); err != nil {
+		return err
+	}
+	if m.notifier != nil {
+		go m.notify(BreakglassEvent{
+			Event:     "breakglass",
+			ID:        r.ID,
+			User:      r.UserEmail,
+			Cluster:   r.ClusterName,
+			Role:      r.Role,
+			Reason:    r.Reason,
+			ExpiresAt: expires,
+		})
+	}
+	return nil
+}
+
+// notify delivers a break-glass event. Delivery failures are logged and never
+// revoke the grant.
+func (m *JITManager) notify(event BreakglassEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := m.notifier.Notify(ctx, event); err != nil {
+		log.Printf("breakglass: notification for %s failed: %v", event.ID, err)
+	}
+}
+
+// Get returns a request, or ErrJITNotFound.
+func (m *JITManager) Get(ctx context.Context, id string) (*JITRequest, error) {
+	r, err := m.store.GetJITRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrJITNotFound
+	}
+	return r, nil
+}
+
+// List returns requests matching filter, newest first.
+func (m *JITManager) List(ctx context.Context, filter JITRequestFilter) ([]*JITRequest, error) {
+	return m.store.ListJITRequests(ctx, filter)
+}
+
+// Refresh reloads the approved, unexpired requests into the policy engine.
+func (m *JITManager) Refresh(ctx context.Context) error {
+	active, err := m.store.ListActiveJITRequests(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("loading active jit grants: %w", err)
+	}
+	m.policy.SetTemporaryGrants(active)
+	return nil
+}
+
+// Run refreshes the active grants every interval until stop is closed, so
+// approvals made through another replica take effect here too.
+func (m *JITManager) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), jitRefreshTimeout)
+			if err := m.Refresh(ctx); err != nil {
+				log.Printf("jit: %v", err)
+			}
+			cancel()
+		case <-stop:
+			return
+		}
+	}
+}
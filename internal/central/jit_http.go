@@ -0,0 +1,232 @@
+package central
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+// createJITRequest is the body of POST /api/v1/access/requests. Duration is a
+// Go duration string such as "2h" or "30m".
+type createJITRequest struct {
+	Cluster  string `json:"cluster" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+	Duration string `json:"duration" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// breakglassRequest is the body of POST /api/v1/breakglass.
+type breakglassRequest struct {
+	Cluster string `json:"cluster" binding:"required"`
+	Reason  string `json:"reason" binding:"required"`
+}
+
+// SetJITManager enables the just-in-time access request endpoints.
+func (s *HTTPServer) SetJITManager(m *JITManager) {
+	s.jit = m
+}
+
+// jitEnabled rejects the request with 404 when JIT access is not configured.
+func (s *HTTPServer) jitEnabled(c *gin.Context) bool {
+	if s.jit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "access requests are not enabled"})
+		return false
+	}
+	return true
+}
+
+// handleCreateJITRequest files a pending request for a role on a cluster.
+func (s *HTTPServer) handleCreateJITRequest(c *gin.Context) {
+	if !s.jitEnabled(c) {
+		return
+	}
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req createJITRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cluster, role, duration and reason are required"})
+		return
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid duration %q", req.Duration)})
+		return
+	}
+
+	r := &JITRequest{
+		UserID:      claims.UserID,
+		UserEmail:   claims.Email,
+		ClusterName: req.Cluster,
+		Role:        req.Role,
+		Reason:      req.Reason,
+		DurationSec: int64(d / time.Second),
+	}
+	if err := s.jit.Request(c.Request.Context(), r); err != nil {
+		s.writeJITError(c, err)
+		return
+	}
+	s.recordJITAudit(c, r, AuditStatusJITRequested,
+		fmt.Sprintf("access request %s: role %s for %s (%s)", r.ID, r.Role, r.Duration(), r.Reason))
+	c.JSON(http.StatusCreated, r)
+}
+
+// handleListJITRequests lists requests, newest first. Approvers see every
+// request unless mine=true; other users only see their own.
+func (s *HTTPServer) handleListJITRequests(c *gin.Context) {
+	if !s.jitEnabled(c) {
+		return
+	}
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	filter := JITRequestFilter{Status: c.Query("status"), Limit: 200}
+	if c.Query("mine") == "true" || !s.jit.CanApprove(subjectFromClaims(claims), claims.IsAdmin) {
+		filter.UserEmail = claims.Email
+	}
+	reqs, err := s.jit.List(c.Request.Context(), filter)
+	if err != nil {
+		s.writeJITError(c, err)
+		return
+	}
+	if reqs == nil {
+		reqs = []*JITRequest{}
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": reqs})
+}
+
+// handleGetJITRequest returns one request to its requester or an approver.
+func (s *HTTPServer) handleGetJITRequest(c *gin.Context) {
+	if !s.jitEnabled(c) {
+		return
+	}
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	r, err := s.jit.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		s.writeJITError(c, err)
+		return
+	}
+	if !strings.EqualFold(r.UserEmail, claims.Email) && !s.jit.CanApprove(subjectFromClaims(claims), claims.IsAdmin) {
+		s.writeJITError(c, ErrJITNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// handleApproveJITRequest approves a pending request, activating the grant.
+func (s *HTTPServer) handleApproveJITRequest(c *gin.Context) {
+	s.decideJITRequest(c, true)
+}
+
+// handleDenyJITRequest denies a pending request.
+func (s *HTTPServer) handleDenyJITRequest(c *gin.Context) {
+	s.decideJITRequest(c, false)
+}
+
+func (s *HTTPServer) decideJITRequest(c *gin.Context, approve bool) {
+	if !s.jitEnabled(c) {
+		return
+	}
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	decide, status, verb := s.jit.Deny, AuditStatusJITDenied, "denied"
+	if approve {
+		decide, status, verb = s.jit.Approve, AuditStatusJITApproved, "approved"
+	}
+	r, err := decide(c.Request.Context(), c.Param("id"), subjectFromClaims(claims), claims.IsAdmin)
+	if err != nil {
+		s.writeJITError(c, err)
+		return
+	}
+	msg := fmt.Sprintf("access request %s %s: role %s for %s", r.ID, verb, r.Role, r.UserEmail)
+	if r.ExpiresAt != nil {
+		msg += " until " + r.ExpiresAt.Format(time.RFC3339)
+	}
+	s.recordJITAudit(c, r, status, msg)
+	c.JSON(http.StatusOK, r)
+}
+
+// handleBreakglass grants the caller the break-glass role on a cluster for the
+// configured window, without approval.
+func (s *HTTPServer) handleBreakglass(c *gin.Context) {
+	if !s.jitEnabled(c) {
+		return
+	}
+	claims := auth.GetUserFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req breakglassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cluster and reason are required"})
+		return
+	}
+	r := &JITRequest{
+		UserID:      claims.UserID,
+		UserEmail:   claims.Email,
+		ClusterName: req.Cluster,
+		Reason:      req.Reason,
+	}
+	if err := s.jit.Breakglass(c.Request.Context(), r, subjectFromClaims(claims)); err != nil {
+		s.writeJITError(c, err)
+		return
+	}
+	s.recordJITAudit(c, r, AuditStatusBreakglass,
+		fmt.Sprintf("break-glass %s: role %s until %s (%s)", r.ID, r.Role, r.ExpiresAt.Format(time.RFC3339), r.Reason))
+	c.JSON(http.StatusCreated, r)
+}
+
+// writeJITError maps JITManager errors to HTTP responses.
+func (s *HTTPServer) writeJITError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidJITRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrJITNotFound), errors.Is(err, ErrBreakglassDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrJITNotApprover), errors.Is(err, ErrJITSelfApproval), errors.Is(err, ErrBreakglassNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrJITNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("jit: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
+
+// recordJITAudit writes an audit entry for a JIT request event, attributed to
+// the caller (the requester or the approver).
+func (s *HTTPServer) recordJITAudit(c *gin.Context, r *JITRequest, status, command string) {
+	log.Printf("jit: %s", command)
+	if s.audit == nil {
+		return
+	}
+	entry := &AuditLog{
+		ClusterName: r.ClusterName,
+		Command:     command,
+		Status:      status,
+		ClientIP:    c.ClientIP(),
+	}
+	if claims := auth.GetUserFromContext(c); claims != nil {
+		entry.UserID = claims.UserID
+		entry.UserEmail = claims.Email
+	}
+	s.audit.Record(entry)
+}
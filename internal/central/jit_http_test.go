@@ -0,0 +1,210 @@
+package central
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+func TestJITRequestAPI(t *testing.T) {
+	store := newTestStore(t)
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	eng := &PolicyEngine{}
+	eng.current.Store(mustParse(t, jitTestPolicy))
+	agents := NewAgentStore()
+	agents.Register(&AgentInfo{ID: "a1", ClusterName: "prod"})
+	srv := NewHTTPServer(agents, NewCommandQueue(), NewAuthHandlers(store, jm, time.Hour),
+		NewAdminHandlers(store, testPepper), eng, NewAuditRecorder(store), nil, jm)
+	srv.SetJITManager(NewJITManager(store, eng, JITConfig{MaxDuration: 8 * time.Hour, Approvers: []string{"group:sre-leads"}}))
+
+	// Audit entries reference users, so the actors must exist.
+	token := func(email string, groups ...string) string {
+		u := &User{Email: email, Name: email, PasswordHash: "x", IsActive: true}
+		if err := store.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+		tok, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: u.ID, Email: email, Groups: groups})
+		return tok
+	}
+	dev := token("dev@x.com")
+	lead := token("lead@x.com", "sre-leads")
+	other := token("other@x.com")
+
+	var id string
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"request", func(t *testing.T) {
+			w := authzRequest(t, srv, dev, "POST", "/api/v1/access/requests",
+				createJITRequest{Cluster: "prod", Role: "operator", Duration: "2h", Reason: "INC-123"})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("code = %d: %s", w.Code, w.Body.String())
+			}
+			var r JITRequest
+			json.Unmarshal(w.Body.Bytes(), &r)
+			if r.Status != JITStatusPending || r.DurationSec != 7200 || r.UserEmail != "dev@x.com" {
+				t.Errorf("request = %+v", r)
+			}
+			id = r.ID
+		}},
+		{"invalid duration", func(t *testing.T) {
+			w := authzRequest(t, srv, dev, "POST", "/api/v1/access/requests",
+				createJITRequest{Cluster: "prod", Role: "operator", Duration: "forever", Reason: "x"})
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("code = %d, want 400", w.Code)
+			}
+		}},
+		{"non-approvers only see their own requests", func(t *testing.T) {
+			w := authzRequest(t, srv, other, "GET", "/api/v1/access/requests", nil)
+			var list struct{ Requests []JITRequest }
+			json.Unmarshal(w.Body.Bytes(), &list)
+			if len(list.Requests) != 0 {
+				t.Errorf("other sees %d requests, want 0", len(list.Requests))
+			}
+			if w := authzRequest(t, srv, other, "GET", "/api/v1/access/requests/"+id, nil); w.Code != http.StatusNotFound {
+				t.Errorf("get other's request code = %d, want 404", w.Code)
+			}
+			w = authzRequest(t, srv, lead, "GET", "/api/v1/access/requests?status=pending", nil)
+			json.Unmarshal(w.Body.Bytes(), &list)
+			if len(list.Requests) != 1 {
+				t.Errorf("approver sees %d requests, want 1", len(list.Requests))
+			}
+		}},
+		{"non-approver cannot approve", func(t *testing.T) {
+			w := authzRequest(t, srv, other, "POST", "/api/v1/access/requests/"+id+"/approve", nil)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("code = %d, want 403", w.Code)
+			}
+		}},
+		{"approve grants exec access and is audited", func(t *testing.T) {
+			if w := execRequest(t, srv, dev, []string{"delete", "pod", "web"}); w.Code != http.StatusForbidden {
+				t.Fatalf("before approval code = %d, want 403", w.Code)
+			}
+			w := authzRequest(t, srv, lead, "POST", "/api/v1/access/requests/"+id+"/approve", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("approve code = %d: %s", w.Code, w.Body.String())
+			}
+			w = authzRequest(t, srv, dev, "POST", "/api/v1/authz/check",
+				authzCheckRequest{Cluster: "prod", Verb: "delete", Resource: "pods"})
+			var check authzCheckResponse
+			json.Unmarshal(w.Body.Bytes(), &check)
+			if !check.Allowed || check.Checks[0].Role != "operator" {
+				t.Errorf("check after approval = %+v", check)
+			}
+
+			for _, status := range []string{AuditStatusJITRequested, AuditStatusJITApproved} {
+				logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{Status: status})
+				if len(logs) != 1 || logs[0].ClusterName != "prod" {
+					t.Errorf("%s audit = %+v", status, logs)
+				}
+			}
+		}},
+		{"second decision conflicts", func(t *testing.T) {
+			w := authzRequest(t, srv, lead, "POST", "/api/v1/access/requests/"+id+"/deny", nil)
+			if w.Code != http.StatusConflict {
+				t.Errorf("code = %d, want 409", w.Code)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
+
+func TestBreakglassAPI(t *testing.T) {
+	store := newTestStore(t)
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	eng := &PolicyEngine{}
+	eng.current.Store(mustParse(t, jitTestPolicy))
+	agents := NewAgentStore()
+	agents.Register(&AgentInfo{ID: "a1", ClusterName: "prod"})
+	queue := NewCommandQueue()
+	srv := NewHTTPServer(agents, queue, NewAuthHandlers(store, jm, time.Hour),
+		NewAdminHandlers(store, testPepper), eng, NewAuditRecorder(store), nil, jm)
+	srv.SetJITManager(NewJITManager(store, eng, JITConfig{
+		Breakglass: BreakglassConfig{Role: "operator", Duration: time.Hour, Subjects: []string{"group:oncall"}},
+	}))
+
+	token := func(email string, groups ...string) string {
+		u := &User{Email: email, Name: email, PasswordHash: "x", IsActive: true}
+		if err := store.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+		tok, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: u.ID, Email: email, Groups: groups})
+		return tok
+	}
+	oncall := token("oncall@x.com", "oncall")
+	dev := token("dev@x.com")
+
+	// Stand in for the agent: complete every queued command successfully.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			for _, cmd := range queue.GetPendingForAgent("a1") {
+				queue.Complete(cmd.RequestID, &CommandResult{RequestID: cmd.RequestID, Stdout: []byte("ok\n"), Completed: true})
+			}
+		}
+	}()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"not a break-glass subject", func(t *testing.T) {
+			w := authzRequest(t, srv, dev, "POST", "/api/v1/breakglass", breakglassRequest{Cluster: "prod", Reason: "x"})
+			if w.Code != http.StatusForbidden {
+				t.Errorf("code = %d, want 403", w.Code)
+			}
+		}},
+		{"activation grants access and is audited", func(t *testing.T) {
+			if w := execRequest(t, srv, oncall, []string{"delete", "pod", "web"}); w.Code != http.StatusForbidden {
+				t.Fatalf("before break-glass code = %d, want 403", w.Code)
+			}
+			w := authzRequest(t, srv, oncall, "POST", "/api/v1/breakglass", breakglassRequest{Cluster: "prod", Reason: "INC-9"})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("code = %d: %s", w.Code, w.Body.String())
+			}
+			var r JITRequest
+			json.Unmarshal(w.Body.Bytes(), &r)
+			if !r.Breakglass || r.Role != "operator" || r.Status != JITStatusApproved {
+				t.Errorf("request = %+v", r)
+			}
+			if w := execRequest(t, srv, oncall, []string{"delete", "pod", "web"}); w.Code != http.StatusOK {
+				t.Fatalf("exec code = %d: %s", w.Code, w.Body.String())
+			}
+
+			logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{Status: AuditStatusBreakglass})
+			var activated, command bool
+			for _, l := range logs {
+				activated = activated || l.ExitCode == nil
+				command = command || l.Command == "delete pod web"
+			}
+			if len(logs) != 2 || !activated || !command {
+				t.Errorf("breakglass audit = %+v", logs)
+			}
+		}},
+		{"other users' commands are unaffected", func(t *testing.T) {
+			if w := execRequest(t, srv, dev, []string{"get", "pods"}); w.Code != http.StatusOK {
+				t.Fatalf("exec code = %d", w.Code)
+			}
+			logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{UserEmail: "dev@x.com"})
+			if len(logs) != 1 || logs[0].Status != AuditStatusSuccess {
+				t.Errorf("dev audit = %+v", logs)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
@@ -0,0 +1,217 @@
+package central
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const jitTestPolicy = `
+default: viewer
+roles:
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+  - name: operator
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+  - name: admin
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+`
+
+func newTestJITManager(t *testing.T, cfg JITConfig) (*JITManager, *PolicyEngine) {
+	t.Helper()
+	eng := &PolicyEngine{}
+	eng.current.Store(mustParse(t, jitTestPolicy))
+	return NewJITManager(newTestStore(t), eng, cfg), eng
+}
+
+func TestJITManager(t *testing.T) {
+	ctx := context.Background()
+	dev := Subject{Email: "dev@x.com"}
+	lead := Subject{Email: "lead@x.com", Groups: []string{"sre-leads"}}
+	cfg := JITConfig{MaxDuration: 4 * time.Hour, Approvers: []string{"group:sre-leads"}, Roles: []string{"operator"}}
+
+	request := func(t *testing.T, m *JITManager, cluster string) *JITRequest {
+		t.Helper()
+		r := &JITRequest{UserEmail: dev.Email, ClusterName: cluster, Role: "operator", Reason: "INC-123", DurationSec: 7200}
+		if err := m.Request(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"approval grants the role on that cluster only", func(t *testing.T) {
+			m, eng := newTestJITManager(t, cfg)
+			r := request(t, m, "prod")
+			if eng.Allows(dev, access("prod", "app", "pods", "delete")) {
+				t.Fatal("pending request must not grant access")
+			}
+			approved, err := m.Approve(ctx, r.ID, lead, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if approved.ExpiresAt == nil || approved.ExpiresAt.Sub(*approved.DecidedAt) != 2*time.Hour {
+				t.Errorf("expires = %v, want decided + 2h", approved.ExpiresAt)
+			}
+			if !eng.Allows(dev, access("prod", "app", "pods", "delete")) {
+				t.Error("approved request should allow delete on prod")
+			}
+			if eng.Allows(dev, access("staging", "app", "pods", "delete")) {
+				t.Error("grant must not apply to other clusters")
+			}
+			grants := eng.Grants(dev)
+			if last := grants[len(grants)-1]; last.Role != "operator" || last.Source != "jit:"+r.ID || last.Cluster != "prod" {
+				t.Errorf("grants = %+v", grants)
+			}
+		}},
+		{"expired grant no longer applies", func(t *testing.T) {
+			_, eng := newTestJITManager(t, cfg)
+			past := time.Now().Add(-time.Minute)
+			eng.SetTemporaryGrants([]*JITRequest{{ID: "r1", UserEmail: dev.Email, ClusterName: "prod",
+				Role: "operator", Status: JITStatusApproved, ExpiresAt: &past}})
+			if eng.Allows(dev, access("prod", "app", "pods", "delete")) {
+				t.Error("expired grant should not allow")
+			}
+		}},
+		{"validation", func(t *testing.T) {
+			m, _ := newTestJITManager(t, cfg)
+			bad := []*JITRequest{
+				{ClusterName: "prod", Role: "operator", DurationSec: 60},                    // no reason
+				{ClusterName: "prod", Role: "operator", Reason: "x", DurationSec: 5 * 3600}, // too long
+				{ClusterName: "prod", Role: "missing", Reason: "x", DurationSec: 60},        // unknown role
+				{ClusterName: "prod", Role: "admin", Reason: "x", DurationSec: 60},          // not requestable
+				{ClusterName: "", Role: "operator", Reason: "x", DurationSec: 60},           // no cluster
+				{ClusterName: "prod", Role: "operator", Reason: "x", DurationSec: 0},        // no duration
+			}
+			for i, r := range bad {
+				r.UserEmail = dev.Email
+				if err := m.Request(ctx, r); !errors.Is(err, ErrInvalidJITRequest) {
+					t.Errorf("case %d: err = %v, want ErrInvalidJITRequest", i, err)
+				}
+			}
+		}},
+		{"only approvers decide, never their own request", func(t *testing.T) {
+			m, _ := newTestJITManager(t, cfg)
+			r := request(t, m, "prod")
+			if _, err := m.Approve(ctx, r.ID, dev, false); !errors.Is(err, ErrJITNotApprover) {
+				t.Errorf("err = %v, want ErrJITNotApprover", err)
+			}
+			if _, err := m.Approve(ctx, r.ID, dev, true); !errors.Is(err, ErrJITSelfApproval) {
+				t.Errorf("err = %v, want ErrJITSelfApproval", err)
+			}
+			if _, err := m.Deny(ctx, r.ID, lead, false); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Approve(ctx, r.ID, lead, false); !errors.Is(err, ErrJITNotPending) {
+				t.Errorf("err = %v, want ErrJITNotPending", err)
+			}
+			if _, err := m.Approve(ctx, "missing", lead, false); !errors.Is(err, ErrJITNotFound) {
+				t.Errorf("err = %v, want ErrJITNotFound", err)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
+
+func TestJITManager_Breakglass(t *testing.T) {
+	ctx := context.Background()
+	oncall := Subject{Email: "oncall@x.com", Groups: []string{"oncall"}}
+	cfg := JITConfig{Breakglass: BreakglassConfig{Role: "admin", Duration: 30 * time.Minute, Subjects: []string{"group:oncall"}}}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"grants the role immediately and marks commands", func(t *testing.T) {
+			m, eng := newTestJITManager(t, cfg)
+			r := &JITRequest{UserEmail: oncall.Email, ClusterName: "prod", Reason: "INC-9"}
+			if err := m.Breakglass(ctx, r, oncall); err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != JITStatusApproved || !r.Breakglass || r.Role != "admin" || r.ExpiresAt.Sub(*r.DecidedAt) != 30*time.Minute {
+				t.Errorf("request = %+v", r)
+			}
+			if !eng.Allows(oncall, access("prod", "app", "pods", "delete")) {
+				t.Error("break-glass should allow delete on prod")
+			}
+			if !eng.BreakglassActive(oncall, "prod") || eng.BreakglassActive(oncall, "staging") {
+				t.Error("break-glass should be active on prod only")
+			}
+			grants := eng.Grants(oncall)
+			if last := grants[len(grants)-1]; last.Source != "breakglass:"+r.ID {
+				t.Errorf("grants = %+v", grants)
+			}
+		}},
+		{"only configured subjects", func(t *testing.T) {
+			m, _ := newTestJITManager(t, cfg)
+			dev := Subject{Email: "dev@x.com"}
+			r := &JITRequest{UserEmail: dev.Email, ClusterName: "prod", Reason: "x"}
+			if err := m.Breakglass(ctx, r, dev); !errors.Is(err, ErrBreakglassNotAllowed) {
+				t.Errorf("err = %v, want ErrBreakglassNotAllowed", err)
+			}
+		}},
+		{"disabled without a role", func(t *testing.T) {
+			m, _ := newTestJITManager(t, JITConfig{})
+			r := &JITRequest{UserEmail: oncall.Email, ClusterName: "prod", Reason: "x"}
+			if err := m.Breakglass(ctx, r, oncall); !errors.Is(err, ErrBreakglassDisabled) {
+				t.Errorf("err = %v, want ErrBreakglassDisabled", err)
+			}
+		}},
+		{"reason is required", func(t *testing.T) {
+			m, _ := newTestJITManager(t, cfg)
+			r := &JITRequest{UserEmail: oncall.Email, ClusterName: "prod", Reason: "  "}
+			if err := m.Breakglass(ctx, r, oncall); !errors.Is(err, ErrInvalidJITRequest) {
+				t.Errorf("err = %v, want ErrInvalidJITRequest", err)
+			}
+		}},
+		{"fires the notification hook", func(t *testing.T) {
+			events := make(chan BreakglassEvent, 1)
+			hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ev BreakglassEvent
+				json.NewDecoder(r.Body).Decode(&ev)
+				events <- ev
+			}))
+			defer hook.Close()
+
+			withHook := cfg
+			withHook.Breakglass.NotifyURL = hook.URL
+			m, _ := newTestJITManager(t, withHook)
+			r := &JITRequest{UserEmail: oncall.Email, ClusterName: "prod", Reason: "INC-9"}
+			if err := m.Breakglass(ctx, r, oncall); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case ev := <-events:
+				if ev.Event != "breakglass" || ev.ID != r.ID || ev.User != oncall.Email || ev.Cluster != "prod" || ev.Reason != "INC-9" {
+					t.Errorf("event = %+v", ev)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("notification not delivered")
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
@@ -0,0 +1,502 @@
+package central
+
+import (
+	"strings"
+)
+
+// anyResource is the resource recorded when the objects a command touches
+// cannot be determined from its arguments (manifests passed with -f/-k, or
+// --raw API paths). Only rules whose resources include "*" allow it, and any
+// deny rule for the verb matches it.
+const anyResource = "*"
+
+// globalValueFlags are kubectl flags, valid before or after any command, whose
+// value is passed as the next argument.
+var globalValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--cluster": true,
+	"--user": true, "-s": true, "--server": true, "--kubeconfig": true,
+	"--token": true, "--as": true, "--as-group": true, "--as-uid": true,
+	"--request-timeout": true, "--cache-dir": true, "--certificate-authority": true,
+	"--client-certificate": true, "--client-key": true, "--tls-server-name": true,
+	"--username": true, "--password": true, "-v": true, "--v": true,
+	"--vmodule": true, "--log-dir": true, "--log-file": true, "--profile": true,
+	"--profile-output": true, "--kuberc": true,
+}
+
+// connectionFlags choose the API server kubectl talks to, how it verifies it,
+// or the credentials and identity it uses. The agent runs kubectl with its own
+// ServiceAccount, so any of these would send that token elsewhere or step
+// outside the identity central checked; commands carrying them are refused.
+var connectionFlags = map[string]bool{
+	"-s": true, "--server": true, "--insecure-skip-tls-verify": true,
+	"--certificate-authority": true, "--tls-server-name": true,
+	"--kubeconfig": true, "--kuberc": true, "--context": true, "--cluster": true,
+	"--user": true, "--token": true, "--username": true, "--password": true,
+	"--client-certificate": true, "--client-key": true,
+	"--as": true, "--as-group": true, "--as-uid": true,
+}
+
+// commandValueFlags are command-specific kubectl flags whose value is passed as
+// the next argument. Flags with an optional value (--dry-run, --cascade) only
+// accept it in the --flag=value form, so they are not listed.
+var commandValueFlags = map[string]bool{
+	"-o": true, "--output": true, "-l": true, "--selector": true,
+	"-c": true, "--container": true, "-f": true, "--filename": true,
+	"-k": true, "--kustomize": true, "-L": true, "--label-columns": true,
+	"-p": true, "--patch": true, "--patch-file": true, "-e": true, "--env": true,
+	"--field-selector": true, "--sort-by": true, "--template": true,
+	"--since": true, "--since-time": true, "--tail": true, "--limit-bytes": true,
+	"--max-log-requests": true, "--pod-running-timeout": true, "--timeout": true,
+	"--replicas": true, "--current-replicas": true, "--resource-version": true,
+	"--image": true, "--port": true, "--target-port": true, "--type": true,
+	"--protocol": true, "--name": true, "--external-ip": true, "--cluster-ip": true,
+	"--load-balancer-ip": true, "--session-affinity": true, "--labels": true,
+	"--overrides": true, "--restart": true, "--serviceaccount": true,
+	"--schedule": true, "--rule": true, "--verb": true, "--resource": true,
+	"--resource-name": true, "--role": true, "--clusterrole": true, "--group": true,
+	"--from": true, "--from-literal": true, "--from-file": true, "--from-env-file": true,
+	"--docker-server": true, "--docker-username": true, "--docker-password": true,
+	"--docker-email": true, "--cert": true, "--key": true, "--min": true,
+	"--max": true, "--cpu-percent": true, "--revision": true, "--to-revision": true,
+	"--for": true, "--grace-period": true, "--field-manager": true,
+	"--subresource": true, "--raw": true, "--chunk-size": true, "--containers": true,
+	"--requests": true, "--limits": true, "--address": true, "--prefix": true,
+	"--keys": true, "--duration": true, "--audience": true, "--copy-to": true,
+	"--target": true, "--image-pull-policy": true, "--prune-allowlist": true,
+}
+
+// boolFlagOverrides lists, per command, short flags that are booleans there
+// even though another command gives them a value (logs -f is --follow).
+var boolFlagOverrides = map[string]map[string]bool{
+	"logs": {"-f": true, "-p": true},
+}
+
+// subcommandVerbs are kubectl commands whose first argument is a subcommand
+// that forms part of the verb, e.g. "rollout restart". A nil set accepts any
+// subcommand; otherwise only the listed ones are joined to the verb.
+var subcommandVerbs = map[string]map[string]bool{
+	"rollout":      nil,
+	"set":          nil,
+	"config":       nil,
+	"auth":         nil,
+	"certificate":  nil,
+	"cluster-info": nil,
+	"apply":        {"set-last-applied": true, "view-last-applied": true, "edit-last-applied": true},
+}
+
+// noResourceCommands act on the agent's kubectl itself, or only describe the
+// API, rather than on cluster objects named by their arguments.
+var noResourceCommands = map[string]bool{
+	"config": true, "auth": true, "version": true, "api-resources": true,
+	"api-versions": true, "cluster-info": true, "completion": true,
+	"plugin": true, "options": true, "kustomize": true,
+}
+
+// podScopedVerbs are kubectl verbs that operate on pods implicitly rather than
+// naming a resource type as their first argument.
+var podScopedVerbs = map[string]bool{
+	"logs": true, "exec": true, "attach": true, "port-forward": true, "cp": true,
+	"debug": true,
+}
+
+// nodeScopedVerbs operate on the nodes named by their arguments.
+var nodeScopedVerbs = map[string]bool{"cordon": true, "uncordon": true, "drain": true}
+
+// keyValueCommands take key=value (or key-) arguments after the object names.
+var keyValueCommands = map[string]bool{"label": true, "annotate": true, "taint": true, "set": true}
+
+// createSubtypes maps "kubectl create <subtype>" generators to the resource
+// they create. Subtypes with a further variant ("secret generic") are listed
+// in createVariants.
+var createSubtypes = map[string]string{
+	"clusterrole": "clusterroles", "clusterrolebinding": "clusterrolebindings",
+	"configmap": "configmaps", "cm": "configmaps", "cronjob": "cronjobs", "cj": "cronjobs",
+	"deployment": "deployments", "deploy": "deployments", "ingress": "ingresses",
+	"ing": "ingresses", "job": "jobs", "namespace": "namespaces", "ns": "namespaces",
+	"poddisruptionbudget": "poddisruptionbudgets", "pdb": "poddisruptionbudgets",
+	"priorityclass": "priorityclasses", "pc": "priorityclasses",
+	"quota": "resourcequotas", "resourcequota": "resourcequotas",
+	"role": "roles", "rolebinding": "rolebindings", "secret": "secrets",
+	"service": "services", "svc": "services", "serviceaccount": "serviceaccounts",
+	"sa": "serviceaccounts", "token": "serviceaccounts/token",
+}
+
+var createVariants = map[string]bool{"secret": true, "service": true, "svc": true}
+
+// resourceAliases maps kubectl short names and singular forms to the plural
+// resource name used in policy rules.
+var resourceAliases = map[string]string{
+	"po": "pods", "pod": "pods",
+	"svc": "services", "service": "services",
+	"deploy": "deployments", "deployment": "deployments",
+	"rs": "replicasets", "replicaset": "replicasets",
+	"ds": "daemonsets", "daemonset": "daemonsets",
+	"sts": "statefulsets", "statefulset": "statefulsets",
+	"job": "jobs", "cj": "cronjobs", "cronjob": "cronjobs",
+	"cm": "configmaps", "configmap": "configmaps",
+	"secret": "secrets",
+	"ns":     "namespaces", "namespace": "namespaces",
+	"no": "nodes", "node": "nodes",
+	"pv": "persistentvolumes", "persistentvolume": "persistentvolumes",
+	"pvc": "persistentvolumeclaims", "persistentvolumeclaim": "persistentvolumeclaims",
+	"sa": "serviceaccounts", "serviceaccount": "serviceaccounts",
+	"ing": "ingresses", "ingress": "ingresses",
+	"ingressclass": "ingressclasses",
+	"netpol":       "networkpolicies", "networkpolicy": "networkpolicies",
+	"ev": "events", "event": "events",
+	"ep": "endpoints", "endpointslice": "endpointslices",
+	"hpa": "horizontalpodautoscalers", "horizontalpodautoscaler": "horizontalpodautoscalers",
+	"pdb": "poddisruptionbudgets", "poddisruptionbudget": "poddisruptionbudgets",
+	"quota": "resourcequotas", "resourcequota": "resourcequotas",
+	"limits": "limitranges", "limitrange": "limitranges",
+	"rc": "replicationcontrollers", "replicationcontroller": "replicationcontrollers",
+	"crd": "customresourcedefinitions", "crds": "customresourcedefinitions",
+	"customresourcedefinition": "customresourcedefinitions",
+	"sc":                       "storageclasses", "storageclass": "storageclasses",
+	"pc": "priorityclasses", "priorityclass": "priorityclasses",
+	"csr": "certificatesigningrequests", "certificatesigningrequest": "certificatesigningrequests",
+	"cs": "componentstatuses", "componentstatus": "componentstatuses",
+	"role": "roles", "rolebinding": "rolebindings",
+	"clusterrole": "clusterroles", "clusterrolebinding": "clusterrolebindings",
+	"lease": "leases",
+}
+
+// normalizeResource returns the canonical plural name for a resource type as
+// typed on the command line: "po", "pod" and "pods.v1." all become "pods".
+// Unknown types (e.g. custom resources) are lowercased, stripped of any API
+// group suffix and, unless they already look plural, pluralized the way
+// manifest kinds are: "certificate" becomes "certificates", so a rule
+// naming the plural matches either spelling.
+func normalizeResource(r string) string {
+	r = strings.ToLower(r)
+	if i := strings.IndexByte(r, '.'); i > 0 {
+		r = r[:i]
+	}
+	if full, ok := resourceAliases[r]; ok {
+		return full
+	}
+	if r == "" || r == anyResource {
+		return r
+	}
+	// Singular kinds may end in "ss" (ingress, gatewayclass) or "us"
+	// (prometheus); any other trailing "s" is taken as a plural.
+	if strings.HasSuffix(r, "s") && !strings.HasSuffix(r, "ss") && !strings.HasSuffix(r, "us") {
+		return r
+	}
+	return kindToResource(r)
+}
+
+// kubectlArgs is a kubectl command line split according to kubectl's grammar.
+type kubectlArgs struct {
+	command    string   // top-level command, e.g. "rollout"
+	verb       string   // command plus any subcommand, e.g. "rollout restart"
+	positional []string // non-flag arguments after the verb
+	namespace  string   // from -n/--namespace, "*" for -A; empty if unset
+	files      []string // -f/--filename and -k/--kustomize values
+	raw        bool     // --raw was given
+	connection []string // connectionFlags given, in order
+}
+
+// parseKubectlArgs splits command (the args after "kubectl"). Flags may appear
+// anywhere, including before the command; everything after "--" is the
+// payload of exec/run/debug and is ignored.
+func parseKubectlArgs(command []string) kubectlArgs {
+	var k kubectlArgs
+
+	// The command decides which short flags take a value, so find it first.
+	k.command = findCommand(command)
+
+	var positional []string
+	allNamespaces := false
+	for i := 0; i < len(command); i++ {
+		a := command[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			positional = append(positional, a)
+			continue
+		}
+		flags := splitFlags(a, k.command)
+		if last := &flags[len(flags)-1]; !last.hasValue && takesValue(last.name, k.command) && i+1 < len(command) {
+			i++
+			last.value, last.hasValue = command[i], true
+		}
+		for _, f := range flags {
+			switch f.name {
+			case "-n", "--namespace":
+				k.namespace = f.value
+			case "-A", "--all-namespaces":
+				allNamespaces = !f.hasValue || f.value != "false"
+			case "-f", "--filename", "-k", "--kustomize":
+				if f.hasValue {
+					k.files = append(k.files, f.value)
+				}
+			case "--raw":
+				k.raw = true
+			}
+			if connectionFlags[f.name] {
+				k.connection = append(k.connection, f.name)
+			}
+		}
+	}
+	// kubectl ignores -n when --all-namespaces is set, wherever it appears.
+	if allNamespaces {
+		k.namespace = "*"
+	}
+
+	if len(positional) == 0 {
+		return k
+	}
+	k.verb, positional = positional[0], positional[1:]
+	if subs, ok := subcommandVerbs[k.command]; ok && len(positional) > 0 {
+		if subs == nil || subs[positional[0]] {
+			k.verb += " " + positional[0]
+			positional = positional[1:]
+		}
+	}
+	k.positional = positional
+	return k
+}
+
+// findCommand returns the first non-flag argument of command, skipping the
+// values of any flags that precede it.
+func findCommand(command []string) string {
+	for i := 0; i < len(command); i++ {
+		a := command[i]
+		if a == "--" {
+			return ""
+		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			return a
+		}
+		flags := splitFlags(a, "")
+		if last := flags[len(flags)-1]; !last.hasValue && takesValue(last.name, "") {
+			i++
+		}
+	}
+	return ""
+}
+
+// flagArg is one flag set by a command-line argument.
+type flagArg struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// splitFlags splits a flag argument into the flags it sets and any inline
+// value: "--output=yaml", "-oyaml", "-n=app". A bundle of short flags ("-it",
+// "-An") sets each of them; the first that takes a value consumes the rest of
+// the argument and ends the list. The result is never empty.
+func splitFlags(a, cmd string) []flagArg {
+	if strings.HasPrefix(a, "--") {
+		name, value, hasValue := strings.Cut(a, "=")
+		return []flagArg{{name: name, value: value, hasValue: hasValue}}
+	}
+	var flags []flagArg
+	for j := 1; j < len(a); j++ {
+		short := "-" + a[j:j+1]
+		if takesValue(short, cmd) {
+			rest := strings.TrimPrefix(a[j+1:], "=")
+			return append(flags, flagArg{name: short, value: rest, hasValue: rest != ""})
+		}
+		flags = append(flags, flagArg{name: short})
+	}
+	if len(flags) == 0 {
+		flags = append(flags, flagArg{name: a})
+	}
+	return flags
+}
+
+// takesValue reports whether flag consumes the following argument under cmd.
+func takesValue(flag, cmd string) bool {
+	if boolFlagOverrides[cmd][flag] {
+		return false
+	}
+	return globalValueFlags[flag] || commandValueFlags[flag]
+}
+
+// parseAccessRequests derives every AccessRequest a kubectl command (the args
+// after "kubectl") needs; the command may run only if all are allowed.
+//
+// The verb is the command, joined with its subcommand for commands such as
+// rollout, set, config and auth. The namespace comes from -n/--namespace, "*"
+// for --all-namespaces/-A, else fallbackNamespace, else "default". Resource
+// types are normalized (po, pod → pods) and a comma-separated list yields one
+// request per type. Objects come from "type/name" arguments, grouped by type,
+// or from the arguments following a bare type. Commands reading manifests
+// with -f/-k, or raw API paths, yield a single request for any resource.
+func parseAccessRequests(cluster string, command []string, fallbackNamespace string) []AccessRequest {
+	k := parseKubectlArgs(command)
+
+	base := AccessRequest{Cluster: cluster, Namespace: k.namespace, Verb: k.verb}
+	if base.Namespace == "" {
+		base.Namespace = fallbackNamespace
+	}
+	if base.Namespace == "" {
+		base.Namespace = "default"
+	}
+	with := func(resource string, names []string) AccessRequest {
+		r := base
+		r.Resource = resource
+		r.Names = names
+		return r
+	}
+
+	switch {
+	case k.verb == "":
+		return []AccessRequest{base}
+	case k.raw || len(k.files) > 0:
+		return []AccessRequest{with(anyResource, nil)}
+	case noResourceCommands[k.command]:
+		return []AccessRequest{base}
+	case podScopedVerbs[k.command]:
+		return podScopedRequests(k, with)
+	case nodeScopedVerbs[k.command]:
+		return []AccessRequest{with("nodes", k.positional)}
+	case k.command == "run":
+		return []AccessRequest{with("pods", firstN(k.positional, 1))}
+	case k.command == "events":
+		return []AccessRequest{with("events", nil)}
+	case k.command == "create":
+		if r, ok := createRequest(k, with); ok {
+			return []AccessRequest{r}
+		}
+	}
+	return objectRequests(k, with)
+}
+
+// podScopedRequests builds the requests for logs/exec/attach/port-forward/cp/
+// debug. The target is a pod, or a workload whose pod kubectl picks ("deploy/
+// api"); it is matched by that name. debug may also target a node.
+func podScopedRequests(k kubectlArgs, with func(string, []string) AccessRequest) []AccessRequest {
+	if k.command == "cp" {
+		return cpRequests(k, with)
+	}
+	if len(k.positional) == 0 {
+		return []AccessRequest{with("pods", nil)}
+	}
+	typ, name, slashed := strings.Cut(k.positional[0], "/")
+	if !slashed {
+		return []AccessRequest{with("pods", []string{typ})}
+	}
+	if k.command == "debug" && normalizeResource(typ) == "nodes" {
+		return []AccessRequest{with("nodes", []string{name})}
+	}
+	return []AccessRequest{with("pods", []string{name})}
+}
+
+// cpRequests builds the requests for kubectl cp, which addresses pods as
+// "[ns/]pod:path": any argument containing ':' names a pod, in the namespace
+// of its prefix if it has one. Pods are grouped into one request per
+// namespace.
+func cpRequests(k kubectlArgs, with func(string, []string) AccessRequest) []AccessRequest {
+	var reqs []AccessRequest
+	byNamespace := map[string]int{}
+	for _, a := range k.positional {
+		target, _, ok := strings.Cut(a, ":")
+		if !ok || target == "" {
+			continue
+		}
+		r := with("pods", nil)
+		ns, name, prefixed := strings.Cut(target, "/")
+		if prefixed {
+			r.Namespace = ns
+		} else {
+			name = target
+		}
+		i, seen := byNamespace[r.Namespace]
+		if !seen {
+			i = len(reqs)
+			byNamespace[r.Namespace] = i
+			reqs = append(reqs, r)
+		}
+		reqs[i].Names = append(reqs[i].Names, name)
+	}
+	if len(reqs) == 0 {
+		return []AccessRequest{with("pods", nil)}
+	}
+	return reqs
+}
+
+// createRequest handles "kubectl create <subtype> [variant] <name>".
+func createRequest(k kubectlArgs, with func(string, []string) AccessRequest) (AccessRequest, bool) {
+	if len(k.positional) == 0 {
+		return AccessRequest{}, false
+	}
+	subtype := k.positional[0]
+	resource, ok := createSubtypes[subtype]
+	if !ok {
+		return AccessRequest{}, false
+	}
+	rest := k.positional[1:]
+	if createVariants[subtype] && len(rest) > 0 {
+		rest = rest[1:]
+	}
+	return with(resource, firstN(rest, 1)), true
+}
+
+// objectRequests handles the generic "<verb> type[,type...] [name...]" and
+// "<verb> type/name [type/name...]" forms.
+func objectRequests(k kubectlArgs, with func(string, []string) AccessRequest) []AccessRequest {
+	if len(k.positional) == 0 {
+		return []AccessRequest{with("", nil)}
+	}
+
+	isKeyValue := func(a string) bool {
+		return keyValueCommands[k.command] && (strings.Contains(a, "=") || strings.HasSuffix(a, "-"))
+	}
+
+	if strings.Contains(k.positional[0], "/") {
+		var (
+			order []string
+			names = make(map[string][]string)
+		)
+		for _, a := range k.positional {
+			typ, name, ok := strings.Cut(a, "/")
+			if !ok || isKeyValue(a) {
+				continue
+			}
+			typ = normalizeResource(typ)
+			if _, seen := names[typ]; !seen {
+				order = append(order, typ)
+			}
+			names[typ] = append(names[typ], name)
+		}
+		reqs := make([]AccessRequest, 0, len(order))
+		for _, typ := range order {
+			reqs = append(reqs, with(typ, names[typ]))
+		}
+		return reqs
+	}
+
+	var objNames []string
+	for _, a := range k.positional[1:] {
+		if !isKeyValue(a) {
+			objNames = append(objNames, a)
+		}
+	}
+	var reqs []AccessRequest
+	for _, typ := range strings.Split(k.positional[0], ",") {
+		if typ == "" {
+			continue
+		}
+		reqs = append(reqs, with(normalizeResource(typ), objNames))
+	}
+	if len(reqs) == 0 {
+		return []AccessRequest{with("", nil)}
+	}
+	return reqs
+}
+
+// firstN returns at most the first n elements of s, or nil if s is empty.
+func firstN(s []string, n int) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	if len(s) > n {
+		s = s[:n]
+	}
+	return s
+}
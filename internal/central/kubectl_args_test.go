@@ -0,0 +1,131 @@
+package central
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatRequests renders requests compactly as "verb resource[names]@ns; ...".
+func formatRequests(reqs []AccessRequest) string {
+	parts := make([]string, len(reqs))
+	for i, r := range reqs {
+		parts[i] = fmt.Sprintf("%s %s[%s]@%s", r.Verb, r.Resource, strings.Join(r.Names, ","), r.Namespace)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestParseAccessRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  []string
+		fallback string
+		want     string
+	}{
+		// Namespaces.
+		{"simple get", []string{"get", "pods"}, "", "get pods[]@default"},
+		{"fallback namespace", []string{"get", "pods"}, "app", "get pods[]@app"},
+		{"-n flag overrides fallback", []string{"get", "pods", "-n", "kube-system"}, "app", "get pods[]@kube-system"},
+		{"--namespace= form", []string{"get", "svc", "--namespace=infra"}, "", "get services[]@infra"},
+		{"attached -n value", []string{"get", "pods", "-ninfra"}, "", "get pods[]@infra"},
+		{"all namespaces -A", []string{"get", "pods", "-A"}, "", "get pods[]@*"},
+		{"all namespaces long", []string{"get", "pods", "--all-namespaces"}, "", "get pods[]@*"},
+		{"all namespaces false", []string{"get", "pods", "--all-namespaces=false"}, "", "get pods[]@default"},
+		{"-A bundled with -n", []string{"get", "-An", "pods", "secrets"}, "", "get secrets[]@*"},
+		{"-A bundled with -o", []string{"get", "-Ao", "yaml", "secrets"}, "", "get secrets[]@*"},
+		{"-A bundled with -s", []string{"get", "-As", "https://x", "pods"}, "", "get pods[]@*"},
+		{"-n with attached A", []string{"get", "-nA", "pods"}, "", "get pods[]@A"},
+		{"-A before -n", []string{"get", "-A", "-n", "dev", "secrets"}, "", "get secrets[]@*"},
+		{"global flags before command", []string{"-n", "app", "--context", "x", "get", "pods"}, "", "get pods[]@app"},
+
+		// Flags with values are not resources or names.
+		{"output before type", []string{"get", "-o", "yaml", "pods"}, "", "get pods[]@default"},
+		{"attached output", []string{"get", "-oyaml", "pods", "web"}, "", "get pods[web]@default"},
+		{"selector", []string{"delete", "pods", "-l", "app=web"}, "", "delete pods[]@default"},
+		{"bundled booleans", []string{"exec", "-it", "web-1", "--", "sh", "-c", "ls"}, "", "exec pods[web-1]@default"},
+		{"exec container", []string{"exec", "-c", "app", "web-1", "--", "sh"}, "", "exec pods[web-1]@default"},
+		{"logs -f is follow", []string{"logs", "-f", "web-1"}, "", "logs pods[web-1]@default"},
+		{"logs -p is previous", []string{"logs", "-p", "web-1", "-c", "app"}, "", "logs pods[web-1]@default"},
+
+		// Resource types.
+		{"short name", []string{"get", "po"}, "", "get pods[]@default"},
+		{"singular", []string{"describe", "deployment", "api"}, "", "describe deployments[api]@default"},
+		{"group suffix", []string{"get", "deployments.apps", "api"}, "", "get deployments[api]@default"},
+		{"custom resource", []string{"get", "Certificates.cert-manager.io"}, "", "get certificates[]@default"},
+		{"comma-separated types", []string{"get", "pods,svc"}, "", "get pods[]@default; get services[]@default"},
+		{"comma types with name", []string{"get", "deploy,svc", "api"}, "", "get deployments[api]@default; get services[api]@default"},
+
+		// Object names.
+		{"type/name", []string{"delete", "pods/web-1"}, "", "delete pods[web-1]@default"},
+		{"type/name grouped by type", []string{"get", "po/a", "svc/b", "pod/c"}, "", "get pods[a,c]@default; get services[b]@default"},
+		{"type with several names", []string{"delete", "secret", "a", "b"}, "", "delete secrets[a,b]@default"},
+		{"label args are not names", []string{"label", "pods", "web-1", "tier=fe", "old-"}, "", "label pods[web-1]@default"},
+		{"taint", []string{"taint", "nodes", "n1", "dedicated=x:NoSchedule"}, "", "taint nodes[n1]@default"},
+
+		// Subcommand verbs.
+		{"rollout restart", []string{"rollout", "restart", "deploy/x"}, "", "rollout restart deployments[x]@default"},
+		{"rollout status bare type", []string{"rollout", "status", "deployment", "x"}, "", "rollout status deployments[x]@default"},
+		{"set image", []string{"set", "image", "deploy/api", "app=img:v2"}, "", "set image deployments[api]@default"},
+		{"set env with slash value", []string{"set", "env", "deploy/api", "URL=http://x/y"}, "", "set env deployments[api]@default"},
+		{"certificate approve", []string{"certificate", "approve", "csr", "c1"}, "", "certificate approve certificatesigningrequests[c1]@default"},
+		{"config view", []string{"config", "view"}, "", "config view []@default"},
+		{"auth can-i", []string{"auth", "can-i", "delete", "pods"}, "", "auth can-i []@default"},
+		{"version", []string{"version"}, "", "version []@default"},
+		{"apply subcommand", []string{"apply", "view-last-applied", "deploy/api"}, "", "apply view-last-applied deployments[api]@default"},
+
+		// Pod- and node-scoped commands.
+		{"logs is pod-scoped", []string{"logs", "web-1"}, "", "logs pods[web-1]@default"},
+		{"exec workload", []string{"exec", "deploy/api-v2", "--", "sh"}, "", "exec pods[api-v2]@default"},
+		{"port-forward service", []string{"port-forward", "svc/db", "5432:5432"}, "", "port-forward pods[db]@default"},
+		{"cp from pod", []string{"cp", "app/web-1:/tmp/x", "./x"}, "", "cp pods[web-1]@app"},
+		{"cp uses -n without prefix", []string{"cp", "-n", "app", "web-1:/tmp/x", "./x"}, "", "cp pods[web-1]@app"},
+		{"cp across namespaces", []string{"cp", "-n", "app", "web-1:/tmp/x", "prod/db-0:/tmp/x", "app/web-2:/y"}, "",
+			"cp pods[web-1,web-2]@app; cp pods[db-0]@prod"},
+		{"debug node", []string{"debug", "node/n1", "-it", "--image=busybox"}, "", "debug nodes[n1]@default"},
+		{"drain", []string{"drain", "n1", "--ignore-daemonsets"}, "", "drain nodes[n1]@default"},
+		{"run", []string{"run", "tmp", "--image", "busybox", "--", "sleep", "1"}, "", "run pods[tmp]@default"},
+
+		// create generators.
+		{"create deployment", []string{"create", "deployment", "api", "--image=nginx"}, "", "create deployments[api]@default"},
+		{"create secret variant", []string{"create", "secret", "generic", "db", "--from-literal=a=b"}, "", "create secrets[db]@default"},
+		{"create token", []string{"create", "token", "builder"}, "", "create serviceaccounts/token[builder]@default"},
+
+		// Manifests and raw paths cannot be resolved from the arguments.
+		{"apply file", []string{"apply", "-f", "app.yaml"}, "", "apply *[]@default"},
+		{"create from stdin", []string{"create", "-f", "-"}, "", "create *[]@default"},
+		{"delete kustomize", []string{"delete", "-k", "overlays/prod"}, "", "delete *[]@default"},
+		{"raw", []string{"get", "--raw", "/api/v1/secrets"}, "", "get *[]@default"},
+
+		{"empty command", []string{}, "", " []@default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs := parseAccessRequests("c1", tt.command, tt.fallback)
+			for _, r := range reqs {
+				if r.Cluster != "c1" {
+					t.Errorf("cluster = %q, want c1", r.Cluster)
+				}
+			}
+			if got := formatRequests(reqs); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeResource(t *testing.T) {
+	tests := map[string]string{
+		"po": "pods", "Pod": "pods", "pods": "pods", "deploy": "deployments",
+		"deployments.v1.apps": "deployments", "netpol": "networkpolicies",
+		"widgets.example.com": "widgets", "widget": "widgets",
+		"certificate": "certificates", "certificates.cert-manager.io": "certificates",
+		"mutatingwebhookconfiguration":  "mutatingwebhookconfigurations",
+		"mutatingwebhookconfigurations": "mutatingwebhookconfigurations",
+		"gatewayclass":                  "gatewayclasses", "prometheus": "prometheuses", "*": "*",
+	}
+	for in, want := range tests {
+		if got := normalizeResource(in); got != want {
+			t.Errorf("normalizeResource(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package central
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stdinFile is the -f value that tells kubectl to read manifests from stdin.
+const stdinFile = "-"
+
+// manifestObject is the part of a Kubernetes object needed for authorization.
+type manifestObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Items []yaml.Node `yaml:"items"`
+}
+
+// execAccessRequests returns the access requests for an exec request. When
+// the command reads manifests from stdin ("-f -") and the request carries
+// them, each object in the stream is authorized individually by kind,
+// namespace and name instead of requiring access to any resource.
+func execAccessRequests(cluster string, req ExecRequest) ([]AccessRequest, error) {
+	reqs := parseAccessRequests(cluster, req.Command, req.Namespace)
+	k := parseKubectlArgs(req.Command)
+	if k.raw || req.Stdin == "" || !slices.Contains(k.files, stdinFile) {
+		return reqs, nil
+	}
+
+	base := reqs[0]
+	objs, err := manifestAccessRequests(base, req.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	// Other -f files or -k directories live on the agent and stay opaque.
+	if slices.ContainsFunc(k.files, func(f string) bool { return f != stdinFile }) {
+		objs = append(objs, base)
+	}
+	return objs, nil
+}
+
+// clusterScopedResources are the built-in resources whose objects live
+// outside any namespace.
+var clusterScopedResources = map[string]bool{
+	"namespaces": true, "nodes": true, "persistentvolumes": true,
+	"clusterroles": true, "clusterrolebindings": true,
+	"customresourcedefinitions": true, "apiservices": true,
+	"mutatingwebhookconfigurations": true, "validatingwebhookconfigurations": true,
+	"validatingadmissionpolicies": true, "validatingadmissionpolicybindings": true,
+	"mutatingadmissionpolicies": true, "mutatingadmissionpolicybindings": true,
+	"storageclasses": true, "volumeattachments": true, "csidrivers": true,
+	"csinodes": true, "volumeattributesclasses": true, "priorityclasses": true,
+	"runtimeclasses": true, "ingressclasses": true, "certificatesigningrequests": true,
+	"clustertrustbundles": true, "flowschemas": true, "prioritylevelconfigurations": true,
+	"ipaddresses": true, "servicecidrs": true, "deviceclasses": true,
+	"resourceslices": true, "componentstatuses": true, "podsecuritypolicies": true,
+}
+
+// clusterScoped reports whether objects of kind live outside namespaces.
+// Custom resources are unknown to central; kinds named Cluster*, the usual
+// convention (ClusterIssuer, ClusterPolicy), are taken to be cluster-scoped.
+func clusterScoped(kind, resource string) bool {
+	return clusterScopedResources[resource] || strings.HasPrefix(kind, "Cluster")
+}
+
+// manifestAccessRequests parses a YAML or JSON manifest stream and returns one
+// request per object, derived from base. List kinds are expanded into their
+// items. An object without a namespace uses base's namespace; objects of
+// cluster-scoped kinds use "*" whatever they or the command say, since they
+// are not confined to a namespace.
+func manifestAccessRequests(base AccessRequest, manifest string) ([]AccessRequest, error) {
+	var reqs []AccessRequest
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	for doc := 1; ; doc++ {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifest document %d: %w", doc, err)
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue // empty document between "---" separators
+		}
+		objs, err := manifestObjectRequests(base, &node)
+		if err != nil {
+			return nil, fmt.Errorf("manifest document %d: %w", doc, err)
+		}
+		reqs = append(reqs, objs...)
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("manifest contains no objects")
+	}
+	return reqs, nil
+}
+
+// manifestObjectRequests returns the requests for a single decoded object,
+// recursing into the items of a List.
+func manifestObjectRequests(base AccessRequest, node *yaml.Node) ([]AccessRequest, error) {
+	var obj manifestObject
+	if err := node.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("decoding object: %w", err)
+	}
+	if obj.Kind == "" {
+		return nil, fmt.Errorf("object has no kind")
+	}
+
+	if obj.Kind == "List" || (strings.HasSuffix(obj.Kind, "List") && len(obj.Items) > 0) {
+		var reqs []AccessRequest
+		for i := range obj.Items {
+			items, err := manifestObjectRequests(base, &obj.Items[i])
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			reqs = append(reqs, items...)
+		}
+		return reqs, nil
+	}
+
+	r := base
+	r.Resource = kindToResource(obj.Kind)
+	r.Names = nil
+	if obj.Metadata.Name != "" {
+		r.Names = []string{obj.Metadata.Name}
+	}
+	switch {
+	case clusterScoped(obj.Kind, r.Resource):
+		r.Namespace = "*"
+	case obj.Metadata.Namespace != "":
+		r.Namespace = obj.Metadata.Namespace
+	}
+	return []AccessRequest{r}, nil
+}
+
+// kindToResource maps an object kind to its plural resource name, e.g.
+// "Deployment" → "deployments", "NetworkPolicy" → "networkpolicies". Kinds
+// kubectl does not know by short name are pluralized the way the API server
+// pluralizes custom resource kinds.
+func kindToResource(kind string) string {
+	r := strings.ToLower(kind)
+	if full, ok := resourceAliases[r]; ok {
+		return full
+	}
+	switch {
+	case r == "endpoints":
+		return r
+	case strings.HasSuffix(r, "s"), strings.HasSuffix(r, "x"), strings.HasSuffix(r, "ch"), strings.HasSuffix(r, "sh"):
+		return r + "es"
+	case strings.HasSuffix(r, "y") && len(r) > 1 && !strings.ContainsRune("aeiou", rune(r[len(r)-2])):
+		return r[:len(r)-1] + "ies"
+	default:
+		return r + "s"
+	}
+}
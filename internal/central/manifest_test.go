@@ -0,0 +1,94 @@
+package central
+
+import (
+	"testing"
+)
+
+func TestManifestAccessRequests(t *testing.T) {
+	base := AccessRequest{Cluster: "c1", Namespace: "app", Verb: "apply", Resource: anyResource}
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+		wantErr  bool
+	}{
+		{"single object", "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n", "apply deployments[api]@app", false},
+		{"multiple documents", "kind: Service\nmetadata: {name: api}\n---\n---\nkind: Secret\nmetadata: {name: db, namespace: data}\n",
+			"apply services[api]@app; apply secrets[db]@data", false},
+		{"json", `{"kind":"NetworkPolicy","metadata":{"name":"deny-all"}}`, "apply networkpolicies[deny-all]@app", false},
+		{"list", "kind: List\nitems:\n- kind: ConfigMap\n  metadata: {name: a}\n- kind: Pod\n  metadata: {name: b}\n",
+			"apply configmaps[a]@app; apply pods[b]@app", false},
+		{"typed list", `{"kind":"DeploymentList","items":[{"kind":"Deployment","metadata":{"name":"x"}}]}`, "apply deployments[x]@app", false},
+		{"generateName has no name", "kind: Job\nmetadata: {generateName: migrate-}\n", "apply jobs[]@app", false},
+		{"custom resource", "kind: Certificate\nmetadata: {name: tls}\n", "apply certificates[tls]@app", false},
+		{"cluster-scoped kind ignores the base namespace", "kind: ClusterRoleBinding\nmetadata: {name: admin}\n",
+			"apply clusterrolebindings[admin]@*", false},
+		{"cluster-scoped kind ignores its own namespace", "kind: Namespace\nmetadata: {name: x, namespace: app}\n",
+			"apply namespaces[x]@*", false},
+		{"cluster-scoped custom resource", "kind: ClusterIssuer\nmetadata: {name: le}\n", "apply clusterissuers[le]@*", false},
+		{"missing kind", "metadata: {name: x}\n", "", true},
+		{"list item missing kind", "kind: List\nitems:\n- metadata: {name: x}\n", "", true},
+		{"invalid yaml", "kind: [\n", "", true},
+		{"empty", "---\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, err := manifestAccessRequests(base, tt.manifest)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", formatRequests(reqs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatRequests(reqs); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecAccessRequests(t *testing.T) {
+	manifest := "kind: ConfigMap\nmetadata: {name: a}\n"
+
+	tests := []struct {
+		name string
+		req  ExecRequest
+		want string
+	}{
+		{"stdin manifest", ExecRequest{Command: []string{"apply", "-f", "-"}, Stdin: manifest}, "apply configmaps[a]@default"},
+		{"namespace flag applies to objects", ExecRequest{Command: []string{"create", "-n", "app", "-f", "-"}, Stdin: manifest}, "create configmaps[a]@app"},
+		{"other file stays opaque", ExecRequest{Command: []string{"apply", "-f", "-", "-f", "extra.yaml"}, Stdin: manifest},
+			"apply configmaps[a]@default; apply *[]@default"},
+		{"file without stdin", ExecRequest{Command: []string{"apply", "-f", "app.yaml"}, Stdin: manifest}, "apply *[]@default"},
+		{"stdin flag without content", ExecRequest{Command: []string{"apply", "-f", "-"}}, "apply *[]@default"},
+		{"no manifest", ExecRequest{Command: []string{"get", "pods"}}, "get pods[]@default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, err := execAccessRequests("c1", tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatRequests(reqs); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindToResource(t *testing.T) {
+	tests := map[string]string{
+		"Deployment": "deployments", "Ingress": "ingresses", "NetworkPolicy": "networkpolicies",
+		"StorageClass": "storageclasses", "Endpoints": "endpoints", "Gateway": "gateways",
+		"HTTPRoute": "httproutes", "Policy": "policies", "Mesh": "meshes",
+	}
+	for kind, want := range tests {
+		if got := kindToResource(kind); got != want {
+			t.Errorf("kindToResource(%q) = %q, want %q", kind, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package central
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/why-xn/kbridge/internal/metrics"
+)
+
+// Event metrics are process-wide; the gauges that read live state are
+// registered per HTTPServer in SetMetrics.
+var (
+	metricsRegistry = metrics.NewRegistry()
+
+	execRequestsTotal = metricsRegistry.NewCounterVec("kbridge_exec_requests_total",
+		"Command requests by cluster and outcome (the audit status).", "cluster", "status")
+	execDurationSeconds = metricsRegistry.NewHistogramVec("kbridge_exec_duration_seconds",
+		"Time from queueing a command or opening a session to its result.",
+		[]float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300}, "cluster", "status")
+	commandQueueWaitSeconds = metricsRegistry.NewHistogramVec("kbridge_command_queue_wait_seconds",
+		"Time a one-shot command waits in the queue before its agent picks it up.",
+		metrics.DefBuckets, "cluster")
+	slowClientCancelsTotal = metricsRegistry.NewCounterVec("kbridge_stream_slow_client_cancellations_total",
+		"Streaming sessions canceled because the client did not keep up with the output.", "kind")
+	rbacDenialsTotal = metricsRegistry.NewCounterVec("kbridge_rbac_denials_total",
+		"Commands denied by the RBAC policy.", "cluster")
+	loginFailuresTotal = metricsRegistry.NewCounterVec("kbridge_login_failures_total",
+		"Failed logins by method (password or sso) and reason.", "method", "reason")
+	rateLimitRejectionsTotal = metricsRegistry.NewCounterVec("kbridge_rate_limit_rejections_total",
+		"Requests rejected by the login rate limiter, by route.", "route")
+)
+
+// observeExec records the outcome of a command request.
+func observeExec(cluster, status string, durationMs *int64) {
+	execRequestsTotal.WithLabelValues(cluster, status).Inc()
+	if durationMs != nil {
+		execDurationSeconds.WithLabelValues(cluster, status).Observe(float64(*durationMs) / 1000)
+	}
+}
+
+// SetMetrics serves Prometheus metrics on /metrics: the process-wide event
+// metrics plus gauges read from this server's queue, sessions and agents at
+// scrape time.
+func (s *HTTPServer) SetMetrics(cfg MetricsConfig) {
+	live := metrics.NewRegistry()
+	live.NewGaugeFunc("kbridge_command_queue_depth",
+		"One-shot commands in the queue, by cluster and state (pending or running).",
+		[]string{"cluster", "state"}, func(set func(float64, ...string)) {
+			for k, n := range s.commandQueue.depth() {
+				set(float64(n), k.cluster, string(k.status))
+			}
+		})
+	if s.sessions != nil {
+		live.NewGaugeFunc("kbridge_stream_sessions_active",
+			"Open streaming sessions by kind (stream, exec or port-forward).",
+			[]string{"kind"}, func(set func(float64, ...string)) {
+				counts := s.sessions.activeByKind()
+				for _, kind := range []SessionKind{SessionKindStream, SessionKindExec, SessionKindPortForward} {
+					set(float64(counts[kind]), string(kind))
+				}
+			})
+	}
+	live.NewGaugeFunc("kbridge_agent_heartbeat_age_seconds",
+		"Seconds since each registered agent last sent a heartbeat.",
+		[]string{"cluster", "agent_id"}, func(set func(float64, ...string)) {
+			now := time.Now()
+			for _, a := range s.agentStore.List() {
+				set(now.Sub(a.LastSeen).Seconds(), a.ClusterName, a.ID)
+			}
+		})
+	live.NewGaugeFunc("kbridge_agents_connected",
+		"Registered agents that are currently connected.",
+		nil, func(set func(float64, ...string)) {
+			n := 0
+			for _, a := range s.agentStore.List() {
+				if a.Status == AgentStatusConnected {
+					n++
+				}
+			}
+			set(float64(n))
+		})
+
+	handler := gin.WrapH(metrics.Handler(metricsRegistry, live))
+	s.router.GET("/metrics", metricsAuth(cfg.Token), handler)
+}
+
+// metricsAuth requires "Authorization: Bearer <token>" when token is set.
+func metricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+			return
+		}
+	}
+}
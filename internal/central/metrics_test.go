@@ -0,0 +1,92 @@
+package central
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/why-xn/kbridge/api/proto/agentpb"
+)
+
+func TestHTTPServer_Metrics(t *testing.T) {
+	agents := NewAgentStore()
+	agents.Register(&AgentInfo{ID: "metrics-a1", ClusterName: "metrics-prod"})
+	queue := NewCommandQueue()
+	sm := NewSessionManager(10)
+	sm.RegisterAgentStream("metrics-a1", &fakeSender{})
+	srv := NewHTTPServer(agents, queue, nil, nil, nil, nil, sm, nil)
+	srv.SetMetrics(MetricsConfig{Token: "scrape-token"})
+
+	running, _ := queue.Enqueue("metrics-a1", "metrics-prod", []string{"get", "pods"}, "", 30, nil)
+	queue.Enqueue("metrics-a1", "metrics-prod", []string{"get", "nodes"}, "", 30, nil) //nolint:errcheck
+	queue.MarkRunning(running)
+	if _, err := sm.StartPortForward("metrics-a1", Identity{}, "web", "default", []uint32{8080}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client that never drains its stream is canceled and counted.
+	slowBefore := slowClientCancelsTotal.WithLabelValues(string(SessionKindStream)).Value()
+	slow, err := sm.Start("metrics-a1", Identity{}, []string{"logs", "-f", "web"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= sessionOutputBuffer; i++ {
+		sm.Route(&agentpb.AgentStreamMessage{Msg: &agentpb.AgentStreamMessage_Output{
+			Output: &agentpb.StreamOutput{SessionId: slow.ID, Data: []byte("x")},
+		}})
+	}
+	if got := slowClientCancelsTotal.WithLabelValues(string(SessionKindStream)).Value() - slowBefore; got != 1 {
+		t.Errorf("slow-client cancellations = %v, want 1", got)
+	}
+
+	dur := int64(1500)
+	observeExec("metrics-prod", AuditStatusSuccess, &dur)
+	observeExec("metrics-prod", AuditStatusDenied, nil)
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("scrape without token: want 401, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-token")
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape: %d %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`kbridge_command_queue_depth{cluster="metrics-prod",state="pending"} 1`,
+		`kbridge_command_queue_depth{cluster="metrics-prod",state="running"} 1`,
+		`kbridge_command_queue_wait_seconds_count{cluster="metrics-prod"} 1`,
+		`kbridge_stream_sessions_active{kind="port-forward"} 1`,
+		`kbridge_stream_sessions_active{kind="stream"} 0`,
+		`kbridge_exec_requests_total{cluster="metrics-prod",status="success"} 1`,
+		`kbridge_exec_requests_total{cluster="metrics-prod",status="denied"} 1`,
+		`kbridge_exec_duration_seconds_bucket{cluster="metrics-prod",status="success",le="2.5"} 1`,
+		`kbridge_agent_heartbeat_age_seconds{cluster="metrics-prod",agent_id="metrics-a1"} `,
+		"# TYPE kbridge_login_failures_total counter",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestLoginFailureMetrics(t *testing.T) {
+	store := newTestStore(t)
+	ah := NewAuthHandlers(store, nil, time.Hour)
+	before := loginFailuresTotal.WithLabelValues("password", "unknown_user").Value()
+	w := doRequest(t, "POST", "/auth/login", ah.HandleLogin, "POST", "/auth/login",
+		[]byte(`{"email":"nobody@x.com","password":"pw"}`))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("want 401, got %d", w.Code)
+	}
+	if got := loginFailuresTotal.WithLabelValues("password", "unknown_user").Value() - before; got != 1 {
+		t.Errorf("login failures = %v, want 1", got)
+	}
+}
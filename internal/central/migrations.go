@@ -1,12 +1,466 @@
 package central
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"strings"
+	"log"
+	"sort"
+	"time"
 )
 
-const schemaSQL = `
+// ErrSchemaTooNew is returned when the database records migrations this
+// binary does not know about, i.e. it was migrated by a newer release.
+// Starting anyway could corrupt data, so central refuses.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// Migration states reported by MigrationStatus.
+const (
+	MigrationApplied  = "applied"
+	MigrationPending  = "pending"
+	MigrationModified = "modified" // applied, but the SQL has since changed
+	MigrationUnknown  = "unknown"  // applied by a newer binary
+)
+
+// migration is one numbered, forward-only schema change. Applied migrations
+// are recorded in schema_migrations together with a checksum of their SQL, so
+// an edit to an already-shipped migration is reported instead of skipped.
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// Fixup optionally runs after SQL in the same transaction. It is not
+	// covered by the checksum and must be idempotent.
+	Fixup func(ctx context.Context, tx *sql.Tx) error
+}
+
+func (m migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.SQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// sqliteMigrations and postgresMigrations list every schema change in order.
+// Append new entries; never edit or renumber one that has shipped.
+var sqliteMigrations = []migration{
+	{Version: 1, Name: "initial_schema", SQL: sqliteInitialSchema, Fixup: adoptLegacySQLiteSchema},
+	{Version: 2, Name: "refresh_token_idp_groups", SQL: `ALTER TABLE refresh_tokens ADD COLUMN idp_groups TEXT NOT NULL DEFAULT ''`},
+	{Version: 3, Name: "user_groups", SQL: `ALTER TABLE users ADD COLUMN group_names TEXT NOT NULL DEFAULT ''`},
+	{Version: 4, Name: "policy_versions", SQL: `
+CREATE TABLE IF NOT EXISTS policy_versions (
+    version    INTEGER PRIMARY KEY,
+    document   TEXT NOT NULL,
+    author     TEXT NOT NULL,
+    comment    TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);`},
+	{Version: 5, Name: "jit_requests", SQL: `
+CREATE TABLE IF NOT EXISTS jit_requests (
+    id               TEXT PRIMARY KEY,
+    user_id          TEXT,
+    user_email       TEXT NOT NULL,
+    cluster_name     TEXT NOT NULL,
+    role             TEXT NOT NULL,
+    reason           TEXT NOT NULL,
+    duration_seconds INTEGER NOT NULL,
+    status           TEXT NOT NULL,
+    decided_by       TEXT,
+    decided_at       TEXT,
+    expires_at       TEXT,
+    created_at       TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_jit_requests_status ON jit_requests(status, expires_at);`},
+	{Version: 6, Name: "jit_breakglass", SQL: `ALTER TABLE jit_requests ADD COLUMN breakglass INTEGER NOT NULL DEFAULT 0`},
+	{Version: 7, Name: "audit_chain", SQL: `
+ALTER TABLE audit_logs ADD COLUMN seq INTEGER;
+ALTER TABLE audit_logs ADD COLUMN prev_hash TEXT;
+ALTER TABLE audit_logs ADD COLUMN hash TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq);
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id         TEXT PRIMARY KEY,
+    seq        INTEGER NOT NULL,
+    hash       TEXT NOT NULL,
+    deleted    INTEGER NOT NULL,
+    signature  TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);`},
+	{Version: 8, Name: "session_recordings", SQL: `
+CREATE TABLE IF NOT EXISTS session_recordings (
+    id           TEXT PRIMARY KEY,
+    audit_log_id TEXT NOT NULL REFERENCES audit_logs(id) ON DELETE CASCADE,
+    user_email   TEXT NOT NULL,
+    cluster_name TEXT NOT NULL,
+    namespace    TEXT,
+    command      TEXT NOT NULL,
+    duration_ms  INTEGER NOT NULL,
+    size_bytes   INTEGER NOT NULL,
+    truncated    INTEGER NOT NULL DEFAULT 0,
+    data         BLOB NOT NULL,
+    created_at   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_session_recordings_created ON session_recordings(created_at);`},
+	{Version: 9, Name: "audit_outputs", SQL: `
+CREATE TABLE IF NOT EXISTS audit_outputs (
+    audit_log_id TEXT PRIMARY KEY REFERENCES audit_logs(id) ON DELETE CASCADE,
+    stdout       TEXT NOT NULL DEFAULT '',
+    stderr       TEXT NOT NULL DEFAULT '',
+    stdout_bytes INTEGER NOT NULL,
+    stderr_bytes INTEGER NOT NULL,
+    truncated    INTEGER NOT NULL DEFAULT 0,
+    redactions   INTEGER NOT NULL DEFAULT 0,
+    created_at   TEXT NOT NULL
+);`},
+	{Version: 10, Name: "audit_query_columns", SQL: `
+ALTER TABLE audit_logs ADD COLUMN verb TEXT;
+ALTER TABLE audit_logs ADD COLUMN resource TEXT;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_id ON audit_logs(created_at, id);`},
+}
+
+var postgresMigrations = []migration{
+	{Version: 1, Name: "initial_schema", SQL: postgresInitialSchema},
+	{Version: 2, Name: "refresh_token_idp_groups", SQL: `ALTER TABLE refresh_tokens ADD COLUMN idp_groups TEXT NOT NULL DEFAULT ''`},
+	{Version: 3, Name: "user_groups", SQL: `ALTER TABLE users ADD COLUMN group_names TEXT NOT NULL DEFAULT ''`},
+	{Version: 4, Name: "policy_versions", SQL: `
+CREATE TABLE IF NOT EXISTS policy_versions (
+    version    INTEGER PRIMARY KEY,
+    document   TEXT NOT NULL,
+    author     TEXT NOT NULL,
+    comment    TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`},
+	{Version: 5, Name: "jit_requests", SQL: `
+CREATE TABLE IF NOT EXISTS jit_requests (
+    id               TEXT PRIMARY KEY,
+    user_id          TEXT,
+    user_email       TEXT NOT NULL,
+    cluster_name     TEXT NOT NULL,
+    role             TEXT NOT NULL,
+    reason           TEXT NOT NULL,
+    duration_seconds BIGINT NOT NULL,
+    status           TEXT NOT NULL,
+    decided_by       TEXT,
+    decided_at       TIMESTAMPTZ,
+    expires_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_jit_requests_status ON jit_requests(status, expires_at);`},
+	{Version: 6, Name: "jit_breakglass", SQL: `ALTER TABLE jit_requests ADD COLUMN breakglass BOOLEAN NOT NULL DEFAULT FALSE`},
+	{Version: 7, Name: "audit_chain", SQL: `
+ALTER TABLE audit_logs ADD COLUMN seq BIGINT;
+ALTER TABLE audit_logs ADD COLUMN prev_hash TEXT;
+ALTER TABLE audit_logs ADD COLUMN hash TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq);
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id         TEXT PRIMARY KEY,
+    seq        BIGINT NOT NULL,
+    hash       TEXT NOT NULL,
+    deleted    INTEGER NOT NULL,
+    signature  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);`},
+	{Version: 8, Name: "session_recordings", SQL: `
+CREATE TABLE IF NOT EXISTS session_recordings (
+    id           TEXT PRIMARY KEY,
+    audit_log_id TEXT NOT NULL REFERENCES audit_logs(id) ON DELETE CASCADE,
+    user_email   TEXT NOT NULL,
+    cluster_name TEXT NOT NULL,
+    namespace    TEXT,
+    command      TEXT NOT NULL,
+    duration_ms  BIGINT NOT NULL,
+    size_bytes   BIGINT NOT NULL,
+    truncated    BOOLEAN NOT NULL DEFAULT FALSE,
+    data         BYTEA NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_session_recordings_created ON session_recordings(created_at);`},
+	{Version: 9, Name: "audit_outputs", SQL: `
+CREATE TABLE IF NOT EXISTS audit_outputs (
+    audit_log_id TEXT PRIMARY KEY REFERENCES audit_logs(id) ON DELETE CASCADE,
+    stdout       TEXT NOT NULL DEFAULT '',
+    stderr       TEXT NOT NULL DEFAULT '',
+    stdout_bytes BIGINT NOT NULL,
+    stderr_bytes BIGINT NOT NULL,
+    truncated    BOOLEAN NOT NULL DEFAULT FALSE,
+    redactions   INTEGER NOT NULL DEFAULT 0,
+    created_at   TIMESTAMPTZ NOT NULL
+);`},
+	{Version: 10, Name: "audit_query_columns", SQL: `
+ALTER TABLE audit_logs ADD COLUMN verb TEXT;
+ALTER TABLE audit_logs ADD COLUMN resource TEXT;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_id ON audit_logs(created_at, id);`},
+}
+
+// MigrationStatus describes one migration known to the binary or recorded in
+// the database.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// migrator applies migrations to a database and reports their status.
+type migrator struct {
+	db         *sql.DB
+	postgres   bool
+	migrations []migration
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt *time.Time
+}
+
+// migrationLockID is the PostgreSQL advisory lock key held while migrating,
+// so replicas starting together do not race. Arbitrary but fixed.
+const migrationLockID = 7317064301
+
+func (m *migrator) bind(query string) string {
+	if m.postgres {
+		return rebind(query)
+	}
+	return query
+}
+
+func (m *migrator) createTableSQL() string {
+	if m.postgres {
+		return `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    checksum   TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+	}
+	return `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    checksum   TEXT NOT NULL,
+    applied_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+)`
+}
+
+// tableExists reports whether a table is visible to the connection.
+func tableExists(ctx context.Context, q querier, postgres bool, table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if postgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1`
+	}
+	var n int
+	if err := q.QueryRowContext(ctx, query, table).Scan(&n); err != nil {
+		return false, fmt.Errorf("check table %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// querier is satisfied by *sql.DB, *sql.Conn and *sql.Tx. The migrator reads
+// through the connection it holds, since SQLite allows only one.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// applied returns the rows recorded in schema_migrations, or an empty map if
+// the table does not exist yet.
+func (m *migrator) applied(ctx context.Context, q querier) (map[int]appliedMigration, error) {
+	ok, err := tableExists(ctx, q, m.postgres, "schema_migrations")
+	if err != nil || !ok {
+		return map[int]appliedMigration{}, err
+	}
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if m.postgres {
+			var at time.Time
+			if err := rows.Scan(&version, &a.name, &a.checksum, &at); err != nil {
+				return nil, fmt.Errorf("scan schema_migrations: %w", err)
+			}
+			at = at.UTC()
+			a.appliedAt = &at
+		} else {
+			var at string
+			if err := rows.Scan(&version, &a.name, &a.checksum, &at); err != nil {
+				return nil, fmt.Errorf("scan schema_migrations: %w", err)
+			}
+			a.appliedAt = parseNullableTime(&at)
+		}
+		out[version] = a
+	}
+	return out, rows.Err()
+}
+
+// status merges the binary's migrations with the database's record, in
+// version order. Versions present only in the database are MigrationUnknown.
+func (m *migrator) status(ctx context.Context, q querier) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	var out []MigrationStatus
+	known := map[int]bool{}
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name, State: MigrationPending}
+		if a, ok := applied[mig.Version]; ok {
+			st.State = MigrationApplied
+			st.AppliedAt = a.appliedAt
+			if a.checksum != mig.checksum() {
+				st.State = MigrationModified
+			}
+		}
+		out = append(out, st)
+	}
+	for v, a := range applied {
+		if !known[v] {
+			out = append(out, MigrationStatus{Version: v, Name: a.name, State: MigrationUnknown, AppliedAt: a.appliedAt})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// checkApplied rejects a database that is ahead of the binary or whose
+// applied migrations no longer match the binary's SQL.
+func checkApplied(statuses []MigrationStatus) error {
+	for _, st := range statuses {
+		switch st.State {
+		case MigrationUnknown:
+			return fmt.Errorf("%w: migration %d (%s) is not known to this release", ErrSchemaTooNew, st.Version, st.Name)
+		case MigrationModified:
+			return fmt.Errorf("migration %d (%s) was modified after it was applied", st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// CheckSchema returns an error unless every migration in statuses is applied
+// and unmodified. ErrSchemaTooNew is wrapped when the database is ahead.
+func CheckSchema(statuses []MigrationStatus) error {
+	if err := checkApplied(statuses); err != nil {
+		return err
+	}
+	var pending int
+	for _, st := range statuses {
+		if st.State == MigrationPending {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d pending migration(s); run `kbridge-central migrate up`", pending)
+	}
+	return nil
+}
+
+// up applies all pending migrations in order, each in its own transaction.
+// It refuses to run against a database that is newer than the binary or whose
+// applied migrations were modified.
+func (m *migrator) up(ctx context.Context) (int, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.postgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return 0, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}
+
+	if _, err := conn.ExecContext(ctx, m.createTableSQL()); err != nil {
+		return 0, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	statuses, err := m.status(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkApplied(statuses); err != nil {
+		return 0, err
+	}
+
+	pending := map[int]bool{}
+	for _, st := range statuses {
+		if st.State == MigrationPending {
+			pending[st.Version] = true
+		}
+	}
+	applied := 0
+	for _, mig := range m.migrations {
+		if !pending[mig.Version] {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig); err != nil {
+			return applied, err
+		}
+		log.Printf("Applied migration %d (%s)", mig.Version, mig.Name)
+		applied++
+	}
+	return applied, nil
+}
+
+func (m *migrator) apply(ctx context.Context, conn *sql.Conn, mig migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin migration %d: %w", mig.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.SQL); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, err)
+	}
+	if mig.Fixup != nil {
+		if err := mig.Fixup(ctx, tx); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		m.bind(`INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)`),
+		mig.Version, mig.Name, mig.checksum()); err != nil {
+		return fmt.Errorf("record migration %d: %w", mig.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %d: %w", mig.Version, err)
+	}
+	return nil
+}
+
+// adoptLegacySQLiteSchema brings a database created before schema_migrations
+// existed up to the shape of migration 1: it adds users.is_admin when missing
+// and drops the tables of the retired DB-backed RBAC model. On a fresh
+// database both steps are no-ops.
+func adoptLegacySQLiteSchema(ctx context.Context, tx *sql.Tx) error {
+	var n int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'is_admin'`).Scan(&n); err != nil {
+		return fmt.Errorf("inspect users columns: %w", err)
+	}
+	if n == 0 {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0`); err != nil {
+			return fmt.Errorf("add is_admin column: %w", err)
+		}
+	}
+	for _, tbl := range []string{"user_roles", "permissions", "roles"} {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+tbl); err != nil {
+			return fmt.Errorf("drop table %s: %w", tbl, err)
+		}
+	}
+	return nil
+}
+
+// sqliteInitialSchema is the 1.0 schema. It keeps IF NOT EXISTS so that
+// databases created before versioned migrations can adopt it in place.
+const sqliteInitialSchema = `
 CREATE TABLE IF NOT EXISTS users (
     id            TEXT PRIMARY KEY,
     email         TEXT NOT NULL UNIQUE,
@@ -76,27 +530,75 @@ CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)
 CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
 `
 
-func createSchema(db *sql.DB) error {
-	if _, err := db.Exec(schemaSQL); err != nil {
-		return fmt.Errorf("create schema: %w", err)
-	}
-	// Add is_admin to existing DBs; ignore "duplicate column" on fresh ones.
-	if err := addIsAdminColumn(db); err != nil {
-		return err
-	}
-	// Drop obsolete tables if they exist (no-op on fresh DBs).
-	for _, tbl := range []string{"user_roles", "permissions", "roles"} {
-		if _, err := db.Exec("DROP TABLE IF EXISTS " + tbl); err != nil {
-			return fmt.Errorf("drop table %s: %w", tbl, err)
-		}
-	}
-	return nil
-}
+// postgresInitialSchema is the PostgreSQL port of sqliteInitialSchema.
+// Timestamps use TIMESTAMPTZ and flags use BOOLEAN. No pre-1.0 Postgres
+// deployments exist, so there is no legacy adoption step.
+const postgresInitialSchema = `
+CREATE TABLE IF NOT EXISTS users (
+    id            TEXT PRIMARY KEY,
+    email         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    is_active     BOOLEAN NOT NULL DEFAULT TRUE,
+    is_admin      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
 
-func addIsAdminColumn(db *sql.DB) error {
-	_, err := db.Exec(`ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0`)
-	if err != nil && !strings.Contains(err.Error(), "duplicate column") {
-		return fmt.Errorf("add is_admin column: %w", err)
-	}
-	return nil
-}
+CREATE TABLE IF NOT EXISTS clusters (
+    id           TEXT PRIMARY KEY,
+    name         TEXT NOT NULL UNIQUE,
+    status       TEXT NOT NULL DEFAULT 'disconnected',
+    agent_id     TEXT,
+    last_seen_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clusters_name ON clusters(name);
+CREATE INDEX IF NOT EXISTS idx_clusters_status ON clusters(status);
+
+CREATE TABLE IF NOT EXISTS agent_tokens (
+    id           TEXT PRIMARY KEY,
+    cluster_id   TEXT NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+    token_hash   TEXT NOT NULL,
+    token_prefix TEXT NOT NULL,
+    description  TEXT,
+    is_revoked   BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_agent_tokens_cluster_id ON agent_tokens(cluster_id);
+CREATE INDEX IF NOT EXISTS idx_agent_tokens_token_hash ON agent_tokens(token_hash);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id            TEXT PRIMARY KEY,
+    user_id       TEXT REFERENCES users(id) ON DELETE SET NULL,
+    user_email    TEXT NOT NULL,
+    cluster_name  TEXT NOT NULL,
+    cluster_id    TEXT REFERENCES clusters(id) ON DELETE SET NULL,
+    command       TEXT NOT NULL,
+    namespace     TEXT,
+    status        TEXT NOT NULL,
+    exit_code     INTEGER,
+    duration_ms   BIGINT,
+    error_message TEXT,
+    client_ip     TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_cluster_id ON audit_logs(cluster_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_email ON audit_logs(user_email);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+`
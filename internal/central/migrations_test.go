@@ -0,0 +1,159 @@
+package central
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"all migrations applied", func(t *testing.T) {
+				statuses, err := store.MigrationStatus(ctx)
+				if err != nil {
+					t.Fatalf("status: %v", err)
+				}
+				if len(statuses) == 0 {
+					t.Fatal("expected at least one migration")
+				}
+				for _, st := range statuses {
+					if st.State != MigrationApplied {
+						t.Errorf("migration %d: state %q, want applied", st.Version, st.State)
+					}
+					if st.AppliedAt == nil {
+						t.Errorf("migration %d: applied_at not set", st.Version)
+					}
+				}
+				if err := CheckSchema(statuses); err != nil {
+					t.Errorf("CheckSchema: %v", err)
+				}
+			}},
+			{"modified migration is rejected", func(t *testing.T) {
+				store.exec(t, `UPDATE schema_migrations SET checksum = ? WHERE version = 1`, "tampered")
+				defer func() {
+					var sum string
+					if store.postgres {
+						sum = postgresMigrations[0].checksum()
+					} else {
+						sum = sqliteMigrations[0].checksum()
+					}
+					store.exec(t, `UPDATE schema_migrations SET checksum = ? WHERE version = 1`, sum)
+				}()
+				if err := store.Migrate(ctx); err == nil {
+					t.Fatal("expected error for modified migration")
+				}
+				statuses, _ := store.MigrationStatus(ctx)
+				if statuses[0].State != MigrationModified {
+					t.Errorf("state %q, want modified", statuses[0].State)
+				}
+			}},
+			{"newer database is rejected", func(t *testing.T) {
+				store.exec(t, `INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)`,
+					9999, "from_the_future", "x")
+				defer store.exec(t, `DELETE FROM schema_migrations WHERE version = ?`, 9999)
+
+				err := store.Migrate(ctx)
+				if !errors.Is(err, ErrSchemaTooNew) {
+					t.Fatalf("Migrate: got %v, want ErrSchemaTooNew", err)
+				}
+				statuses, _ := store.MigrationStatus(ctx)
+				if err := CheckSchema(statuses); !errors.Is(err, ErrSchemaTooNew) {
+					t.Fatalf("CheckSchema: got %v, want ErrSchemaTooNew", err)
+				}
+				if last := statuses[len(statuses)-1]; last.Version != 9999 || last.State != MigrationUnknown {
+					t.Errorf("last status = %+v, want unknown 9999", last)
+				}
+			}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
+
+func TestMigrations_FreshDatabaseIsPending(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	statuses, err := store.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(statuses) != len(sqliteMigrations) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(sqliteMigrations))
+	}
+	for _, st := range statuses {
+		if st.State != MigrationPending {
+			t.Errorf("migration %d: state %q, want pending", st.Version, st.State)
+		}
+	}
+	if err := CheckSchema(statuses); err == nil {
+		t.Error("CheckSchema should fail with pending migrations")
+	}
+	if err := prepareSchema(store, false); err == nil {
+		t.Error("prepareSchema without auto-migrate should refuse a pending schema")
+	}
+	if err := prepareSchema(store, true); err != nil {
+		t.Fatalf("prepareSchema with auto-migrate: %v", err)
+	}
+	if err := prepareSchema(store, false); err != nil {
+		t.Errorf("prepareSchema after migrating: %v", err)
+	}
+}
+
+func TestMigrations_AdoptsLegacySQLiteSchema(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// A pre-migration-framework database: no schema_migrations, no
+	// users.is_admin, and a table from the retired DB-backed RBAC model.
+	for _, stmt := range []string{
+		`CREATE TABLE users (
+			id TEXT PRIMARY KEY, email TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL,
+			name TEXT NOT NULL, is_active INTEGER NOT NULL DEFAULT 1,
+			created_at TEXT NOT NULL, updated_at TEXT NOT NULL)`,
+		`CREATE TABLE roles (id TEXT PRIMARY KEY, name TEXT)`,
+		`INSERT INTO users (id, email, password_hash, name, created_at, updated_at)
+			VALUES ('u1', 'old@test.com', 'h', 'Old', '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z')`,
+	} {
+		if _, err := store.db.Exec(stmt); err != nil {
+			t.Fatalf("seed legacy schema: %v", err)
+		}
+	}
+
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("migrate legacy db: %v", err)
+	}
+
+	u, err := store.GetUserByEmail(ctx, "old@test.com")
+	if err != nil || u == nil {
+		t.Fatalf("legacy user lost: %v", err)
+	}
+	if u.IsAdmin {
+		t.Error("backfilled is_admin should default to false")
+	}
+	if ok, _ := tableExists(ctx, store.db, false, "roles"); ok {
+		t.Error("obsolete roles table should be dropped")
+	}
+	if ok, _ := tableExists(ctx, store.db, false, "audit_logs"); !ok {
+		t.Error("missing tables should be created")
+	}
+	statuses, _ := store.MigrationStatus(ctx)
+	if err := CheckSchema(statuses); err != nil {
+		t.Errorf("CheckSchema after adoption: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package central
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// notifyTimeout bounds a single webhook delivery.
+const notifyTimeout = 5 * time.Second
+
+// BreakglassEvent is the JSON body POSTed to rbac.jit.breakglass.notify_url
+// when a user activates break-glass access.
+type BreakglassEvent struct {
+	Event     string    `json:"event"`
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	Cluster   string    `json:"cluster"`
+	Role      string    `json:"role"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Notifier delivers an event to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, event any) error
+}
+
+// WebhookNotifier POSTs events as JSON to a fixed URL.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that posts to url.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: notifyTimeout}}
+}
+
+// Notify posts event and fails on any non-2xx response.
+func (n *WebhookNotifier) Notify(ctx context.Context, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", n.url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posting to %s: status %d", n.url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,456 @@
+package central
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// errIdPUnavailable is returned when the identity provider cannot be
+	// reached or its discovery document is unusable.
+	errIdPUnavailable = errors.New("identity provider unavailable")
+	// errDevicePending and errDeviceSlowDown report that the user has not yet
+	// approved a device-code login.
+	errDevicePending  = errors.New("authorization_pending")
+	errDeviceSlowDown = errors.New("slow_down")
+	// errDeviceUnsupported is returned when the IdP advertises no device
+	// authorization endpoint.
+	errDeviceUnsupported = errors.New("identity provider does not support device login")
+)
+
+// oidcIdentity is the verified identity of a user signing in via SSO.
+type oidcIdentity struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+// OIDCProvider is central's relying-party view of an OpenID Connect identity
+// provider. Discovery is performed lazily and retried on failure so an IdP
+// outage does not prevent central from starting.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider creates an OIDCProvider for the given configuration.
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *OIDCProvider) clientContext(ctx context.Context) context.Context {
+	return oidc.ClientContext(ctx, p.client)
+}
+
+// discover returns the provider metadata and ID token verifier, fetching the
+// discovery document on first use.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider != nil {
+		return p.provider, p.verifier, nil
+	}
+	provider, err := oidc.NewProvider(p.clientContext(ctx), p.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errIdPUnavailable, err)
+	}
+	p.provider = provider
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.provider, p.verifier, nil
+}
+
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider, redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURI,
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+// AuthCodeURL returns the IdP authorization URL for an auth-code + PKCE login
+// whose code will be delivered to redirectURI.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeChallenge string) (string, error) {
+	provider, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider, redirectURI).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
+}
+
+// Exchange redeems an authorization code and returns the verified identity.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, redirectURI, nonce string) (*oidcIdentity, error) {
+	provider, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := p.oauth2Config(provider, redirectURI).Exchange(p.clientContext(ctx), code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+	return p.identify(ctx, provider, verifier, tok, nonce)
+}
+
+// StartDeviceAuth begins a device-code login.
+func (p *OIDCProvider) StartDeviceAuth(ctx context.Context) (*oauth2.DeviceAuthResponse, error) {
+	provider, _, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Endpoint().DeviceAuthURL == "" {
+		return nil, errDeviceUnsupported
+	}
+	return p.oauth2Config(provider, "").DeviceAuth(p.clientContext(ctx))
+}
+
+// PollDeviceToken makes a single token request for a device-code login. It
+// returns errDevicePending or errDeviceSlowDown while the user has not yet
+// approved the login; the CLI drives the polling loop.
+func (p *OIDCProvider) PollDeviceToken(ctx context.Context, deviceCode string) (*oidcIdentity, error) {
+	provider, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"device_code": {deviceCode},
+		"client_id":   {p.cfg.ClientID},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.Endpoint().TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIdPUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+	switch body.Error {
+	case "":
+	case "authorization_pending":
+		return nil, errDevicePending
+	case "slow_down":
+		return nil, errDeviceSlowDown
+	default:
+		return nil, fmt.Errorf("device login failed: %s", body.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device login failed: token endpoint returned %s", resp.Status)
+	}
+
+	tok := (&oauth2.Token{AccessToken: body.AccessToken, TokenType: body.TokenType}).
+		WithExtra(map[string]any{"id_token": body.IDToken})
+	return p.identify(ctx, provider, verifier, tok, "")
+}
+
+// identify verifies the ID token in tok and extracts the user's identity.
+// Groups fall back to the userinfo endpoint when the ID token omits them.
+func (p *OIDCProvider) identify(ctx context.Context, provider *oidc.Provider, verifier *oidc.IDTokenVerifier, tok *oauth2.Token, nonce string) (*oidcIdentity, error) {
+	rawIDToken, _ := tok.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	idToken, err := verifier.Verify(p.clientContext(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying id_token: %w", err)
+	}
+	if nonce != "" && idToken.Nonce != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decoding id_token claims: %w", err)
+	}
+	if _, ok := claims[p.cfg.GroupsClaim]; !ok && provider.UserInfoEndpoint() != "" && tok.AccessToken != "" {
+		info, err := provider.UserInfo(p.clientContext(ctx), oauth2.StaticTokenSource(tok))
+		if err != nil {
+			return nil, fmt.Errorf("fetching userinfo: %w", err)
+		}
+		extra := map[string]any{}
+		if err := info.Claims(&extra); err == nil {
+			for k, v := range extra {
+				if _, ok := claims[k]; !ok {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, fmt.Errorf("id_token has no email claim")
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, fmt.Errorf("email %s is not verified by the identity provider", email)
+	}
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name = email
+	}
+	return &oidcIdentity{
+		Subject: idToken.Subject,
+		Email:   email,
+		Name:    name,
+		Groups:  stringListClaim(claims[p.cfg.GroupsClaim]),
+	}, nil
+}
+
+// stringListClaim normalises a groups claim, which providers emit either as a
+// JSON array or as a single string.
+func stringListClaim(v any) []string {
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return nil
+		}
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// isLoopbackRedirect reports whether uri is an http URL on the loopback
+// interface, the only redirect target the CLI can receive a code on.
+func isLoopbackRedirect(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+type oidcAuthorizeRequest struct {
+	RedirectURI   string `json:"redirect_uri" binding:"required"`
+	State         string `json:"state" binding:"required"`
+	Nonce         string `json:"nonce" binding:"required"`
+	CodeChallenge string `json:"code_challenge" binding:"required"`
+}
+
+type oidcTokenRequest struct {
+	Code         string `json:"code" binding:"required"`
+	CodeVerifier string `json:"code_verifier" binding:"required"`
+	RedirectURI  string `json:"redirect_uri" binding:"required"`
+	Nonce        string `json:"nonce" binding:"required"`
+}
+
+type oidcDeviceResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in,omitempty"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+type oidcDeviceTokenRequest struct {
+	DeviceCode string `json:"device_code" binding:"required"`
+}
+
+// SetOIDCProvider enables SSO login through the given provider.
+func (h *AuthHandlers) SetOIDCProvider(p *OIDCProvider) {
+	h.oidc = p
+}
+
+// OIDCEnabled reports whether SSO login routes should be served.
+func (h *AuthHandlers) OIDCEnabled() bool {
+	return h.oidc != nil
+}
+
+// HandleOIDCAuthorize returns the IdP authorization URL for a CLI-driven
+// auth-code + PKCE login. The CLI generates state, nonce and the PKCE
+// verifier; central only builds the URL so the client ID and scopes stay
+// server-side.
+func (h *AuthHandlers) HandleOIDCAuthorize(c *gin.Context) {
+	var req oidcAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if !isLoopbackRedirect(req.RedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "redirect_uri must be an http loopback address"})
+		return
+	}
+	authURL, err := h.oidc.AuthCodeURL(c.Request.Context(), req.RedirectURI, req.State, req.Nonce, req.CodeChallenge)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
+}
+
+// HandleOIDCToken redeems an authorization code obtained by the CLI and
+// returns kbridge tokens for the signed-in user.
+func (h *AuthHandlers) HandleOIDCToken(c *gin.Context) {
+	var req oidcTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if !isLoopbackRedirect(req.RedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "redirect_uri must be an http loopback address"})
+		return
+	}
+	ident, err := h.oidc.Exchange(c.Request.Context(), req.Code, req.CodeVerifier, req.RedirectURI, req.Nonce)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+	h.loginSSOUser(c, ident)
+}
+
+// HandleOIDCDeviceStart begins a device-code login for headless clients.
+func (h *AuthHandlers) HandleOIDCDeviceStart(c *gin.Context) {
+	da, err := h.oidc.StartDeviceAuth(c.Request.Context())
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+	resp := oidcDeviceResponse{
+		DeviceCode:              da.DeviceCode,
+		UserCode:                da.UserCode,
+		VerificationURI:         da.VerificationURI,
+		VerificationURIComplete: da.VerificationURIComplete,
+		Interval:                int(da.Interval),
+	}
+	if !da.Expiry.IsZero() {
+		resp.ExpiresIn = int(time.Until(da.Expiry).Seconds())
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// HandleOIDCDeviceToken polls the IdP once for a device-code login. It
+// answers 202 with the pending status until the user approves the login.
+func (h *AuthHandlers) HandleOIDCDeviceToken(c *gin.Context) {
+	var req oidcDeviceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	ident, err := h.oidc.PollDeviceToken(c.Request.Context(), req.DeviceCode)
+	if errors.Is(err, errDevicePending) || errors.Is(err, errDeviceSlowDown) {
+		c.JSON(http.StatusAccepted, gin.H{"status": err.Error()})
+		return
+	}
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+	h.loginSSOUser(c, ident)
+}
+
+// loginSSOUser maps a verified SSO identity onto a kbridge user, creating it
+// on first login when auto-provisioning is enabled, and issues tokens carrying
+// the IdP groups.
+func (h *AuthHandlers) loginSSOUser(c *gin.Context, ident *oidcIdentity) {
+	ctx := c.Request.Context()
+	user, err := h.store.GetUserByEmail(ctx, ident.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if user == nil {
+		if !h.oidc.cfg.AutoProvision {
+			loginFailuresTotal.WithLabelValues("sso", "unknown_user").Inc()
+			c.JSON(http.StatusForbidden, gin.H{"error": "no kbridge account for " + ident.Email})
+			return
+		}
+		// SSO-only users have no password hash, so password login always
+		// fails for them.
+		user = &User{Email: ident.Email, Name: ident.Name, IsActive: true}
+		if err := h.store.CreateUser(ctx, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		log.Printf("Provisioned SSO user %s (subject %s)", user.Email, ident.Subject)
+	}
+	if !user.IsActive {
+		loginFailuresTotal.WithLabelValues("sso", "disabled").Inc()
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is disabled"})
+		return
+	}
+	// The refresh token, and every one rotated from it, expires at the end
+	// of the SSO session, so the IdP groups are dropped with it.
+	lifetime := min(h.oidc.cfg.SessionLifetime, h.refreshExpiry)
+	if lifetime <= 0 {
+		lifetime = h.refreshExpiry
+	}
+	h.issueTokens(c, user, ident.Groups, time.Now().Add(lifetime))
+}
+
+func (h *AuthHandlers) oidcError(c *gin.Context, err error) {
+	log.Printf("SSO login failed: %v", err)
+	if errors.Is(err, errIdPUnavailable) {
+		loginFailuresTotal.WithLabelValues("sso", "idp_unavailable").Inc()
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	if errors.Is(err, errDeviceUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	loginFailuresTotal.WithLabelValues("sso", "failed").Inc()
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "sso login failed"})
+}
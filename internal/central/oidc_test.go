@@ -0,0 +1,460 @@
+package central
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+const fakeClientID = "kbridge-test"
+
+// fakeIssuer is a minimal OpenID provider: discovery, JWKS, an authorization
+// code + PKCE token endpoint and a device authorization endpoint.
+type fakeIssuer struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu sync.Mutex
+	// codes maps an issued authorization code to the PKCE challenge and
+	// nonce it was bound to.
+	codes map[string]fakeAuthCode
+	// claims are merged into every ID token issued.
+	claims map[string]any
+	// devicePolls counts down the polls answered with authorization_pending.
+	devicePolls int
+}
+
+type fakeAuthCode struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{t: t, key: key, codes: map[string]fakeAuthCode{}, claims: map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", f.serveDiscovery)
+	mux.HandleFunc("/keys", f.serveKeys)
+	mux.HandleFunc("/token", f.serveToken)
+	mux.HandleFunc("/device", f.serveDevice)
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// authorize simulates the browser leg: the user signs in and the IdP issues a
+// code bound to the PKCE challenge and nonce from the authorization URL.
+func (f *fakeIssuer) authorize(authURL string) (code, state, redirectURI string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatalf("parse auth url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		f.t.Fatalf("auth url missing PKCE challenge: %s", authURL)
+	}
+	code = "code-" + q.Get("state")
+	f.mu.Lock()
+	f.codes[code] = fakeAuthCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	f.mu.Unlock()
+	return code, q.Get("state"), q.Get("redirect_uri")
+}
+
+func (f *fakeIssuer) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                f.srv.URL,
+		"authorization_endpoint":                f.srv.URL + "/authorize",
+		"token_endpoint":                        f.srv.URL + "/token",
+		"device_authorization_endpoint":         f.srv.URL + "/device",
+		"jwks_uri":                              f.srv.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (f *fakeIssuer) serveKeys(w http.ResponseWriter, r *http.Request) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "test", "alg": "RS256", "use": "sig",
+		"n": b64(f.key.N.Bytes()),
+		"e": b64(big.NewInt(int64(f.key.E)).Bytes()),
+	}}})
+}
+
+func (f *fakeIssuer) serveDevice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"device_code":      "dev-123",
+		"user_code":        "ABCD-EFGH",
+		"verification_uri": f.srv.URL + "/activate",
+		"expires_in":       600,
+		"interval":         1,
+	})
+}
+
+func (f *fakeIssuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var nonce string
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		ac, ok := f.codes[r.Form.Get("code")]
+		delete(f.codes, r.Form.Get("code"))
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != ac.challenge {
+			tokenError(w, "invalid_grant")
+			return
+		}
+		nonce = ac.nonce
+	case deviceCodeGrantType:
+		if r.Form.Get("device_code") != "dev-123" {
+			tokenError(w, "invalid_grant")
+			return
+		}
+		if f.devicePolls > 0 {
+			f.devicePolls--
+			tokenError(w, "authorization_pending")
+			return
+		}
+	default:
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": f.srv.URL,
+		"sub": "user-1",
+		"aud": fakeClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	for k, v := range f.claims {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "test"
+	idToken, err := tok.SignedString(f.key)
+	if err != nil {
+		f.t.Fatalf("sign id token: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "idp-access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+		"expires_in":   3600,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+type oidcTestEnv struct {
+	idp   *fakeIssuer
+	store *SQLiteStore
+	jm    *auth.JWTManager
+	srv   *HTTPServer
+}
+
+func newOIDCTestEnv(t *testing.T, autoProvision bool) *oidcTestEnv {
+	t.Helper()
+	idp := newFakeIssuer(t)
+	idp.claims = map[string]any{
+		"email":          "sso@example.com",
+		"email_verified": true,
+		"name":           "SSO User",
+		"groups":         []string{"platform-oncall", "dev"},
+	}
+	store := newTestStore(t)
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	ah := NewAuthHandlers(store, jm, 24*time.Hour)
+	ah.SetOIDCProvider(NewOIDCProvider(OIDCConfig{
+		Enabled:         true,
+		IssuerURL:       idp.srv.URL,
+		ClientID:        fakeClientID,
+		AutoProvision:   autoProvision,
+		SessionLifetime: time.Hour,
+	}))
+	return &oidcTestEnv{
+		idp:   idp,
+		store: store,
+		jm:    jm,
+		srv:   NewHTTPServer(NewAgentStore(), NewCommandQueue(), ah, nil, nil, nil, nil, jm),
+	}
+}
+
+func (e *oidcTestEnv) post(t *testing.T, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	e.srv.Handler().ServeHTTP(w, req)
+	return w
+}
+
+// browserLogin runs the auth-code + PKCE flow the way `kb login --sso` does,
+// returning the final /auth/oidc/token response.
+func (e *oidcTestEnv) browserLogin(t *testing.T, nonce string) *httptest.ResponseRecorder {
+	t.Helper()
+	const verifier = "test-verifier-0123456789-0123456789-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	w := e.post(t, "/auth/oidc/authorize", map[string]string{
+		"redirect_uri":   "http://127.0.0.1:41234/callback",
+		"state":          "state-1",
+		"nonce":          "nonce-1",
+		"code_challenge": base64.RawURLEncoding.EncodeToString(sum[:]),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("authorize: %d %s", w.Code, w.Body.String())
+	}
+	var ar struct {
+		AuthURL string `json:"auth_url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &ar)
+	code, state, redirectURI := e.idp.authorize(ar.AuthURL)
+	if state != "state-1" || redirectURI != "http://127.0.0.1:41234/callback" {
+		t.Fatalf("auth url lost state/redirect: %s", ar.AuthURL)
+	}
+	return e.post(t, "/auth/oidc/token", map[string]string{
+		"code":          code,
+		"code_verifier": verifier,
+		"redirect_uri":  redirectURI,
+		"nonce":         nonce,
+	})
+}
+
+func (e *oidcTestEnv) claimsFrom(t *testing.T, w *httptest.ResponseRecorder) (*auth.UserClaims, tokenResponse) {
+	t.Helper()
+	var resp tokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode token response: %v", err)
+	}
+	claims, err := e.jm.ValidateAccessToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("validate access token: %v", err)
+	}
+	return claims, resp
+}
+
+func TestOIDC_BrowserLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"provisions user and maps groups", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			w := env.browserLogin(t, "nonce-1")
+			if w.Code != http.StatusOK {
+				t.Fatalf("token: %d %s", w.Code, w.Body.String())
+			}
+			claims, _ := env.claimsFrom(t, w)
+			if claims.Email != "sso@example.com" || claims.Name != "SSO User" {
+				t.Errorf("claims = %+v", claims)
+			}
+			if len(claims.Groups) != 2 || claims.Groups[0] != "platform-oncall" {
+				t.Errorf("groups = %v, want [platform-oncall dev]", claims.Groups)
+			}
+			user, _ := env.store.GetUserByEmail(context.Background(), "sso@example.com")
+			if user == nil || !user.IsActive || user.PasswordHash != "" {
+				t.Fatalf("provisioned user = %+v", user)
+			}
+			if claims.UserID != user.ID {
+				t.Errorf("claims user id %q, want %q", claims.UserID, user.ID)
+			}
+		}},
+		{"refresh keeps groups", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			_, tokens := env.claimsFrom(t, env.browserLogin(t, "nonce-1"))
+			w := env.post(t, "/auth/refresh", map[string]string{"refresh_token": tokens.RefreshToken})
+			if w.Code != http.StatusOK {
+				t.Fatalf("refresh: %d %s", w.Code, w.Body.String())
+			}
+			claims, _ := env.claimsFrom(t, w)
+			if len(claims.Groups) != 2 {
+				t.Errorf("groups after refresh = %v", claims.Groups)
+			}
+		}},
+		{"refresh does not outlive the SSO session", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			ctx := context.Background()
+			_, tokens := env.claimsFrom(t, env.browserLogin(t, "nonce-1"))
+			login, _ := env.store.GetRefreshTokenByHash(ctx, hashToken(tokens.RefreshToken))
+			if login == nil || time.Until(login.ExpiresAt) > time.Hour {
+				t.Fatalf("SSO refresh token = %+v, want it to expire within the 1h session", login)
+			}
+			w := env.post(t, "/auth/refresh", map[string]string{"refresh_token": tokens.RefreshToken})
+			if w.Code != http.StatusOK {
+				t.Fatalf("refresh: %d %s", w.Code, w.Body.String())
+			}
+			_, tokens = env.claimsFrom(t, w)
+			rotated, _ := env.store.GetRefreshTokenByHash(ctx, hashToken(tokens.RefreshToken))
+			if rotated == nil || !rotated.ExpiresAt.Equal(login.ExpiresAt) {
+				t.Fatalf("rotated token = %+v, want the login's expiry %v", rotated, login.ExpiresAt)
+			}
+
+			// Once the session is over the groups are gone: refreshing fails
+			// and the user has to sign in with the IdP again.
+			if _, err := env.store.db.Exec(`UPDATE refresh_tokens SET expires_at = ?`,
+				time.Now().Add(-time.Minute).UTC().Format(timeFormat)); err != nil {
+				t.Fatalf("expire session: %v", err)
+			}
+			w = env.post(t, "/auth/refresh", map[string]string{"refresh_token": tokens.RefreshToken})
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("refresh after the session: %d %s, want 401", w.Code, w.Body.String())
+			}
+		}},
+		{"existing user is reused", func(t *testing.T) {
+			env := newOIDCTestEnv(t, false)
+			existing := &User{Email: "sso@example.com", Name: "Existing", IsActive: true, IsAdmin: true, Groups: []string{"dev", "kb-admins"}}
+			env.store.CreateUser(context.Background(), existing)
+			w := env.browserLogin(t, "nonce-1")
+			if w.Code != http.StatusOK {
+				t.Fatalf("token: %d %s", w.Code, w.Body.String())
+			}
+			claims, _ := env.claimsFrom(t, w)
+			if claims.UserID != existing.ID || !claims.IsAdmin {
+				t.Errorf("claims = %+v, want existing admin user", claims)
+			}
+			// kbridge-managed groups first, then IdP groups, without duplicates.
+			if got := strings.Join(claims.Groups, ","); got != "dev,kb-admins,platform-oncall" {
+				t.Errorf("groups = %s, want dev,kb-admins,platform-oncall", got)
+			}
+		}},
+		{"unknown user without auto-provision is forbidden", func(t *testing.T) {
+			env := newOIDCTestEnv(t, false)
+			if w := env.browserLogin(t, "nonce-1"); w.Code != http.StatusForbidden {
+				t.Fatalf("got %d, want 403: %s", w.Code, w.Body.String())
+			}
+		}},
+		{"disabled user is forbidden", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			env.store.CreateUser(context.Background(), &User{Email: "sso@example.com", Name: "Off"})
+			if w := env.browserLogin(t, "nonce-1"); w.Code != http.StatusForbidden {
+				t.Fatalf("got %d, want 403: %s", w.Code, w.Body.String())
+			}
+		}},
+		{"nonce mismatch is rejected", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			if w := env.browserLogin(t, "other-nonce"); w.Code != http.StatusUnauthorized {
+				t.Fatalf("got %d, want 401: %s", w.Code, w.Body.String())
+			}
+		}},
+		{"unverified email is rejected", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			env.idp.claims["email_verified"] = false
+			if w := env.browserLogin(t, "nonce-1"); w.Code != http.StatusUnauthorized {
+				t.Fatalf("got %d, want 401: %s", w.Code, w.Body.String())
+			}
+		}},
+		{"non-loopback redirect is rejected", func(t *testing.T) {
+			env := newOIDCTestEnv(t, true)
+			w := env.post(t, "/auth/oidc/authorize", map[string]string{
+				"redirect_uri":   "https://evil.example.com/callback",
+				"state":          "s",
+				"nonce":          "n",
+				"code_challenge": "c",
+			})
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("got %d, want 400", w.Code)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
+
+func TestOIDC_DeviceLogin(t *testing.T) {
+	env := newOIDCTestEnv(t, true)
+	env.idp.devicePolls = 1
+	env.idp.claims["groups"] = "sre"
+
+	w := env.post(t, "/auth/oidc/device", struct{}{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("device start: %d %s", w.Code, w.Body.String())
+	}
+	var dr oidcDeviceResponse
+	json.Unmarshal(w.Body.Bytes(), &dr)
+	if dr.DeviceCode != "dev-123" || dr.UserCode != "ABCD-EFGH" || dr.Interval != 1 {
+		t.Fatalf("device response = %+v", dr)
+	}
+
+	w = env.post(t, "/auth/oidc/device/token", map[string]string{"device_code": dr.DeviceCode})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("first poll: got %d, want 202: %s", w.Code, w.Body.String())
+	}
+	w = env.post(t, "/auth/oidc/device/token", map[string]string{"device_code": dr.DeviceCode})
+	if w.Code != http.StatusOK {
+		t.Fatalf("second poll: %d %s", w.Code, w.Body.String())
+	}
+	claims, _ := env.claimsFrom(t, w)
+	if len(claims.Groups) != 1 || claims.Groups[0] != "sre" {
+		t.Errorf("groups = %v, want [sre]", claims.Groups)
+	}
+
+	w = env.post(t, "/auth/oidc/device/token", map[string]string{"device_code": "bogus"})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus device code: got %d, want 401", w.Code)
+	}
+}
+
+func TestOIDC_RoutesDisabled(t *testing.T) {
+	store := newTestStore(t)
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	srv := NewHTTPServer(NewAgentStore(), NewCommandQueue(), NewAuthHandlers(store, jm, time.Hour), nil, nil, nil, nil, jm)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/oidc/authorize", bytes.NewReader([]byte(`{}`)))
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 when SSO is not configured", w.Code)
+	}
+}
+
+func TestIsLoopbackRedirect(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"http://127.0.0.1:8000/callback", true},
+		{"http://localhost:8000/callback", true},
+		{"http://[::1]:8000/callback", true},
+		{"https://127.0.0.1/callback", false},
+		{"http://10.0.0.1/callback", false},
+		{"http://example.com/callback", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopbackRedirect(tt.uri); got != tt.want {
+			t.Errorf("isLoopbackRedirect(%q) = %v, want %v", tt.uri, got, tt.want)
+		}
+	}
+}
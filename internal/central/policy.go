@@ -1,25 +1,46 @@
 package central
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
 )
 
-// PolicyRule grants a set of verbs on resources within clusters/namespaces.
-// Each field is a list of glob patterns ('*' wildcard); a request must match at
-// least one entry in every field.
+// Rule effects. An empty effect is treated as RuleEffectAllow.
+const (
+	RuleEffectAllow = "allow"
+	RuleEffectDeny  = "deny"
+)
+
+// PolicyRule grants (or, with effect "deny", forbids) a set of verbs on
+// resources within clusters/namespaces. Each field is a list of glob patterns
+// ('*' wildcard); a request must match at least one entry in every field.
+// Names optionally narrows the rule to specific objects; see matchesNames.
+// RequireApproval makes an allow rule hold matching commands until a second
+// user approves them.
 type PolicyRule struct {
-	Clusters   []string `yaml:"clusters"`
-	Namespaces []string `yaml:"namespaces"`
-	Resources  []string `yaml:"resources"`
-	Verbs      []string `yaml:"verbs"`
+	Effect          string   `yaml:"effect,omitempty"`
+	Clusters        []string `yaml:"clusters"`
+	Namespaces      []string `yaml:"namespaces"`
+	Resources       []string `yaml:"resources"`
+	Names           []string `yaml:"names,omitempty"`
+	Verbs           []string `yaml:"verbs"`
+	RequireApproval bool     `yaml:"require_approval,omitempty"`
+}
+
+// denies reports whether the rule is a deny rule.
+func (r PolicyRule) denies() bool {
+	return r.Effect == RuleEffectDeny
 }
 
 // PolicyRole is a named collection of rules.
@@ -28,13 +49,22 @@ type PolicyRole struct {
 	Rules []PolicyRule `yaml:"rules"`
 }
 
-// PolicyBinding maps a subject (matched against the JWT email, may contain
-// wildcards) to one or more role names.
+// PolicyBinding maps a subject to one or more role names. A plain subject (or
+// one prefixed "user:") is matched against the JWT email; "group:<name>"
+// matches any of the user's groups. Both may contain wildcards.
 type PolicyBinding struct {
 	Subject string   `yaml:"subject"`
 	Roles   []string `yaml:"roles"`
 }
 
+// matches reports whether the binding applies to s.
+func (b PolicyBinding) matches(s Subject) bool {
+	if group, ok := strings.CutPrefix(b.Subject, groupSubjectPrefix); ok {
+		return anyGroupMatch(group, s.Groups)
+	}
+	return matchPattern(strings.TrimPrefix(b.Subject, "user:"), s.Email)
+}
+
 // Policy is a parsed RBAC policy document.
 type Policy struct {
 	Default  string          `yaml:"default"`
@@ -42,46 +72,146 @@ type Policy struct {
 	Bindings []PolicyBinding `yaml:"bindings"`
 }
 
-// allows reports whether the rule grants the requested access.
-func (r PolicyRule) allows(req AccessRequest) bool {
+// matches reports whether the rule applies to the requested access,
+// regardless of its effect. A "*" namespace or resource in the request (all
+// namespaces, or objects read from a manifest) only matches an allow rule
+// that covers everything, but matches any deny rule, since the command may
+// touch the denied objects.
+func (r PolicyRule) matches(req AccessRequest) bool {
+	match := anyMatch
+	if r.denies() {
+		match = anyMatchOrWildcard
+	}
 	return anyMatch(r.Clusters, req.Cluster) &&
-		anyMatch(r.Namespaces, req.Namespace) &&
-		anyMatch(r.Resources, req.Resource) &&
-		anyVerb(r.Verbs, req.Verb)
+		match(r.Namespaces, req.Namespace) &&
+		match(r.Resources, req.Resource) &&
+		anyVerb(r.Verbs, req.Verb) &&
+		r.matchesNames(req.Names)
 }
 
-// rolesFor returns the role names that apply to subject: every binding whose
-// subject pattern matches, plus the default role when set.
-func (p *Policy) rolesFor(subject string) []string {
-	var roles []string
+// matchesNames applies the rule's name scope. A rule without names matches
+// any request. An allow rule with names only matches requests that name
+// objects, all of which must match; it never grants collection-wide access. A
+// deny rule with names matches when any named object matches, and also when
+// the request names none, since it then acts on every object.
+func (r PolicyRule) matchesNames(names []string) bool {
+	if len(r.Names) == 0 {
+		return true
+	}
+	if len(names) == 0 {
+		return r.denies()
+	}
+	if r.denies() {
+		for _, n := range names {
+			if anyMatch(r.Names, n) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range names {
+		if !anyMatch(r.Names, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoleGrant records that a role applies to a subject and why: Source is the
+// binding subject that matched, "default" for the default role, or
+// "jit:<request-id>" for a just-in-time grant, which is limited to Cluster
+// until ExpiresAt.
+type RoleGrant struct {
+	Role      string     `json:"role"`
+	Source    string     `json:"source"`
+	Cluster   string     `json:"cluster,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Decision is the outcome of evaluating one AccessRequest, with the rule that
+// decided it. Role is empty (and Rule zero) when no rule matched.
+type Decision struct {
+	Allowed         bool   `json:"allowed"`
+	Role            string `json:"role,omitempty"`
+	Rule            int    `json:"rule,omitempty"` // 1-based index within the role's rules
+	Effect          string `json:"effect,omitempty"`
+	RequireApproval bool   `json:"require_approval,omitempty"`
+}
+
+// grants returns the roles that apply to subject, in binding order followed by
+// the default role. A role bound more than once is reported once, for its
+// first matching binding.
+func (p *Policy) grants(subject Subject) []RoleGrant {
+	var out []RoleGrant
+	seen := make(map[string]bool)
+	add := func(role, source string) {
+		if !seen[role] {
+			seen[role] = true
+			out = append(out, RoleGrant{Role: role, Source: source})
+		}
+	}
 	for _, b := range p.Bindings {
-		if matchPattern(b.Subject, subject) {
-			roles = append(roles, b.Roles...)
+		if b.matches(subject) {
+			for _, r := range b.Roles {
+				add(r, b.Subject)
+			}
 		}
 	}
 	if p.Default != "" {
-		roles = append(roles, p.Default)
+		add(p.Default, "default")
+	}
+	return out
+}
+
+// rolesFor returns the role names that apply to subject: every binding whose
+// user or group subject matches, plus the default role when set.
+func (p *Policy) rolesFor(subject Subject) []string {
+	grants := p.grants(subject)
+	roles := make([]string, len(grants))
+	for i, g := range grants {
+		roles[i] = g.Role
 	}
 	return roles
 }
 
-// allows reports whether subject may perform req under this policy.
-func (p *Policy) allows(subject string, req AccessRequest) bool {
+// decide evaluates req for subject. Every rule of every active role is
+// considered: a matching deny rule always wins, otherwise the first matching
+// allow rule grants access.
+func (p *Policy) decide(subject Subject, req AccessRequest) Decision {
+	return p.decideRoles(p.rolesFor(subject), req)
+}
+
+// decideRoles evaluates req against the named roles, as decide does.
+func (p *Policy) decideRoles(roles []string, req AccessRequest) Decision {
 	active := make(map[string]bool)
-	for _, name := range p.rolesFor(subject) {
+	for _, name := range roles {
 		active[name] = true
 	}
+	var d Decision
 	for _, role := range p.Roles {
 		if !active[role.Name] {
 			continue
 		}
-		for _, rule := range role.Rules {
-			if rule.allows(req) {
-				return true
+		for i, rule := range role.Rules {
+			if !rule.matches(req) {
+				continue
+			}
+			if rule.denies() {
+				return Decision{Role: role.Name, Rule: i + 1, Effect: RuleEffectDeny}
+			}
+			// The first allow decides, unless a later matching allow requires
+			// approval: like deny, approval is never bypassed by a broader rule.
+			if !d.Allowed || (rule.RequireApproval && !d.RequireApproval) {
+				d = Decision{Allowed: true, Role: role.Name, Rule: i + 1, Effect: RuleEffectAllow, RequireApproval: rule.RequireApproval}
 			}
 		}
 	}
-	return false
+	return d
+}
+
+// allows reports whether subject may perform req under this policy.
+func (p *Policy) allows(subject Subject, req AccessRequest) bool {
+	return p.decide(subject, req).Allowed
 }
 
 // ParsePolicy parses and validates a YAML policy document.
@@ -96,7 +226,8 @@ func ParsePolicy(data []byte) (*Policy, error) {
 	return &p, nil
 }
 
-// validate checks that role names are unique and that all referenced roles
+// validate checks that role names are unique, that rule effects are known,
+// that binding subjects are well-formed, and that all referenced roles
 // (bindings and default) are defined.
 func (p *Policy) validate() error {
 	defined := make(map[string]bool, len(p.Roles))
@@ -108,8 +239,24 @@ func (p *Policy) validate() error {
 			return fmt.Errorf("duplicate policy role %q", r.Name)
 		}
 		defined[r.Name] = true
+		for i, rule := range r.Rules {
+			switch rule.Effect {
+			case "", RuleEffectAllow, RuleEffectDeny:
+			default:
+				return fmt.Errorf("role %q rule %d: unknown effect %q (want allow or deny)", r.Name, i+1, rule.Effect)
+			}
+			if rule.denies() && rule.RequireApproval {
+				return fmt.Errorf("role %q rule %d: require_approval has no effect on a deny rule", r.Name, i+1)
+			}
+		}
 	}
 	for _, b := range p.Bindings {
+		switch b.Subject {
+		case "":
+			return fmt.Errorf("policy binding missing subject")
+		case groupSubjectPrefix, "user:":
+			return fmt.Errorf("policy binding subject %q has no name", b.Subject)
+		}
 		for _, name := range b.Roles {
 			if !defined[name] {
 				return fmt.Errorf("binding for %q references unknown role %q", b.Subject, name)
@@ -122,6 +269,16 @@ func (p *Policy) validate() error {
 	return nil
 }
 
+// anyGroupMatch reports whether any of groups matches pattern.
+func anyGroupMatch(pattern string, groups []string) bool {
+	for _, g := range groups {
+		if matchPattern(pattern, g) {
+			return true
+		}
+	}
+	return false
+}
+
 func anyMatch(patterns []string, value string) bool {
 	for _, p := range patterns {
 		if matchPattern(p, value) {
@@ -131,9 +288,24 @@ func anyMatch(patterns []string, value string) bool {
 	return false
 }
 
+// anyMatchOrWildcard is anyMatch, except that a "*" value matches any
+// non-empty pattern list.
+func anyMatchOrWildcard(patterns []string, value string) bool {
+	if value == "*" && len(patterns) > 0 {
+		return true
+	}
+	return anyMatch(patterns, value)
+}
+
+// anyVerb reports whether verb is listed in verbs. A listed command also
+// covers its subcommands: "rollout" matches "rollout restart".
 func anyVerb(verbs []string, verb string) bool {
 	for _, v := range verbs {
-		if v == "*" || strings.EqualFold(strings.TrimSpace(v), verb) {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.EqualFold(v, verb) {
+			return true
+		}
+		if len(verb) > len(v) && verb[len(v)] == ' ' && strings.EqualFold(verb[:len(v)], v) {
 			return true
 		}
 	}
@@ -141,9 +313,18 @@ func anyVerb(verbs []string, verb string) bool {
 }
 
 // PolicyEngine holds the active policy and supports lock-free hot-swapping.
+// The policy comes either from a file (path) or from versioned documents in
+// the database (store); see NewPolicyEngineFromStore. Temporary grants from
+// approved JIT requests are layered on top of whichever policy is active.
 type PolicyEngine struct {
-	current atomic.Pointer[Policy]
-	path    string
+	current   atomic.Pointer[Policy]
+	path      string
+	temporary atomic.Pointer[[]*JITRequest]
+
+	store        Store
+	pollInterval time.Duration
+	loaded       atomic.Pointer[PolicyVersion] // version behind current; database source only
+	writeMu      sync.Mutex                    // serializes Apply on this replica
 }
 
 // NewPolicyEngineFromFile loads a policy from path into a new engine.
@@ -166,13 +347,89 @@ func loadPolicyFile(path string) (*Policy, error) {
 }
 
 // Allows reports whether subject may perform req under the current policy.
-func (e *PolicyEngine) Allows(subject string, req AccessRequest) bool {
-	return e.current.Load().allows(subject, req)
+func (e *PolicyEngine) Allows(subject Subject, req AccessRequest) bool {
+	return e.Decide(subject, req).Allowed
+}
+
+// Decide evaluates req for subject under the current policy, including any
+// unexpired JIT grant for req's cluster, and reports the deciding rule.
+func (e *PolicyEngine) Decide(subject Subject, req AccessRequest) Decision {
+	p := e.current.Load()
+	roles := p.rolesFor(subject)
+	for _, g := range e.temporaryGrants(subject, time.Now()) {
+		if g.Cluster == req.Cluster {
+			roles = append(roles, g.Role)
+		}
+	}
+	return p.decideRoles(roles, req)
+}
+
+// Grants returns the roles that apply to subject under the current policy,
+// followed by its unexpired JIT grants.
+func (e *PolicyEngine) Grants(subject Subject) []RoleGrant {
+	return append(e.current.Load().grants(subject), e.temporaryGrants(subject, time.Now())...)
+}
+
+// HasRole reports whether the current policy defines role.
+func (e *PolicyEngine) HasRole(role string) bool {
+	return slices.ContainsFunc(e.current.Load().Roles, func(r PolicyRole) bool { return r.Name == role })
 }
 
-// Reload re-reads the policy file and atomically swaps it in. On error the
-// current policy is left unchanged.
+// SetTemporaryGrants replaces the approved JIT requests layered on the policy.
+// Expired requests are ignored at evaluation time, so a grant lapses on time
+// even if the set is not refreshed.
+func (e *PolicyEngine) SetTemporaryGrants(reqs []*JITRequest) {
+	e.temporary.Store(&reqs)
+}
+
+// BreakglassActive reports whether subject has an unexpired break-glass grant
+// on cluster.
+func (e *PolicyEngine) BreakglassActive(subject Subject, cluster string) bool {
+	for _, r := range e.activeRequests(subject, time.Now()) {
+		if r.Breakglass && r.ClusterName == cluster {
+			return true
+		}
+	}
+	return false
+}
+
+// temporaryGrants returns subject's JIT grants that are active at now.
+func (e *PolicyEngine) temporaryGrants(subject Subject, now time.Time) []RoleGrant {
+	var out []RoleGrant
+	for _, r := range e.activeRequests(subject, now) {
+		source := "jit:" + r.ID
+		if r.Breakglass {
+			source = "breakglass:" + r.ID
+		}
+		out = append(out, RoleGrant{Role: r.Role, Source: source, Cluster: r.ClusterName, ExpiresAt: r.ExpiresAt})
+	}
+	return out
+}
+
+// activeRequests returns subject's approved JIT requests that are active at now.
+func (e *PolicyEngine) activeRequests(subject Subject, now time.Time) []*JITRequest {
+	reqs := e.temporary.Load()
+	if reqs == nil {
+		return nil
+	}
+	var out []*JITRequest
+	for _, r := range *reqs {
+		if r.Active(now) && strings.EqualFold(r.UserEmail, subject.Email) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Reload re-reads the policy file, or the latest version from the database,
+// and atomically swaps it in. On error the current policy is left unchanged.
 func (e *PolicyEngine) Reload() error {
+	if e.store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), policyStoreTimeout)
+		defer cancel()
+		_, err := e.reloadFromStore(ctx)
+		return err
+	}
 	policy, err := loadPolicyFile(e.path)
 	if err != nil {
 		return err
@@ -184,8 +441,12 @@ func (e *PolicyEngine) Reload() error {
 // Watch reloads the policy whenever its file changes, until stop is closed.
 // It watches the containing directory so atomic editor rename/replace writes
 // are still observed. Reload failures are logged and the previous policy is
-// kept.
+// kept. A database-backed engine polls for new versions instead.
 func (e *PolicyEngine) Watch(stop <-chan struct{}) {
+	if e.store != nil {
+		go e.poll(stop)
+		return
+	}
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Printf("rbac watch disabled: %v", err)
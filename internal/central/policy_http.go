@@ -0,0 +1,152 @@
+package central
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+// policyResponse describes the active policy.
+type policyResponse struct {
+	Source   string `json:"source"`
+	Version  int    `json:"version"`
+	Document string `json:"document"`
+}
+
+// updatePolicyRequest submits a new policy document. BaseVersion, when set,
+// must be the current version or the update is rejected with 409, so two
+// admins editing the same version cannot silently overwrite each other.
+type updatePolicyRequest struct {
+	Document    string `json:"document" binding:"required"`
+	Comment     string `json:"comment"`
+	BaseVersion int    `json:"base_version"`
+}
+
+type rollbackPolicyRequest struct {
+	Version int    `json:"version" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// handleGetPolicy returns the active policy document and its version.
+func (s *HTTPServer) handleGetPolicy(c *gin.Context) {
+	doc, err := s.policy.Document()
+	if err != nil {
+		log.Printf("get policy: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, policyResponse{Source: s.policy.Source(), Version: s.policy.Version(), Document: doc})
+}
+
+// handleUpdatePolicy validates a policy document and activates it as a new
+// version.
+func (s *HTTPServer) handleUpdatePolicy(c *gin.Context) {
+	var req updatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	pv, err := s.policy.Apply(c.Request.Context(), req.Document, policyAuthor(c), req.Comment, req.BaseVersion)
+	if err != nil {
+		s.writePolicyError(c, err)
+		return
+	}
+	s.recordPolicyAudit(c, fmt.Sprintf("policy update to version %d", pv.Version))
+	c.JSON(http.StatusOK, pv)
+}
+
+// handleRollbackPolicy re-activates an earlier version's document as a new
+// version.
+func (s *HTTPServer) handleRollbackPolicy(c *gin.Context) {
+	var req rollbackPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	pv, err := s.policy.Rollback(c.Request.Context(), req.Version, policyAuthor(c), req.Comment)
+	if err != nil {
+		s.writePolicyError(c, err)
+		return
+	}
+	s.recordPolicyAudit(c, fmt.Sprintf("policy rollback to version %d (now version %d)", req.Version, pv.Version))
+	c.JSON(http.StatusOK, pv)
+}
+
+// handleListPolicyVersions returns the version history, newest first.
+func (s *HTTPServer) handleListPolicyVersions(c *gin.Context) {
+	versions, err := s.policy.History(c.Request.Context())
+	if err != nil {
+		s.writePolicyError(c, err)
+		return
+	}
+	if versions == nil {
+		versions = []*PolicyVersion{}
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// handleGetPolicyVersion returns a single version including its document.
+func (s *HTTPServer) handleGetPolicyVersion(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+	pv, err := s.policy.GetVersion(c.Request.Context(), version)
+	if err != nil {
+		s.writePolicyError(c, err)
+		return
+	}
+	if pv == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrPolicyVersionNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pv)
+}
+
+// writePolicyError maps PolicyEngine errors to HTTP responses.
+func (s *HTTPServer) writePolicyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrPolicyVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrPolicyConflict), errors.Is(err, ErrPolicyReadOnly):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("policy: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
+
+// policyAuthor returns the email recorded as the author of a policy change.
+func policyAuthor(c *gin.Context) string {
+	if claims := auth.GetUserFromContext(c); claims != nil {
+		return claims.Email
+	}
+	return "unknown"
+}
+
+// recordPolicyAudit writes an audit entry for a policy change. Policy changes
+// are not tied to a cluster, so ClusterName is empty.
+func (s *HTTPServer) recordPolicyAudit(c *gin.Context, command string) {
+	log.Printf("rbac: %s by %s", command, policyAuthor(c))
+	if s.audit == nil {
+		return
+	}
+	entry := &AuditLog{
+		Command:  command,
+		Status:   AuditStatusPolicyChange,
+		ClientIP: c.ClientIP(),
+	}
+	if claims := auth.GetUserFromContext(c); claims != nil {
+		entry.UserID = claims.UserID
+		entry.UserEmail = claims.Email
+	}
+	s.audit.Record(entry)
+}
@@ -0,0 +1,120 @@
+package central
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/why-xn/kbridge/internal/auth"
+)
+
+func TestPolicyAdminAPI(t *testing.T) {
+	store := newTestStore(t)
+	jm := auth.NewJWTManager("test-secret-at-least-32-chars!!", time.Hour)
+	eng, err := NewPolicyEngineFromStore(context.Background(), store, "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewHTTPServer(NewAgentStore(), NewCommandQueue(), NewAuthHandlers(store, jm, time.Hour),
+		NewAdminHandlers(store, testPepper), eng, NewAuditRecorder(store), nil, jm)
+
+	// Audit entries reference the user, so the admin must exist.
+	adminUser := &User{Email: "admin@x.com", Name: "Admin", PasswordHash: "x", IsActive: true, IsAdmin: true}
+	if err := store.CreateUser(context.Background(), adminUser); err != nil {
+		t.Fatal(err)
+	}
+	admin, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: adminUser.ID, Email: adminUser.Email, IsAdmin: true})
+	user, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u2", Email: "dev@x.com"})
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"non-admin is rejected", func(t *testing.T) {
+			w := authzRequest(t, srv, user, "PUT", "/api/v1/admin/policy", updatePolicyRequest{Document: policyV1})
+			if w.Code != http.StatusForbidden {
+				t.Errorf("code = %d, want 403", w.Code)
+			}
+		}},
+		{"invalid document is a 400", func(t *testing.T) {
+			w := authzRequest(t, srv, admin, "PUT", "/api/v1/admin/policy", updatePolicyRequest{Document: "roles: [\n"})
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("code = %d, want 400: %s", w.Code, w.Body.String())
+			}
+		}},
+		{"update, get and audit", func(t *testing.T) {
+			w := authzRequest(t, srv, admin, "PUT", "/api/v1/admin/policy", updatePolicyRequest{Document: policyV1, Comment: "initial"})
+			if w.Code != http.StatusOK {
+				t.Fatalf("code = %d: %s", w.Code, w.Body.String())
+			}
+			w = authzRequest(t, srv, admin, "GET", "/api/v1/admin/policy", nil)
+			var got policyResponse
+			json.Unmarshal(w.Body.Bytes(), &got)
+			if got.Version != 1 || got.Source != RBACSourceDatabase || got.Document != policyV1 {
+				t.Errorf("policy = %+v", got)
+			}
+			logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{Status: AuditStatusPolicyChange})
+			if len(logs) != 1 || logs[0].UserEmail != "admin@x.com" || logs[0].Command != "policy update to version 1" {
+				t.Errorf("audit = %+v", logs)
+			}
+		}},
+		{"stale base version is a 409", func(t *testing.T) {
+			authzRequest(t, srv, admin, "PUT", "/api/v1/admin/policy", updatePolicyRequest{Document: policyV2})
+			w := authzRequest(t, srv, admin, "PUT", "/api/v1/admin/policy", updatePolicyRequest{Document: policyV1, BaseVersion: 1})
+			if w.Code != http.StatusConflict {
+				t.Errorf("code = %d, want 409", w.Code)
+			}
+		}},
+		{"history and single version", func(t *testing.T) {
+			w := authzRequest(t, srv, admin, "GET", "/api/v1/admin/policy/versions", nil)
+			var list struct {
+				Versions []PolicyVersion `json:"versions"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &list)
+			if len(list.Versions) != 2 || list.Versions[0].Version != 2 {
+				t.Errorf("versions = %+v", list.Versions)
+			}
+			w = authzRequest(t, srv, admin, "GET", "/api/v1/admin/policy/versions/1", nil)
+			var pv PolicyVersion
+			json.Unmarshal(w.Body.Bytes(), &pv)
+			if pv.Document != policyV1 || pv.Comment != "initial" {
+				t.Errorf("version 1 = %+v", pv)
+			}
+			if w := authzRequest(t, srv, admin, "GET", "/api/v1/admin/policy/versions/9", nil); w.Code != http.StatusNotFound {
+				t.Errorf("missing version code = %d, want 404", w.Code)
+			}
+		}},
+		{"rollback", func(t *testing.T) {
+			w := authzRequest(t, srv, admin, "POST", "/api/v1/admin/policy/rollback", rollbackPolicyRequest{Version: 1})
+			if w.Code != http.StatusOK || eng.Version() != 3 {
+				t.Fatalf("code = %d version = %d: %s", w.Code, eng.Version(), w.Body.String())
+			}
+			logs, _, _ := store.ListAuditLogs(context.Background(), AuditLogFilter{Status: AuditStatusPolicyChange})
+			found := false
+			for _, l := range logs {
+				found = found || l.Command == "policy rollback to version 1 (now version 3)"
+			}
+			if len(logs) != 3 || !found {
+				t.Errorf("audit = %+v, want a rollback entry", logs)
+			}
+			w = authzRequest(t, srv, admin, "POST", "/api/v1/admin/policy/rollback", rollbackPolicyRequest{Version: 9})
+			if w.Code != http.StatusNotFound {
+				t.Errorf("unknown version code = %d, want 404", w.Code)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
+
+func TestPolicyAdminAPI_FileSourceIsReadOnly(t *testing.T) {
+	srv, jm := newRBACTestServer(t, policyV1)
+	admin, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "admin@x.com", IsAdmin: true})
+	w := authzRequest(t, srv, admin, "PUT", "/api/v1/admin/policy", updatePolicyRequest{Document: policyV2})
+	if w.Code != http.StatusConflict {
+		t.Errorf("code = %d, want 409", w.Code)
+	}
+}
@@ -34,6 +34,11 @@ bindings:
     roles: ["developer"]
 `
 
+// access builds an AccessRequest, optionally naming target objects.
+func access(cluster, namespace, resource, verb string, names ...string) AccessRequest {
+	return AccessRequest{Cluster: cluster, Namespace: namespace, Resource: resource, Verb: verb, Names: names}
+}
+
 func mustParse(t *testing.T, data string) *Policy {
 	t.Helper()
 	p, err := ParsePolicy([]byte(data))
@@ -96,22 +101,349 @@ func TestPolicy_Allows(t *testing.T) {
 		req     AccessRequest
 		want    bool
 	}{
-		{"admin can delete anywhere", "alice@corp.com", AccessRequest{"prod-1", "kube-system", "pods", "delete"}, true},
-		{"dev wildcard subject gets developer", "carol@dev.corp.com", AccessRequest{"dev-2", "default", "pods", "logs"}, true},
-		{"developer denied on prod", "carol@dev.corp.com", AccessRequest{"prod-1", "default", "pods", "logs"}, false},
-		{"developer denied delete verb", "carol@dev.corp.com", AccessRequest{"dev-2", "default", "pods", "delete"}, false},
-		{"unbound user falls back to viewer (read allowed)", "stranger@x.com", AccessRequest{"prod-1", "default", "pods", "get"}, true},
-		{"unbound user denied writes via viewer default", "stranger@x.com", AccessRequest{"prod-1", "default", "pods", "delete"}, false},
+		{"admin can delete anywhere", "alice@corp.com", access("prod-1", "kube-system", "pods", "delete"), true},
+		{"dev wildcard subject gets developer", "carol@dev.corp.com", access("dev-2", "default", "pods", "logs"), true},
+		{"developer denied on prod", "carol@dev.corp.com", access("prod-1", "default", "pods", "logs"), false},
+		{"developer denied delete verb", "carol@dev.corp.com", access("dev-2", "default", "pods", "delete"), false},
+		{"unbound user falls back to viewer (read allowed)", "stranger@x.com", access("prod-1", "default", "pods", "get"), true},
+		{"unbound user denied writes via viewer default", "stranger@x.com", access("prod-1", "default", "pods", "delete"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.allows(Subject{Email: tt.subject}, tt.req); got != tt.want {
+				t.Errorf("allows(%q, %+v) = %v, want %v", tt.subject, tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+const groupPolicy = `
+roles:
+  - name: oncall
+    rules:
+      - clusters: ["prod-*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+bindings:
+  - subject: group:platform-oncall
+    roles: ["oncall"]
+  - subject: "group:team-*"
+    roles: ["viewer"]
+  - subject: user:bob@corp.com
+    roles: ["viewer"]
+`
+
+func TestPolicy_GroupSubjects(t *testing.T) {
+	p := mustParse(t, groupPolicy)
+	del := access("prod-1", "default", "pods", "delete")
+	get := access("dev-1", "default", "pods", "get")
+
+	tests := []struct {
+		name    string
+		subject Subject
+		req     AccessRequest
+		want    bool
+	}{
+		{"group member gets group role", Subject{Email: "a@corp.com", Groups: []string{"dev", "platform-oncall"}}, del, true},
+		{"non-member denied", Subject{Email: "a@corp.com", Groups: []string{"dev"}}, del, false},
+		{"group wildcard", Subject{Email: "a@corp.com", Groups: []string{"team-payments"}}, get, true},
+		{"group binding does not match email", Subject{Email: "platform-oncall"}, del, false},
+		{"user prefix matches email", Subject{Email: "bob@corp.com"}, get, true},
+		{"no groups, no binding", Subject{Email: "c@corp.com"}, get, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if got := p.allows(tt.subject, tt.req); got != tt.want {
+				t.Errorf("allows(%+v, %+v) = %v, want %v", tt.subject, tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicy_RejectsEmptyGroupSubject(t *testing.T) {
+	bad := `
+roles:
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+bindings:
+  - subject: "group:"
+    roles: ["viewer"]
+`
+	if _, err := ParsePolicy([]byte(bad)); err == nil {
+		t.Fatal("expected error for group subject without a name")
+	}
+}
+
+const denyPolicy = `
+default: viewer
+roles:
+  - name: developer
+    rules:
+      - clusters: ["dev-*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+      - effect: deny
+        clusters: ["dev-*"]
+        namespaces: ["*"]
+        resources: ["secrets"]
+        verbs: ["delete"]
+  - name: admin
+    rules:
+      - effect: allow
+        clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get", "list"]
+      - effect: deny
+        clusters: ["*"]
+        namespaces: ["kube-system"]
+        resources: ["*"]
+        verbs: ["*"]
+bindings:
+  - subject: dev@corp.com
+    roles: ["developer"]
+  - subject: lead@corp.com
+    roles: ["developer", "admin"]
+`
+
+func TestPolicy_DenyOverridesAllow(t *testing.T) {
+	p := mustParse(t, denyPolicy)
+
+	tests := []struct {
+		name    string
+		subject string
+		req     AccessRequest
+		want    bool
+	}{
+		{"allow rule still applies", "dev@corp.com", access("dev-1", "default", "secrets", "get"), true},
+		{"deny in same role wins", "dev@corp.com", access("dev-1", "default", "secrets", "delete"), false},
+		{"deny only covers its resource", "dev@corp.com", access("dev-1", "default", "pods", "delete"), true},
+		{"deny wins over allow from another role", "lead@corp.com", access("dev-1", "default", "secrets", "delete"), false},
+		{"other role allows outside deny scope", "lead@corp.com", access("prod-1", "default", "secrets", "delete"), true},
+		{"default role deny applies to bound users", "lead@corp.com", access("prod-1", "kube-system", "pods", "get"), false},
+		{"default role deny applies to unbound users", "stranger@x.com", access("prod-1", "kube-system", "pods", "get"), false},
+		{"default role allow outside deny scope", "stranger@x.com", access("prod-1", "default", "pods", "get"), true},
+		{"deny alone grants nothing", "stranger@x.com", access("prod-1", "kube-system", "pods", "delete"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.allows(Subject{Email: tt.subject}, tt.req); got != tt.want {
 				t.Errorf("allows(%q, %+v) = %v, want %v", tt.subject, tt.req, got, tt.want)
 			}
 		})
 	}
 }
 
+func TestParsePolicy_RejectsUnknownEffect(t *testing.T) {
+	bad := `
+roles:
+  - name: viewer
+    rules:
+      - effect: forbid
+        clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+`
+	if _, err := ParsePolicy([]byte(bad)); err == nil {
+		t.Fatal("expected error for unknown rule effect")
+	}
+}
+
+const approvalPolicy = `
+roles:
+  - name: operator
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+      - clusters: ["prod-*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["delete"]
+        require_approval: true
+bindings:
+  - subject: ops@corp.com
+    roles: ["operator"]
+`
+
+func TestPolicy_RequireApproval(t *testing.T) {
+	p := mustParse(t, approvalPolicy)
+	ops := Subject{Email: "ops@corp.com"}
+
+	tests := []struct {
+		name         string
+		req          AccessRequest
+		wantApproval bool
+	}{
+		{"matching rule requires approval despite a broader allow", access("prod-1", "app", "pods", "delete"), true},
+		{"other verbs need no approval", access("prod-1", "app", "pods", "get"), false},
+		{"other clusters need no approval", access("dev-1", "app", "pods", "delete"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.decide(ops, tt.req)
+			if !d.Allowed || d.RequireApproval != tt.wantApproval {
+				t.Errorf("decide(%+v) = %+v, want allowed with require_approval=%v", tt.req, d, tt.wantApproval)
+			}
+			if tt.wantApproval && d.Rule != 2 {
+				t.Errorf("decision should name the approval rule, got rule %d", d.Rule)
+			}
+		})
+	}
+}
+
+func TestParsePolicy_RejectsApprovalOnDeny(t *testing.T) {
+	bad := `
+roles:
+  - name: viewer
+    rules:
+      - effect: deny
+        clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["delete"]
+        require_approval: true
+`
+	if _, err := ParsePolicy([]byte(bad)); err == nil {
+		t.Fatal("expected error for require_approval on a deny rule")
+	}
+}
+
+const namesPolicy = `
+roles:
+  - name: api-operator
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["pods"]
+        names: ["api-*"]
+        verbs: ["exec", "logs"]
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["secrets"]
+        verbs: ["get", "list"]
+      - effect: deny
+        clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["secrets"]
+        names: ["root-*"]
+        verbs: ["*"]
+bindings:
+  - subject: ops@corp.com
+    roles: ["api-operator"]
+`
+
+func TestPolicy_NameScopedRules(t *testing.T) {
+	p := mustParse(t, namesPolicy)
+	ops := Subject{Email: "ops@corp.com"}
+
+	tests := []struct {
+		name string
+		req  AccessRequest
+		want bool
+	}{
+		{"matching name allowed", access("c", "default", "pods", "exec", "api-7d9f"), true},
+		{"other name denied", access("c", "default", "pods", "exec", "db-0"), false},
+		{"every name must match", access("c", "default", "pods", "logs", "api-1", "db-0"), false},
+		{"all names match", access("c", "default", "pods", "logs", "api-1", "api-2"), true},
+		{"scoped allow does not grant collection", access("c", "default", "pods", "logs"), false},
+		{"unscoped rule allows any name", access("c", "default", "secrets", "get", "app-token"), true},
+		{"scoped deny matches name", access("c", "default", "secrets", "get", "root-ca"), false},
+		{"scoped deny matches any listed name", access("c", "default", "secrets", "get", "app-token", "root-ca"), false},
+		{"scoped deny covers collection", access("c", "default", "secrets", "list"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.allows(ops, tt.req); got != tt.want {
+				t.Errorf("allows(%+v) = %v, want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_SubcommandVerbs(t *testing.T) {
+	p := mustParse(t, `
+roles:
+  - name: release
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["deployments"]
+        verbs: ["rollout"]
+  - name: observer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["deployments"]
+        verbs: ["rollout status"]
+bindings:
+  - subject: rel@corp.com
+    roles: ["release"]
+  - subject: obs@corp.com
+    roles: ["observer"]
+`)
+	tests := []struct {
+		subject string
+		verb    string
+		want    bool
+	}{
+		{"rel@corp.com", "rollout restart", true},
+		{"rel@corp.com", "rollout", true},
+		{"rel@corp.com", "rollouts", false},
+		{"obs@corp.com", "rollout status", true},
+		{"obs@corp.com", "rollout restart", false},
+	}
+	for _, tt := range tests {
+		got := p.allows(Subject{Email: tt.subject}, access("c", "default", "deployments", tt.verb))
+		if got != tt.want {
+			t.Errorf("allows(%s, %q) = %v, want %v", tt.subject, tt.verb, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_WildcardRequestFields(t *testing.T) {
+	p := mustParse(t, denyPolicy)
+	// "-A" and manifest-sourced requests carry "*": the developer's allow-all
+	// rule covers them, but the viewer default's kube-system deny and the
+	// secrets deny may apply, so they win.
+	tests := []struct {
+		name string
+		req  AccessRequest
+		want bool
+	}{
+		{"all namespaces hits namespace deny", access("dev-1", "*", "pods", "get"), false},
+		{"any resource hits resource deny", access("dev-1", "default", "*", "delete"), false},
+		{"any resource outside deny verbs", access("dev-1", "default", "*", "get"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.allows(Subject{Email: "dev@corp.com"}, tt.req); got != tt.want {
+				t.Errorf("allows(%+v) = %v, want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPolicy_NoDefault_UnboundDenied(t *testing.T) {
 	noDefault := `
 roles:
@@ -126,7 +458,7 @@ bindings:
     roles: ["admin"]
 `
 	p := mustParse(t, noDefault)
-	if p.allows("nobody@x.com", AccessRequest{"c", "default", "pods", "get"}) {
+	if p.allows(Subject{Email: "nobody@x.com"}, access("c", "default", "pods", "get")) {
 		t.Error("expected deny for unbound user when no default role")
 	}
 }
@@ -153,8 +485,8 @@ roles:
 		t.Fatalf("load: %v", err)
 	}
 
-	req := AccessRequest{"prod", "default", "pods", "delete"}
-	if engine.Allows("u@x.com", req) {
+	req := access("prod", "default", "pods", "delete")
+	if engine.Allows(Subject{Email: "u@x.com"}, req) {
 		t.Fatal("delete should be denied under restrictive policy")
 	}
 
@@ -175,7 +507,7 @@ roles:
 	if err := engine.Reload(); err != nil {
 		t.Fatalf("reload: %v", err)
 	}
-	if !engine.Allows("u@x.com", req) {
+	if !engine.Allows(Subject{Email: "u@x.com"}, req) {
 		t.Error("delete should be allowed after hot-reload to permissive policy")
 	}
 }
@@ -0,0 +1,215 @@
+package central
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// policyStoreTimeout bounds a single policy read or write against the store.
+const policyStoreTimeout = 5 * time.Second
+
+// policySeedAuthor is recorded as the author of the version seeded from a file.
+const policySeedAuthor = "system"
+
+var (
+	// ErrInvalidPolicy wraps parse and validation errors of a submitted document.
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrPolicyConflict is returned when a change was based on a version that
+	// is no longer the latest.
+	ErrPolicyConflict = errors.New("policy was changed concurrently")
+	// ErrPolicyReadOnly is returned when changing a file-backed policy.
+	ErrPolicyReadOnly = errors.New("policy is loaded from a file and cannot be changed through the API")
+	// ErrPolicyVersionNotFound is returned when rolling back to an unknown version.
+	ErrPolicyVersionNotFound = errors.New("policy version not found")
+)
+
+// emptyPolicy grants nothing. A database-backed engine starts with it when
+// the database holds no policy and no seed file is configured.
+var emptyPolicy = &Policy{}
+
+// NewPolicyEngineFromStore creates an engine backed by versioned policy
+// documents in store. If the store has no policy yet and seedFile is set, the
+// file becomes version 1; without a seed file all commands are denied until
+// an admin submits a policy. Watch polls for new versions every pollInterval,
+// so changes made through any replica propagate to all of them.
+func NewPolicyEngineFromStore(ctx context.Context, store Store, seedFile string, pollInterval time.Duration) (*PolicyEngine, error) {
+	e := &PolicyEngine{store: store, pollInterval: pollInterval}
+	e.current.Store(emptyPolicy)
+
+	latest, err := e.reloadFromStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil || seedFile == "" {
+		if latest == nil {
+			log.Printf("rbac: no policy in the database yet; all commands are denied until one is set")
+		}
+		return e, nil
+	}
+
+	data, err := os.ReadFile(seedFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+	pv, err := e.Apply(ctx, string(data), policySeedAuthor, "seeded from "+seedFile, 0)
+	if err != nil {
+		// Another replica may have seeded the database first.
+		if latest, rerr := e.reloadFromStore(ctx); rerr == nil && latest != nil {
+			return e, nil
+		}
+		return nil, fmt.Errorf("seeding policy from %s: %w", seedFile, err)
+	}
+	log.Printf("rbac: seeded policy version %d from %s", pv.Version, seedFile)
+	return e, nil
+}
+
+// reloadFromStore swaps in the latest stored version if it differs from the
+// loaded one and returns it, or nil when the store holds no policy.
+func (e *PolicyEngine) reloadFromStore(ctx context.Context) (*PolicyVersion, error) {
+	latest, err := e.store.GetLatestPolicyVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading policy: %w", err)
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	if cur := e.loaded.Load(); cur != nil && cur.Version == latest.Version {
+		return latest, nil
+	}
+	policy, err := ParsePolicy([]byte(latest.Document))
+	if err != nil {
+		return nil, fmt.Errorf("policy version %d: %w", latest.Version, err)
+	}
+	e.swap(policy, latest)
+	return latest, nil
+}
+
+func (e *PolicyEngine) swap(policy *Policy, pv *PolicyVersion) {
+	e.current.Store(policy)
+	e.loaded.Store(pv)
+}
+
+// poll reloads the latest stored version every pollInterval until stop is
+// closed. Failures are logged and the previous policy is kept.
+func (e *PolicyEngine) poll(stop <-chan struct{}) {
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			before := e.Version()
+			if err := e.Reload(); err != nil {
+				log.Printf("rbac reload failed, keeping previous policy: %v", err)
+			} else if v := e.Version(); v != before {
+				log.Printf("rbac policy reloaded at version %d", v)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Source reports where the policy comes from: RBACSourceFile or
+// RBACSourceDatabase.
+func (e *PolicyEngine) Source() string {
+	if e.store != nil {
+		return RBACSourceDatabase
+	}
+	return RBACSourceFile
+}
+
+// Version returns the active database version, or 0 for a file-backed engine
+// or an empty database.
+func (e *PolicyEngine) Version() int {
+	if pv := e.loaded.Load(); pv != nil {
+		return pv.Version
+	}
+	return 0
+}
+
+// Document returns the source text of the active policy.
+func (e *PolicyEngine) Document() (string, error) {
+	if e.store == nil {
+		data, err := os.ReadFile(e.path)
+		if err != nil {
+			return "", fmt.Errorf("reading policy file: %w", err)
+		}
+		return string(data), nil
+	}
+	if pv := e.loaded.Load(); pv != nil {
+		return pv.Document, nil
+	}
+	return "", nil
+}
+
+// Apply validates document, stores it as a new version and activates it. When
+// baseVersion is non-zero the change is rejected with ErrPolicyConflict unless
+// baseVersion is still the latest version.
+func (e *PolicyEngine) Apply(ctx context.Context, document, author, comment string, baseVersion int) (*PolicyVersion, error) {
+	if e.store == nil {
+		return nil, ErrPolicyReadOnly
+	}
+	policy, err := ParsePolicy([]byte(document))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if baseVersion != 0 {
+		latest, err := e.store.GetLatestPolicyVersion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loading policy: %w", err)
+		}
+		if latest == nil || latest.Version != baseVersion {
+			return nil, ErrPolicyConflict
+		}
+	}
+
+	pv := &PolicyVersion{Document: document, Author: author, Comment: comment}
+	if err := e.store.CreatePolicyVersion(ctx, pv); err != nil {
+		return nil, err
+	}
+	e.swap(policy, pv)
+	return pv, nil
+}
+
+// Rollback re-applies the document of an earlier version as a new version, so
+// history is never rewritten.
+func (e *PolicyEngine) Rollback(ctx context.Context, version int, author, comment string) (*PolicyVersion, error) {
+	if e.store == nil {
+		return nil, ErrPolicyReadOnly
+	}
+	old, err := e.store.GetPolicyVersion(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("loading policy version: %w", err)
+	}
+	if old == nil {
+		return nil, ErrPolicyVersionNotFound
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("rollback to version %d", version)
+	}
+	return e.Apply(ctx, old.Document, author, comment, 0)
+}
+
+// History lists stored versions, newest first, without their documents.
+func (e *PolicyEngine) History(ctx context.Context) ([]*PolicyVersion, error) {
+	if e.store == nil {
+		return nil, ErrPolicyReadOnly
+	}
+	return e.store.ListPolicyVersions(ctx)
+}
+
+// GetVersion returns a stored version, or nil if it does not exist.
+func (e *PolicyEngine) GetVersion(ctx context.Context, version int) (*PolicyVersion, error) {
+	if e.store == nil {
+		return nil, ErrPolicyReadOnly
+	}
+	return e.store.GetPolicyVersion(ctx, version)
+}
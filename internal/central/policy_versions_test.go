@@ -0,0 +1,151 @@
+package central
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	policyV1 = `
+default: viewer
+roles:
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get"]
+`
+	policyV2 = `
+default: editor
+roles:
+  - name: editor
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["get", "delete"]
+`
+)
+
+func TestPolicyEngineFromStore(t *testing.T) {
+	ctx := context.Background()
+	subject := Subject{Email: "dev@x.com"}
+	del := access("prod", "app", "pods", "delete")
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"empty store without seed denies everything", func(t *testing.T) {
+			e, err := NewPolicyEngineFromStore(ctx, newTestStore(t), "", time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Version() != 0 || e.Allows(subject, access("prod", "app", "pods", "get")) {
+				t.Errorf("version = %d, want 0 and deny-all", e.Version())
+			}
+		}},
+		{"seeds version 1 from file once", func(t *testing.T) {
+			store := newTestStore(t)
+			seed := filepath.Join(t.TempDir(), "rbac.yaml")
+			if err := os.WriteFile(seed, []byte(policyV1), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			e, err := NewPolicyEngineFromStore(ctx, store, seed, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Version() != 1 || e.Source() != RBACSourceDatabase {
+				t.Fatalf("version = %d source = %s", e.Version(), e.Source())
+			}
+			pv, _ := store.GetPolicyVersion(ctx, 1)
+			if pv.Author != policySeedAuthor {
+				t.Errorf("author = %q, want %q", pv.Author, policySeedAuthor)
+			}
+
+			// A restart with the same seed keeps the stored policy.
+			if _, err := NewPolicyEngineFromStore(ctx, store, seed, time.Second); err != nil {
+				t.Fatal(err)
+			}
+			if versions, _ := store.ListPolicyVersions(ctx); len(versions) != 1 {
+				t.Errorf("versions = %d, want 1", len(versions))
+			}
+		}},
+		{"apply activates a new version", func(t *testing.T) {
+			e, _ := NewPolicyEngineFromStore(ctx, newTestStore(t), "", time.Second)
+			if _, err := e.Apply(ctx, policyV1, "a@x.com", "", 0); err != nil {
+				t.Fatal(err)
+			}
+			if e.Allows(subject, del) {
+				t.Error("v1 should not allow delete")
+			}
+			pv, err := e.Apply(ctx, policyV2, "a@x.com", "allow delete", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pv.Version != 2 || e.Version() != 2 || !e.Allows(subject, del) {
+				t.Errorf("version = %d, want 2 allowing delete", e.Version())
+			}
+			if doc, _ := e.Document(); doc != policyV2 {
+				t.Errorf("document = %q", doc)
+			}
+		}},
+		{"invalid document is rejected", func(t *testing.T) {
+			e, _ := NewPolicyEngineFromStore(ctx, newTestStore(t), "", time.Second)
+			_, err := e.Apply(ctx, "default: missing\n", "a@x.com", "", 0)
+			if !errors.Is(err, ErrInvalidPolicy) || e.Version() != 0 {
+				t.Errorf("err = %v, version = %d", err, e.Version())
+			}
+		}},
+		{"stale base version conflicts", func(t *testing.T) {
+			e, _ := NewPolicyEngineFromStore(ctx, newTestStore(t), "", time.Second)
+			e.Apply(ctx, policyV1, "a@x.com", "", 0)
+			e.Apply(ctx, policyV2, "b@x.com", "", 0)
+			if _, err := e.Apply(ctx, policyV1, "a@x.com", "", 1); !errors.Is(err, ErrPolicyConflict) {
+				t.Errorf("err = %v, want ErrPolicyConflict", err)
+			}
+		}},
+		{"rollback appends the old document", func(t *testing.T) {
+			e, _ := NewPolicyEngineFromStore(ctx, newTestStore(t), "", time.Second)
+			e.Apply(ctx, policyV1, "a@x.com", "", 0)
+			e.Apply(ctx, policyV2, "a@x.com", "", 0)
+			pv, err := e.Rollback(ctx, 1, "a@x.com", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pv.Version != 3 || pv.Comment != "rollback to version 1" || e.Allows(subject, del) {
+				t.Errorf("rollback = %+v, want version 3 with v1 rules", pv)
+			}
+			if _, err := e.Rollback(ctx, 42, "a@x.com", ""); !errors.Is(err, ErrPolicyVersionNotFound) {
+				t.Errorf("err = %v, want ErrPolicyVersionNotFound", err)
+			}
+		}},
+		{"reload picks up versions written by another replica", func(t *testing.T) {
+			store := newTestStore(t)
+			a, _ := NewPolicyEngineFromStore(ctx, store, "", time.Second)
+			b, _ := NewPolicyEngineFromStore(ctx, store, "", time.Second)
+			a.Apply(ctx, policyV2, "a@x.com", "", 0)
+			if err := b.Reload(); err != nil {
+				t.Fatal(err)
+			}
+			if b.Version() != 1 || !b.Allows(subject, del) {
+				t.Errorf("replica version = %d, want 1", b.Version())
+			}
+		}},
+		{"file engine is read-only", func(t *testing.T) {
+			e := &PolicyEngine{}
+			e.current.Store(mustParse(t, policyV1))
+			if _, err := e.Apply(ctx, policyV2, "a@x.com", "", 0); !errors.Is(err, ErrPolicyReadOnly) {
+				t.Errorf("err = %v, want ErrPolicyReadOnly", err)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.fn)
+	}
+}
@@ -110,7 +110,7 @@ func (s *HTTPServer) handlePortForward(c *gin.Context) {
 		return
 	}
 
-	sess, err := s.sessions.StartPortForward(agent.ID, pod, namespace, ports)
+	sess, err := s.sessions.StartPortForward(agent.ID, requestIdentity(c), pod, namespace, ports)
 	if err != nil {
 		if err == ErrTooManyStreams {
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many concurrent streams"})
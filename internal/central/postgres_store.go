@@ -0,0 +1,937 @@
+package central
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// PostgresStore implements Store using PostgreSQL via the pgx database/sql
+// driver. Unlike SQLiteStore it uses native BOOLEAN and TIMESTAMPTZ columns and
+// a regular connection pool, so it is safe to run several central replicas
+// against the same database.
+type PostgresStore struct {
+	db    *sql.DB
+	chain *AuditChain
+}
+
+// NewPostgresStore connects to PostgreSQL using dsn (URL or key=value form)
+// and returns a store. The connection is verified before returning.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect postgres: %w", err)
+	}
+	return &PostgresStore{db: db, chain: NewAuditChain(nil)}, nil
+}
+
+// SetAuditChain sets the hasher used to chain new audit entries.
+func (s *PostgresStore) SetAuditChain(chain *AuditChain) {
+	s.chain = chain
+}
+
+// Migrate applies pending schema migrations.
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	_, err := s.migrator().up(ctx)
+	return err
+}
+
+// MigrationStatus reports the state of every known schema migration.
+func (s *PostgresStore) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return s.migrator().status(ctx, s.db)
+}
+
+func (s *PostgresStore) migrator() *migrator {
+	return &migrator{db: s.db, postgres: true, migrations: postgresMigrations}
+}
+
+// Close closes the connection pool.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind rewrites SQLite-style "?" placeholders into PostgreSQL's "$n" form so
+// query fragments (such as buildAuditFilter's WHERE clause) can be shared.
+func rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// --- Users ---
+
+func (s *PostgresStore) CreateUser(ctx context.Context, user *User) error {
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO users (id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		user.ID, user.Email, user.PasswordHash, user.Name, user.IsActive, user.IsAdmin, encodeStringList(user.Groups), now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	return s.scanUser(s.db.QueryRowContext(ctx,
+		`SELECT id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at
+		 FROM users WHERE id = $1`, id))
+}
+
+func (s *PostgresStore) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return s.scanUser(s.db.QueryRowContext(ctx,
+		`SELECT id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at
+		 FROM users WHERE email = $1`, email))
+}
+
+func (s *PostgresStore) scanUser(row *sql.Row) (*User, error) {
+	var u User
+	var groups string
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.IsActive, &u.IsAdmin, &groups, &u.CreatedAt, &u.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan user: %w", err)
+	}
+	u.Groups = decodeStringList(groups)
+	u.CreatedAt = u.CreatedAt.UTC()
+	u.UpdatedAt = u.UpdatedAt.UTC()
+	return &u, nil
+}
+
+func (s *PostgresStore) ListUsers(ctx context.Context) ([]*User, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at FROM users`)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		var u User
+		var groups string
+		err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.IsActive, &u.IsAdmin, &groups, &u.CreatedAt, &u.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan user row: %w", err)
+		}
+		u.Groups = decodeStringList(groups)
+		u.CreatedAt = u.CreatedAt.UTC()
+		u.UpdatedAt = u.UpdatedAt.UTC()
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
+
+func (s *PostgresStore) UpdateUser(ctx context.Context, user *User) error {
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET email = $1, name = $2, password_hash = $3, is_active = $4, is_admin = $5, group_names = $6, updated_at = $7 WHERE id = $8`,
+		user.Email, user.Name, user.PasswordHash, user.IsActive, user.IsAdmin, encodeStringList(user.Groups), now, user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update user: %w", err)
+	}
+	user.UpdatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) DeleteUser(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
+
+// --- Clusters ---
+
+func (s *PostgresStore) CreateCluster(ctx context.Context, cluster *Cluster) error {
+	if cluster.ID == "" {
+		cluster.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO clusters (id, name, status, agent_id, last_seen_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		cluster.ID, cluster.Name, cluster.Status, nilIfEmpty(cluster.AgentID),
+		utcPtr(cluster.LastSeenAt), now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("create cluster: %w", err)
+	}
+	cluster.CreatedAt = now
+	cluster.UpdatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetClusterByID(ctx context.Context, id string) (*Cluster, error) {
+	return s.scanCluster(s.db.QueryRowContext(ctx,
+		`SELECT id, name, status, agent_id, last_seen_at, created_at, updated_at
+		 FROM clusters WHERE id = $1`, id))
+}
+
+func (s *PostgresStore) GetClusterByName(ctx context.Context, name string) (*Cluster, error) {
+	return s.scanCluster(s.db.QueryRowContext(ctx,
+		`SELECT id, name, status, agent_id, last_seen_at, created_at, updated_at
+		 FROM clusters WHERE name = $1`, name))
+}
+
+func (s *PostgresStore) scanCluster(row *sql.Row) (*Cluster, error) {
+	var c Cluster
+	var agentID *string
+	err := row.Scan(&c.ID, &c.Name, &c.Status, &agentID, &c.LastSeenAt, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan cluster: %w", err)
+	}
+	c.AgentID = derefStr(agentID)
+	c.LastSeenAt = utcPtr(c.LastSeenAt)
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	return &c, nil
+}
+
+func (s *PostgresStore) ListClusters(ctx context.Context) ([]*Cluster, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, status, agent_id, last_seen_at, created_at, updated_at
+		 FROM clusters`)
+	if err != nil {
+		return nil, fmt.Errorf("list clusters: %w", err)
+	}
+	defer rows.Close()
+
+	var clusters []*Cluster
+	for rows.Next() {
+		var c Cluster
+		var agentID *string
+		err := rows.Scan(&c.ID, &c.Name, &c.Status, &agentID, &c.LastSeenAt, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan cluster row: %w", err)
+		}
+		c.AgentID = derefStr(agentID)
+		c.LastSeenAt = utcPtr(c.LastSeenAt)
+		c.CreatedAt = c.CreatedAt.UTC()
+		c.UpdatedAt = c.UpdatedAt.UTC()
+		clusters = append(clusters, &c)
+	}
+	return clusters, rows.Err()
+}
+
+func (s *PostgresStore) UpdateCluster(ctx context.Context, cluster *Cluster) error {
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE clusters SET name = $1, status = $2, agent_id = $3, last_seen_at = $4, updated_at = $5
+		 WHERE id = $6`,
+		cluster.Name, cluster.Status, nilIfEmpty(cluster.AgentID),
+		utcPtr(cluster.LastSeenAt), now, cluster.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update cluster: %w", err)
+	}
+	cluster.UpdatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) DeleteCluster(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM clusters WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete cluster: %w", err)
+	}
+	return nil
+}
+
+// --- Agent Tokens ---
+
+func (s *PostgresStore) CreateAgentToken(ctx context.Context, token *AgentToken) error {
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO agent_tokens (id, cluster_id, token_hash, token_prefix, description, is_revoked, last_used_at, expires_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		token.ID, token.ClusterID, token.TokenHash, token.TokenPrefix,
+		nilIfEmpty(token.Description), token.IsRevoked,
+		utcPtr(token.LastUsedAt), utcPtr(token.ExpiresAt), now,
+	)
+	if err != nil {
+		return fmt.Errorf("create agent token: %w", err)
+	}
+	token.CreatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetAgentTokenByHash(ctx context.Context, tokenHash string) (*AgentToken, error) {
+	var t AgentToken
+	var desc *string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, cluster_id, token_hash, token_prefix, description, is_revoked, last_used_at, expires_at, created_at
+		 FROM agent_tokens WHERE token_hash = $1`, tokenHash,
+	).Scan(&t.ID, &t.ClusterID, &t.TokenHash, &t.TokenPrefix, &desc,
+		&t.IsRevoked, &t.LastUsedAt, &t.ExpiresAt, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get agent token: %w", err)
+	}
+	t.Description = derefStr(desc)
+	t.LastUsedAt = utcPtr(t.LastUsedAt)
+	t.ExpiresAt = utcPtr(t.ExpiresAt)
+	t.CreatedAt = t.CreatedAt.UTC()
+	return &t, nil
+}
+
+func (s *PostgresStore) ListAgentTokensByCluster(ctx context.Context, clusterID string) ([]*AgentToken, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, cluster_id, token_hash, token_prefix, description, is_revoked, last_used_at, expires_at, created_at
+		 FROM agent_tokens WHERE cluster_id = $1`, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("list agent tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*AgentToken
+	for rows.Next() {
+		var t AgentToken
+		var desc *string
+		err := rows.Scan(&t.ID, &t.ClusterID, &t.TokenHash, &t.TokenPrefix, &desc,
+			&t.IsRevoked, &t.LastUsedAt, &t.ExpiresAt, &t.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan agent token row: %w", err)
+		}
+		t.Description = derefStr(desc)
+		t.LastUsedAt = utcPtr(t.LastUsedAt)
+		t.ExpiresAt = utcPtr(t.ExpiresAt)
+		t.CreatedAt = t.CreatedAt.UTC()
+		tokens = append(tokens, &t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *PostgresStore) RevokeAgentToken(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE agent_tokens SET is_revoked = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("revoke agent token: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) TouchAgentToken(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE agent_tokens SET last_used_at = $1 WHERE id = $2`, usedAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("touch agent token: %w", err)
+	}
+	return nil
+}
+
+// --- Refresh Tokens ---
+
+func (s *PostgresStore) CreateRefreshToken(ctx context.Context, rt *RefreshToken) error {
+	if rt.ID == "" {
+		rt.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (id, user_id, token_hash, idp_groups, expires_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		rt.ID, rt.UserID, rt.TokenHash, encodeStringList(rt.Groups), rt.ExpiresAt.UTC(), now,
+	)
+	if err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+	rt.CreatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var rt RefreshToken
+	var groups string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, token_hash, idp_groups, expires_at, created_at
+		 FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
+	).Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &groups, &rt.ExpiresAt, &rt.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+	rt.Groups = decodeStringList(groups)
+	rt.ExpiresAt = rt.ExpiresAt.UTC()
+	rt.CreatedAt = rt.CreatedAt.UTC()
+	return &rt, nil
+}
+
+func (s *PostgresStore) DeleteRefreshToken(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteRefreshTokensByUser(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("delete refresh tokens by user: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) CleanupExpiredRefreshTokens(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("cleanup expired refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// --- Audit Logs ---
+
+// CreateAuditLog appends log to the audit chain. Replicas share the chain, so
+// the append holds an advisory lock for the length of the transaction.
+func (s *PostgresStore) CreateAuditLog(ctx context.Context, log *AuditLog) error {
+	if log.ID == "" {
+		log.ID = uuid.New().String()
+	}
+	// Truncate to the column's precision so the hash matches on read-back.
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	tx, err := s.beginAuditChainTx(ctx)
+	if err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
+	defer tx.Rollback()
+	headSeq, headHash, err := auditChainHead(ctx, tx)
+	if err != nil {
+		return err
+	}
+	s.chain.link(log, headSeq, headHash)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_logs (id, user_id, user_email, cluster_name, cluster_id, command, namespace, status, exit_code, duration_ms, error_message, client_ip, created_at, seq, prev_hash, hash, verb, resource)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+		log.ID, nilIfEmpty(log.UserID), log.UserEmail, log.ClusterName,
+		nilIfEmpty(log.ClusterID), log.Command, nilIfEmpty(log.Namespace),
+		log.Status, log.ExitCode, log.DurationMs,
+		nilIfEmpty(log.ErrorMessage), nilIfEmpty(log.ClientIP), log.CreatedAt,
+		log.Seq, log.PrevHash, log.Hash, nilIfEmpty(log.Verb), nilIfEmpty(log.Resource),
+	)
+	if err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
+	return nil
+}
+
+// beginAuditChainTx starts a transaction holding the audit chain lock.
+func (s *PostgresStore) beginAuditChainTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLockID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("locking audit chain: %w", err)
+	}
+	return tx, nil
+}
+
+func (s *PostgresStore) ListAuditLogs(ctx context.Context, filter AuditLogFilter) ([]*AuditLog, int, error) {
+	where, args := buildAuditFilter(filter)
+
+	// Count total
+	countQuery := rebind("SELECT COUNT(*) FROM audit_logs" + where)
+	var total int
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit logs: %w", err)
+	}
+
+	// Fetch page
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs` + where + auditLogOrder(filter)
+	pageArgs := append([]any{}, args...)
+	if filter.PerPage > 0 {
+		query += " LIMIT ? OFFSET ?"
+		offset := 0
+		if filter.Page > 1 {
+			offset = (filter.Page - 1) * filter.PerPage
+		}
+		pageArgs = append(pageArgs, filter.PerPage, offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, rebind(query), pageArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*AuditLog
+	for rows.Next() {
+		l, err := scanPostgresAuditRow(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, rows.Err()
+}
+
+func (s *PostgresStore) ListAuditLogsAfter(ctx context.Context, filter AuditLogFilter, after *AuditCursor, limit int) ([]*AuditLog, error) {
+	query, args := buildAuditPageQuery(filter, after, limit)
+	rows, err := s.db.QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit logs: %w", err)
+	}
+	defer rows.Close()
+	var logs []*AuditLog
+	for rows.Next() {
+		l, err := scanPostgresAuditRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+func scanPostgresAuditRow(rows rowScanner) (*AuditLog, error) {
+	var l AuditLog
+	var userID, clusterID, ns, errMsg, clientIP, prevHash, hash, verb, resource *string
+	var seq *int64
+	err := rows.Scan(&l.ID, &userID, &l.UserEmail, &l.ClusterName, &clusterID,
+		&l.Command, &ns, &l.Status, &l.ExitCode, &l.DurationMs,
+		&errMsg, &clientIP, &l.CreatedAt, &seq, &prevHash, &hash, &verb, &resource)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan audit log: %w", err)
+	}
+	l.UserID = derefStr(userID)
+	l.ClusterID = derefStr(clusterID)
+	l.Namespace = derefStr(ns)
+	l.ErrorMessage = derefStr(errMsg)
+	l.ClientIP = derefStr(clientIP)
+	l.CreatedAt = l.CreatedAt.UTC()
+	if seq != nil {
+		l.Seq = *seq
+	}
+	l.PrevHash = derefStr(prevHash)
+	l.Hash = derefStr(hash)
+	l.Verb = derefStr(verb)
+	l.Resource = derefStr(resource)
+	return &l, nil
+}
+
+func (s *PostgresStore) GetAuditLog(ctx context.Context, id string) (*AuditLog, error) {
+	l, err := scanPostgresAuditRow(s.db.QueryRowContext(ctx,
+		`SELECT `+auditLogColumns+` FROM audit_logs WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+func (s *PostgresStore) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]*AuditLog, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+auditLogColumns+` FROM audit_logs WHERE seq > $1 ORDER BY seq LIMIT $2`, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit chain: %w", err)
+	}
+	defer rows.Close()
+	var logs []*AuditLog
+	for rows.Next() {
+		l, err := scanPostgresAuditRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+func (s *PostgresStore) CleanupOldAuditLogs(ctx context.Context, before time.Time) (int, error) {
+	tx, err := s.beginAuditChainTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	var lastHash string
+	err = tx.QueryRowContext(ctx,
+		`SELECT seq, hash FROM audit_logs WHERE seq IS NOT NULL AND created_at < $1 ORDER BY seq DESC LIMIT 1`,
+		before.UTC()).Scan(&lastSeq, &lastHash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM audit_logs WHERE (seq IS NULL AND created_at < $1) OR seq <= $2`, before.UTC(), lastSeq)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+	if lastSeq > 0 {
+		cp := &AuditCheckpoint{
+			ID:        uuid.New().String(),
+			Seq:       lastSeq,
+			Hash:      lastHash,
+			Deleted:   int(n),
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		}
+		cp.Signature = s.chain.Sign(cp)
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO audit_checkpoints (id, seq, hash, deleted, signature, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			cp.ID, cp.Seq, cp.Hash, cp.Deleted, cp.Signature, cp.CreatedAt)
+		if err != nil {
+			return 0, fmt.Errorf("create audit checkpoint: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
+	return int(n), nil
+}
+
+func (s *PostgresStore) GetLatestAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error) {
+	var cp AuditCheckpoint
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, seq, hash, deleted, signature, created_at FROM audit_checkpoints ORDER BY seq DESC LIMIT 1`,
+	).Scan(&cp.ID, &cp.Seq, &cp.Hash, &cp.Deleted, &cp.Signature, &cp.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get audit checkpoint: %w", err)
+	}
+	cp.CreatedAt = cp.CreatedAt.UTC()
+	return &cp, nil
+}
+
+// --- Session Recordings ---
+
+func (s *PostgresStore) CreateSessionRecording(ctx context.Context, r *SessionRecording) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if r.Data == nil {
+		r.Data = []byte{}
+	}
+	r.Size = int64(len(r.Data))
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO session_recordings (`+sessionRecordingColumns+`, data)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		r.ID, r.AuditLogID, r.UserEmail, r.ClusterName, nilIfEmpty(r.Namespace), r.Command,
+		r.DurationMs, r.Size, r.Truncated, now, r.Data,
+	)
+	if err != nil {
+		return fmt.Errorf("create session recording: %w", err)
+	}
+	r.CreatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetSessionRecording(ctx context.Context, id string) (*SessionRecording, error) {
+	var data []byte
+	r, err := scanPostgresSessionRecording(s.db.QueryRowContext(ctx,
+		`SELECT `+sessionRecordingColumns+`, data FROM session_recordings WHERE id = $1`, id), &data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Data = data
+	return r, nil
+}
+
+func (s *PostgresStore) ListSessionRecordings(ctx context.Context, filter SessionRecordingFilter) ([]*SessionRecording, error) {
+	query, args := buildSessionRecordingQuery(filter)
+	rows, err := s.db.QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list session recordings: %w", err)
+	}
+	defer rows.Close()
+	var out []*SessionRecording
+	for rows.Next() {
+		r, err := scanPostgresSessionRecording(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func scanPostgresSessionRecording(row rowScanner, extra ...any) (*SessionRecording, error) {
+	var r SessionRecording
+	var ns *string
+	dest := append([]any{&r.ID, &r.AuditLogID, &r.UserEmail, &r.ClusterName, &ns, &r.Command,
+		&r.DurationMs, &r.Size, &r.Truncated, &r.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan session recording: %w", err)
+	}
+	r.Namespace = derefStr(ns)
+	r.CreatedAt = r.CreatedAt.UTC()
+	return &r, nil
+}
+
+// --- Audit Outputs ---
+
+func (s *PostgresStore) CreateAuditOutput(ctx context.Context, o *AuditOutput) error {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_outputs (audit_log_id, stdout, stderr, stdout_bytes, stderr_bytes, truncated, redactions, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		o.AuditLogID, o.Stdout, o.Stderr, o.StdoutBytes, o.StderrBytes, o.Truncated, o.Redactions, now,
+	)
+	if err != nil {
+		return fmt.Errorf("create audit output: %w", err)
+	}
+	o.CreatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetAuditOutput(ctx context.Context, auditLogID string) (*AuditOutput, error) {
+	var o AuditOutput
+	err := s.db.QueryRowContext(ctx,
+		`SELECT audit_log_id, stdout, stderr, stdout_bytes, stderr_bytes, truncated, redactions, created_at
+		 FROM audit_outputs WHERE audit_log_id = $1`, auditLogID,
+	).Scan(&o.AuditLogID, &o.Stdout, &o.Stderr, &o.StdoutBytes, &o.StderrBytes, &o.Truncated, &o.Redactions, &o.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get audit output: %w", err)
+	}
+	o.CreatedAt = o.CreatedAt.UTC()
+	return &o, nil
+}
+
+// --- Policy Versions ---
+
+func (s *PostgresStore) CreatePolicyVersion(ctx context.Context, pv *PolicyVersion) error {
+	now := time.Now().UTC()
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO policy_versions (version, document, author, comment, created_at)
+		 SELECT COALESCE(MAX(version), 0) + 1, $1, $2, $3, $4 FROM policy_versions
+		 RETURNING version`,
+		pv.Document, pv.Author, pv.Comment, now,
+	).Scan(&pv.Version)
+	if err != nil {
+		return fmt.Errorf("create policy version: %w", err)
+	}
+	pv.CreatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetPolicyVersion(ctx context.Context, version int) (*PolicyVersion, error) {
+	return s.scanPolicyVersion(s.db.QueryRowContext(ctx,
+		`SELECT version, document, author, comment, created_at
+		 FROM policy_versions WHERE version = $1`, version))
+}
+
+func (s *PostgresStore) GetLatestPolicyVersion(ctx context.Context) (*PolicyVersion, error) {
+	return s.scanPolicyVersion(s.db.QueryRowContext(ctx,
+		`SELECT version, document, author, comment, created_at
+		 FROM policy_versions ORDER BY version DESC LIMIT 1`))
+}
+
+func (s *PostgresStore) scanPolicyVersion(row *sql.Row) (*PolicyVersion, error) {
+	var pv PolicyVersion
+	err := row.Scan(&pv.Version, &pv.Document, &pv.Author, &pv.Comment, &pv.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan policy version: %w", err)
+	}
+	pv.CreatedAt = pv.CreatedAt.UTC()
+	return &pv, nil
+}
+
+func (s *PostgresStore) ListPolicyVersions(ctx context.Context) ([]*PolicyVersion, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT version, author, comment, created_at FROM policy_versions ORDER BY version DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list policy versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*PolicyVersion
+	for rows.Next() {
+		var pv PolicyVersion
+		if err := rows.Scan(&pv.Version, &pv.Author, &pv.Comment, &pv.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan policy version: %w", err)
+		}
+		pv.CreatedAt = pv.CreatedAt.UTC()
+		versions = append(versions, &pv)
+	}
+	return versions, rows.Err()
+}
+
+// --- JIT Requests ---
+
+func (s *PostgresStore) CreateJITRequest(ctx context.Context, r *JITRequest) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO jit_requests (`+jitRequestColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		r.ID, nilIfEmpty(r.UserID), r.UserEmail, r.ClusterName, r.Role, r.Reason, r.DurationSec,
+		r.Status, r.Breakglass, nilIfEmpty(r.DecidedBy), utcPtr(r.DecidedAt), utcPtr(r.ExpiresAt), now,
+	)
+	if err != nil {
+		return fmt.Errorf("create jit request: %w", err)
+	}
+	r.CreatedAt = now
+	return nil
+}
+
+func (s *PostgresStore) GetJITRequest(ctx context.Context, id string) (*JITRequest, error) {
+	r, err := scanPostgresJITRequest(s.db.QueryRowContext(ctx,
+		`SELECT `+jitRequestColumns+` FROM jit_requests WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+func (s *PostgresStore) ListJITRequests(ctx context.Context, filter JITRequestFilter) ([]*JITRequest, error) {
+	var clauses []string
+	var args []any
+	if filter.UserEmail != "" {
+		clauses = append(clauses, "user_email = ?")
+		args = append(args, filter.UserEmail)
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, "status = ?")
+		args = append(args, filter.Status)
+	}
+	query := `SELECT ` + jitRequestColumns + ` FROM jit_requests`
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return s.queryJITRequests(ctx, rebind(query), args...)
+}
+
+func (s *PostgresStore) DecideJITRequest(ctx context.Context, r *JITRequest) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jit_requests SET status = $1, decided_by = $2, decided_at = $3, expires_at = $4
+		 WHERE id = $5 AND status = $6`,
+		r.Status, nilIfEmpty(r.DecidedBy), utcPtr(r.DecidedAt), utcPtr(r.ExpiresAt),
+		r.ID, JITStatusPending,
+	)
+	if err != nil {
+		return false, fmt.Errorf("decide jit request: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (s *PostgresStore) ListActiveJITRequests(ctx context.Context, now time.Time) ([]*JITRequest, error) {
+	return s.queryJITRequests(ctx,
+		`SELECT `+jitRequestColumns+` FROM jit_requests WHERE status = $1 AND expires_at > $2`,
+		JITStatusApproved, now.UTC())
+}
+
+func (s *PostgresStore) queryJITRequests(ctx context.Context, query string, args ...any) ([]*JITRequest, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list jit requests: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*JITRequest
+	for rows.Next() {
+		r, err := scanPostgresJITRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func scanPostgresJITRequest(row rowScanner) (*JITRequest, error) {
+	var r JITRequest
+	var userID, decidedBy *string
+	err := row.Scan(&r.ID, &userID, &r.UserEmail, &r.ClusterName, &r.Role, &r.Reason, &r.DurationSec,
+		&r.Status, &r.Breakglass, &decidedBy, &r.DecidedAt, &r.ExpiresAt, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan jit request: %w", err)
+	}
+	r.UserID = derefStr(userID)
+	r.DecidedBy = derefStr(decidedBy)
+	r.DecidedAt = utcPtr(r.DecidedAt)
+	r.ExpiresAt = utcPtr(r.ExpiresAt)
+	r.CreatedAt = r.CreatedAt.UTC()
+	return &r, nil
+}
@@ -56,6 +56,7 @@ func loginRateLimitMiddleware(l *loginLimiter) gin.HandlerFunc {
 		email := peekEmail(c)
 		key := c.ClientIP() + "|" + email
 		if !l.allow(key) {
+			rateLimitRejectionsTotal.WithLabelValues(c.FullPath()).Inc()
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many attempts, slow down"})
 			return
 		}
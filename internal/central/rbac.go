@@ -1,15 +1,34 @@
 package central
 
 import (
-	"strings"
+	"github.com/why-xn/kbridge/internal/auth"
 )
 
+// groupSubjectPrefix marks a policy binding subject that names a group rather
+// than a user, e.g. "group:platform-oncall".
+const groupSubjectPrefix = "group:"
+
+// Subject identifies who is asking for access: the user's email plus every
+// group they belong to, whether kbridge-managed or asserted by an SSO provider.
+type Subject struct {
+	Email  string
+	Groups []string
+}
+
+// subjectFromClaims builds the RBAC subject for an authenticated user.
+func subjectFromClaims(claims *auth.UserClaims) Subject {
+	return Subject{Email: claims.Email, Groups: claims.Groups}
+}
+
 // AccessRequest describes a single kubectl action a user wants to perform.
+// Names lists the objects named on the command line; it is empty when the
+// command acts on the whole collection (e.g. "get pods").
 type AccessRequest struct {
 	Cluster   string
 	Namespace string
 	Resource  string
 	Verb      string
+	Names     []string
 }
 
 // matchPattern reports whether value matches pattern, where '*' is a wildcard
@@ -40,70 +59,3 @@ func matchPattern(pattern, value string) bool {
 	}
 	return p == len(pattern)
 }
-
-// verbAllowed reports whether verb is contained in a comma-separated verbs
-// spec. A "*" entry allows any verb. Matching is case-insensitive.
-func verbAllowed(verbs, verb string) bool {
-	for _, v := range strings.Split(verbs, ",") {
-		v = strings.TrimSpace(v)
-		if v == "*" || strings.EqualFold(v, verb) {
-			return true
-		}
-	}
-	return false
-}
-
-// podScopedVerbs are kubectl verbs that operate on pods implicitly rather than
-// naming a resource type as their first argument.
-var podScopedVerbs = map[string]bool{
-	"logs": true, "exec": true, "attach": true, "port-forward": true, "cp": true,
-}
-
-// parseAccessRequest derives an AccessRequest from a kubectl command (the args
-// after "kubectl"). The verb is the first token; the resource is the first
-// non-flag token after it (with any "/name" suffix stripped), or "pods" for
-// pod-scoped verbs. The namespace comes from -n/--namespace, "*" for
-// --all-namespaces/-A, else fallbackNamespace, else "default".
-func parseAccessRequest(cluster string, command []string, fallbackNamespace string) AccessRequest {
-	req := AccessRequest{Cluster: cluster, Namespace: fallbackNamespace}
-	if req.Namespace == "" {
-		req.Namespace = "default"
-	}
-	if len(command) == 0 {
-		return req
-	}
-	req.Verb = command[0]
-
-	args := command[1:]
-	for i := 0; i < len(args); i++ {
-		a := args[i]
-		switch {
-		case a == "-A" || a == "--all-namespaces":
-			req.Namespace = "*"
-		case a == "-n" || a == "--namespace":
-			if i+1 < len(args) {
-				req.Namespace = args[i+1]
-				i++
-			}
-		case strings.HasPrefix(a, "--namespace="):
-			req.Namespace = strings.TrimPrefix(a, "--namespace=")
-		case strings.HasPrefix(a, "-n="):
-			req.Namespace = strings.TrimPrefix(a, "-n=")
-		}
-	}
-
-	if podScopedVerbs[req.Verb] {
-		req.Resource = "pods"
-	} else {
-		for _, a := range args {
-			if !strings.HasPrefix(a, "-") {
-				req.Resource = a
-				break
-			}
-		}
-	}
-	if idx := strings.IndexByte(req.Resource, '/'); idx >= 0 {
-		req.Resource = req.Resource[:idx]
-	}
-	return req
-}
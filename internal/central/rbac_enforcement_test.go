@@ -31,7 +31,12 @@ func newRBACTestServer(t *testing.T, policyYAML string) (*HTTPServer, *auth.JWTM
 
 func execRequest(t *testing.T, srv *HTTPServer, token string, command []string) *httptest.ResponseRecorder {
 	t.Helper()
-	body, _ := json.Marshal(ExecRequest{Command: command})
+	return execRequestBody(t, srv, token, ExecRequest{Command: command})
+}
+
+func execRequestBody(t *testing.T, srv *HTTPServer, token string, exec ExecRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(exec)
 	req, _ := http.NewRequest("POST", "/api/v1/clusters/prod/exec", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	if token != "" {
@@ -85,3 +90,248 @@ roles:
 		t.Fatalf("want 401 without token, got %d: %s", w.Code, w.Body.String())
 	}
 }
+
+// runFakeAgent completes every command queued for agentID with exit code 0
+// until the test ends, standing in for a polling agent.
+func runFakeAgent(t *testing.T, q *CommandQueue, agentID string) {
+	t.Helper()
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+			for _, cmd := range q.GetPendingForAgent(agentID) {
+				q.MarkRunning(cmd.RequestID)
+				q.Complete(cmd.RequestID, &CommandResult{RequestID: cmd.RequestID})
+			}
+		}
+	}()
+}
+
+func TestExecHandler_RBACGroupBinding(t *testing.T) {
+	srv, jm := newRBACTestServer(t, `
+roles:
+  - name: oncall
+    rules:
+      - clusters: ["prod"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+bindings:
+  - subject: group:platform-oncall
+    roles: ["oncall"]
+`)
+	runFakeAgent(t, srv.commandQueue, "a1")
+
+	tests := []struct {
+		name   string
+		groups []string
+		want   int
+	}{
+		{"member of bound group", []string{"dev", "platform-oncall"}, http.StatusOK},
+		{"not a member", []string{"dev"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "sre@x.com", Groups: tt.groups})
+			if err != nil {
+				t.Fatalf("token: %v", err)
+			}
+			w := execRequest(t, srv, token, []string{"delete", "pods", "web-1"})
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExecHandler_RBACRequiresEveryResource(t *testing.T) {
+	srv, jm := newRBACTestServer(t, `
+default: viewer
+roles:
+  - name: viewer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["pods", "services"]
+        verbs: ["get"]
+`)
+	runFakeAgent(t, srv.commandQueue, "a1")
+	token, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command []string
+		want    int
+	}{
+		{"all types allowed", []string{"get", "po,svc"}, http.StatusOK},
+		{"one type denied", []string{"get", "pods,secrets"}, http.StatusForbidden},
+		{"one object denied", []string{"get", "pod/web", "secret/db"}, http.StatusForbidden},
+		{"manifest needs wildcard resource", []string{"get", "-f", "app.yaml"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := execRequest(t, srv, token, tt.command)
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExecHandler_RBACAuthorizesStdinManifest(t *testing.T) {
+	srv, jm := newRBACTestServer(t, `
+default: deployer
+roles:
+  - name: deployer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["app"]
+        resources: ["deployments", "services", "configmaps"]
+        verbs: ["apply", "create"]
+`)
+	runFakeAgent(t, srv.commandQueue, "a1")
+	token, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		stdin string
+		want  int
+	}{
+		{"all objects allowed", "kind: Deployment\nmetadata: {name: api}\n---\nkind: Service\nmetadata: {name: api}\n", http.StatusOK},
+		{"one object forbidden", "kind: Deployment\nmetadata: {name: api}\n---\nkind: Secret\nmetadata: {name: db}\n", http.StatusForbidden},
+		{"object in another namespace", "kind: ConfigMap\nmetadata: {name: c, namespace: kube-system}\n", http.StatusForbidden},
+		{"forbidden object inside a List", `{"kind":"List","items":[{"kind":"ConfigMap","metadata":{"name":"c"}},{"kind":"Role","metadata":{"name":"r"}}]}`, http.StatusForbidden},
+		{"invalid manifest", "kind: [\n", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := execRequestBody(t, srv, token, ExecRequest{
+				Command: []string{"apply", "-f", "-"}, Namespace: "app", Stdin: tt.stdin,
+			})
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExecHandler_RBACManifestClusterScopedKinds(t *testing.T) {
+	srv, jm := newRBACTestServer(t, `
+default: developer
+roles:
+  - name: developer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["dev-*"]
+        resources: ["*"]
+        verbs: ["*"]
+`)
+	runFakeAgent(t, srv.commandQueue, "a1")
+	token, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		stdin string
+		want  int
+	}{
+		{"namespaced object", "kind: RoleBinding\nmetadata: {name: edit}\n", http.StatusOK},
+		{"cluster role binding", "kind: ClusterRoleBinding\nmetadata: {name: me-admin}\nroleRef: {kind: ClusterRole, name: cluster-admin}\n", http.StatusForbidden},
+		{"cluster role binding claiming a namespace", "kind: ClusterRoleBinding\nmetadata: {name: me-admin, namespace: dev-x}\n", http.StatusForbidden},
+		{"namespace", "kind: Namespace\nmetadata: {name: prod}\n", http.StatusForbidden},
+		{"custom resource definition", "kind: CustomResourceDefinition\nmetadata: {name: widgets.example.com}\n", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := execRequestBody(t, srv, token, ExecRequest{
+				Command: []string{"apply", "-n", "dev-x", "-f", "-"}, Stdin: tt.stdin,
+			})
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExecHandler_RBACCpChecksEveryNamespace(t *testing.T) {
+	srv, jm := newRBACTestServer(t, `
+default: developer
+roles:
+  - name: developer
+    rules:
+      - clusters: ["*"]
+        namespaces: ["app"]
+        resources: ["pods"]
+        verbs: ["cp"]
+`)
+	runFakeAgent(t, srv.commandQueue, "a1")
+	token, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command []string
+		want    int
+	}{
+		{"pod in allowed namespace", []string{"cp", "app/web-1:/tmp/x", "./x"}, http.StatusOK},
+		{"prefix overrides -n", []string{"cp", "-n", "app", "prod/db-0:/etc/x", "./x"}, http.StatusForbidden},
+		{"across namespaces", []string{"cp", "-n", "app", "web-1:/tmp/x", "prod/db-0:/tmp/x"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := execRequest(t, srv, token, tt.command)
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExecHandler_RBACDeniesSingularCustomResource(t *testing.T) {
+	srv, jm := newRBACTestServer(t, `
+default: operator
+roles:
+  - name: operator
+    rules:
+      - clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["*"]
+        verbs: ["*"]
+      - effect: deny
+        clusters: ["*"]
+        namespaces: ["*"]
+        resources: ["certificates", "mutatingwebhookconfigurations"]
+        verbs: ["delete"]
+`)
+	token, err := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	for _, command := range [][]string{
+		{"delete", "certificate", "web-tls"},
+		{"delete", "certificate.cert-manager.io/web-tls"},
+		{"delete", "certificates", "web-tls"},
+		{"delete", "mutatingwebhookconfiguration", "hook"},
+		{"delete", "MutatingWebhookConfiguration.admissionregistration.k8s.io/hook"},
+	} {
+		if w := execRequest(t, srv, token, command); w.Code != http.StatusForbidden {
+			t.Errorf("%q: got %d, want 403: %s", command, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package central
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultRecordingMaxBytes caps one session recording when
+// audit.recording.max_bytes is not set.
+const DefaultRecordingMaxBytes = 10 << 20
+
+// CastContentType is the media type of an asciicast v2 recording.
+const CastContentType = "application/x-asciicast"
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// sessionRecorder captures an interactive session as asciicast v2: a JSON
+// header line followed by one [seconds, code, data] event per line, where code
+// is "o" for output, "i" for input and "r" for a resize ("COLSxROWS").
+// Recording stops, with a final marker event, once maxBytes is reached. A nil
+// recorder records nothing.
+type sessionRecorder struct {
+	mu        sync.Mutex
+	start     time.Time
+	buf       bytes.Buffer
+	maxBytes  int
+	truncated bool
+	// partial holds a UTF-8 sequence split across chunks, per event code, so
+	// multi-byte characters are not mangled into replacement characters.
+	partial map[string][]byte
+}
+
+func newSessionRecorder(width, height int, command, title string, maxBytes int64) *sessionRecorder {
+	if maxBytes <= 0 {
+		maxBytes = DefaultRecordingMaxBytes
+	}
+	r := &sessionRecorder{start: time.Now(), maxBytes: int(maxBytes), partial: make(map[string][]byte)}
+	hdr, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Command:   command,
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	r.buf.Write(hdr)
+	r.buf.WriteByte('\n')
+	return r
+}
+
+// Output records bytes sent to the user's terminal.
+func (r *sessionRecorder) Output(data []byte) { r.event("o", data) }
+
+// Input records bytes typed by the user.
+func (r *sessionRecorder) Input(data []byte) { r.event("i", data) }
+
+// Resize records a terminal resize.
+func (r *sessionRecorder) Resize(rows, cols uint16) {
+	r.event("r", []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+func (r *sessionRecorder) event(code string, data []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return
+	}
+	if p := r.partial[code]; len(p) > 0 {
+		data = append(p, data...)
+	}
+	data, r.partial[code] = splitIncompleteUTF8(data)
+	if len(data) == 0 {
+		return
+	}
+	line, _ := json.Marshal([]any{r.elapsed(), code, string(data)})
+	if r.buf.Len()+len(line)+1 > r.maxBytes {
+		r.truncated = true
+		line, _ = json.Marshal([]any{r.elapsed(), "o", "\r\n[kbridge: recording truncated]\r\n"})
+	}
+	r.buf.Write(line)
+	r.buf.WriteByte('\n')
+}
+
+// elapsed returns seconds since the start, rounded to microseconds.
+func (r *sessionRecorder) elapsed() float64 {
+	return float64(time.Since(r.start).Microseconds()) / 1e6
+}
+
+// Bytes returns the recording so far.
+func (r *sessionRecorder) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return bytes.Clone(r.buf.Bytes())
+}
+
+// Truncated reports whether the size limit cut the recording short.
+func (r *sessionRecorder) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+// splitIncompleteUTF8 splits off a trailing, not yet complete UTF-8 sequence.
+func splitIncompleteUTF8(b []byte) (complete, rest []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if !utf8.FullRune(b[i:]) {
+			return b[:i], bytes.Clone(b[i:])
+		}
+		break
+	}
+	return b, nil
+}
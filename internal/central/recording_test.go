@@ -0,0 +1,191 @@
+package central
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// castEvents parses an asciicast v2 recording into its header and events.
+func castEvents(t *testing.T, cast []byte) (castHeader, [][]any) {
+	t.Helper()
+	sc := bufio.NewScanner(bytes.NewReader(cast))
+	if !sc.Scan() {
+		t.Fatal("recording has no header")
+	}
+	var hdr castHeader
+	if err := json.Unmarshal(sc.Bytes(), &hdr); err != nil {
+		t.Fatalf("header: %v", err)
+	}
+	var events [][]any
+	for sc.Scan() {
+		var ev []any
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil || len(ev) != 3 {
+			t.Fatalf("bad event line %q: %v", sc.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	return hdr, events
+}
+
+func TestSessionRecorder(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T)
+	}{
+		{"header and events", func(t *testing.T) {
+			r := newSessionRecorder(120, 40, "exec web -- sh", "dev@x.com@prod", 0)
+			r.Output([]byte("$ "))
+			r.Input([]byte("ls\r"))
+			r.Resize(50, 132)
+			hdr, events := castEvents(t, r.Bytes())
+			if hdr.Version != 2 || hdr.Width != 120 || hdr.Height != 40 || hdr.Command != "exec web -- sh" {
+				t.Errorf("unexpected header: %+v", hdr)
+			}
+			want := [][2]string{{"o", "$ "}, {"i", "ls\r"}, {"r", "132x50"}}
+			if len(events) != len(want) {
+				t.Fatalf("want %d events, got %v", len(want), events)
+			}
+			for i, w := range want {
+				if events[i][1] != w[0] || events[i][2] != w[1] {
+					t.Errorf("event %d = %v, want %v", i, events[i], w)
+				}
+			}
+		}},
+		{"multi-byte characters split across chunks", func(t *testing.T) {
+			r := newSessionRecorder(80, 24, "", "", 0)
+			euro := []byte("€") // 3 bytes
+			r.Output(euro[:1])
+			r.Output(append(euro[1:], '!'))
+			_, events := castEvents(t, r.Bytes())
+			if len(events) != 1 || events[0][2] != "€!" {
+				t.Fatalf("want one event \"€!\", got %v", events)
+			}
+		}},
+		{"stops at the size limit", func(t *testing.T) {
+			r := newSessionRecorder(80, 24, "", "", 200)
+			for i := 0; i < 20; i++ {
+				r.Output([]byte("0123456789"))
+			}
+			if !r.Truncated() {
+				t.Fatal("recording should be truncated")
+			}
+			_, events := castEvents(t, r.Bytes())
+			if last := events[len(events)-1][2].(string); !strings.Contains(last, "recording truncated") {
+				t.Errorf("last event should mark the truncation, got %q", last)
+			}
+			n := len(r.Bytes())
+			r.Output([]byte("more"))
+			if len(r.Bytes()) != n {
+				t.Error("nothing should be recorded after truncation")
+			}
+		}},
+		{"nil recorder records nothing", func(t *testing.T) {
+			var r *sessionRecorder
+			r.Output([]byte("x"))
+			r.Input([]byte("x"))
+			r.Resize(1, 1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.fn)
+	}
+}
+
+func TestSessionRecordingStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+		entry := &AuditLog{UserEmail: "dev@x.com", ClusterName: "prod", Command: "exec web -- sh", Status: AuditStatusSuccess}
+		if err := store.CreateAuditLog(ctx, entry); err != nil {
+			t.Fatalf("create audit log: %v", err)
+		}
+		rec := &SessionRecording{AuditLogID: entry.ID, UserEmail: "dev@x.com", ClusterName: "prod",
+			Namespace: "web", Command: entry.Command, DurationMs: 1500, Data: []byte("cast")}
+		if err := store.CreateSessionRecording(ctx, rec); err != nil {
+			t.Fatalf("create recording: %v", err)
+		}
+		if err := store.CreateSessionRecording(ctx, &SessionRecording{AuditLogID: entry.ID,
+			UserEmail: "ops@x.com", ClusterName: "staging", Command: "exec db -- psql"}); err != nil {
+			t.Fatalf("create recording: %v", err)
+		}
+
+		got, err := store.GetSessionRecording(ctx, rec.ID)
+		if err != nil || got == nil {
+			t.Fatalf("get = %v, %v", got, err)
+		}
+		if string(got.Data) != "cast" || got.Size != 4 || got.Namespace != "web" || got.DurationMs != 1500 {
+			t.Errorf("unexpected recording: %+v", got)
+		}
+		if missing, err := store.GetSessionRecording(ctx, "nope"); err != nil || missing != nil {
+			t.Errorf("missing recording = %v, %v; want nil, nil", missing, err)
+		}
+
+		list, err := store.ListSessionRecordings(ctx, SessionRecordingFilter{ClusterName: "prod"})
+		if err != nil || len(list) != 1 || list[0].ID != rec.ID {
+			t.Fatalf("list by cluster = %v, %v", list, err)
+		}
+		if list[0].Data != nil {
+			t.Error("list should not load recording data")
+		}
+		if all, _ := store.ListSessionRecordings(ctx, SessionRecordingFilter{Limit: 1}); len(all) != 1 {
+			t.Errorf("limit 1 returned %d recordings", len(all))
+		}
+
+		// Recordings go with their audit entry.
+		store.exec(t, `UPDATE audit_logs SET created_at = ?`, time.Now().Add(-48*time.Hour))
+		if _, err := store.CleanupOldAuditLogs(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+			t.Fatalf("cleanup: %v", err)
+		}
+		if all, _ := store.ListSessionRecordings(ctx, SessionRecordingFilter{}); len(all) != 0 {
+			t.Errorf("recordings outlived their audit entry: %v", all)
+		}
+	})
+}
+
+func TestAdminHandler_SessionRecordings(t *testing.T) {
+	ah, store := newTestAdminHandlers(t)
+	ctx := context.Background()
+	entry := &AuditLog{UserEmail: "dev@x.com", ClusterName: "prod", Command: "exec web -- sh", Status: AuditStatusSuccess}
+	if err := store.CreateAuditLog(ctx, entry); err != nil {
+		t.Fatalf("create audit log: %v", err)
+	}
+	cast := newSessionRecorder(80, 24, entry.Command, "", 0)
+	cast.Output([]byte("hello"))
+	rec := &SessionRecording{AuditLogID: entry.ID, UserEmail: "dev@x.com", ClusterName: "prod",
+		Command: entry.Command, Data: cast.Bytes()}
+	if err := store.CreateSessionRecording(ctx, rec); err != nil {
+		t.Fatalf("create recording: %v", err)
+	}
+
+	w := doRequest(t, "GET", "/api/v1/admin/sessions", ah.HandleListSessionRecordings,
+		"GET", "/api/v1/admin/sessions?user=dev@x.com", nil)
+	var list struct {
+		Sessions []SessionRecording `json:"sessions"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil || len(list.Sessions) != 1 {
+		t.Fatalf("list: %d %s", w.Code, w.Body.String())
+	}
+	if list.Sessions[0].AuditLogID != entry.ID {
+		t.Errorf("unexpected session: %+v", list.Sessions[0])
+	}
+
+	w = doRequest(t, "GET", "/api/v1/admin/sessions/:id/cast", ah.HandleDownloadSessionRecording,
+		"GET", "/api/v1/admin/sessions/"+rec.ID+"/cast", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != CastContentType {
+		t.Fatalf("cast: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !bytes.Equal(w.Body.Bytes(), rec.Data) {
+		t.Errorf("cast body = %q", w.Body.String())
+	}
+
+	w = doRequest(t, "GET", "/api/v1/admin/sessions/:id", ah.HandleGetSessionRecording,
+		"GET", "/api/v1/admin/sessions/nope", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing recording: want 404, got %d", w.Code)
+	}
+}
@@ -27,8 +27,10 @@ type Server struct {
 	grpcServer   *grpc.Server
 	agentStore   *AgentStore
 	store        Store
+	audit        *AuditRecorder
 	commandQueue *CommandQueue
 	policy       *PolicyEngine
+	jit          *JITManager
 	stopCh       chan struct{}
 }
 
@@ -37,16 +39,14 @@ func NewServer(cfg *Config) (*Server, error) {
 	agentStore := NewAgentStore()
 	commandQueue := NewCommandQueue()
 
-	// Open SQLite store
-	dbStore, err := NewSQLiteStore(cfg.Database.Path)
+	dbStore, err := OpenStore(cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
 
-	// Run migrations
-	if err := dbStore.Migrate(context.Background()); err != nil {
+	if err := prepareSchema(dbStore, cfg.Database.AutoMigrate); err != nil {
 		dbStore.Close()
-		return nil, fmt.Errorf("running migrations: %w", err)
+		return nil, err
 	}
 
 	// Seed admin user if configured
@@ -62,13 +62,28 @@ func NewServer(cfg *Config) (*Server, error) {
 	// Set up auth components
 	jwtManager := auth.NewJWTManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenExpiry)
 	authHandlers := NewAuthHandlers(dbStore, jwtManager, cfg.Auth.RefreshTokenExpiry)
+	if cfg.Auth.OIDC.Enabled {
+		authHandlers.SetOIDCProvider(NewOIDCProvider(cfg.Auth.OIDC))
+		log.Printf("SSO login enabled via %s", cfg.Auth.OIDC.IssuerURL)
+	}
 	adminHandlers := NewAdminHandlers(dbStore, cfg.AgentTokenPepper())
+	auditChain := NewAuditChain([]byte(cfg.Audit.ChainSecret))
+	dbStore.SetAuditChain(auditChain)
+	adminHandlers.SetAuditChain(auditChain)
 	authenticator := NewAgentAuthenticator(dbStore, cfg.AgentTokenPepper())
-	auditRecorder := NewAuditRecorder(dbStore)
 
 	// Load the RBAC policy if configured; nil engine means enforcement is off.
 	var policy *PolicyEngine
-	if cfg.RBAC.PolicyFile != "" {
+	if cfg.RBAC.Source == RBACSourceDatabase {
+		ctx, cancel := context.WithTimeout(context.Background(), policyStoreTimeout)
+		policy, err = NewPolicyEngineFromStore(ctx, dbStore, cfg.RBAC.PolicyFile, cfg.RBAC.PollInterval)
+		cancel()
+		if err != nil {
+			dbStore.Close()
+			return nil, fmt.Errorf("loading rbac policy: %w", err)
+		}
+		log.Printf("RBAC enforcement enabled from the database (version %d)", policy.Version())
+	} else if cfg.RBAC.PolicyFile != "" {
 		policy, err = NewPolicyEngineFromFile(cfg.RBAC.PolicyFile)
 		if err != nil {
 			dbStore.Close()
@@ -79,13 +94,57 @@ func NewServer(cfg *Config) (*Server, error) {
 		log.Printf("RBAC enforcement disabled (no rbac.policy_file configured)")
 	}
 
+	auditSinks, err := NewAuditSinks(cfg.Audit.Sinks)
+	if err != nil {
+		dbStore.Close()
+		return nil, fmt.Errorf("configuring audit sinks: %w", err)
+	}
+	if len(auditSinks) > 0 {
+		log.Printf("Forwarding audit entries to %d sink(s)", len(auditSinks))
+	}
+	auditRecorder := NewAuditRecorder(dbStore, auditSinks...)
+
 	sessionManager := NewSessionManager(cfg.Streams.MaxConcurrent)
 
 	httpHandler := NewHTTPServer(agentStore, commandQueue, authHandlers, adminHandlers, policy, auditRecorder, sessionManager, jwtManager)
+	if cfg.Metrics.Enabled {
+		httpHandler.SetMetrics(cfg.Metrics)
+	}
+	if cfg.Audit.Recording.Enabled {
+		log.Printf("Recording interactive exec sessions")
+		httpHandler.SetSessionRecording(cfg.Audit.Recording)
+	}
+	if cfg.Audit.Output.enabled() {
+		capture, err := NewOutputCapture(cfg.Audit.Output)
+		if err != nil {
+			auditRecorder.Close()
+			dbStore.Close()
+			return nil, fmt.Errorf("configuring audit output capture: %w", err)
+		}
+		log.Printf("Capturing command output in the audit log (mode %s)", cfg.Audit.Output.Mode)
+		httpHandler.SetOutputCapture(capture)
+	}
+
+	// JIT access requests grant temporary roles on top of the policy.
+	var jit *JITManager
+	if policy != nil {
+		jit = NewJITManager(dbStore, policy, cfg.RBAC.JIT)
+		ctx, cancel := context.WithTimeout(context.Background(), jitRefreshTimeout)
+		err = jit.Refresh(ctx)
+		cancel()
+		if err != nil {
+			auditRecorder.Close()
+			dbStore.Close()
+			return nil, err
+		}
+		httpHandler.SetJITManager(jit)
+		httpHandler.SetApprovalQueue(NewApprovalQueue(cfg.RBAC.ApprovalTimeout))
+	}
 	grpcHandler := NewGRPCServer(agentStore, commandQueue, authenticator, sessionManager)
 
 	grpcOpts, err := grpcServerOptions(cfg.TLS)
 	if err != nil {
+		auditRecorder.Close()
 		dbStore.Close()
 		return nil, fmt.Errorf("configuring grpc tls: %w", err)
 	}
@@ -110,13 +169,48 @@ func NewServer(cfg *Config) (*Server, error) {
 		grpcServer:   grpcSrv,
 		agentStore:   agentStore,
 		store:        dbStore,
+		audit:        auditRecorder,
 		commandQueue: commandQueue,
 		policy:       policy,
+		jit:          jit,
 		stopCh:       make(chan struct{}),
 	}, nil
 }
 
-func seedAdminUser(store *SQLiteStore, cfg *Config) {
+// OpenStore opens the Store backend selected by cfg.Driver.
+func OpenStore(cfg DatabaseConfig) (Store, error) {
+	switch cfg.Driver {
+	case "postgres":
+		return NewPostgresStore(cfg.DSN)
+	case "sqlite", "":
+		return NewSQLiteStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// prepareSchema applies pending migrations, or with autoMigrate off only
+// verifies that none are pending. Either way a database migrated by a newer
+// release is rejected.
+func prepareSchema(store Store, autoMigrate bool) error {
+	ctx := context.Background()
+	if autoMigrate {
+		if err := store.Migrate(ctx); err != nil {
+			return fmt.Errorf("running migrations: %w", err)
+		}
+		return nil
+	}
+	statuses, err := store.MigrationStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("reading migration status: %w", err)
+	}
+	if err := CheckSchema(statuses); err != nil {
+		return fmt.Errorf("checking schema: %w", err)
+	}
+	return nil
+}
+
+func seedAdminUser(store Store, cfg *Config) {
 	ctx := context.Background()
 	existing, _ := store.GetUserByEmail(ctx, cfg.Auth.AdminEmail)
 	if existing != nil {
@@ -145,7 +239,7 @@ func seedAdminUser(store *SQLiteStore, cfg *Config) {
 
 // seedAgentToken creates a bootstrap agent token (and its cluster) if one with
 // the same value does not already exist. Idempotent across restarts.
-func seedAgentToken(store *SQLiteStore, cfg *Config) {
+func seedAgentToken(store Store, cfg *Config) {
 	ctx := context.Background()
 	hash := hashAgentToken(cfg.AgentTokenPepper(), cfg.Bootstrap.AgentToken)
 	if existing, _ := store.GetAgentTokenByHash(ctx, hash); existing != nil {
@@ -200,6 +294,15 @@ func (s *Server) Run() error {
 		go s.runPolicyReloadOnSignal()
 	}
 
+	// Pick up JIT approvals made through other replicas.
+	if s.jit != nil {
+		interval := s.config.RBAC.PollInterval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		go s.jit.Run(interval, s.stopCh)
+	}
+
 	// Start the audit log retention cleanup loop if configured
 	if s.config.Audit.RetentionDays > 0 && s.config.Audit.CleanupInterval > 0 {
 		go s.runAuditCleanup()
@@ -375,6 +478,11 @@ func (s *Server) shutdown() error {
 	}
 	log.Println("HTTP server stopped")
 
+	// Flush audit sinks before the database goes away
+	if s.audit != nil {
+		s.audit.Close()
+	}
+
 	// Close database
 	if s.store != nil {
 		s.store.Close()
@@ -3,6 +3,7 @@ package central
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -15,7 +16,8 @@ const timeFormat = "2006-01-02T15:04:05Z"
 
 // SQLiteStore implements Store using SQLite.
 type SQLiteStore struct {
-	db *sql.DB
+	db    *sql.DB
+	chain *AuditChain
 }
 
 // NewSQLiteStore opens a SQLite database and returns a store.
@@ -37,12 +39,27 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 	if _, err := db.Exec("PRAGMA journal_mode = WAL"); err != nil {
 		return nil, fmt.Errorf("set journal mode: %w", err)
 	}
-	return &SQLiteStore{db: db}, nil
+	return &SQLiteStore{db: db, chain: NewAuditChain(nil)}, nil
 }
 
-// Migrate creates the schema.
+// SetAuditChain sets the hasher used to chain new audit entries.
+func (s *SQLiteStore) SetAuditChain(chain *AuditChain) {
+	s.chain = chain
+}
+
+// Migrate applies pending schema migrations.
 func (s *SQLiteStore) Migrate(ctx context.Context) error {
-	return createSchema(s.db)
+	_, err := s.migrator().up(ctx)
+	return err
+}
+
+// MigrationStatus reports the state of every known schema migration.
+func (s *SQLiteStore) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return s.migrator().status(ctx, s.db)
+}
+
+func (s *SQLiteStore) migrator() *migrator {
+	return &migrator{db: s.db, migrations: sqliteMigrations}
 }
 
 // Close closes the database connection.
@@ -77,9 +94,9 @@ func (s *SQLiteStore) CreateUser(ctx context.Context, user *User) error {
 	}
 	now := time.Now().UTC().Format(timeFormat)
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO users (id, email, password_hash, name, is_active, is_admin, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		user.ID, user.Email, user.PasswordHash, user.Name, user.IsActive, user.IsAdmin, now, now,
+		`INSERT INTO users (id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Email, user.PasswordHash, user.Name, user.IsActive, user.IsAdmin, encodeStringList(user.Groups), now, now,
 	)
 	if err != nil {
 		return fmt.Errorf("create user: %w", err)
@@ -91,27 +108,28 @@ func (s *SQLiteStore) CreateUser(ctx context.Context, user *User) error {
 
 func (s *SQLiteStore) GetUserByID(ctx context.Context, id string) (*User, error) {
 	return s.scanUser(s.db.QueryRowContext(ctx,
-		`SELECT id, email, password_hash, name, is_active, is_admin, created_at, updated_at
+		`SELECT id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at
 		 FROM users WHERE id = ?`, id))
 }
 
 func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	return s.scanUser(s.db.QueryRowContext(ctx,
-		`SELECT id, email, password_hash, name, is_active, is_admin, created_at, updated_at
+		`SELECT id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at
 		 FROM users WHERE email = ?`, email))
 }
 
 func (s *SQLiteStore) scanUser(row *sql.Row) (*User, error) {
 	var u User
 	var isActive, isAdmin int
-	var createdAt, updatedAt string
-	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &isActive, &isAdmin, &createdAt, &updatedAt)
+	var groups, createdAt, updatedAt string
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &isActive, &isAdmin, &groups, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("scan user: %w", err)
 	}
+	u.Groups = decodeStringList(groups)
 	u.IsActive = isActive != 0
 	u.IsAdmin = isAdmin != 0
 	u.CreatedAt, _ = time.Parse(timeFormat, createdAt)
@@ -121,7 +139,7 @@ func (s *SQLiteStore) scanUser(row *sql.Row) (*User, error) {
 
 func (s *SQLiteStore) ListUsers(ctx context.Context) ([]*User, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, email, password_hash, name, is_active, is_admin, created_at, updated_at FROM users`)
+		`SELECT id, email, password_hash, name, is_active, is_admin, group_names, created_at, updated_at FROM users`)
 	if err != nil {
 		return nil, fmt.Errorf("list users: %w", err)
 	}
@@ -134,11 +152,12 @@ func (s *SQLiteStore) scanUsers(rows *sql.Rows) ([]*User, error) {
 	for rows.Next() {
 		var u User
 		var isActive, isAdmin int
-		var createdAt, updatedAt string
-		err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &isActive, &isAdmin, &createdAt, &updatedAt)
+		var groups, createdAt, updatedAt string
+		err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &isActive, &isAdmin, &groups, &createdAt, &updatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("scan user row: %w", err)
 		}
+		u.Groups = decodeStringList(groups)
 		u.IsActive = isActive != 0
 		u.IsAdmin = isAdmin != 0
 		u.CreatedAt, _ = time.Parse(timeFormat, createdAt)
@@ -151,8 +170,8 @@ func (s *SQLiteStore) scanUsers(rows *sql.Rows) ([]*User, error) {
 func (s *SQLiteStore) UpdateUser(ctx context.Context, user *User) error {
 	now := time.Now().UTC().Format(timeFormat)
 	_, err := s.db.ExecContext(ctx,
-		`UPDATE users SET email = ?, name = ?, password_hash = ?, is_active = ?, is_admin = ?, updated_at = ? WHERE id = ?`,
-		user.Email, user.Name, user.PasswordHash, user.IsActive, user.IsAdmin, now, user.ID,
+		`UPDATE users SET email = ?, name = ?, password_hash = ?, is_active = ?, is_admin = ?, group_names = ?, updated_at = ? WHERE id = ?`,
+		user.Email, user.Name, user.PasswordHash, user.IsActive, user.IsAdmin, encodeStringList(user.Groups), now, user.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update user: %w", err)
@@ -234,6 +253,27 @@ func derefStr(s *string) string {
 	return *s
 }
 
+// encodeStringList stores a string slice as a JSON array in a TEXT column;
+// an empty slice is stored as "".
+func encodeStringList(v []string) string {
+	if len(v) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func decodeStringList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var v []string
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil
+	}
+	return v
+}
+
 func (s *SQLiteStore) ListClusters(ctx context.Context) ([]*Cluster, error) {
 	rows, err := s.db.QueryContext(ctx,
 		`SELECT id, name, status, agent_id, last_seen_at, created_at, updated_at
@@ -386,9 +426,9 @@ func (s *SQLiteStore) CreateRefreshToken(ctx context.Context, rt *RefreshToken)
 	}
 	now := time.Now().UTC().Format(timeFormat)
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at)
-		 VALUES (?, ?, ?, ?, ?)`,
-		rt.ID, rt.UserID, rt.TokenHash, rt.ExpiresAt.UTC().Format(timeFormat), now,
+		`INSERT INTO refresh_tokens (id, user_id, token_hash, idp_groups, expires_at, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		rt.ID, rt.UserID, rt.TokenHash, encodeStringList(rt.Groups), rt.ExpiresAt.UTC().Format(timeFormat), now,
 	)
 	if err != nil {
 		return fmt.Errorf("create refresh token: %w", err)
@@ -399,17 +439,18 @@ func (s *SQLiteStore) CreateRefreshToken(ctx context.Context, rt *RefreshToken)
 
 func (s *SQLiteStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
 	var rt RefreshToken
-	var expiresAt, createdAt string
+	var groups, expiresAt, createdAt string
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, user_id, token_hash, expires_at, created_at
+		`SELECT id, user_id, token_hash, idp_groups, expires_at, created_at
 		 FROM refresh_tokens WHERE token_hash = ?`, tokenHash,
-	).Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &expiresAt, &createdAt)
+	).Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &groups, &expiresAt, &createdAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get refresh token: %w", err)
 	}
+	rt.Groups = decodeStringList(groups)
 	rt.ExpiresAt, _ = time.Parse(timeFormat, expiresAt)
 	rt.CreatedAt, _ = time.Parse(timeFormat, createdAt)
 	return &rt, nil
@@ -442,26 +483,66 @@ func (s *SQLiteStore) CleanupExpiredRefreshTokens(ctx context.Context) error {
 
 // --- Audit Logs ---
 
+// CreateAuditLog appends log to the audit chain. The single connection
+// serialises appends, so reading the head and inserting in one transaction is
+// enough to keep the chain linear.
 func (s *SQLiteStore) CreateAuditLog(ctx context.Context, log *AuditLog) error {
 	if log.ID == "" {
 		log.ID = uuid.New().String()
 	}
 	now := time.Now().UTC().Format(timeFormat)
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO audit_logs (id, user_id, user_email, cluster_name, cluster_id, command, namespace, status, exit_code, duration_ms, error_message, client_ip, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	log.CreatedAt, _ = time.Parse(timeFormat, now)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
+	defer tx.Rollback()
+	headSeq, headHash, err := auditChainHead(ctx, tx)
+	if err != nil {
+		return err
+	}
+	s.chain.link(log, headSeq, headHash)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_logs (id, user_id, user_email, cluster_name, cluster_id, command, namespace, status, exit_code, duration_ms, error_message, client_ip, created_at, seq, prev_hash, hash, verb, resource)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		log.ID, nilIfEmpty(log.UserID), log.UserEmail, log.ClusterName,
 		nilIfEmpty(log.ClusterID), log.Command, nilIfEmpty(log.Namespace),
 		log.Status, log.ExitCode, log.DurationMs,
 		nilIfEmpty(log.ErrorMessage), nilIfEmpty(log.ClientIP), now,
+		log.Seq, log.PrevHash, log.Hash, nilIfEmpty(log.Verb), nilIfEmpty(log.Resource),
 	)
 	if err != nil {
 		return fmt.Errorf("create audit log: %w", err)
 	}
-	log.CreatedAt, _ = time.Parse(timeFormat, now)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
 	return nil
 }
 
+// auditChainHead returns the sequence number and hash that the next audit
+// entry links to: the newest chained entry, else the latest checkpoint if
+// cleanup removed every entry, else zero values for an empty chain. The query
+// has no placeholders, so both stores share it.
+func auditChainHead(ctx context.Context, tx *sql.Tx) (int64, string, error) {
+	var seq int64
+	var hash string
+	err := tx.QueryRowContext(ctx,
+		`SELECT seq, hash FROM audit_logs WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1`).Scan(&seq, &hash)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx,
+			`SELECT seq, hash FROM audit_checkpoints ORDER BY seq DESC LIMIT 1`).Scan(&seq, &hash)
+	}
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("reading audit chain head: %w", err)
+	}
+	return seq, hash, nil
+}
+
 func (s *SQLiteStore) ListAuditLogs(ctx context.Context, filter AuditLogFilter) ([]*AuditLog, int, error) {
 	where, args := buildAuditFilter(filter)
 
@@ -473,8 +554,7 @@ func (s *SQLiteStore) ListAuditLogs(ctx context.Context, filter AuditLogFilter)
 	}
 
 	// Fetch page
-	query := `SELECT id, user_id, user_email, cluster_name, cluster_id, command, namespace, status, exit_code, duration_ms, error_message, client_ip, created_at
-		 FROM audit_logs` + where + ` ORDER BY created_at DESC`
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs` + where + auditLogOrder(filter)
 	pageArgs := append([]any{}, args...)
 	if filter.PerPage > 0 {
 		query += " LIMIT ? OFFSET ?"
@@ -502,6 +582,61 @@ func (s *SQLiteStore) ListAuditLogs(ctx context.Context, filter AuditLogFilter)
 	return logs, total, rows.Err()
 }
 
+func (s *SQLiteStore) ListAuditLogsAfter(ctx context.Context, filter AuditLogFilter, after *AuditCursor, limit int) ([]*AuditLog, error) {
+	query, args := buildAuditPageQuery(filter, after, limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit logs: %w", err)
+	}
+	defer rows.Close()
+	var logs []*AuditLog
+	for rows.Next() {
+		l, err := scanAuditRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// buildAuditPageQuery returns the keyset-paginated listing query, with "?"
+// placeholders, for ListAuditLogsAfter.
+func buildAuditPageQuery(f AuditLogFilter, after *AuditCursor, limit int) (string, []any) {
+	where, args := buildAuditFilter(f)
+	if after != nil {
+		op := "<"
+		if f.Ascending {
+			op = ">"
+		}
+		// RFC3339Nano matches the SQLite column format for whole seconds and
+		// keeps PostgreSQL's microseconds.
+		ts := after.CreatedAt.UTC().Format(time.RFC3339Nano)
+		clause := "(created_at " + op + " ? OR (created_at = ? AND (COALESCE(seq, 0) " + op +
+			" ? OR (COALESCE(seq, 0) = ? AND id " + op + " ?))))"
+		if where == "" {
+			where = " WHERE " + clause
+		} else {
+			where += " AND " + clause
+		}
+		args = append(args, ts, ts, after.Seq, after.Seq, after.ID)
+	}
+	return `SELECT ` + auditLogColumns + ` FROM audit_logs` + where + auditLogOrder(f) + ` LIMIT ?`, append(args, limit)
+}
+
+// auditLogOrder returns the ORDER BY clause for f. Entries written in the
+// same instant are ordered by chain position, then ID for entries from before
+// the chain, so keyset pages neither skip nor repeat entries.
+func auditLogOrder(f AuditLogFilter) string {
+	if f.Ascending {
+		return " ORDER BY created_at ASC, COALESCE(seq, 0) ASC, id ASC"
+	}
+	return " ORDER BY created_at DESC, COALESCE(seq, 0) DESC, id DESC"
+}
+
+// likeEscaper escapes LIKE wildcards for patterns using ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func buildAuditFilter(f AuditLogFilter) (string, []any) {
 	var clauses []string
 	var args []any
@@ -517,6 +652,26 @@ func buildAuditFilter(f AuditLogFilter) (string, []any) {
 		clauses = append(clauses, "status = ?")
 		args = append(args, f.Status)
 	}
+	if f.Namespace != "" {
+		clauses = append(clauses, "namespace = ?")
+		args = append(args, f.Namespace)
+	}
+	if f.Verb != "" {
+		clauses = append(clauses, "verb = ?")
+		args = append(args, f.Verb)
+	}
+	if f.Resource != "" {
+		clauses = append(clauses, "(',' || resource || ',') LIKE ? ESCAPE '\\'")
+		args = append(args, "%,"+likeEscaper.Replace(normalizeResource(f.Resource))+",%")
+	}
+	if f.ClientIP != "" {
+		clauses = append(clauses, "client_ip = ?")
+		args = append(args, f.ClientIP)
+	}
+	if f.Search != "" {
+		clauses = append(clauses, "LOWER(command) LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(f.Search))+"%")
+	}
 	if f.From != nil {
 		clauses = append(clauses, "created_at >= ?")
 		args = append(args, f.From.UTC().Format(timeFormat))
@@ -531,13 +686,20 @@ func buildAuditFilter(f AuditLogFilter) (string, []any) {
 	return " WHERE " + strings.Join(clauses, " AND "), args
 }
 
-func scanAuditRow(rows *sql.Rows) (*AuditLog, error) {
+// auditLogColumns is the column list scanned by scanAuditRow.
+const auditLogColumns = `id, user_id, user_email, cluster_name, cluster_id, command, namespace, status, exit_code, duration_ms, error_message, client_ip, created_at, seq, prev_hash, hash, verb, resource`
+
+func scanAuditRow(rows rowScanner) (*AuditLog, error) {
 	var l AuditLog
-	var userID, clusterID, ns, errMsg, clientIP *string
+	var userID, clusterID, ns, errMsg, clientIP, prevHash, hash, verb, resource *string
+	var seq *int64
 	var createdAt string
 	err := rows.Scan(&l.ID, &userID, &l.UserEmail, &l.ClusterName, &clusterID,
 		&l.Command, &ns, &l.Status, &l.ExitCode, &l.DurationMs,
-		&errMsg, &clientIP, &createdAt)
+		&errMsg, &clientIP, &createdAt, &seq, &prevHash, &hash, &verb, &resource)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("scan audit log: %w", err)
 	}
@@ -547,12 +709,66 @@ func scanAuditRow(rows *sql.Rows) (*AuditLog, error) {
 	l.ErrorMessage = derefStr(errMsg)
 	l.ClientIP = derefStr(clientIP)
 	l.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+	if seq != nil {
+		l.Seq = *seq
+	}
+	l.PrevHash = derefStr(prevHash)
+	l.Hash = derefStr(hash)
+	l.Verb = derefStr(verb)
+	l.Resource = derefStr(resource)
 	return &l, nil
 }
 
+func (s *SQLiteStore) GetAuditLog(ctx context.Context, id string) (*AuditLog, error) {
+	l, err := scanAuditRow(s.db.QueryRowContext(ctx,
+		`SELECT `+auditLogColumns+` FROM audit_logs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// ListAuditChain returns up to limit chained entries after afterSeq, in chain
+// order.
+func (s *SQLiteStore) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]*AuditLog, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+auditLogColumns+` FROM audit_logs WHERE seq > ? ORDER BY seq LIMIT ?`, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit chain: %w", err)
+	}
+	defer rows.Close()
+	var logs []*AuditLog
+	for rows.Next() {
+		l, err := scanAuditRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// CleanupOldAuditLogs deletes entries created before before. Chained entries
+// are only deleted as a prefix of the chain, and the last one deleted is
+// recorded in a signed checkpoint so the rest of the chain still verifies.
 func (s *SQLiteStore) CleanupOldAuditLogs(ctx context.Context, before time.Time) (int, error) {
-	res, err := s.db.ExecContext(ctx,
-		`DELETE FROM audit_logs WHERE created_at < ?`, before.UTC().Format(timeFormat))
+	cutoff := before.UTC().Format(timeFormat)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	var lastHash string
+	err = tx.QueryRowContext(ctx,
+		`SELECT seq, hash FROM audit_logs WHERE seq IS NOT NULL AND created_at < ? ORDER BY seq DESC LIMIT 1`,
+		cutoff).Scan(&lastSeq, &lastHash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM audit_logs WHERE (seq IS NULL AND created_at < ?) OR seq <= ?`, cutoff, lastSeq)
 	if err != nil {
 		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
 	}
@@ -560,5 +776,368 @@ func (s *SQLiteStore) CleanupOldAuditLogs(ctx context.Context, before time.Time)
 	if err != nil {
 		return 0, fmt.Errorf("rows affected: %w", err)
 	}
+	if lastSeq > 0 {
+		now := time.Now().UTC().Format(timeFormat)
+		cp := &AuditCheckpoint{ID: uuid.New().String(), Seq: lastSeq, Hash: lastHash, Deleted: int(n)}
+		cp.CreatedAt, _ = time.Parse(timeFormat, now)
+		cp.Signature = s.chain.Sign(cp)
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO audit_checkpoints (id, seq, hash, deleted, signature, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			cp.ID, cp.Seq, cp.Hash, cp.Deleted, cp.Signature, now)
+		if err != nil {
+			return 0, fmt.Errorf("create audit checkpoint: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cleanup old audit logs: %w", err)
+	}
 	return int(n), nil
 }
+
+// GetLatestAuditCheckpoint returns the newest checkpoint, or nil if cleanup
+// has never removed chained entries.
+func (s *SQLiteStore) GetLatestAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error) {
+	var cp AuditCheckpoint
+	var createdAt string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, seq, hash, deleted, signature, created_at FROM audit_checkpoints ORDER BY seq DESC LIMIT 1`,
+	).Scan(&cp.ID, &cp.Seq, &cp.Hash, &cp.Deleted, &cp.Signature, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get audit checkpoint: %w", err)
+	}
+	cp.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+	return &cp, nil
+}
+
+// --- Session Recordings ---
+
+// sessionRecordingColumns lists every column except data.
+const sessionRecordingColumns = `id, audit_log_id, user_email, cluster_name, namespace, command, duration_ms, size_bytes, truncated, created_at`
+
+func (s *SQLiteStore) CreateSessionRecording(ctx context.Context, r *SessionRecording) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if r.Data == nil {
+		r.Data = []byte{}
+	}
+	r.Size = int64(len(r.Data))
+	now := time.Now().UTC().Format(timeFormat)
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO session_recordings (`+sessionRecordingColumns+`, data)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.AuditLogID, r.UserEmail, r.ClusterName, nilIfEmpty(r.Namespace), r.Command,
+		r.DurationMs, r.Size, r.Truncated, now, r.Data,
+	)
+	if err != nil {
+		return fmt.Errorf("create session recording: %w", err)
+	}
+	r.CreatedAt, _ = time.Parse(timeFormat, now)
+	return nil
+}
+
+func (s *SQLiteStore) GetSessionRecording(ctx context.Context, id string) (*SessionRecording, error) {
+	var data []byte
+	r, err := scanSQLiteSessionRecording(s.db.QueryRowContext(ctx,
+		`SELECT `+sessionRecordingColumns+`, data FROM session_recordings WHERE id = ?`, id), &data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Data = data
+	return r, nil
+}
+
+func (s *SQLiteStore) ListSessionRecordings(ctx context.Context, filter SessionRecordingFilter) ([]*SessionRecording, error) {
+	query, args := buildSessionRecordingQuery(filter)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list session recordings: %w", err)
+	}
+	defer rows.Close()
+	var out []*SessionRecording
+	for rows.Next() {
+		r, err := scanSQLiteSessionRecording(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// buildSessionRecordingQuery returns the listing query, with "?"
+// placeholders, for filter.
+func buildSessionRecordingQuery(filter SessionRecordingFilter) (string, []any) {
+	var clauses []string
+	var args []any
+	if filter.UserEmail != "" {
+		clauses = append(clauses, "user_email = ?")
+		args = append(args, filter.UserEmail)
+	}
+	if filter.ClusterName != "" {
+		clauses = append(clauses, "cluster_name = ?")
+		args = append(args, filter.ClusterName)
+	}
+	query := `SELECT ` + sessionRecordingColumns + ` FROM session_recordings`
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return query, args
+}
+
+// scanSQLiteSessionRecording scans sessionRecordingColumns plus any extra
+// destinations that follow them.
+func scanSQLiteSessionRecording(row rowScanner, extra ...any) (*SessionRecording, error) {
+	var r SessionRecording
+	var ns *string
+	var truncated int
+	var createdAt string
+	dest := append([]any{&r.ID, &r.AuditLogID, &r.UserEmail, &r.ClusterName, &ns, &r.Command,
+		&r.DurationMs, &r.Size, &truncated, &createdAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan session recording: %w", err)
+	}
+	r.Namespace = derefStr(ns)
+	r.Truncated = truncated != 0
+	r.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+	return &r, nil
+}
+
+// --- Audit Outputs ---
+
+func (s *SQLiteStore) CreateAuditOutput(ctx context.Context, o *AuditOutput) error {
+	now := time.Now().UTC().Format(timeFormat)
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_outputs (audit_log_id, stdout, stderr, stdout_bytes, stderr_bytes, truncated, redactions, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		o.AuditLogID, o.Stdout, o.Stderr, o.StdoutBytes, o.StderrBytes, o.Truncated, o.Redactions, now,
+	)
+	if err != nil {
+		return fmt.Errorf("create audit output: %w", err)
+	}
+	o.CreatedAt, _ = time.Parse(timeFormat, now)
+	return nil
+}
+
+func (s *SQLiteStore) GetAuditOutput(ctx context.Context, auditLogID string) (*AuditOutput, error) {
+	var o AuditOutput
+	var truncated int
+	var createdAt string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT audit_log_id, stdout, stderr, stdout_bytes, stderr_bytes, truncated, redactions, created_at
+		 FROM audit_outputs WHERE audit_log_id = ?`, auditLogID,
+	).Scan(&o.AuditLogID, &o.Stdout, &o.Stderr, &o.StdoutBytes, &o.StderrBytes, &truncated, &o.Redactions, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get audit output: %w", err)
+	}
+	o.Truncated = truncated != 0
+	o.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+	return &o, nil
+}
+
+// --- Policy Versions ---
+
+// CreatePolicyVersion stores pv.Document as the next policy version and sets
+// pv.Version. Concurrent writers racing for the same version number fail on
+// the primary key rather than overwriting each other.
+func (s *SQLiteStore) CreatePolicyVersion(ctx context.Context, pv *PolicyVersion) error {
+	now := time.Now().UTC().Format(timeFormat)
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO policy_versions (version, document, author, comment, created_at)
+		 SELECT COALESCE(MAX(version), 0) + 1, ?, ?, ?, ? FROM policy_versions
+		 RETURNING version`,
+		pv.Document, pv.Author, pv.Comment, now,
+	).Scan(&pv.Version)
+	if err != nil {
+		return fmt.Errorf("create policy version: %w", err)
+	}
+	pv.CreatedAt, _ = time.Parse(timeFormat, now)
+	return nil
+}
+
+func (s *SQLiteStore) GetPolicyVersion(ctx context.Context, version int) (*PolicyVersion, error) {
+	return s.scanPolicyVersion(s.db.QueryRowContext(ctx,
+		`SELECT version, document, author, comment, created_at
+		 FROM policy_versions WHERE version = ?`, version))
+}
+
+func (s *SQLiteStore) GetLatestPolicyVersion(ctx context.Context) (*PolicyVersion, error) {
+	return s.scanPolicyVersion(s.db.QueryRowContext(ctx,
+		`SELECT version, document, author, comment, created_at
+		 FROM policy_versions ORDER BY version DESC LIMIT 1`))
+}
+
+func (s *SQLiteStore) scanPolicyVersion(row *sql.Row) (*PolicyVersion, error) {
+	var pv PolicyVersion
+	var createdAt string
+	err := row.Scan(&pv.Version, &pv.Document, &pv.Author, &pv.Comment, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan policy version: %w", err)
+	}
+	pv.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+	return &pv, nil
+}
+
+// ListPolicyVersions returns all versions, newest first, without documents.
+func (s *SQLiteStore) ListPolicyVersions(ctx context.Context) ([]*PolicyVersion, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT version, author, comment, created_at FROM policy_versions ORDER BY version DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list policy versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*PolicyVersion
+	for rows.Next() {
+		var pv PolicyVersion
+		var createdAt string
+		if err := rows.Scan(&pv.Version, &pv.Author, &pv.Comment, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan policy version: %w", err)
+		}
+		pv.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+		versions = append(versions, &pv)
+	}
+	return versions, rows.Err()
+}
+
+// --- JIT Requests ---
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+const jitRequestColumns = `id, user_id, user_email, cluster_name, role, reason, duration_seconds, status, breakglass, decided_by, decided_at, expires_at, created_at`
+
+func (s *SQLiteStore) CreateJITRequest(ctx context.Context, r *JITRequest) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	now := time.Now().UTC().Format(timeFormat)
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO jit_requests (`+jitRequestColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, nilIfEmpty(r.UserID), r.UserEmail, r.ClusterName, r.Role, r.Reason, r.DurationSec,
+		r.Status, r.Breakglass, nilIfEmpty(r.DecidedBy), formatNullableTime(r.DecidedAt), formatNullableTime(r.ExpiresAt), now,
+	)
+	if err != nil {
+		return fmt.Errorf("create jit request: %w", err)
+	}
+	r.CreatedAt, _ = time.Parse(timeFormat, now)
+	return nil
+}
+
+func (s *SQLiteStore) GetJITRequest(ctx context.Context, id string) (*JITRequest, error) {
+	r, err := scanSQLiteJITRequest(s.db.QueryRowContext(ctx,
+		`SELECT `+jitRequestColumns+` FROM jit_requests WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+func (s *SQLiteStore) ListJITRequests(ctx context.Context, filter JITRequestFilter) ([]*JITRequest, error) {
+	var clauses []string
+	var args []any
+	if filter.UserEmail != "" {
+		clauses = append(clauses, "user_email = ?")
+		args = append(args, filter.UserEmail)
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, "status = ?")
+		args = append(args, filter.Status)
+	}
+	query := `SELECT ` + jitRequestColumns + ` FROM jit_requests`
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return s.queryJITRequests(ctx, query, args...)
+}
+
+func (s *SQLiteStore) DecideJITRequest(ctx context.Context, r *JITRequest) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jit_requests SET status = ?, decided_by = ?, decided_at = ?, expires_at = ?
+		 WHERE id = ? AND status = ?`,
+		r.Status, nilIfEmpty(r.DecidedBy), formatNullableTime(r.DecidedAt), formatNullableTime(r.ExpiresAt),
+		r.ID, JITStatusPending,
+	)
+	if err != nil {
+		return false, fmt.Errorf("decide jit request: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (s *SQLiteStore) ListActiveJITRequests(ctx context.Context, now time.Time) ([]*JITRequest, error) {
+	return s.queryJITRequests(ctx,
+		`SELECT `+jitRequestColumns+` FROM jit_requests WHERE status = ? AND expires_at > ?`,
+		JITStatusApproved, now.UTC().Format(timeFormat))
+}
+
+func (s *SQLiteStore) queryJITRequests(ctx context.Context, query string, args ...any) ([]*JITRequest, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list jit requests: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*JITRequest
+	for rows.Next() {
+		r, err := scanSQLiteJITRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func scanSQLiteJITRequest(row rowScanner) (*JITRequest, error) {
+	var r JITRequest
+	var userID, decidedBy, decidedAt, expiresAt *string
+	var createdAt string
+	var breakglass int
+	err := row.Scan(&r.ID, &userID, &r.UserEmail, &r.ClusterName, &r.Role, &r.Reason, &r.DurationSec,
+		&r.Status, &breakglass, &decidedBy, &decidedAt, &expiresAt, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan jit request: %w", err)
+	}
+	r.Breakglass = breakglass != 0
+	r.UserID = derefStr(userID)
+	r.DecidedBy = derefStr(decidedBy)
+	r.DecidedAt = parseNullableTime(decidedAt)
+	r.ExpiresAt = parseNullableTime(expiresAt)
+	r.CreatedAt, _ = time.Parse(timeFormat, createdAt)
+	return &r, nil
+}
@@ -7,12 +7,15 @@ import (
 
 // AgentInfo represents a registered agent and its current state.
 type AgentInfo struct {
-	ID          string
-	ClusterName string
-	Token       string
-	Status      string
+	ID           string
+	ClusterName  string
+	Token        string
+	Status       string
 	RegisteredAt time.Time
-	LastSeen    time.Time
+	LastSeen     time.Time
+	// MaxCommands is how many one-shot commands the agent runs at once, as
+	// advertised at registration; 0 if it did not say.
+	MaxCommands int
 }
 
 // AgentStatus constants for agent connection state.
@@ -122,6 +125,17 @@ func (s *AgentStore) List() []*AgentInfo {
 	return result
 }
 
+// MaxCommands returns the concurrency the agent advertised at registration,
+// or 0 for an unknown agent or one that did not advertise it.
+func (s *AgentStore) MaxCommands(agentID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if agent, exists := s.agents[agentID]; exists {
+		return agent.MaxCommands
+	}
+	return 0
+}
+
 // MarkDisconnected marks agents without recent heartbeats as disconnected.
 func (s *AgentStore) MarkDisconnected() {
 	s.mu.Lock()
@@ -37,12 +37,55 @@ type Store interface {
 	DeleteRefreshTokensByUser(ctx context.Context, userID string) error
 	CleanupExpiredRefreshTokens(ctx context.Context) error
 
-	// Audit Logs
+	// Audit Logs. CreateAuditLog appends to the hash chain using the
+	// AuditChain passed to SetAuditChain (unkeyed by default), and
+	// CleanupOldAuditLogs writes a checkpoint for the entries it removes.
+	SetAuditChain(chain *AuditChain)
 	CreateAuditLog(ctx context.Context, log *AuditLog) error
 	ListAuditLogs(ctx context.Context, filter AuditLogFilter) ([]*AuditLog, int, error)
+	// ListAuditLogsAfter returns up to limit entries matching filter that sort
+	// after the cursor (from the start when nil), without counting the total.
+	// Page and PerPage are ignored.
+	ListAuditLogsAfter(ctx context.Context, filter AuditLogFilter, after *AuditCursor, limit int) ([]*AuditLog, error)
 	CleanupOldAuditLogs(ctx context.Context, before time.Time) (int, error)
+	// ListAuditChain returns up to limit chained entries with seq > afterSeq,
+	// in chain order.
+	ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]*AuditLog, error)
+	// GetLatestAuditCheckpoint returns the newest checkpoint, or nil.
+	GetLatestAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error)
+	// GetAuditLog returns one entry, or nil.
+	GetAuditLog(ctx context.Context, id string) (*AuditLog, error)
+
+	// Audit Outputs. Captured output is deleted with its audit entry.
+	CreateAuditOutput(ctx context.Context, o *AuditOutput) error
+	// GetAuditOutput returns the output captured for an entry, or nil.
+	GetAuditOutput(ctx context.Context, auditLogID string) (*AuditOutput, error)
+
+	// Session Recordings. Recordings are deleted with their audit entry.
+	CreateSessionRecording(ctx context.Context, r *SessionRecording) error
+	// GetSessionRecording returns a recording including its Data, or nil.
+	GetSessionRecording(ctx context.Context, id string) (*SessionRecording, error)
+	// ListSessionRecordings returns recordings without Data, newest first.
+	ListSessionRecordings(ctx context.Context, filter SessionRecordingFilter) ([]*SessionRecording, error)
+
+	// Policy Versions
+	CreatePolicyVersion(ctx context.Context, pv *PolicyVersion) error
+	GetPolicyVersion(ctx context.Context, version int) (*PolicyVersion, error)
+	GetLatestPolicyVersion(ctx context.Context) (*PolicyVersion, error)
+	ListPolicyVersions(ctx context.Context) ([]*PolicyVersion, error)
+
+	// JIT Requests
+	CreateJITRequest(ctx context.Context, r *JITRequest) error
+	GetJITRequest(ctx context.Context, id string) (*JITRequest, error)
+	ListJITRequests(ctx context.Context, filter JITRequestFilter) ([]*JITRequest, error)
+	// DecideJITRequest records r's decision (status, decided_by, decided_at,
+	// expires_at) if the request is still pending, and reports whether it was.
+	DecideJITRequest(ctx context.Context, r *JITRequest) (bool, error)
+	// ListActiveJITRequests returns approved requests that expire after now.
+	ListActiveJITRequests(ctx context.Context, now time.Time) ([]*JITRequest, error)
 
 	// Lifecycle
 	Migrate(ctx context.Context) error
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
 	Close() error
 }
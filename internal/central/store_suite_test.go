@@ -0,0 +1,922 @@
+package central
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postgresTestDSNEnv names the env var holding a DSN for a scratch PostgreSQL
+// database. When unset, the Postgres half of the store suite is skipped.
+const postgresTestDSNEnv = "KBRIDGE_TEST_POSTGRES_DSN"
+
+// testBackend wraps a Store under test together with its raw connection so
+// suite cases can seed rows the Store API cannot express (e.g. backdated logs).
+type testBackend struct {
+	Store
+	db       *sql.DB
+	postgres bool
+}
+
+// exec runs a raw statement written with "?" placeholders, adapting the
+// placeholder syntax and time encoding to the backend.
+func (b *testBackend) exec(t *testing.T, query string, args ...any) {
+	t.Helper()
+	if b.postgres {
+		query = rebind(query)
+	} else {
+		for i, a := range args {
+			if ts, ok := a.(time.Time); ok {
+				args[i] = ts.UTC().Format(timeFormat)
+			}
+		}
+	}
+	if _, err := b.db.ExecContext(context.Background(), query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+// forEachStore runs fn against every available Store backend, each with a
+// fresh, migrated database.
+func forEachStore(t *testing.T, fn func(t *testing.T, store *testBackend)) {
+	t.Run("sqlite", func(t *testing.T) {
+		store := newTestStore(t)
+		fn(t, &testBackend{Store: store, db: store.db})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		store := newTestPostgresStore(t)
+		fn(t, &testBackend{Store: store, db: store.db, postgres: true})
+	})
+}
+
+// newTestPostgresStore returns a PostgresStore confined to a throwaway schema
+// in the database named by KBRIDGE_TEST_POSTGRES_DSN, or skips the test.
+func newTestPostgresStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv(postgresTestDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresTestDSNEnv)
+	}
+
+	admin, err := NewPostgresStore(dsn)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	schema := "kbridge_test_" + hex.EncodeToString(buf)
+	if _, err := admin.db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.db.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	store, err := NewPostgresStore(withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// withSearchPath appends a search_path runtime parameter to a URL or
+// key=value DSN.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+func TestStore_Migrate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"users table", func(t *testing.T) {
+				var count int
+				if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+					t.Fatalf("table should exist: %v", err)
+				}
+			}},
+			{"clusters table", func(t *testing.T) {
+				var count int
+				if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM clusters").Scan(&count); err != nil {
+					t.Fatalf("table should exist: %v", err)
+				}
+			}},
+			{"agent_tokens table", func(t *testing.T) {
+				var count int
+				if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM agent_tokens").Scan(&count); err != nil {
+					t.Fatalf("table should exist: %v", err)
+				}
+			}},
+			{"audit_logs table", func(t *testing.T) {
+				var count int
+				if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_logs").Scan(&count); err != nil {
+					t.Fatalf("table should exist: %v", err)
+				}
+			}},
+			{"refresh_tokens table", func(t *testing.T) {
+				var count int
+				if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM refresh_tokens").Scan(&count); err != nil {
+					t.Fatalf("table should exist: %v", err)
+				}
+			}},
+			{"is_admin column", func(t *testing.T) {
+				var count int
+				if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE is_admin = 0").Scan(&count); err != nil {
+					t.Fatalf("is_admin column should exist: %v", err)
+				}
+			}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+
+		// Verify idempotency
+		t.Run("migrate is idempotent", func(t *testing.T) {
+			if err := store.Migrate(ctx); err != nil {
+				t.Fatalf("second migration should succeed: %v", err)
+			}
+		})
+	})
+}
+
+func TestStore_Users(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"create and get by ID", func(t *testing.T) {
+				user := &User{Email: "alice@test.com", PasswordHash: "hash1", Name: "Alice", IsActive: true}
+				if err := store.CreateUser(ctx, user); err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+				if user.ID == "" {
+					t.Fatal("ID should be set")
+				}
+				got, err := store.GetUserByID(ctx, user.ID)
+				if err != nil {
+					t.Fatalf("get user: %v", err)
+				}
+				if got.Email != "alice@test.com" {
+					t.Errorf("expected email alice@test.com, got %q", got.Email)
+				}
+				if got.Name != "Alice" {
+					t.Errorf("expected name Alice, got %q", got.Name)
+				}
+			}},
+			{"get by email", func(t *testing.T) {
+				user := &User{Email: "bob@test.com", PasswordHash: "hash2", Name: "Bob", IsActive: true}
+				store.CreateUser(ctx, user)
+				got, err := store.GetUserByEmail(ctx, "bob@test.com")
+				if err != nil {
+					t.Fatalf("get user by email: %v", err)
+				}
+				if got == nil {
+					t.Fatal("user not found")
+				}
+				if got.Name != "Bob" {
+					t.Errorf("expected name Bob, got %q", got.Name)
+				}
+			}},
+			{"get non-existent returns nil", func(t *testing.T) {
+				got, err := store.GetUserByID(ctx, "nonexistent")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != nil {
+					t.Error("expected nil for non-existent user")
+				}
+			}},
+			{"list users", func(t *testing.T) {
+				users, err := store.ListUsers(ctx)
+				if err != nil {
+					t.Fatalf("list users: %v", err)
+				}
+				if len(users) < 2 {
+					t.Errorf("expected at least 2 users, got %d", len(users))
+				}
+			}},
+			{"update user", func(t *testing.T) {
+				user := &User{Email: "update@test.com", PasswordHash: "hash3", Name: "Update", IsActive: true}
+				store.CreateUser(ctx, user)
+				user.Name = "Updated"
+				user.IsActive = false
+				if err := store.UpdateUser(ctx, user); err != nil {
+					t.Fatalf("update user: %v", err)
+				}
+				got, _ := store.GetUserByID(ctx, user.ID)
+				if got.Name != "Updated" {
+					t.Errorf("expected name Updated, got %q", got.Name)
+				}
+				if got.IsActive {
+					t.Error("expected is_active false")
+				}
+			}},
+			{"delete user", func(t *testing.T) {
+				user := &User{Email: "delete@test.com", PasswordHash: "hash4", Name: "Delete", IsActive: true}
+				store.CreateUser(ctx, user)
+				if err := store.DeleteUser(ctx, user.ID); err != nil {
+					t.Fatalf("delete user: %v", err)
+				}
+				got, _ := store.GetUserByID(ctx, user.ID)
+				if got != nil {
+					t.Error("user should be deleted")
+				}
+			}},
+			{"duplicate email error", func(t *testing.T) {
+				user1 := &User{Email: "dupe@test.com", PasswordHash: "hash5", Name: "Dupe1", IsActive: true}
+				store.CreateUser(ctx, user1)
+				user2 := &User{Email: "dupe@test.com", PasswordHash: "hash6", Name: "Dupe2", IsActive: true}
+				err := store.CreateUser(ctx, user2)
+				if err == nil {
+					t.Error("expected error for duplicate email")
+				}
+			}},
+			{"is_admin persisted", func(t *testing.T) {
+				user := &User{Email: "admin@test.com", PasswordHash: "h", Name: "Admin", IsActive: true, IsAdmin: true}
+				if err := store.CreateUser(ctx, user); err != nil {
+					t.Fatalf("create admin user: %v", err)
+				}
+				got, _ := store.GetUserByID(ctx, user.ID)
+				if !got.IsAdmin {
+					t.Error("expected is_admin true")
+				}
+				got.IsAdmin = false
+				store.UpdateUser(ctx, got)
+				updated, _ := store.GetUserByID(ctx, got.ID)
+				if updated.IsAdmin {
+					t.Error("expected is_admin false after update")
+				}
+			}},
+			{"groups persisted", func(t *testing.T) {
+				user := &User{Email: "groups@test.com", PasswordHash: "h", Name: "G", IsActive: true, Groups: []string{"sre", "dev"}}
+				if err := store.CreateUser(ctx, user); err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+				got, _ := store.GetUserByEmail(ctx, "groups@test.com")
+				if len(got.Groups) != 2 || got.Groups[0] != "sre" || got.Groups[1] != "dev" {
+					t.Errorf("groups = %v, want [sre dev]", got.Groups)
+				}
+				got.Groups = nil
+				store.UpdateUser(ctx, got)
+				users, _ := store.ListUsers(ctx)
+				for _, u := range users {
+					if u.ID == got.ID && len(u.Groups) != 0 {
+						t.Errorf("groups after clearing = %v", u.Groups)
+					}
+				}
+			}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
+
+func TestStore_Clusters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"create and get by ID", func(t *testing.T) {
+				c := &Cluster{Name: "prod", Status: "disconnected"}
+				if err := store.CreateCluster(ctx, c); err != nil {
+					t.Fatalf("create cluster: %v", err)
+				}
+				if c.ID == "" {
+					t.Fatal("ID should be set")
+				}
+				got, err := store.GetClusterByID(ctx, c.ID)
+				if err != nil {
+					t.Fatalf("get cluster: %v", err)
+				}
+				if got.Name != "prod" {
+					t.Errorf("expected name prod, got %q", got.Name)
+				}
+			}},
+			{"get by name", func(t *testing.T) {
+				c := &Cluster{Name: "staging", Status: "connected"}
+				store.CreateCluster(ctx, c)
+				got, err := store.GetClusterByName(ctx, "staging")
+				if err != nil {
+					t.Fatalf("get cluster by name: %v", err)
+				}
+				if got == nil {
+					t.Fatal("cluster not found")
+				}
+				if got.Status != "connected" {
+					t.Errorf("expected status connected, got %q", got.Status)
+				}
+			}},
+			{"get non-existent returns nil", func(t *testing.T) {
+				got, err := store.GetClusterByID(ctx, "nonexistent")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != nil {
+					t.Error("expected nil for non-existent cluster")
+				}
+			}},
+			{"list clusters", func(t *testing.T) {
+				clusters, err := store.ListClusters(ctx)
+				if err != nil {
+					t.Fatalf("list clusters: %v", err)
+				}
+				if len(clusters) < 2 {
+					t.Errorf("expected at least 2 clusters, got %d", len(clusters))
+				}
+			}},
+			{"update cluster", func(t *testing.T) {
+				c := &Cluster{Name: "update-cluster", Status: "disconnected"}
+				store.CreateCluster(ctx, c)
+				c.Status = "connected"
+				now := time.Now()
+				c.LastSeenAt = &now
+				if err := store.UpdateCluster(ctx, c); err != nil {
+					t.Fatalf("update cluster: %v", err)
+				}
+				got, _ := store.GetClusterByID(ctx, c.ID)
+				if got.Status != "connected" {
+					t.Errorf("expected status connected, got %q", got.Status)
+				}
+				if got.LastSeenAt == nil {
+					t.Error("expected last_seen_at to be set")
+				}
+			}},
+			{"delete cluster", func(t *testing.T) {
+				c := &Cluster{Name: "delete-cluster", Status: "disconnected"}
+				store.CreateCluster(ctx, c)
+				if err := store.DeleteCluster(ctx, c.ID); err != nil {
+					t.Fatalf("delete cluster: %v", err)
+				}
+				got, _ := store.GetClusterByID(ctx, c.ID)
+				if got != nil {
+					t.Error("cluster should be deleted")
+				}
+			}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
+
+func TestStore_AgentTokens(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		// Create a cluster first
+		cluster := &Cluster{Name: "token-cluster", Status: "disconnected"}
+		store.CreateCluster(ctx, cluster)
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"create and get by hash", func(t *testing.T) {
+				tok := &AgentToken{
+					ClusterID:   cluster.ID,
+					TokenHash:   "hash123",
+					TokenPrefix: "kb_",
+					Description: "test token",
+				}
+				if err := store.CreateAgentToken(ctx, tok); err != nil {
+					t.Fatalf("create agent token: %v", err)
+				}
+				got, err := store.GetAgentTokenByHash(ctx, "hash123")
+				if err != nil {
+					t.Fatalf("get agent token: %v", err)
+				}
+				if got == nil {
+					t.Fatal("token not found")
+				}
+				if got.Description != "test token" {
+					t.Errorf("expected description 'test token', got %q", got.Description)
+				}
+			}},
+			{"get non-existent returns nil", func(t *testing.T) {
+				got, err := store.GetAgentTokenByHash(ctx, "nonexistent")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != nil {
+					t.Error("expected nil for non-existent token")
+				}
+			}},
+			{"list by cluster", func(t *testing.T) {
+				tok2 := &AgentToken{
+					ClusterID:   cluster.ID,
+					TokenHash:   "hash456",
+					TokenPrefix: "kb_",
+				}
+				store.CreateAgentToken(ctx, tok2)
+				tokens, err := store.ListAgentTokensByCluster(ctx, cluster.ID)
+				if err != nil {
+					t.Fatalf("list agent tokens: %v", err)
+				}
+				if len(tokens) < 2 {
+					t.Errorf("expected at least 2 tokens, got %d", len(tokens))
+				}
+			}},
+			{"revoke token", func(t *testing.T) {
+				tok := &AgentToken{
+					ClusterID:   cluster.ID,
+					TokenHash:   "revokeme",
+					TokenPrefix: "kb_",
+				}
+				store.CreateAgentToken(ctx, tok)
+				if err := store.RevokeAgentToken(ctx, tok.ID); err != nil {
+					t.Fatalf("revoke token: %v", err)
+				}
+				got, _ := store.GetAgentTokenByHash(ctx, "revokeme")
+				if !got.IsRevoked {
+					t.Error("token should be revoked")
+				}
+			}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
+
+func TestStore_RefreshTokens(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		user := &User{Email: "rt@test.com", PasswordHash: "h", Name: "RT", IsActive: true}
+		store.CreateUser(ctx, user)
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"create and get by hash", func(t *testing.T) {
+				rt := &RefreshToken{
+					UserID:    user.ID,
+					TokenHash: "rthash1",
+					ExpiresAt: time.Now().Add(24 * time.Hour),
+				}
+				if err := store.CreateRefreshToken(ctx, rt); err != nil {
+					t.Fatalf("create refresh token: %v", err)
+				}
+				got, err := store.GetRefreshTokenByHash(ctx, "rthash1")
+				if err != nil {
+					t.Fatalf("get refresh token: %v", err)
+				}
+				if got == nil {
+					t.Fatal("refresh token not found")
+				}
+				if got.UserID != user.ID {
+					t.Errorf("expected user_id %q, got %q", user.ID, got.UserID)
+				}
+			}},
+			{"get non-existent returns nil", func(t *testing.T) {
+				got, err := store.GetRefreshTokenByHash(ctx, "nonexistent")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != nil {
+					t.Error("expected nil for non-existent token")
+				}
+			}},
+			{"delete by ID", func(t *testing.T) {
+				rt := &RefreshToken{
+					UserID:    user.ID,
+					TokenHash: "rthash-del",
+					ExpiresAt: time.Now().Add(24 * time.Hour),
+				}
+				store.CreateRefreshToken(ctx, rt)
+				if err := store.DeleteRefreshToken(ctx, rt.ID); err != nil {
+					t.Fatalf("delete refresh token: %v", err)
+				}
+				got, _ := store.GetRefreshTokenByHash(ctx, "rthash-del")
+				if got != nil {
+					t.Error("token should be deleted")
+				}
+			}},
+			{"delete by user", func(t *testing.T) {
+				user2 := &User{Email: "rt2@test.com", PasswordHash: "h", Name: "RT2", IsActive: true}
+				store.CreateUser(ctx, user2)
+				store.CreateRefreshToken(ctx, &RefreshToken{
+					UserID: user2.ID, TokenHash: "u2h1", ExpiresAt: time.Now().Add(time.Hour),
+				})
+				store.CreateRefreshToken(ctx, &RefreshToken{
+					UserID: user2.ID, TokenHash: "u2h2", ExpiresAt: time.Now().Add(time.Hour),
+				})
+				if err := store.DeleteRefreshTokensByUser(ctx, user2.ID); err != nil {
+					t.Fatalf("delete by user: %v", err)
+				}
+				got1, _ := store.GetRefreshTokenByHash(ctx, "u2h1")
+				got2, _ := store.GetRefreshTokenByHash(ctx, "u2h2")
+				if got1 != nil || got2 != nil {
+					t.Error("all tokens should be deleted")
+				}
+			}},
+			{"cleanup expired", func(t *testing.T) {
+				// Create an expired token
+				store.CreateRefreshToken(ctx, &RefreshToken{
+					UserID: user.ID, TokenHash: "expired1",
+					ExpiresAt: time.Now().Add(-1 * time.Hour),
+				})
+				// Create a valid token
+				store.CreateRefreshToken(ctx, &RefreshToken{
+					UserID: user.ID, TokenHash: "valid1",
+					ExpiresAt: time.Now().Add(24 * time.Hour),
+				})
+				if err := store.CleanupExpiredRefreshTokens(ctx); err != nil {
+					t.Fatalf("cleanup: %v", err)
+				}
+				got, _ := store.GetRefreshTokenByHash(ctx, "expired1")
+				if got != nil {
+					t.Error("expired token should be cleaned up")
+				}
+				got2, _ := store.GetRefreshTokenByHash(ctx, "valid1")
+				if got2 == nil {
+					t.Error("valid token should still exist")
+				}
+			}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
+
+func TestStore_AuditLogs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"create and list", func(t *testing.T) {
+				exitCode := int32(0)
+				dur := int64(100)
+				log := &AuditLog{
+					UserEmail:   "audit@test.com",
+					ClusterName: "prod",
+					Command:     "get pods",
+					Status:      "success",
+					ExitCode:    &exitCode,
+					DurationMs:  &dur,
+					ClientIP:    "127.0.0.1",
+				}
+				if err := store.CreateAuditLog(ctx, log); err != nil {
+					t.Fatalf("create audit log: %v", err)
+				}
+				logs, total, err := store.ListAuditLogs(ctx, AuditLogFilter{})
+				if err != nil {
+					t.Fatalf("list audit logs: %v", err)
+				}
+				if total < 1 {
+					t.Fatalf("expected at least 1 log, got %d", total)
+				}
+				if len(logs) < 1 {
+					t.Fatal("expected at least 1 log entry")
+				}
+			}},
+			{"filter by email", func(t *testing.T) {
+				store.CreateAuditLog(ctx, &AuditLog{
+					UserEmail: "filter@test.com", ClusterName: "dev",
+					Command: "get ns", Status: "success",
+				})
+				logs, total, err := store.ListAuditLogs(ctx, AuditLogFilter{UserEmail: "filter@test.com"})
+				if err != nil {
+					t.Fatalf("list: %v", err)
+				}
+				if total != 1 {
+					t.Errorf("expected 1 log, got %d", total)
+				}
+				if len(logs) != 1 {
+					t.Errorf("expected 1 entry, got %d", len(logs))
+				}
+			}},
+			{"filter by cluster", func(t *testing.T) {
+				store.CreateAuditLog(ctx, &AuditLog{
+					UserEmail: "x@test.com", ClusterName: "unique-cluster",
+					Command: "get pods", Status: "success",
+				})
+				logs, total, _ := store.ListAuditLogs(ctx, AuditLogFilter{ClusterName: "unique-cluster"})
+				if total != 1 {
+					t.Errorf("expected 1 log, got %d", total)
+				}
+				if len(logs) != 1 {
+					t.Errorf("expected 1 entry, got %d", len(logs))
+				}
+			}},
+			{"filter by status", func(t *testing.T) {
+				store.CreateAuditLog(ctx, &AuditLog{
+					UserEmail: "err@test.com", ClusterName: "prod",
+					Command: "delete pod", Status: "error",
+				})
+				logs, _, _ := store.ListAuditLogs(ctx, AuditLogFilter{Status: "error"})
+				if len(logs) < 1 {
+					t.Error("expected at least 1 error log")
+				}
+			}},
+			{"pagination", func(t *testing.T) {
+				for i := 0; i < 5; i++ {
+					store.CreateAuditLog(ctx, &AuditLog{
+						UserEmail: "page@test.com", ClusterName: "page-cluster",
+						Command: "get pods", Status: "success",
+					})
+				}
+				logs, total, _ := store.ListAuditLogs(ctx, AuditLogFilter{
+					ClusterName: "page-cluster", Page: 1, PerPage: 2,
+				})
+				if total != 5 {
+					t.Errorf("expected total 5, got %d", total)
+				}
+				if len(logs) != 2 {
+					t.Errorf("expected 2 entries on page, got %d", len(logs))
+				}
+			}},
+			{"query filters", func(t *testing.T) {
+				for _, l := range []*AuditLog{
+					{Command: "get pods -n web", Namespace: "web", Verb: "get", Resource: "pods", ClientIP: "10.0.0.1"},
+					{Command: "delete deploy/api", Namespace: "web", Verb: "delete", Resource: "deployments", ClientIP: "10.0.0.2"},
+					{Command: "get svc,pods", Namespace: "db", Verb: "get", Resource: "services,pods", ClientIP: "10.0.0.1"},
+					{Command: "logs my_pod", Namespace: "db", Verb: "logs", Resource: "pods", ClientIP: "10.0.0.3"},
+				} {
+					l.UserEmail, l.ClusterName, l.Status = "q@test.com", "query-cluster", "success"
+					if err := store.CreateAuditLog(ctx, l); err != nil {
+						t.Fatalf("create: %v", err)
+					}
+				}
+				count := func(f AuditLogFilter) int {
+					t.Helper()
+					f.ClusterName = "query-cluster"
+					_, total, err := store.ListAuditLogs(ctx, f)
+					if err != nil {
+						t.Fatalf("list %+v: %v", f, err)
+					}
+					return total
+				}
+				for _, tc := range []struct {
+					filter AuditLogFilter
+					want   int
+				}{
+					{AuditLogFilter{Namespace: "web"}, 2},
+					{AuditLogFilter{Verb: "get"}, 2},
+					{AuditLogFilter{Resource: "pods"}, 3},
+					{AuditLogFilter{Resource: "po"}, 3},
+					{AuditLogFilter{Resource: "services"}, 1},
+					{AuditLogFilter{ClientIP: "10.0.0.1"}, 2},
+					{AuditLogFilter{Search: "DEPLOY/"}, 1},
+					{AuditLogFilter{Search: "y_p"}, 1},
+					{AuditLogFilter{Search: "%"}, 0},
+					{AuditLogFilter{Verb: "get", Namespace: "db"}, 1},
+				} {
+					if got := count(tc.filter); got != tc.want {
+						t.Errorf("filter %+v matched %d, want %d", tc.filter, got, tc.want)
+					}
+				}
+			}},
+			{"keyset pagination", func(t *testing.T) {
+				// Entries written in the same second are ordered by seq.
+				for i := 0; i < 7; i++ {
+					if err := store.CreateAuditLog(ctx, &AuditLog{
+						UserEmail: "keyset@test.com", ClusterName: "keyset-cluster", Command: "get pods", Status: "success",
+					}); err != nil {
+						t.Fatalf("create: %v", err)
+					}
+				}
+				for _, asc := range []bool{false, true} {
+					filter := AuditLogFilter{ClusterName: "keyset-cluster", Ascending: asc}
+					var after *AuditCursor
+					seen := map[string]bool{}
+					var prev *AuditLog
+					for pages := 0; ; pages++ {
+						page, err := store.ListAuditLogsAfter(ctx, filter, after, 3)
+						if err != nil {
+							t.Fatalf("list after: %v", err)
+						}
+						for _, l := range page {
+							if seen[l.ID] {
+								t.Fatalf("ascending=%v: entry %s repeated", asc, l.ID)
+							}
+							seen[l.ID] = true
+							if prev != nil && (asc && l.CreatedAt.Before(prev.CreatedAt) || !asc && l.CreatedAt.After(prev.CreatedAt)) {
+								t.Fatalf("ascending=%v: entries out of order", asc)
+							}
+							prev = l
+						}
+						if len(page) < 3 {
+							break
+						}
+						after = auditCursorFor(page[len(page)-1])
+					}
+					if len(seen) != 7 {
+						t.Errorf("ascending=%v: paged through %d entries, want 7", asc, len(seen))
+					}
+				}
+			}},
+			{"cleanup old", func(t *testing.T) {
+				// Insert a log with old timestamp by directly using DB
+				store.exec(t,
+					`INSERT INTO audit_logs (id, user_email, cluster_name, command, status, created_at)
+					 VALUES (?, ?, ?, ?, ?, ?)`,
+					"old-log-id", "old@test.com", "old-cluster", "get pods", "success",
+					time.Now().Add(-48*time.Hour),
+				)
+				n, err := store.CleanupOldAuditLogs(ctx, time.Now().Add(-24*time.Hour))
+				if err != nil {
+					t.Fatalf("cleanup: %v", err)
+				}
+				if n < 1 {
+					t.Errorf("expected at least 1 deleted, got %d", n)
+				}
+			}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
+
+func TestStore_PolicyVersions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		latest, err := store.GetLatestPolicyVersion(ctx)
+		if err != nil || latest != nil {
+			t.Fatalf("latest on empty store = %+v, %v; want nil, nil", latest, err)
+		}
+
+		for i, doc := range []string{"default: a\n", "default: b\n", "default: c\n"} {
+			pv := &PolicyVersion{Document: doc, Author: "admin@test.com", Comment: fmt.Sprintf("change %d", i+1)}
+			if err := store.CreatePolicyVersion(ctx, pv); err != nil {
+				t.Fatalf("create version: %v", err)
+			}
+			if pv.Version != i+1 {
+				t.Errorf("version = %d, want %d", pv.Version, i+1)
+			}
+			if pv.CreatedAt.IsZero() {
+				t.Error("created_at not set")
+			}
+		}
+
+		latest, err = store.GetLatestPolicyVersion(ctx)
+		if err != nil {
+			t.Fatalf("latest: %v", err)
+		}
+		if latest.Version != 3 || latest.Document != "default: c\n" || latest.Author != "admin@test.com" {
+			t.Errorf("latest = %+v, want version 3", latest)
+		}
+
+		pv, err := store.GetPolicyVersion(ctx, 2)
+		if err != nil || pv == nil || pv.Document != "default: b\n" || pv.Comment != "change 2" {
+			t.Errorf("get version 2 = %+v, %v", pv, err)
+		}
+		if pv, err := store.GetPolicyVersion(ctx, 99); err != nil || pv != nil {
+			t.Errorf("get missing version = %+v, %v; want nil, nil", pv, err)
+		}
+
+		versions, err := store.ListPolicyVersions(ctx)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(versions) != 3 || versions[0].Version != 3 || versions[2].Version != 1 {
+			t.Fatalf("versions = %+v, want 3..1", versions)
+		}
+		if versions[0].Document != "" {
+			t.Error("list should not include documents")
+		}
+	})
+}
+
+func TestStore_JITRequests(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *testBackend) {
+		ctx := context.Background()
+
+		newReq := func(email string) *JITRequest {
+			r := &JITRequest{UserEmail: email, ClusterName: "prod", Role: "operator",
+				Reason: "INC-1", DurationSec: 3600, Status: JITStatusPending}
+			if err := store.CreateJITRequest(ctx, r); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			return r
+		}
+
+		tests := []struct {
+			name string
+			fn   func(t *testing.T)
+		}{
+			{"create and get", func(t *testing.T) {
+				r := newReq("a@test.com")
+				if r.ID == "" || r.CreatedAt.IsZero() {
+					t.Fatalf("create did not set id/created_at: %+v", r)
+				}
+				got, err := store.GetJITRequest(ctx, r.ID)
+				if err != nil || got == nil {
+					t.Fatalf("get: %+v, %v", got, err)
+				}
+				if got.Role != "operator" || got.DurationSec != 3600 || got.DecidedAt != nil || got.ExpiresAt != nil {
+					t.Errorf("got %+v", got)
+				}
+				if got, err := store.GetJITRequest(ctx, "missing"); err != nil || got != nil {
+					t.Errorf("get missing = %+v, %v; want nil, nil", got, err)
+				}
+			}},
+			{"decide only once", func(t *testing.T) {
+				r := newReq("b@test.com")
+				now := time.Now().UTC().Truncate(time.Second)
+				expires := now.Add(time.Hour)
+				r.Status, r.DecidedBy, r.DecidedAt, r.ExpiresAt = JITStatusApproved, "boss@test.com", &now, &expires
+				if ok, err := store.DecideJITRequest(ctx, r); err != nil || !ok {
+					t.Fatalf("decide = %v, %v", ok, err)
+				}
+				if ok, _ := store.DecideJITRequest(ctx, r); ok {
+					t.Error("second decision should not apply")
+				}
+				got, _ := store.GetJITRequest(ctx, r.ID)
+				if got.Status != JITStatusApproved || got.DecidedBy != "boss@test.com" || !got.ExpiresAt.Equal(expires) {
+					t.Errorf("got %+v", got)
+				}
+			}},
+			{"active excludes expired and pending", func(t *testing.T) {
+				past := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Second)
+				expired := past.Add(time.Hour)
+				r := newReq("c@test.com")
+				r.Status, r.DecidedAt, r.ExpiresAt = JITStatusApproved, &past, &expired
+				store.DecideJITRequest(ctx, r)
+				newReq("c@test.com")
+
+				active, err := store.ListActiveJITRequests(ctx, time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, a := range active {
+					if a.UserEmail == "c@test.com" {
+						t.Errorf("unexpected active request %+v", a)
+					}
+				}
+			}},
+			{"breakglass flag round-trips", func(t *testing.T) {
+				now := time.Now().UTC().Truncate(time.Second)
+				expires := now.Add(time.Hour)
+				r := &JITRequest{UserEmail: "e@test.com", ClusterName: "prod", Role: "admin", Reason: "INC-2",
+					DurationSec: 3600, Status: JITStatusApproved, Breakglass: true, DecidedBy: "breakglass",
+					DecidedAt: &now, ExpiresAt: &expires}
+				if err := store.CreateJITRequest(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+				active, _ := store.ListActiveJITRequests(ctx, time.Now())
+				var found bool
+				for _, a := range active {
+					found = found || (a.ID == r.ID && a.Breakglass)
+				}
+				if !found {
+					t.Errorf("break-glass request missing from active: %+v", active)
+				}
+			}},
+			{"list filters", func(t *testing.T) {
+				newReq("d@test.com")
+				reqs, err := store.ListJITRequests(ctx, JITRequestFilter{UserEmail: "d@test.com", Status: JITStatusPending})
+				if err != nil || len(reqs) != 1 {
+					t.Fatalf("list = %d, %v; want 1", len(reqs), err)
+				}
+				all, _ := store.ListJITRequests(ctx, JITRequestFilter{Limit: 2})
+				if len(all) != 2 {
+					t.Errorf("limit: got %d, want 2", len(all))
+				}
+			}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, tc.fn)
+		}
+	})
+}
@@ -24,7 +24,7 @@ func TestHandleStreamCommand_StreamsOutput(t *testing.T) {
 	srv := NewHTTPServer(agents, NewCommandQueue(), NewAuthHandlers(store, jm, time.Hour),
 		NewAdminHandlers(store, testPepper), nil, NewAuditRecorder(store), sm, jm)
 
-	token, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com"})
+	token, _ := jm.GenerateAccessToken(&auth.UserClaims{UserID: "u1", Email: "dev@x.com", Groups: []string{"sre"}})
 	body, _ := json.Marshal(ExecRequest{Command: []string{"logs", "-f", "web"}})
 	req, _ := http.NewRequest("POST", "/api/v1/clusters/prod/stream", bytes.NewReader(body))
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -65,6 +65,10 @@ func TestHandleStreamCommand_StreamsOutput(t *testing.T) {
 	if !bytes.Contains(w.Body.Bytes(), []byte("line-1")) {
 		t.Errorf("expected streamed output, got %q", w.Body.String())
 	}
+	// The agent is told who asked, for impersonation.
+	if start := snd.lastStart(); start.GetUser() != "dev@x.com" || len(start.GetGroups()) != 1 || start.GetGroups()[0] != "sre" {
+		t.Errorf("StartStream identity = %q %q, want dev@x.com [sre]", start.GetUser(), start.GetGroups())
+	}
 }
 
 func TestHandleStreamCommand_NoAgentStream(t *testing.T) {
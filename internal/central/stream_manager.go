@@ -47,10 +47,21 @@ type PfChunk struct {
 	Err    string
 }
 
+// SessionKind is what a streaming session is used for.
+type SessionKind string
+
+// Session kinds.
+const (
+	SessionKindStream      SessionKind = "stream"       // logs -f, get -w
+	SessionKindExec        SessionKind = "exec"         // exec and attach
+	SessionKindPortForward SessionKind = "port-forward" // port-forward
+)
+
 // Session is a single streaming command in flight.
 type Session struct {
 	ID       string
 	AgentID  string
+	Kind     SessionKind
 	Output   chan StreamChunk
 	PfOutput chan PfChunk // non-nil only for port-forward sessions
 	done     chan struct{}
@@ -85,6 +96,7 @@ type agentConn struct {
 	sender   streamSender
 	mu       sync.Mutex // gRPC streams are not safe for concurrent Send
 	sessions map[string]*Session
+	commands bool // the agent runs one-shot commands pushed on the stream; guarded by SessionManager.mu
 }
 
 // SessionManager multiplexes streaming sessions over per-agent bidi streams.
@@ -114,6 +126,45 @@ func (m *SessionManager) RegisterAgentStream(agentID string, s streamSender) {
 	m.agents[agentID] = &agentConn{sender: s, sessions: make(map[string]*Session)}
 }
 
+// AcceptCommands marks an agent's stream as accepting pushed one-shot
+// commands (see PushCommand).
+func (m *SessionManager) AcceptCommands(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conn := m.agents[agentID]; conn != nil {
+		conn.commands = true
+	}
+}
+
+// PushCommand sends a one-shot command down an agent's stream. It returns
+// ErrNoAgentStream when the agent has no stream or has not asked for pushed
+// commands.
+func (m *SessionManager) PushCommand(agentID string, req *agentpb.CommandRequest) error {
+	m.mu.Lock()
+	conn := m.agents[agentID]
+	ok := conn != nil && conn.commands
+	m.mu.Unlock()
+	if !ok {
+		return ErrNoAgentStream
+	}
+	return sendLocked(conn, &agentpb.CentralStreamMessage{Msg: &agentpb.CentralStreamMessage_Command{Command: req}})
+}
+
+// CancelCommand tells an agent to kill a one-shot command. Like PushCommand
+// it returns ErrNoAgentStream unless the agent's stream takes commands.
+func (m *SessionManager) CancelCommand(agentID, requestID string) error {
+	m.mu.Lock()
+	conn := m.agents[agentID]
+	ok := conn != nil && conn.commands
+	m.mu.Unlock()
+	if !ok {
+		return ErrNoAgentStream
+	}
+	return sendLocked(conn, &agentpb.CentralStreamMessage{Msg: &agentpb.CentralStreamMessage_CancelCommand{
+		CancelCommand: &agentpb.CancelCommand{RequestId: requestID},
+	}})
+}
+
 // UnregisterAgentStream drops an agent and closes all of its sessions.
 func (m *SessionManager) UnregisterAgentStream(agentID string) {
 	m.mu.Lock()
@@ -133,27 +184,30 @@ func (m *SessionManager) UnregisterAgentStream(agentID string) {
 }
 
 // Start opens a non-interactive session (logs -f / get -w).
-func (m *SessionManager) Start(agentID string, command []string, namespace string) (*Session, error) {
-	return m.startSession(agentID, &agentpb.StartStream{Command: command, Namespace: namespace})
+func (m *SessionManager) Start(agentID string, id Identity, command []string, namespace string) (*Session, error) {
+	return m.startSession(agentID, SessionKindStream, &agentpb.StartStream{
+		Command: command, Namespace: namespace, User: id.User, Groups: id.Groups,
+	})
 }
 
 // StartInteractive opens an interactive (tty) session and includes the initial size.
-func (m *SessionManager) StartInteractive(agentID string, command []string, namespace string, rows, cols uint16) (*Session, error) {
-	return m.startSession(agentID, &agentpb.StartStream{
+func (m *SessionManager) StartInteractive(agentID string, id Identity, command []string, namespace string, rows, cols uint16) (*Session, error) {
+	return m.startSession(agentID, SessionKindExec, &agentpb.StartStream{
 		Command: command, Namespace: namespace, Tty: true, Rows: uint32(rows), Cols: uint32(cols),
+		User: id.User, Groups: id.Groups,
 	})
 }
 
 // StartWithStdin opens a stdin-streaming session without a TTY.
-func (m *SessionManager) StartWithStdin(agentID string, command []string, namespace string) (*Session, error) {
-	return m.startSession(agentID, &agentpb.StartStream{
-		Command: command, Namespace: namespace, Tty: false,
+func (m *SessionManager) StartWithStdin(agentID string, id Identity, command []string, namespace string) (*Session, error) {
+	return m.startSession(agentID, SessionKindExec, &agentpb.StartStream{
+		Command: command, Namespace: namespace, Tty: false, User: id.User, Groups: id.Groups,
 	})
 }
 
 // startSession is the shared open path: send StartStream before inserting into
 // the maps to close the phantom-session window (see prior comment).
-func (m *SessionManager) startSession(agentID string, start *agentpb.StartStream) (*Session, error) {
+func (m *SessionManager) startSession(agentID string, kind SessionKind, start *agentpb.StartStream) (*Session, error) {
 	m.mu.Lock()
 	conn := m.agents[agentID]
 	if conn == nil {
@@ -167,6 +221,7 @@ func (m *SessionManager) startSession(agentID string, start *agentpb.StartStream
 	sess := &Session{
 		ID:      uuid.New().String(),
 		AgentID: agentID,
+		Kind:    kind,
 		Output:  make(chan StreamChunk, sessionOutputBuffer),
 		done:    make(chan struct{}),
 	}
@@ -216,7 +271,7 @@ func (m *SessionManager) SendResize(sessionID string, rows, cols uint32) error {
 }
 
 // StartPortForward opens a port-forward session and pushes PortForwardStart.
-func (m *SessionManager) StartPortForward(agentID, pod, namespace string, ports []uint32) (*Session, error) {
+func (m *SessionManager) StartPortForward(agentID string, id Identity, pod, namespace string, ports []uint32) (*Session, error) {
 	m.mu.Lock()
 	conn := m.agents[agentID]
 	if conn == nil {
@@ -230,13 +285,16 @@ func (m *SessionManager) StartPortForward(agentID, pod, namespace string, ports
 	sess := &Session{
 		ID:       uuid.New().String(),
 		AgentID:  agentID,
+		Kind:     SessionKindPortForward,
 		PfOutput: make(chan PfChunk, sessionOutputBuffer),
 		done:     make(chan struct{}),
 	}
 	m.mu.Unlock()
 
 	err := sendLocked(conn, &agentpb.CentralStreamMessage{Msg: &agentpb.CentralStreamMessage_PfStart{
-		PfStart: &agentpb.PortForwardStart{SessionId: sess.ID, Pod: pod, Namespace: namespace, Ports: ports},
+		PfStart: &agentpb.PortForwardStart{
+			SessionId: sess.ID, Pod: pod, Namespace: namespace, Ports: ports, User: id.User, Groups: id.Groups,
+		},
 	}})
 	if err != nil {
 		return nil, err
@@ -301,6 +359,7 @@ func (m *SessionManager) Route(msg *agentpb.AgentStreamMessage) {
 			case sess.Output <- StreamChunk{Type: v.Output.GetType(), Data: v.Output.GetData()}:
 			default:
 				// Slow client: cancel rather than block the shared recv loop.
+				slowClientCancelsTotal.WithLabelValues(string(sess.Kind)).Inc()
 				m.Cancel(sess.ID)
 			}
 		}
@@ -333,6 +392,7 @@ func (m *SessionManager) routePf(sessionID string, chunk PfChunk) {
 	select {
 	case sess.PfOutput <- chunk:
 	default:
+		slowClientCancelsTotal.WithLabelValues(string(sess.Kind)).Inc()
 		m.Cancel(sess.ID)
 	}
 }
@@ -360,6 +420,17 @@ func (m *SessionManager) Cancel(sessionID string) {
 	sess.close(-1, "canceled")
 }
 
+// activeByKind counts the open sessions of each kind.
+func (m *SessionManager) activeByKind() map[SessionKind]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[SessionKind]int)
+	for _, sess := range m.sessions {
+		counts[sess.Kind]++
+	}
+	return counts
+}
+
 func (m *SessionManager) lookup(id string) *Session {
 	m.mu.Lock()
 	defer m.mu.Unlock()
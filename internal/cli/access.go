@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var accessCmd = &cobra.Command{
+	Use:   "access",
+	Short: "Request and approve temporary access",
+	Long: `Just-in-time access: request a policy role on a cluster for a limited
+time, and (as an approver) approve or deny other users' requests. An approved
+role applies on top of your normal roles until it expires.`,
+}
+
+var (
+	accessCluster  string
+	accessRole     string
+	accessDuration time.Duration
+	accessReason   string
+)
+
+var accessRequestCmd = &cobra.Command{
+	Use:     "request",
+	Short:   "Request a role on a cluster for a limited time",
+	Example: `  kb access request --cluster prod --role operator --duration 2h --reason INC-123`,
+	Args:    cobra.NoArgs,
+	RunE:    runAccessRequest,
+}
+
+var (
+	accessListStatus string
+	accessListMine   bool
+)
+
+var accessListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List access requests",
+	Long: `List access requests, newest first. Approvers see every request; other
+users see their own.`,
+	Args: cobra.NoArgs,
+	RunE: runAccessList,
+}
+
+var accessApproveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Approve a pending access request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAccessDecide(args[0], true)
+	},
+}
+
+var accessDenyCmd = &cobra.Command{
+	Use:   "deny <id>",
+	Short: "Deny a pending access request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAccessDecide(args[0], false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accessCmd)
+	accessCmd.AddCommand(accessRequestCmd)
+	accessCmd.AddCommand(accessListCmd)
+	accessCmd.AddCommand(accessApproveCmd)
+	accessCmd.AddCommand(accessDenyCmd)
+
+	accessRequestCmd.Flags().StringVar(&accessCluster, "cluster", "", "cluster to request access to (default: the selected cluster)")
+	accessRequestCmd.Flags().StringVar(&accessRole, "role", "", "policy role to request (required)")
+	accessRequestCmd.Flags().DurationVar(&accessDuration, "duration", time.Hour, "how long the role applies once approved")
+	accessRequestCmd.Flags().StringVar(&accessReason, "reason", "", "why access is needed, e.g. an incident ID (required)")
+	_ = accessRequestCmd.MarkFlagRequired("role")
+	_ = accessRequestCmd.MarkFlagRequired("reason")
+
+	accessListCmd.Flags().StringVar(&accessListStatus, "status", "", "filter by status (pending/approved/denied)")
+	accessListCmd.Flags().BoolVar(&accessListMine, "mine", false, "only show your own requests")
+}
+
+func accessClient() (*CentralClient, error) {
+	centralURL := viper.GetString(ConfigKeyCentralURL)
+	if centralURL == "" {
+		return nil, fmt.Errorf("central URL not configured. Run 'kb login' first")
+	}
+	return newAuthenticatedClient(centralURL), nil
+}
+
+func runAccessRequest(cmd *cobra.Command, args []string) error {
+	cluster := accessCluster
+	if cluster == "" {
+		cluster = viper.GetString(ConfigKeyCurrentCluster)
+	}
+	if cluster == "" {
+		return fmt.Errorf("no cluster selected. Run 'kb clusters use <name>' or pass --cluster")
+	}
+	client, err := accessClient()
+	if err != nil {
+		return err
+	}
+	r, err := client.RequestAccess(cluster, accessRole, accessDuration.String(), accessReason)
+	if err != nil {
+		return fmt.Errorf("failed to request access: %w", err)
+	}
+	fmt.Printf("Requested role %q on %s for %s (request %s).\n", r.Role, r.ClusterName, accessDuration, r.ID)
+	fmt.Printf("An approver can grant it with: kb access approve %s\n", r.ID)
+	return nil
+}
+
+func runAccessList(cmd *cobra.Command, args []string) error {
+	client, err := accessClient()
+	if err != nil {
+		return err
+	}
+	reqs, err := client.ListAccessRequests(accessListStatus, accessListMine)
+	if err != nil {
+		return fmt.Errorf("failed to list access requests: %w", err)
+	}
+	if len(reqs) == 0 {
+		fmt.Println("No access requests found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER\tCLUSTER\tROLE\tDURATION\tSTATUS\tEXPIRES\tREASON")
+	for _, r := range reqs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.UserEmail, r.ClusterName, r.Role,
+			time.Duration(r.DurationSec)*time.Second, accessStatus(r), valueOrDash(r.ExpiresAt), r.Reason)
+	}
+	return w.Flush()
+}
+
+// accessStatus reports "expired" for approved requests whose window has passed
+// and marks break-glass grants.
+func accessStatus(r JITRequestInfo) string {
+	status := r.Status
+	if r.Status == "approved" && r.ExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, r.ExpiresAt); err == nil && time.Now().After(t) {
+			status = "expired"
+		}
+	}
+	if r.Breakglass {
+		status += " (break-glass)"
+	}
+	return status
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func runAccessDecide(id string, approve bool) error {
+	client, err := accessClient()
+	if err != nil {
+		return err
+	}
+	r, err := client.DecideAccessRequest(id, approve)
+	if err != nil {
+		return fmt.Errorf("failed to decide access request: %w", err)
+	}
+	if approve {
+		fmt.Printf("Approved: %s has role %q on %s until %s.\n", r.UserEmail, r.Role, r.ClusterName, r.ExpiresAt)
+	} else {
+		fmt.Printf("Denied access request %s from %s.\n", r.ID, r.UserEmail)
+	}
+	return nil
+}
@@ -3,11 +3,13 @@ package cli
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,6 +39,7 @@ var (
 	createUserEmail    string
 	createUserName     string
 	createUserPassword string
+	createUserGroups   []string
 )
 
 var adminUsersCreateCmd = &cobra.Command{
@@ -46,6 +49,16 @@ var adminUsersCreateCmd = &cobra.Command{
 	RunE:  runAdminUsersCreate,
 }
 
+var adminUsersSetGroupsCmd = &cobra.Command{
+	Use:   "set-groups <user-id> [group...]",
+	Short: "Replace a user's groups",
+	Long: `Replace the kbridge-managed groups of a user. Policy bindings with a
+"group:<name>" subject apply to members. Pass no groups to clear them. Changes
+take effect when the user's access token is next refreshed.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAdminUsersSetGroups,
+}
+
 var adminTokensCmd = &cobra.Command{
 	Use:     "agent-tokens",
 	Aliases: []string{"agent-token", "tokens"},
@@ -80,17 +93,65 @@ var adminTokensRevokeCmd = &cobra.Command{
 }
 
 var (
-	auditUser    string
-	auditCluster string
-	auditStatus  string
-	auditLimit   int
+	auditUser      string
+	auditCluster   string
+	auditStatus    string
+	auditNamespace string
+	auditVerb      string
+	auditResource  string
+	auditClientIP  string
+	auditSearch    string
+	auditFrom      string
+	auditTo        string
+	auditLimit     int
+	auditCursor    string
 )
 
 var adminAuditCmd = &cobra.Command{
 	Use:   "audit",
 	Short: "View command audit logs",
-	Long:  `View the audit log of kubectl commands, optionally filtered by user, cluster, or status.`,
-	RunE:  runAdminAudit,
+	Long: `View the audit log of kubectl commands, newest first. Filters combine; --search
+matches any part of the command. Pass the printed cursor to --cursor for the
+next page.`,
+	Example: `  kb admin audit --user dev@corp.com --status denied
+  kb admin audit --verb delete --resource pods --from 2026-01-01
+  kb admin audit --search "rollout restart" --cursor MjAy...`,
+	RunE: runAdminAudit,
+}
+
+var (
+	auditExportFormat string
+	auditExportOutput string
+)
+
+var adminAuditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export audit logs as CSV or JSON lines",
+	Long: `Export every audit entry matching the filters, oldest first. Central streams
+the export, so it can cover the whole retention window.`,
+	Example: `  kb admin audit export --format csv --from 2026-01-01 --to 2026-02-01 -o january.csv
+  kb admin audit export --cluster prod --verb delete > deletes.jsonl`,
+	Args: cobra.NoArgs,
+	RunE: runAdminAuditExport,
+}
+
+var adminAuditShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show one audit entry and its captured output",
+	Long: `Show an audit entry in full, including the command output captured for it
+when central runs with audit.output.mode set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAdminAuditShow,
+}
+
+var adminAuditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the audit log hash chain",
+	Long: `Walk the audit log hash chain from the latest retention checkpoint and
+report entries that are missing or were edited. Exits non-zero if the chain is
+broken.`,
+	Args: cobra.NoArgs,
+	RunE: runAdminAuditVerify,
 }
 
 func init() {
@@ -98,7 +159,11 @@ func init() {
 	adminCmd.AddCommand(adminUsersCmd)
 	adminUsersCmd.AddCommand(adminUsersListCmd)
 	adminUsersCmd.AddCommand(adminUsersCreateCmd)
+	adminUsersCmd.AddCommand(adminUsersSetGroupsCmd)
 	adminCmd.AddCommand(adminAuditCmd)
+	adminAuditCmd.AddCommand(adminAuditShowCmd)
+	adminAuditCmd.AddCommand(adminAuditVerifyCmd)
+	adminAuditCmd.AddCommand(adminAuditExportCmd)
 
 	adminCmd.AddCommand(adminTokensCmd)
 	adminTokensCmd.AddCommand(adminTokensCreateCmd)
@@ -113,13 +178,72 @@ func init() {
 	adminUsersCreateCmd.Flags().StringVar(&createUserEmail, "email", "", "user email (required)")
 	adminUsersCreateCmd.Flags().StringVar(&createUserName, "name", "", "user display name (required)")
 	adminUsersCreateCmd.Flags().StringVar(&createUserPassword, "password", "", "user password (prompted if omitted)")
+	adminUsersCreateCmd.Flags().StringSliceVar(&createUserGroups, "groups", nil, "comma-separated groups the user belongs to")
 	adminUsersCreateCmd.MarkFlagRequired("email")
 	adminUsersCreateCmd.MarkFlagRequired("name")
 
-	adminAuditCmd.Flags().StringVar(&auditUser, "user", "", "filter by user email")
-	adminAuditCmd.Flags().StringVar(&auditCluster, "cluster", "", "filter by cluster name")
-	adminAuditCmd.Flags().StringVar(&auditStatus, "status", "", "filter by status (success/failed/denied/timeout)")
+	addAuditFilterFlags(adminAuditCmd)
 	adminAuditCmd.Flags().IntVar(&auditLimit, "limit", 50, "maximum number of entries to show")
+	adminAuditCmd.Flags().StringVar(&auditCursor, "cursor", "", "continue from a cursor printed by a previous page")
+
+	addAuditFilterFlags(adminAuditExportCmd)
+	adminAuditExportCmd.Flags().StringVar(&auditExportFormat, "format", "jsonl", "output format (csv or jsonl)")
+	adminAuditExportCmd.Flags().StringVarP(&auditExportOutput, "output", "o", "", "file to write (default stdout)")
+}
+
+// addAuditFilterFlags registers the audit filters shared by kb admin audit and
+// kb admin audit export.
+func addAuditFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&auditUser, "user", "", "filter by user email")
+	cmd.Flags().StringVar(&auditCluster, "cluster", "", "filter by cluster name")
+	cmd.Flags().StringVar(&auditStatus, "status", "", "filter by status (success/failed/denied/timeout/...)")
+	cmd.Flags().StringVar(&auditNamespace, "namespace", "", "filter by namespace")
+	cmd.Flags().StringVar(&auditVerb, "verb", "", "filter by kubectl verb (get, delete, ...)")
+	cmd.Flags().StringVar(&auditResource, "resource", "", "filter by resource type (pods, deployments, ...)")
+	cmd.Flags().StringVar(&auditClientIP, "client-ip", "", "filter by client IP address")
+	cmd.Flags().StringVar(&auditSearch, "search", "", "filter by text in the command")
+	cmd.Flags().StringVar(&auditFrom, "from", "", "only entries at or after this time (RFC3339 or YYYY-MM-DD)")
+	cmd.Flags().StringVar(&auditTo, "to", "", "only entries before this time (RFC3339 or YYYY-MM-DD)")
+}
+
+// auditFilters returns the query parameters for the audit filter flags.
+func auditFilters() (map[string]string, error) {
+	from, err := parseAuditTime(auditFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --from: %w", err)
+	}
+	to, err := parseAuditTime(auditTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --to: %w", err)
+	}
+	return map[string]string{
+		"user":      auditUser,
+		"cluster":   auditCluster,
+		"status":    auditStatus,
+		"namespace": auditNamespace,
+		"verb":      auditVerb,
+		"resource":  auditResource,
+		"client_ip": auditClientIP,
+		"q":         auditSearch,
+		"from":      from,
+		"to":        to,
+	}, nil
+}
+
+// parseAuditTime accepts an RFC3339 time or a YYYY-MM-DD date (midnight UTC)
+// and returns it in the RFC3339 form the API expects.
+func parseAuditTime(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Format(time.RFC3339), nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return "", fmt.Errorf("%q is not an RFC3339 time or YYYY-MM-DD date", s)
+	}
+	return t.Format(time.RFC3339), nil
 }
 
 func adminClient() (*CentralClient, error) {
@@ -145,9 +269,13 @@ func runAdminUsersList(cmd *cobra.Command, args []string) error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "EMAIL\tNAME\tACTIVE\tADMIN\tID")
+	fmt.Fprintln(w, "EMAIL\tNAME\tACTIVE\tADMIN\tGROUPS\tID")
 	for _, u := range users {
-		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\n", u.Email, u.Name, u.IsActive, u.IsAdmin, u.ID)
+		groups := strings.Join(u.Groups, ",")
+		if groups == "" {
+			groups = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\t%s\n", u.Email, u.Name, u.IsActive, u.IsAdmin, groups, u.ID)
 	}
 	return w.Flush()
 }
@@ -169,7 +297,7 @@ func runAdminUsersCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("password must not be empty")
 	}
 
-	user, err := client.CreateUser(createUserEmail, createUserName, password)
+	user, err := client.CreateUser(createUserEmail, createUserName, password, createUserGroups)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -177,42 +305,215 @@ func runAdminUsersCreate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runAdminUsersSetGroups(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	user, err := client.SetUserGroups(args[0], args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if len(user.Groups) == 0 {
+		fmt.Printf("Cleared groups for %q.\n", user.Email)
+	} else {
+		fmt.Printf("Groups for %q: %s\n", user.Email, strings.Join(user.Groups, ", "))
+	}
+	return nil
+}
+
 func runAdminAudit(cmd *cobra.Command, args []string) error {
 	client, err := adminClient()
 	if err != nil {
 		return err
 	}
 
-	filters := map[string]string{
-		"user":     auditUser,
-		"cluster":  auditCluster,
-		"status":   auditStatus,
-		"per_page": strconv.Itoa(auditLimit),
+	filters, err := auditFilters()
+	if err != nil {
+		return err
 	}
-	logs, total, err := client.ListAuditLogs(filters)
+	filters["per_page"] = strconv.Itoa(auditLimit)
+	filters["cursor"] = auditCursor
+	page, err := client.ListAuditLogs(filters)
 	if err != nil {
 		return fmt.Errorf("failed to fetch audit logs: %w", err)
 	}
+	logs := page.Logs
 	if len(logs) == 0 {
 		fmt.Println("No audit logs found.")
 		return nil
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "TIME\tUSER\tCLUSTER\tSTATUS\tEXIT\tCOMMAND")
+	fmt.Fprintln(w, "TIME\tUSER\tCLUSTER\tSTATUS\tEXIT\tCOMMAND\tID")
 	for _, l := range logs {
 		exit := "-"
 		if l.ExitCode != nil {
 			exit = strconv.Itoa(int(*l.ExitCode))
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-			l.CreatedAt, l.UserEmail, l.ClusterName, l.Status, exit, l.Command)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			l.CreatedAt, l.UserEmail, l.ClusterName, l.Status, exit, l.Command, l.ID)
 	}
 	w.Flush()
-	fmt.Printf("\nShowing %d of %d entries.\n", len(logs), total)
+	if auditCursor == "" {
+		fmt.Printf("\nShowing %d of %d entries.\n", len(logs), page.Total)
+	} else {
+		fmt.Printf("\nShowing %d entries.\n", len(logs))
+	}
+	if page.NextCursor != "" {
+		fmt.Printf("More entries: --cursor %s\n", page.NextCursor)
+	}
 	return nil
 }
 
+func runAdminAuditExport(cmd *cobra.Command, args []string) error {
+	if auditExportFormat != "csv" && auditExportFormat != "jsonl" {
+		return fmt.Errorf("--format must be csv or jsonl")
+	}
+	filters, err := auditFilters()
+	if err != nil {
+		return err
+	}
+	filters["format"] = auditExportFormat
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+
+	if auditExportOutput == "" || auditExportOutput == "-" {
+		if err := client.ExportAuditLogs(filters, os.Stdout); err != nil {
+			return fmt.Errorf("failed to export audit logs: %w", err)
+		}
+		return nil
+	}
+	f, err := os.OpenFile(auditExportOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", auditExportOutput, err)
+	}
+	if err := client.ExportAuditLogs(filters, f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to export audit logs: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", auditExportOutput, err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported audit logs to %s\n", auditExportOutput)
+	return nil
+}
+
+func runAdminAuditShow(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	l, out, err := client.GetAuditLog(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch audit entry: %w", err)
+	}
+	printAuditEntry(os.Stdout, l, out)
+	return nil
+}
+
+// printAuditEntry writes an audit entry, and its captured output if any, in
+// the format of kb admin audit show.
+func printAuditEntry(w io.Writer, l *AuditLogInfo, out *AuditOutputInfo) {
+	exit, duration := "-", "-"
+	if l.ExitCode != nil {
+		exit = strconv.Itoa(int(*l.ExitCode))
+	}
+	if l.DurationMs != nil {
+		duration = (time.Duration(*l.DurationMs) * time.Millisecond).String()
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", l.ID)
+	if l.Seq > 0 {
+		fmt.Fprintf(tw, "Seq:\t%d\n", l.Seq)
+	}
+	fmt.Fprintf(tw, "Time:\t%s\n", l.CreatedAt)
+	fmt.Fprintf(tw, "User:\t%s\n", valueOrDash(l.UserEmail))
+	fmt.Fprintf(tw, "Client IP:\t%s\n", valueOrDash(l.ClientIP))
+	fmt.Fprintf(tw, "Cluster:\t%s\n", l.ClusterName)
+	fmt.Fprintf(tw, "Namespace:\t%s\n", valueOrDash(l.Namespace))
+	if l.Verb != "" {
+		fmt.Fprintf(tw, "Verb:\t%s\n", l.Verb)
+		fmt.Fprintf(tw, "Resource:\t%s\n", valueOrDash(l.Resource))
+	}
+	fmt.Fprintf(tw, "Command:\t%s\n", l.Command)
+	fmt.Fprintf(tw, "Status:\t%s\n", l.Status)
+	fmt.Fprintf(tw, "Exit code:\t%s\n", exit)
+	fmt.Fprintf(tw, "Duration:\t%s\n", duration)
+	if l.ErrorMessage != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", l.ErrorMessage)
+	}
+	tw.Flush()
+
+	if out == nil {
+		fmt.Fprintln(w, "\nNo output captured.")
+		return
+	}
+	for _, s := range []struct {
+		name, text string
+		size       int64
+	}{{"stdout", out.Stdout, out.StdoutBytes}, {"stderr", out.Stderr, out.StderrBytes}} {
+		if s.text == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- %s (%d bytes) ---\n%s", s.name, s.size, s.text)
+		if !strings.HasSuffix(s.text, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+	var notes []string
+	if out.Truncated {
+		notes = append(notes, "output truncated")
+	}
+	if out.Redactions > 0 {
+		notes = append(notes, fmt.Sprintf("%d secret(s) redacted", out.Redactions))
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(w, "\n(%s)\n", strings.Join(notes, "; "))
+	}
+}
+
+func runAdminAuditVerify(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	r, err := client.VerifyAuditChain()
+	if err != nil {
+		return fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	if r.Checkpoint != nil {
+		fmt.Printf("Checkpoint: entries up to #%d removed by retention (%d deleted at %s).\n",
+			r.Checkpoint.Seq, r.Checkpoint.Deleted, r.Checkpoint.CreatedAt)
+	}
+	if r.Checked == 0 {
+		fmt.Println("No chained audit entries.")
+	} else {
+		fmt.Printf("Checked %d entries (#%d-#%d), head %s.\n", r.Checked, r.FirstSeq, r.LastSeq, r.HeadHash)
+	}
+	if !r.Keyed {
+		fmt.Println("Warning: audit.chain_secret is not set; the chain is not keyed.")
+	}
+	if r.OK {
+		fmt.Println("Audit chain OK.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEQ\tPROBLEM\tID\tDETAIL")
+	for _, p := range r.Problems {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", p.Seq, p.Kind, valueOrDash(p.ID), p.Detail)
+	}
+	w.Flush()
+	if r.Truncated {
+		fmt.Println("(more problems not shown)")
+	}
+	return fmt.Errorf("audit chain verification failed")
+}
+
 func runAdminTokensCreate(cmd *cobra.Command, args []string) error {
 	client, err := adminClient()
 	if err != nil {
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var adminPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage the RBAC policy",
+	Long: `View and change the RBAC policy. Changes, history and rollback require
+central to run with rbac.source: database; a file-backed policy is read-only.`,
+}
+
+var policyGetVersion int
+
+var adminPolicyGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Print the active policy (or an earlier version)",
+	Example: `  kb admin policy get > policy.yaml
+  kb admin policy get --version 3`,
+	Args: cobra.NoArgs,
+	RunE: runAdminPolicyGet,
+}
+
+var (
+	policySetFile        string
+	policySetComment     string
+	policySetBaseVersion int
+)
+
+var adminPolicySetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Validate and activate a new policy version",
+	Long: `Validate a policy document and activate it as a new version. Pass
+--base-version with the version you edited to fail instead of overwriting a
+change someone else made in the meantime.`,
+	Example: `  kb admin policy set -f policy.yaml --comment "grant oncall exec"
+  kb admin policy set -f policy.yaml --base-version 4`,
+	Args: cobra.NoArgs,
+	RunE: runAdminPolicySet,
+}
+
+var adminPolicyHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List policy versions",
+	Args:  cobra.NoArgs,
+	RunE:  runAdminPolicyHistory,
+}
+
+var policyRollbackComment string
+
+var adminPolicyRollbackCmd = &cobra.Command{
+	Use:   "rollback <version>",
+	Short: "Re-activate an earlier policy version",
+	Long: `Re-activate the document of an earlier version. The rollback is recorded
+as a new version, so history is preserved.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAdminPolicyRollback,
+}
+
+func init() {
+	adminCmd.AddCommand(adminPolicyCmd)
+	adminPolicyCmd.AddCommand(adminPolicyGetCmd)
+	adminPolicyCmd.AddCommand(adminPolicySetCmd)
+	adminPolicyCmd.AddCommand(adminPolicyHistoryCmd)
+	adminPolicyCmd.AddCommand(adminPolicyRollbackCmd)
+
+	adminPolicyGetCmd.Flags().IntVar(&policyGetVersion, "version", 0, "print this stored version instead of the active one")
+
+	adminPolicySetCmd.Flags().StringVarP(&policySetFile, "filename", "f", "", "policy file to submit, or - for stdin (required)")
+	adminPolicySetCmd.Flags().StringVar(&policySetComment, "comment", "", "describe the change")
+	adminPolicySetCmd.Flags().IntVar(&policySetBaseVersion, "base-version", 0, "fail if the active version is no longer this one")
+	_ = adminPolicySetCmd.MarkFlagRequired("filename")
+
+	adminPolicyRollbackCmd.Flags().StringVar(&policyRollbackComment, "comment", "", "describe the rollback")
+}
+
+func runAdminPolicyGet(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	if policyGetVersion > 0 {
+		pv, err := client.GetPolicyVersion(policyGetVersion)
+		if err != nil {
+			return fmt.Errorf("failed to get policy version: %w", err)
+		}
+		fmt.Print(pv.Document)
+		return nil
+	}
+	p, err := client.GetPolicy()
+	if err != nil {
+		return fmt.Errorf("failed to get policy: %w", err)
+	}
+	if p.Version > 0 {
+		fmt.Fprintf(os.Stderr, "# version %d (source: %s)\n", p.Version, p.Source)
+	} else {
+		fmt.Fprintf(os.Stderr, "# source: %s\n", p.Source)
+	}
+	fmt.Print(p.Document)
+	return nil
+}
+
+func runAdminPolicySet(cmd *cobra.Command, args []string) error {
+	var data []byte
+	var err error
+	if policySetFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(policySetFile)
+	}
+	if err != nil {
+		return fmt.Errorf("reading policy: %w", err)
+	}
+
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	pv, err := client.SetPolicy(string(data), policySetComment, policySetBaseVersion)
+	if err != nil {
+		return fmt.Errorf("failed to set policy: %w", err)
+	}
+	fmt.Printf("Policy version %d is now active.\n", pv.Version)
+	return nil
+}
+
+func runAdminPolicyHistory(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	versions, err := client.ListPolicyVersions()
+	if err != nil {
+		return fmt.Errorf("failed to list policy versions: %w", err)
+	}
+	if len(versions) == 0 {
+		fmt.Println("No policy versions found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCREATED\tAUTHOR\tCOMMENT")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", v.Version, v.CreatedAt, v.Author, v.Comment)
+	}
+	return w.Flush()
+}
+
+func runAdminPolicyRollback(cmd *cobra.Command, args []string) error {
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 1 {
+		return fmt.Errorf("invalid version %q", args[0])
+	}
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	pv, err := client.RollbackPolicy(version, policyRollbackComment)
+	if err != nil {
+		return fmt.Errorf("failed to roll back policy: %w", err)
+	}
+	fmt.Printf("Rolled back to version %d; policy version %d is now active.\n", version, pv.Version)
+	return nil
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var adminSessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List and replay recorded exec sessions",
+	Long: `List and replay interactive exec sessions recorded by central. Recording
+must be enabled with audit.recording.enabled in the central config.`,
+}
+
+var (
+	sessionsUser    string
+	sessionsCluster string
+	sessionsLimit   int
+)
+
+var adminSessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded sessions, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runAdminSessionsList,
+}
+
+var (
+	sessionsPlaySpeed   float64
+	sessionsPlayMaxIdle time.Duration
+)
+
+var adminSessionsPlayCmd = &cobra.Command{
+	Use:   "play <id>",
+	Short: "Replay a recorded session in the terminal",
+	Long: `Replay the output of a recorded session with its original timing. Use
+--speed to play faster and --max-idle to skip long pauses.`,
+	Example: `  kb admin sessions play 4f0c...
+  kb admin sessions play 4f0c... --speed 2 --max-idle 1s`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAdminSessionsPlay,
+}
+
+var sessionsDownloadOutput string
+
+var adminSessionsDownloadCmd = &cobra.Command{
+	Use:   "download <id>",
+	Short: "Save a recorded session as an asciicast v2 file",
+	Long: `Save a recorded session as an asciicast v2 file, which asciinema and
+other asciicast players can replay.`,
+	Example: `  kb admin sessions download 4f0c... -o session.cast
+  asciinema play session.cast`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAdminSessionsDownload,
+}
+
+func init() {
+	adminCmd.AddCommand(adminSessionsCmd)
+	adminSessionsCmd.AddCommand(adminSessionsListCmd)
+	adminSessionsCmd.AddCommand(adminSessionsPlayCmd)
+	adminSessionsCmd.AddCommand(adminSessionsDownloadCmd)
+
+	adminSessionsListCmd.Flags().StringVar(&sessionsUser, "user", "", "filter by user email")
+	adminSessionsListCmd.Flags().StringVar(&sessionsCluster, "cluster", "", "filter by cluster name")
+	adminSessionsListCmd.Flags().IntVar(&sessionsLimit, "limit", 50, "maximum number of sessions to show")
+
+	adminSessionsPlayCmd.Flags().Float64Var(&sessionsPlaySpeed, "speed", 1, "playback speed multiplier")
+	adminSessionsPlayCmd.Flags().DurationVar(&sessionsPlayMaxIdle, "max-idle", 0, "cap pauses between output at this duration (0 keeps them)")
+
+	adminSessionsDownloadCmd.Flags().StringVarP(&sessionsDownloadOutput, "output", "o", "", "file to write (default <id>.cast, - for stdout)")
+}
+
+func runAdminSessionsList(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	sessions, err := client.ListSessionRecordings(sessionsUser, sessionsCluster, sessionsLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No recorded sessions found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tUSER\tCLUSTER\tNAMESPACE\tDURATION\tSIZE\tCOMMAND")
+	for _, s := range sessions {
+		size := fmt.Sprintf("%d", s.Size)
+		if s.Truncated {
+			size += " (truncated)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.CreatedAt, s.UserEmail, s.ClusterName,
+			s.Namespace, time.Duration(s.DurationMs)*time.Millisecond, size, s.Command)
+	}
+	return w.Flush()
+}
+
+func runAdminSessionsPlay(cmd *cobra.Command, args []string) error {
+	if sessionsPlaySpeed <= 0 {
+		return fmt.Errorf("--speed must be positive")
+	}
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	cast, err := client.DownloadSessionRecording(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch session: %w", err)
+	}
+	return playCast(bytes.NewReader(cast), os.Stdout, sessionsPlaySpeed, sessionsPlayMaxIdle, time.Sleep)
+}
+
+func runAdminSessionsDownload(cmd *cobra.Command, args []string) error {
+	client, err := adminClient()
+	if err != nil {
+		return err
+	}
+	cast, err := client.DownloadSessionRecording(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch session: %w", err)
+	}
+	out := sessionsDownloadOutput
+	if out == "-" {
+		_, err := os.Stdout.Write(cast)
+		return err
+	}
+	if out == "" {
+		out = args[0] + ".cast"
+	}
+	if err := os.WriteFile(out, cast, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved session %s to %s\n", args[0], out)
+	return nil
+}
+
+// playCast writes the output events of an asciicast v2 recording to w,
+// sleeping between them to reproduce the original timing divided by speed.
+// Pauses longer than maxIdle, if set, are shortened to maxIdle. Input and
+// resize events are skipped.
+func playCast(r io.Reader, w io.Writer, speed float64, maxIdle time.Duration, sleep func(time.Duration)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("empty recording")
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(sc.Bytes(), &header); err != nil || header.Version != 2 {
+		return fmt.Errorf("not an asciicast v2 recording")
+	}
+
+	var last float64
+	for sc.Scan() {
+		var ev []any
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil || len(ev) != 3 {
+			return fmt.Errorf("malformed event: %s", sc.Text())
+		}
+		at, _ := ev[0].(float64)
+		code, _ := ev[1].(string)
+		data, _ := ev[2].(string)
+		if code != "o" {
+			continue
+		}
+		delay := time.Duration((at - last) / speed * float64(time.Second))
+		if maxIdle > 0 && delay > maxIdle {
+			delay = maxIdle
+		}
+		if delay > 0 {
+			sleep(delay)
+		}
+		last = at
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlayCast(t *testing.T) {
+	cast := `{"version":2,"width":80,"height":24,"timestamp":1700000000}
+[0.5,"o","$ "]
+[1.0,"i","ls\r"]
+[1.5,"o","ls\r\n"]
+[11.5,"r","100x30"]
+[12.5,"o","bye"]
+`
+	var out bytes.Buffer
+	var sleeps []time.Duration
+	err := playCast(strings.NewReader(cast), &out, 2, 3*time.Second, func(d time.Duration) { sleeps = append(sleeps, d) })
+	if err != nil {
+		t.Fatalf("playCast: %v", err)
+	}
+	if out.String() != "$ ls\r\nbye" {
+		t.Errorf("output = %q", out.String())
+	}
+	want := []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, 3 * time.Second}
+	if len(sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", sleeps, want)
+	}
+	for i := range want {
+		if sleeps[i] != want[i] {
+			t.Errorf("sleep %d = %v, want %v", i, sleeps[i], want[i])
+		}
+	}
+
+	if err := playCast(strings.NewReader(`{"version":1}`), &out, 1, 0, func(time.Duration) {}); err == nil {
+		t.Error("want an error for a non-v2 recording")
+	}
+}
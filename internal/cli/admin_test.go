@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintAuditEntry(t *testing.T) {
+	exit := int32(1)
+	dur := int64(1500)
+	l := &AuditLogInfo{ID: "log-1", UserEmail: "dev@x.com", ClusterName: "prod", Command: "get secret db",
+		Status: "failed", ExitCode: &exit, DurationMs: &dur, CreatedAt: "2026-01-02T03:04:05Z"}
+
+	var buf bytes.Buffer
+	printAuditEntry(&buf, l, &AuditOutputInfo{Stdout: "password: [REDACTED]", StdoutBytes: 17,
+		Stderr: "warning\n", StderrBytes: 8, Redactions: 1})
+	got := buf.String()
+	for _, want := range []string{"log-1", "get secret db", "Exit code:  1", "Duration:   1.5s",
+		"--- stdout (17 bytes) ---\npassword: [REDACTED]\n", "--- stderr (8 bytes) ---\nwarning\n", "(1 secret(s) redacted)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+
+	buf.Reset()
+	printAuditEntry(&buf, l, nil)
+	if !strings.Contains(buf.String(), "No output captured.") {
+		t.Errorf("missing no-output note:\n%s", buf.String())
+	}
+}
+
+func TestParseAuditTime(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"2026-01-02", "2026-01-02T00:00:00Z", false},
+		{"2026-01-02T03:04:05+02:00", "2026-01-02T03:04:05+02:00", false},
+		{"yesterday", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseAuditTime(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAuditTime(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
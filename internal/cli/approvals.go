@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var approvalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "Review commands waiting for approval",
+	Long: `Policy rules with require_approval hold matching commands until a second
+user approves them. The requester's kb waits, and the command runs and prints
+its output as soon as it is approved. Anyone the policy allows to run the
+command themselves, and admins, can approve it; the requester cannot.`,
+}
+
+var approvalsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List pending commands you can decide, and your own",
+	Args:    cobra.NoArgs,
+	RunE:    runApprovalsList,
+}
+
+var approvalsApproveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Approve a pending command; it runs immediately",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runApprovalsDecide(args[0], true)
+	},
+}
+
+var approvalsDenyCmd = &cobra.Command{
+	Use:   "deny <id>",
+	Short: "Deny a pending command",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runApprovalsDecide(args[0], false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(approvalsCmd)
+	approvalsCmd.AddCommand(approvalsListCmd)
+	approvalsCmd.AddCommand(approvalsApproveCmd)
+	approvalsCmd.AddCommand(approvalsDenyCmd)
+}
+
+// notifyApprovalPending tells a requester whose command was held for approval
+// how to get it approved.
+func notifyApprovalPending(a *CommandApprovalInfo) {
+	fmt.Fprintf(os.Stderr, "This command requires approval (request %s, expires %s).\n", a.ID, a.ExpiresAt)
+	fmt.Fprintf(os.Stderr, "Ask another user to run: kb approvals approve %s\n", a.ID)
+	fmt.Fprintln(os.Stderr, "Waiting for approval (Ctrl-C withdraws the request)...")
+}
+
+func runApprovalsList(cmd *cobra.Command, args []string) error {
+	client, err := accessClient()
+	if err != nil {
+		return err
+	}
+	approvals, err := client.ListApprovals()
+	if err != nil {
+		return fmt.Errorf("failed to list approvals: %w", err)
+	}
+	if len(approvals) == 0 {
+		fmt.Println("No commands are waiting for approval.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER\tCLUSTER\tNAMESPACE\tEXPIRES\tCOMMAND")
+	for _, a := range approvals {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.ID, a.UserEmail, a.ClusterName,
+			valueOrDash(a.Namespace), a.ExpiresAt, strings.Join(a.Command, " "))
+	}
+	return w.Flush()
+}
+
+func runApprovalsDecide(id string, approve bool) error {
+	client, err := accessClient()
+	if err != nil {
+		return err
+	}
+	a, err := client.DecideApproval(id, approve)
+	if err != nil {
+		return fmt.Errorf("failed to decide approval: %w", err)
+	}
+	if approve {
+		fmt.Printf("Approved: %q on %s for %s.\n", strings.Join(a.Command, " "), a.ClusterName, a.UserEmail)
+	} else {
+		fmt.Printf("Denied: %q on %s for %s.\n", strings.Join(a.Command, " "), a.ClusterName, a.UserEmail)
+	}
+	return nil
+}
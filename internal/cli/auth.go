@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect your kbridge permissions",
+	Long: `Inspect what the kbridge RBAC policy lets you do.
+
+'kb auth can-i' and 'kb auth whoami' are answered by the central service's
+policy. Other 'kb auth' subcommands (e.g. reconcile) run as kubectl.`,
+}
+
+var (
+	canINamespace string
+	canICluster   string
+	canIAs        string
+	canIAsGroups  []string
+	canIQuiet     bool
+)
+
+var authCanICmd = &cobra.Command{
+	Use:   "can-i <verb> <resource>",
+	Short: "Check whether an action is allowed",
+	Long: `Check whether the RBAC policy allows a verb on a resource, and show the
+role and rule that decided it. The resource may be a type (pods, deploy) or
+type/name (deploy/api). Exits with status 1 when the action is denied.
+
+Admins can check for another user with --as (their kbridge-managed groups
+are included) and --as-group.`,
+	Example: `  kb auth can-i delete pods -n app
+  kb auth can-i "rollout restart" deploy/api
+  kb auth can-i exec pods --as dev@corp.com --cluster prod`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAuthCanI,
+}
+
+var authWhoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show your identity and effective roles",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthWhoami,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authCanICmd)
+	authCmd.AddCommand(authWhoamiCmd)
+
+	authCanICmd.Flags().StringVarP(&canINamespace, "namespace", "n", "", "namespace to check (default \"default\")")
+	authCanICmd.Flags().StringVar(&canICluster, "cluster", "", "cluster to check (default: the selected cluster)")
+	authCanICmd.Flags().StringVar(&canIAs, "as", "", "check for another user's email (admin only)")
+	authCanICmd.Flags().StringSliceVar(&canIAsGroups, "as-group", nil, "check with these groups (admin only)")
+	authCanICmd.Flags().BoolVarP(&canIQuiet, "quiet", "q", false, "print nothing; only set the exit status")
+}
+
+func runAuthCanI(cmd *cobra.Command, args []string) error {
+	centralURL := viper.GetString(ConfigKeyCentralURL)
+	if centralURL == "" {
+		return fmt.Errorf("central URL not configured. Run 'kb login' first")
+	}
+	cluster := canICluster
+	if cluster == "" {
+		cluster = viper.GetString(ConfigKeyCurrentCluster)
+	}
+	if cluster == "" {
+		return fmt.Errorf("no cluster selected. Run 'kb clusters use <name>' or pass --cluster")
+	}
+
+	client := newAuthenticatedClient(centralURL)
+	resp, err := client.AuthzCheck(AuthzCheckRequest{
+		Cluster:   cluster,
+		Namespace: canINamespace,
+		Verb:      args[0],
+		Resource:  args[1],
+		As:        canIAs,
+		AsGroups:  canIAsGroups,
+	})
+	if err != nil {
+		return fmt.Errorf("check failed: %w", err)
+	}
+
+	if !canIQuiet {
+		printCanI(resp)
+	}
+	if !resp.Allowed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// printCanI prints "yes" or "no" followed by the rule that decided each check.
+func printCanI(resp *AuthzCheckResponse) {
+	if resp.Allowed {
+		fmt.Println("yes")
+	} else {
+		fmt.Println("no")
+	}
+	if !resp.Enforced {
+		fmt.Println("  RBAC is not enforced on this central service")
+		return
+	}
+	for _, c := range resp.Checks {
+		target := c.Resource
+		if len(c.Names) > 0 {
+			target += "/" + strings.Join(c.Names, ",")
+		}
+		var reason string
+		switch {
+		case c.Role == "":
+			reason = "no rule matches"
+		case c.Effect == "deny":
+			reason = fmt.Sprintf("denied by role %q rule %d", c.Role, c.Rule)
+		case c.RequireApproval:
+			reason = fmt.Sprintf("allowed with approval by role %q rule %d", c.Role, c.Rule)
+		default:
+			reason = fmt.Sprintf("allowed by role %q rule %d", c.Role, c.Rule)
+		}
+		fmt.Printf("  %s %s in %s: %s\n", c.Verb, target, c.Namespace, reason)
+	}
+}
+
+func runAuthWhoami(cmd *cobra.Command, args []string) error {
+	centralURL := viper.GetString(ConfigKeyCentralURL)
+	if centralURL == "" {
+		return fmt.Errorf("central URL not configured. Run 'kb login' first")
+	}
+	me, err := newAuthenticatedClient(centralURL).WhoAmI()
+	if err != nil {
+		return fmt.Errorf("whoami failed: %w", err)
+	}
+
+	groups := strings.Join(me.Groups, ", ")
+	if groups == "" {
+		groups = "-"
+	}
+	fmt.Printf("Email:   %s\n", me.Email)
+	fmt.Printf("Groups:  %s\n", groups)
+	fmt.Printf("Admin:   %t\n", me.IsAdmin)
+	if !me.Enforced {
+		fmt.Println("Roles:   (RBAC is not enforced; all commands are allowed)")
+		return nil
+	}
+	if len(me.Roles) == 0 {
+		fmt.Println("Roles:   (none; all commands are denied)")
+		return nil
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tGRANTED BY\tSCOPE")
+	for _, r := range me.Roles {
+		scope := "-"
+		if r.Cluster != "" {
+			scope = fmt.Sprintf("cluster %s until %s", r.Cluster, r.ExpiresAt)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Role, r.Source, scope)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	breakglassCluster string
+	breakglassReason  string
+)
+
+var breakglassCmd = &cobra.Command{
+	Use:   "breakglass",
+	Short: "Take emergency elevated access to a cluster",
+	Long: `Grant yourself the pre-configured break-glass role on a cluster for a short
+window, without waiting for approval. Only users listed in the central
+configuration can do this. The activation notifies the operators, and every
+command you run on the cluster during the window is audited as break-glass.`,
+	Example: `  kb breakglass --cluster prod --reason "INC-123: API down, on-call unreachable"`,
+	Args:    cobra.NoArgs,
+	RunE:    runBreakglass,
+}
+
+func init() {
+	rootCmd.AddCommand(breakglassCmd)
+
+	breakglassCmd.Flags().StringVar(&breakglassCluster, "cluster", "", "cluster to take access to (default: the selected cluster)")
+	breakglassCmd.Flags().StringVar(&breakglassReason, "reason", "", "why emergency access is needed (required)")
+	_ = breakglassCmd.MarkFlagRequired("reason")
+}
+
+func runBreakglass(cmd *cobra.Command, args []string) error {
+	cluster := breakglassCluster
+	if cluster == "" {
+		cluster = viper.GetString(ConfigKeyCurrentCluster)
+	}
+	if cluster == "" {
+		return fmt.Errorf("no cluster selected. Run 'kb clusters use <name>' or pass --cluster")
+	}
+	client, err := accessClient()
+	if err != nil {
+		return err
+	}
+	r, err := client.Breakglass(cluster, breakglassReason)
+	if err != nil {
+		return fmt.Errorf("failed to activate break-glass access: %w", err)
+	}
+	fmt.Printf("Break-glass active: role %q on %s until %s (request %s).\n", r.Role, r.ClusterName, r.ExpiresAt, r.ID)
+	fmt.Println("Every command you run on this cluster is audited as break-glass.")
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/spf13/viper"
@@ -180,6 +181,112 @@ func (c *CentralClient) Login(email, password string) (*LoginResponse, error) {
 	return &loginResp, nil
 }
 
+// DeviceAuthResponse is the response from POST /auth/oidc/device.
+type DeviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in,omitempty"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// ssoPost posts payload to an unauthenticated SSO endpoint and decodes a 200
+// or 202 response into out, returning the status code.
+func (c *CentralClient) ssoPost(path string, payload any, out any) (int, error) {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+	case http.StatusNotFound:
+		return resp.StatusCode, fmt.Errorf("SSO login is not enabled on the central service")
+	default:
+		var e struct {
+			Error string `json:"error"`
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			return resp.StatusCode, fmt.Errorf("sso login failed: %s", e.Error)
+		}
+		return resp.StatusCode, fmt.Errorf("sso login failed: %s", string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("decoding response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// OIDCAuthorize asks central for the identity provider's authorization URL
+// for a PKCE login that redirects back to redirectURI.
+func (c *CentralClient) OIDCAuthorize(redirectURI, state, nonce, codeChallenge string) (string, error) {
+	var out struct {
+		AuthURL string `json:"auth_url"`
+	}
+	_, err := c.ssoPost("/auth/oidc/authorize", map[string]string{
+		"redirect_uri":   redirectURI,
+		"state":          state,
+		"nonce":          nonce,
+		"code_challenge": codeChallenge,
+	}, &out)
+	if err != nil {
+		return "", err
+	}
+	return out.AuthURL, nil
+}
+
+// OIDCToken exchanges an authorization code for kbridge tokens.
+func (c *CentralClient) OIDCToken(code, codeVerifier, redirectURI, nonce string) (*LoginResponse, error) {
+	var out LoginResponse
+	_, err := c.ssoPost("/auth/oidc/token", map[string]string{
+		"code":          code,
+		"code_verifier": codeVerifier,
+		"redirect_uri":  redirectURI,
+		"nonce":         nonce,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OIDCDeviceStart begins a device-code login.
+func (c *CentralClient) OIDCDeviceStart() (*DeviceAuthResponse, error) {
+	var out DeviceAuthResponse
+	if _, err := c.ssoPost("/auth/oidc/device", struct{}{}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OIDCDeviceToken polls once for a device-code login. While the user has not
+// yet approved it, the returned response is nil and status is
+// "authorization_pending" or "slow_down".
+func (c *CentralClient) OIDCDeviceToken(deviceCode string) (resp *LoginResponse, status string, err error) {
+	var out struct {
+		LoginResponse
+		Status string `json:"status"`
+	}
+	code, err := c.ssoPost("/auth/oidc/device/token", map[string]string{"device_code": deviceCode}, &out)
+	if err != nil {
+		return nil, "", err
+	}
+	if code == http.StatusAccepted {
+		return nil, out.Status, nil
+	}
+	return &out.LoginResponse, "", nil
+}
+
 // newJSONRequest builds a POST request with a buffered JSON body and sets
 // GetBody so doRequest can replay the body on a 401-triggered retry.
 func newJSONRequest(method, url string, bodyBytes []byte) (*http.Request, error) {
@@ -259,12 +366,13 @@ func (c *CentralClient) GetCluster(name string) (*ClusterInfo, error) {
 
 // UserInfo represents a user returned by the admin API.
 type UserInfo struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	IsActive  bool   `json:"is_active"`
-	IsAdmin   bool   `json:"is_admin"`
-	CreatedAt string `json:"created_at"`
+	ID        string   `json:"id"`
+	Email     string   `json:"email"`
+	Name      string   `json:"name"`
+	IsActive  bool     `json:"is_active"`
+	IsAdmin   bool     `json:"is_admin"`
+	Groups    []string `json:"groups,omitempty"`
+	CreatedAt string   `json:"created_at"`
 }
 
 // ListUsers fetches all users via the admin API.
@@ -297,9 +405,9 @@ func (c *CentralClient) ListUsers() ([]UserInfo, error) {
 }
 
 // CreateUser creates a new user via the admin API.
-func (c *CentralClient) CreateUser(email, name, password string) (*UserInfo, error) {
-	body, _ := json.Marshal(map[string]string{
-		"email": email, "name": name, "password": password,
+func (c *CentralClient) CreateUser(email, name, password string, groups []string) (*UserInfo, error) {
+	body, _ := json.Marshal(map[string]any{
+		"email": email, "name": name, "password": password, "groups": groups,
 	})
 	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/admin/users", body)
 	if err != nil {
@@ -329,57 +437,287 @@ func (c *CentralClient) CreateUser(email, name, password string) (*UserInfo, err
 	}
 }
 
+// SetUserGroups replaces a user's kbridge-managed groups via the admin API.
+func (c *CentralClient) SetUserGroups(id string, groups []string) (*UserInfo, error) {
+	if groups == nil {
+		groups = []string{}
+	}
+	body, _ := json.Marshal(map[string]any{"groups": groups})
+	req, err := newJSONRequest(http.MethodPut, c.baseURL+"/api/v1/admin/users/"+url.PathEscape(id), body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var u UserInfo
+		if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+		return &u, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("user %q not found", id)
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("admin role required")
+	default:
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(b))
+	}
+}
+
 // AuditLogInfo represents one audit record returned by the admin API.
 type AuditLogInfo struct {
-	UserEmail   string `json:"user_email"`
-	ClusterName string `json:"cluster_name"`
-	Command     string `json:"command"`
-	Namespace   string `json:"namespace"`
-	Status      string `json:"status"`
-	ExitCode    *int32 `json:"exit_code"`
-	CreatedAt   string `json:"created_at"`
+	ID           string `json:"id"`
+	UserEmail    string `json:"user_email"`
+	ClusterName  string `json:"cluster_name"`
+	Command      string `json:"command"`
+	Namespace    string `json:"namespace"`
+	Status       string `json:"status"`
+	ExitCode     *int32 `json:"exit_code"`
+	DurationMs   *int64 `json:"duration_ms"`
+	ErrorMessage string `json:"error_message"`
+	ClientIP     string `json:"client_ip"`
+	Verb         string `json:"verb"`
+	Resource     string `json:"resource"`
+	Seq          int64  `json:"seq"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// AuditOutputInfo is the output captured for an audit record.
+type AuditOutputInfo struct {
+	Stdout      string `json:"stdout"`
+	Stderr      string `json:"stderr"`
+	StdoutBytes int64  `json:"stdout_bytes"`
+	StderrBytes int64  `json:"stderr_bytes"`
+	Truncated   bool   `json:"truncated"`
+	Redactions  int    `json:"redactions"`
+}
+
+// GetAuditLog fetches one audit record and its captured output, which is nil
+// when none was stored.
+func (c *CentralClient) GetAuditLog(id string) (*AuditLogInfo, *AuditOutputInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/admin/audit/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out struct {
+		Log    AuditLogInfo     `json:"log"`
+		Output *AuditOutputInfo `json:"output"`
+	}
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out.Log, out.Output, nil
+}
+
+// AuditLogPage is one page of audit logs. Total is only set for page-number
+// listings; NextCursor is empty on the last page.
+type AuditLogPage struct {
+	Logs       []AuditLogInfo `json:"logs"`
+	Total      int            `json:"total"`
+	NextCursor string         `json:"next_cursor"`
 }
 
-// ListAuditLogs fetches audit logs, applying the given query filters
-// (user, cluster, status, per_page, ...).
-func (c *CentralClient) ListAuditLogs(filters map[string]string) ([]AuditLogInfo, int, error) {
+// auditQueryURL returns the URL of an audit endpoint with the non-empty
+// filters as query parameters.
+func (c *CentralClient) auditQueryURL(path string, filters map[string]string) string {
 	q := url.Values{}
 	for k, v := range filters {
 		if v != "" {
 			q.Set(k, v)
 		}
 	}
-	reqURL := c.baseURL + "/api/v1/admin/audit"
+	reqURL := c.baseURL + path
 	if encoded := q.Encode(); encoded != "" {
 		reqURL += "?" + encoded
 	}
+	return reqURL
+}
 
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+// ListAuditLogs fetches a page of audit logs, applying the given query
+// filters (user, cluster, status, q, cursor, per_page, ...).
+func (c *CentralClient) ListAuditLogs(filters map[string]string) (*AuditLogPage, error) {
+	req, err := http.NewRequest(http.MethodGet, c.auditQueryURL("/api/v1/admin/audit", filters), nil)
 	if err != nil {
-		return nil, 0, fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	resp, err := c.doRequest(req)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusForbidden {
-		return nil, 0, fmt.Errorf("admin role required")
+		return nil, fmt.Errorf("admin role required")
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, 0, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var out AuditLogPage
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &out, nil
+}
+
+// ExportAuditLogs streams every audit log matching filters to w, in the
+// format named by the "format" filter (csv or jsonl).
+func (c *CentralClient) ExportAuditLogs(filters map[string]string, w io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, c.auditQueryURL("/api/v1/admin/audit/export", filters), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			return fmt.Errorf("reading export: %w", err)
+		}
+		return nil
+	case http.StatusForbidden:
+		return fmt.Errorf("admin role required")
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// AuditChainProblem is one inconsistency reported by audit verification.
+type AuditChainProblem struct {
+	Kind   string `json:"kind"`
+	Seq    int64  `json:"seq"`
+	ID     string `json:"id"`
+	Detail string `json:"detail"`
+}
+
+// AuditVerifyResult is the response of GET /api/v1/admin/audit/verify.
+type AuditVerifyResult struct {
+	OK         bool   `json:"ok"`
+	Checked    int    `json:"checked"`
+	FirstSeq   int64  `json:"first_seq"`
+	LastSeq    int64  `json:"last_seq"`
+	HeadHash   string `json:"head_hash"`
+	Keyed      bool   `json:"keyed"`
+	Checkpoint *struct {
+		Seq       int64  `json:"seq"`
+		Deleted   int    `json:"deleted"`
+		CreatedAt string `json:"created_at"`
+	} `json:"checkpoint"`
+	Problems  []AuditChainProblem `json:"problems"`
+	Truncated bool                `json:"truncated"`
+}
+
+// VerifyAuditChain asks central to walk the audit hash chain.
+func (c *CentralClient) VerifyAuditChain() (*AuditVerifyResult, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/admin/audit/verify", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var out AuditVerifyResult
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+		return &out, nil
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("admin role required")
+	default:
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(b))
 	}
+}
 
+// SessionRecordingInfo describes a recorded interactive exec session.
+type SessionRecordingInfo struct {
+	ID          string `json:"id"`
+	AuditLogID  string `json:"audit_log_id"`
+	UserEmail   string `json:"user_email"`
+	ClusterName string `json:"cluster_name"`
+	Namespace   string `json:"namespace,omitempty"`
+	Command     string `json:"command"`
+	DurationMs  int64  `json:"duration_ms"`
+	Size        int64  `json:"size"`
+	Truncated   bool   `json:"truncated,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// ListSessionRecordings lists recorded exec sessions, newest first. Empty
+// filters match everything; limit 0 uses the server default.
+func (c *CentralClient) ListSessionRecordings(user, cluster string, limit int) ([]SessionRecordingInfo, error) {
+	q := url.Values{}
+	if user != "" {
+		q.Set("user", user)
+	}
+	if cluster != "" {
+		q.Set("cluster", cluster)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	reqURL := c.baseURL + "/api/v1/admin/sessions"
+	if len(q) > 0 {
+		reqURL += "?" + q.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
 	var out struct {
-		Logs  []AuditLogInfo `json:"logs"`
-		Total int            `json:"total"`
+		Sessions []SessionRecordingInfo `json:"sessions"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, 0, fmt.Errorf("decoding response: %w", err)
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return out.Sessions, nil
+}
+
+// DownloadSessionRecording returns a recording as an asciicast v2 file.
+func (c *CentralClient) DownloadSessionRecording(id string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/admin/sessions/"+url.PathEscape(id)+"/cast", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading recording: %w", err)
+		}
+		return b, nil
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("admin role required")
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("session recording %q not found", id)
+	default:
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(b))
 	}
-	return out.Logs, out.Total, nil
 }
 
 // AgentTokenInfo represents an agent token returned by the admin API. The
@@ -519,6 +857,12 @@ type ExecResponse struct {
 
 // ExecCommand executes a kubectl command on the specified cluster.
 func (c *CentralClient) ExecCommand(clusterName string, command []string, namespace string, timeout int) (*ExecResponse, error) {
+	return c.ExecCommandContext(context.Background(), clusterName, command, namespace, timeout)
+}
+
+// ExecCommandContext is ExecCommand with a context. Cancelling ctx abandons
+// the request, and central then cancels the command on the agent.
+func (c *CentralClient) ExecCommandContext(ctx context.Context, clusterName string, command []string, namespace string, timeout int) (*ExecResponse, error) {
 	url := fmt.Sprintf("%s/api/v1/clusters/%s/exec", c.baseURL, clusterName)
 
 	reqBody := ExecRequest{
@@ -537,7 +881,7 @@ func (c *CentralClient) ExecCommand(clusterName string, command []string, namesp
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.doRequest(req)
+	resp, err := c.doRequest(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -710,6 +1054,15 @@ func (c *CentralClient) parseExecResponse(resp *http.Response, clusterName strin
 	if resp.StatusCode == http.StatusGatewayTimeout {
 		return nil, fmt.Errorf("command execution timed out")
 	}
+	if resp.StatusCode == http.StatusAccepted {
+		var pending struct {
+			Approval *CommandApprovalInfo `json:"approval"`
+		}
+		if err := json.Unmarshal(body, &pending); err != nil || pending.Approval == nil {
+			return nil, fmt.Errorf("server returned 202: %s", string(body))
+		}
+		return c.waitForApproval(pending.Approval, clusterName)
+	}
 
 	var execResp ExecResponse
 	if err := json.Unmarshal(body, &execResp); err != nil {
@@ -721,3 +1074,370 @@ func (c *CentralClient) parseExecResponse(resp *http.Response, clusterName strin
 
 	return &execResp, nil
 }
+
+// AuthzCheckRequest asks central whether a command, or a verb on a resource,
+// would be allowed. Set either Command or Verb/Resource.
+type AuthzCheckRequest struct {
+	Cluster   string   `json:"cluster"`
+	Namespace string   `json:"namespace,omitempty"`
+	Command   []string `json:"command,omitempty"`
+	Verb      string   `json:"verb,omitempty"`
+	Resource  string   `json:"resource,omitempty"`
+	As        string   `json:"as,omitempty"`
+	AsGroups  []string `json:"as_groups,omitempty"`
+}
+
+// AuthzCheckResult is the decision for one resource of a check, with the
+// role and 1-based rule index that decided it (empty when no rule matched).
+type AuthzCheckResult struct {
+	Verb            string   `json:"verb"`
+	Resource        string   `json:"resource"`
+	Namespace       string   `json:"namespace"`
+	Names           []string `json:"names,omitempty"`
+	Allowed         bool     `json:"allowed"`
+	Role            string   `json:"role,omitempty"`
+	Rule            int      `json:"rule,omitempty"`
+	Effect          string   `json:"effect,omitempty"`
+	RequireApproval bool     `json:"require_approval,omitempty"`
+}
+
+// AuthzCheckResponse is the result of a policy dry-run.
+type AuthzCheckResponse struct {
+	Allowed  bool `json:"allowed"`
+	Enforced bool `json:"enforced"`
+	Subject  struct {
+		Email  string   `json:"email"`
+		Groups []string `json:"groups,omitempty"`
+	} `json:"subject"`
+	Checks []AuthzCheckResult `json:"checks"`
+}
+
+// RoleGrant is a role that applies to a user and the binding that granted it.
+// Just-in-time grants are limited to Cluster until ExpiresAt.
+type RoleGrant struct {
+	Role      string `json:"role"`
+	Source    string `json:"source"`
+	Cluster   string `json:"cluster,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// WhoAmIResponse describes the authenticated user and their effective roles.
+type WhoAmIResponse struct {
+	UserID   string      `json:"user_id"`
+	Email    string      `json:"email"`
+	IsAdmin  bool        `json:"is_admin"`
+	Groups   []string    `json:"groups,omitempty"`
+	Enforced bool        `json:"enforced"`
+	Roles    []RoleGrant `json:"roles"`
+}
+
+// AuthzCheck evaluates a request against the RBAC policy without running it.
+func (c *CentralClient) AuthzCheck(check AuthzCheckRequest) (*AuthzCheckResponse, error) {
+	body, _ := json.Marshal(check)
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/authz/check", body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out AuthzCheckResponse
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WhoAmI returns the authenticated user's identity and effective roles.
+func (c *CentralClient) WhoAmI() (*WhoAmIResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/authz/whoami", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out WhoAmIResponse
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// doJSON sends req and decodes a 200 response into out. Other statuses are
+// returned as errors carrying the server's error message.
+func (c *CentralClient) doJSON(req *http.Request, out any) error {
+	return c.doJSONStatus(req, http.StatusOK, out)
+}
+
+// doJSONStatus is doJSON for endpoints that succeed with wantStatus.
+func (c *CentralClient) doJSONStatus(req *http.Request, wantStatus int, out any) error {
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// CommandApprovalInfo is a command held by a require_approval rule.
+type CommandApprovalInfo struct {
+	ID          string   `json:"id"`
+	UserEmail   string   `json:"user_email"`
+	ClusterName string   `json:"cluster_name"`
+	Command     []string `json:"command"`
+	Namespace   string   `json:"namespace,omitempty"`
+	Status      string   `json:"status"`
+	DecidedBy   string   `json:"decided_by,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	ExpiresAt   string   `json:"expires_at"`
+}
+
+// waitForApproval tells the user that their command needs approval and blocks
+// until an approver decides it. Once approved the command runs and its result
+// is returned like any other exec.
+func (c *CentralClient) waitForApproval(a *CommandApprovalInfo, clusterName string) (*ExecResponse, error) {
+	notifyApprovalPending(a)
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/approvals/"+url.PathEscape(a.ID)+"/wait", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	// No client timeout: the request waits for a person.
+	waiter := *c
+	waiter.httpClient = &http.Client{Transport: c.httpClient.Transport}
+	resp, err := waiter.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusGone, http.StatusNotFound, http.StatusConflict:
+		respBody, _ := io.ReadAll(resp.Body)
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			return nil, fmt.Errorf("%s", e.Error)
+		}
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	return c.parseExecResponse(resp, clusterName)
+}
+
+// ListApprovals lists pending commands the caller can decide, plus their own.
+func (c *CentralClient) ListApprovals() ([]CommandApprovalInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/approvals", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out struct {
+		Approvals []CommandApprovalInfo `json:"approvals"`
+	}
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return out.Approvals, nil
+}
+
+// DecideApproval approves or denies a pending command.
+func (c *CentralClient) DecideApproval(id string, approve bool) (*CommandApprovalInfo, error) {
+	action := "deny"
+	if approve {
+		action = "approve"
+	}
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/approvals/"+url.PathEscape(id)+"/"+action, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out CommandApprovalInfo
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PolicyInfo is the active RBAC policy as reported by central.
+type PolicyInfo struct {
+	Source   string `json:"source"`
+	Version  int    `json:"version"`
+	Document string `json:"document"`
+}
+
+// PolicyVersionInfo is one stored revision of a database-backed policy.
+// Document is empty in history listings.
+type PolicyVersionInfo struct {
+	Version   int    `json:"version"`
+	Document  string `json:"document,omitempty"`
+	Author    string `json:"author"`
+	Comment   string `json:"comment,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GetPolicy returns the active RBAC policy.
+func (c *CentralClient) GetPolicy() (*PolicyInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/admin/policy", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out PolicyInfo
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPolicyVersion returns a stored policy version including its document.
+func (c *CentralClient) GetPolicyVersion(version int) (*PolicyVersionInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/admin/policy/versions/%d", c.baseURL, version), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out PolicyVersionInfo
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetPolicy submits a new policy document. A non-zero baseVersion makes the
+// update fail if the policy changed since that version.
+func (c *CentralClient) SetPolicy(document, comment string, baseVersion int) (*PolicyVersionInfo, error) {
+	body, _ := json.Marshal(map[string]any{"document": document, "comment": comment, "base_version": baseVersion})
+	req, err := newJSONRequest(http.MethodPut, c.baseURL+"/api/v1/admin/policy", body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out PolicyVersionInfo
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPolicyVersions returns the policy history, newest first.
+func (c *CentralClient) ListPolicyVersions() ([]PolicyVersionInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v1/admin/policy/versions", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out struct {
+		Versions []PolicyVersionInfo `json:"versions"`
+	}
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return out.Versions, nil
+}
+
+// RollbackPolicy re-activates the document of an earlier version as a new
+// version.
+func (c *CentralClient) RollbackPolicy(version int, comment string) (*PolicyVersionInfo, error) {
+	body, _ := json.Marshal(map[string]any{"version": version, "comment": comment})
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/admin/policy/rollback", body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out PolicyVersionInfo
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JITRequestInfo is a just-in-time access request as returned by central.
+type JITRequestInfo struct {
+	ID          string `json:"id"`
+	UserEmail   string `json:"user_email"`
+	ClusterName string `json:"cluster_name"`
+	Role        string `json:"role"`
+	Reason      string `json:"reason"`
+	DurationSec int64  `json:"duration_seconds"`
+	Status      string `json:"status"`
+	Breakglass  bool   `json:"breakglass,omitempty"`
+	DecidedBy   string `json:"decided_by,omitempty"`
+	DecidedAt   string `json:"decided_at,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// RequestAccess files a just-in-time request for role on cluster. duration is
+// a Go duration string such as "2h".
+func (c *CentralClient) RequestAccess(cluster, role, duration, reason string) (*JITRequestInfo, error) {
+	body, _ := json.Marshal(map[string]string{"cluster": cluster, "role": role, "duration": duration, "reason": reason})
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/access/requests", body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out JITRequestInfo
+	if err := c.doJSONStatus(req, http.StatusCreated, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Breakglass activates break-glass access to cluster for the caller.
+func (c *CentralClient) Breakglass(cluster, reason string) (*JITRequestInfo, error) {
+	body, _ := json.Marshal(map[string]string{"cluster": cluster, "reason": reason})
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/breakglass", body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out JITRequestInfo
+	if err := c.doJSONStatus(req, http.StatusCreated, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAccessRequests lists access requests, optionally filtered by status.
+// Approvers see everyone's requests unless mine is set.
+func (c *CentralClient) ListAccessRequests(status string, mine bool) ([]JITRequestInfo, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	if mine {
+		q.Set("mine", "true")
+	}
+	reqURL := c.baseURL + "/api/v1/access/requests"
+	if len(q) > 0 {
+		reqURL += "?" + q.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out struct {
+		Requests []JITRequestInfo `json:"requests"`
+	}
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return out.Requests, nil
+}
+
+// DecideAccessRequest approves (approve=true) or denies a pending request.
+func (c *CentralClient) DecideAccessRequest(id string, approve bool) (*JITRequestInfo, error) {
+	action := "deny"
+	if approve {
+		action = "approve"
+	}
+	req, err := newJSONRequest(http.MethodPost, c.baseURL+"/api/v1/access/requests/"+url.PathEscape(id)+"/"+action, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	var out JITRequestInfo
+	if err := c.doJSON(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
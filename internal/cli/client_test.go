@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCentralClient_ListClusters(t *testing.T) {
@@ -238,6 +242,30 @@ func TestCentralClient_ExecCommand_NonZeroExit(t *testing.T) {
 	}
 }
 
+func TestCentralClient_ExecCommandContext_Canceled(t *testing.T) {
+	gone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold the request like central does while the agent runs the command.
+		// The disconnect is only noticed once the body has been read.
+		io.ReadAll(r.Body) //nolint:errcheck
+		<-r.Context().Done()
+		close(gone)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	client := NewCentralClient(server.URL)
+	if _, err := client.ExecCommandContext(ctx, "prod", []string{"get", "pods", "-A"}, "", 30); err == nil {
+		t.Fatal("expected an error for a canceled request")
+	}
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not see the request canceled")
+	}
+}
+
 func TestCentralClient_ExecCommand_ClusterNotFound(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -358,6 +386,133 @@ func TestCentralClient_RevokeAgentToken(t *testing.T) {
 	}
 }
 
+func TestCentralClient_VerifyAuditChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/admin/audit/verify" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok": false, "checked": 3, "first_seq": 1, "last_seq": 3, "keyed": true,
+			"problems": []map[string]any{{"kind": "modified", "seq": 2, "id": "log-2", "detail": "contents do not match the stored hash"}},
+		})
+	}))
+	defer server.Close()
+
+	r, err := NewCentralClient(server.URL).VerifyAuditChain()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.OK || r.Checked != 3 || len(r.Problems) != 1 || r.Problems[0].Seq != 2 {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestCentralClient_GetAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/admin/audit/log-1" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "audit log not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"log":    map[string]any{"id": "log-1", "command": "get pods", "status": "success"},
+			"output": map[string]any{"stdout": "web 1/1 Running\n", "stdout_bytes": 16},
+		})
+	}))
+	defer server.Close()
+
+	client := NewCentralClient(server.URL)
+	l, out, err := client.GetAuditLog("log-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l.ID != "log-1" || l.Command != "get pods" || out == nil || out.StdoutBytes != 16 {
+		t.Errorf("unexpected result: %+v %+v", l, out)
+	}
+	if _, _, err := client.GetAuditLog("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("want a not found error, got %v", err)
+	}
+}
+
+func TestCentralClient_ListAndExportAuditLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/api/v1/admin/audit":
+			if q.Get("verb") != "delete" || q.Get("q") != "web" || !q.Has("cursor") || q.Has("user") {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"logs":        []map[string]any{{"id": "log-1", "verb": "delete", "resource": "pods"}},
+				"next_cursor": "abc",
+			})
+		case "/api/v1/admin/audit/export":
+			if q.Get("format") != "csv" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "format must be csv or jsonl"})
+				return
+			}
+			w.Write([]byte("id,seq\nlog-1,1\n"))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewCentralClient(server.URL)
+	page, err := client.ListAuditLogs(map[string]string{"verb": "delete", "q": "web", "cursor": "x", "user": ""})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(page.Logs) != 1 || page.Logs[0].Resource != "pods" || page.NextCursor != "abc" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+
+	var buf bytes.Buffer
+	if err := client.ExportAuditLogs(map[string]string{"format": "csv"}, &buf); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if buf.String() != "id,seq\nlog-1,1\n" {
+		t.Errorf("export body = %q", buf.String())
+	}
+	if err := client.ExportAuditLogs(map[string]string{"format": "xml"}, &buf); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("want a 400 error, got %v", err)
+	}
+}
+
+func TestCentralClient_SessionRecordings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/admin/sessions":
+			if r.URL.Query().Get("cluster") != "prod" || r.URL.Query().Get("limit") != "5" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"sessions": []map[string]any{{"id": "s1", "user_email": "dev@x.com", "cluster_name": "prod", "size": 42}},
+			})
+		case "/api/v1/admin/sessions/s1/cast":
+			w.Header().Set("Content-Type", "application/x-asciicast")
+			w.Write([]byte("{\"version\":2}\n")) //nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewCentralClient(server.URL)
+	sessions, err := client.ListSessionRecordings("", "prod", 5)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "s1" || sessions[0].Size != 42 {
+		t.Fatalf("list = %+v, %v", sessions, err)
+	}
+	cast, err := client.DownloadSessionRecording("s1")
+	if err != nil || string(cast) != "{\"version\":2}\n" {
+		t.Fatalf("download = %q, %v", cast, err)
+	}
+	if _, err := client.DownloadSessionRecording("missing"); err == nil {
+		t.Error("want an error for a missing recording")
+	}
+}
+
 func TestTransparentRefresh(t *testing.T) {
 	var refreshed bool
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -437,3 +592,81 @@ func TestTransparentRefresh_NoRefreshToken(t *testing.T) {
 		t.Fatalf("expected 'run kb login' error, got: %v", err)
 	}
 }
+
+func TestCentralClient_AuthzCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/authz/check" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var req AuthzCheckRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.As != "" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"admin role required to check another subject"}`))
+			return
+		}
+		if req.Cluster != "prod" || req.Verb != "delete" || req.Resource != "pods" {
+			t.Errorf("unexpected body: %+v", req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"allowed":false,"enforced":true,"checks":[{"verb":"delete","resource":"pods","namespace":"default","allowed":false,"role":"dev","rule":2,"effect":"deny"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewCentralClient(server.URL)
+	resp, err := client.AuthzCheck(AuthzCheckRequest{Cluster: "prod", Verb: "delete", Resource: "pods"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Allowed || len(resp.Checks) != 1 || resp.Checks[0].Role != "dev" || resp.Checks[0].Rule != 2 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	_, err = client.AuthzCheck(AuthzCheckRequest{Cluster: "prod", Verb: "get", Resource: "pods", As: "x@y.com"})
+	if err == nil || !strings.Contains(err.Error(), "admin role required") {
+		t.Errorf("expected server error message, got %v", err)
+	}
+}
+
+func TestCentralClient_ExecCommand_WaitsForApproval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/clusters/prod/exec":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"approval":{"id":"apr-1","user_email":"dev@x.com","cluster_name":"prod","command":["delete","pod","web"],"status":"pending"}}`))
+		case "/api/v1/approvals/apr-1/wait":
+			w.Write([]byte(`{"output":"pod \"web\" deleted\n","exit_code":0}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewCentralClient(server.URL)
+	resp, err := client.ExecCommand("prod", []string{"delete", "pod", "web"}, "", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Output != "pod \"web\" deleted\n" {
+		t.Errorf("output = %q", resp.Output)
+	}
+}
+
+func TestCentralClient_ExecCommand_ApprovalDenied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/approvals/apr-1/wait" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"command denied by lead@x.com"}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"approval":{"id":"apr-1","status":"pending"}}`))
+	}))
+	defer server.Close()
+
+	client := NewCentralClient(server.URL)
+	_, err := client.ExecCommand("prod", []string{"delete", "pod", "web"}, "", 30)
+	if err == nil || err.Error() != "command denied by lead@x.com" {
+		t.Errorf("err = %v, want the denial", err)
+	}
+}
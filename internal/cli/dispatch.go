@@ -7,7 +7,10 @@ import "strings"
 // in this set is treated as a kubectl command (kubectl-by-default), so
 // `kb get pods` runs kubectl while `kb admin users list` runs the admin command.
 var managementCommands = map[string]bool{
+	"access":     true,
 	"admin":      true,
+	"approvals":  true,
+	"breakglass": true,
 	"clusters":   true,
 	"cluster":    true, // alias for "clusters"
 	"login":      true,
@@ -19,6 +22,13 @@ var managementCommands = map[string]bool{
 	"completion": true, // cobra built-in
 }
 
+// managementSubcommands are kubectl commands of which only some subcommands
+// are answered by kbridge: `kb auth can-i` checks the kbridge policy, while
+// `kb auth reconcile` still runs kubectl.
+var managementSubcommands = map[string]map[string]bool{
+	"auth": {"can-i": true, "whoami": true},
+}
+
 // rewriteArgs implements kubectl-by-default dispatch. Given the raw CLI
 // arguments (os.Args[1:]), it returns the arguments cobra should run: if the
 // first argument is a management subcommand, a top-level CLI flag, or a cobra
@@ -50,6 +60,9 @@ func rewriteArgs(args []string) []string {
 	if managementCommands[first] {
 		return args
 	}
+	if subs := managementSubcommands[first]; subs != nil && len(args) > 1 && subs[args[1]] {
+		return args
+	}
 	return prependKubectl(args)
 }
 
@@ -15,6 +15,9 @@ func TestRewriteArgs(t *testing.T) {
 		{"kubectl verb prepends kubectl", []string{"get", "pods"}, []string{"kubectl", "get", "pods"}},
 		{"kubectl verb with flags", []string{"get", "pods", "-A"}, []string{"kubectl", "get", "pods", "-A"}},
 		{"management admin untouched", []string{"admin", "users", "list"}, []string{"admin", "users", "list"}},
+		{"management access untouched", []string{"access", "approve", "r1"}, []string{"access", "approve", "r1"}},
+		{"management approvals untouched", []string{"approvals", "approve", "apr-1"}, []string{"approvals", "approve", "apr-1"}},
+		{"management breakglass untouched", []string{"breakglass", "--cluster", "prod"}, []string{"breakglass", "--cluster", "prod"}},
 		{"management clusters untouched", []string{"clusters", "use", "prod"}, []string{"clusters", "use", "prod"}},
 		{"cluster alias untouched", []string{"cluster", "use", "prod"}, []string{"cluster", "use", "prod"}},
 		{"login untouched", []string{"login"}, []string{"login"}},
@@ -31,6 +34,10 @@ func TestRewriteArgs(t *testing.T) {
 		{"leading kubectl flag goes to kubectl", []string{"-n", "kube-system", "get", "pods"}, []string{"kubectl", "-n", "kube-system", "get", "pods"}},
 		{"completion directive passes through", []string{"__complete", "get", ""}, []string{"__complete", "get", ""}},
 		{"unknown verb (typo) goes to kubectl", []string{"gte", "pods"}, []string{"kubectl", "gte", "pods"}},
+		{"auth can-i untouched", []string{"auth", "can-i", "get", "pods"}, []string{"auth", "can-i", "get", "pods"}},
+		{"auth whoami untouched", []string{"auth", "whoami"}, []string{"auth", "whoami"}},
+		{"other auth subcommand goes to kubectl", []string{"auth", "reconcile", "-f", "rbac.yaml"}, []string{"kubectl", "auth", "reconcile", "-f", "rbac.yaml"}},
+		{"bare auth goes to kubectl", []string{"auth"}, []string{"kubectl", "auth"}},
 	}
 
 	for _, tt := range tests {
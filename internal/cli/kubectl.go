@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -14,6 +15,10 @@ import (
 // Default timeout for kubectl commands (5 minutes)
 const defaultKubectlTimeout = 5 * time.Minute
 
+// exitInterrupted is the exit code of a command interrupted by a signal,
+// 128+SIGINT as kubectl and shells report it.
+const exitInterrupted = 130
+
 // kubectlCmd represents the kubectl command
 var kubectlCmd = &cobra.Command{
 	Use:   "kubectl [args...]",
@@ -97,7 +102,7 @@ func runKubectl(cmd *cobra.Command, args []string) error {
 
 	// Stream long-lived follow/watch commands via chunked HTTP.
 	if isStreamingCommand(args) {
-		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer cancel()
 		streamClient := newAuthenticatedClient(centralURL)
 		if err := streamClient.StreamCommand(ctx, currentCluster, args, namespace, os.Stdout); err != nil {
@@ -109,9 +114,16 @@ func runKubectl(cmd *cobra.Command, args []string) error {
 	// Create client with longer timeout for command execution
 	client := newAuthenticatedClientWithTimeout(centralURL, defaultKubectlTimeout+10*time.Second)
 
-	// Execute the command
-	resp, err := client.ExecCommand(currentCluster, args, namespace, int(defaultKubectlTimeout.Seconds()))
+	// Execute the command. Ctrl-C (or SIGTERM) abandons the request, and
+	// central then kills the command on the agent.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	resp, err := client.ExecCommandContext(ctx, currentCluster, args, namespace, int(defaultKubectlTimeout.Seconds()))
 	if err != nil {
+		if ctx.Err() != nil {
+			// Exit like an interrupted kubectl, so scripts see the failure.
+			os.Exit(exitInterrupted)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return err
 	}
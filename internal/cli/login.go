@@ -2,27 +2,50 @@ package cli
 
 import (
 	"bufio"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
 	"golang.org/x/term"
 )
 
+// ssoLoginTimeout bounds how long kb waits for the browser redirect.
+const ssoLoginTimeout = 5 * time.Minute
+
+// defaultDevicePollInterval is used when the identity provider does not
+// specify a polling interval for device-code login.
+var defaultDevicePollInterval = 5 * time.Second
+
 // loginCmd represents the login command
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Authenticate with the central service",
 	Long: `Authenticate with the kbridge central service.
 
-This command will prompt for email and password to authenticate
-and obtain an access token.`,
+By default this command prompts for email and password. With --sso it signs
+in through the organisation's identity provider instead: a browser window is
+opened and the login completes on a local callback. On machines without a
+browser, add --device to get a code to enter on another device.
+
+  kb login
+  kb login --sso
+  kb login --sso --device`,
 	RunE: runLogin,
 }
 
 func init() {
+	loginCmd.Flags().Bool("sso", false, "sign in through the identity provider (OIDC)")
+	loginCmd.Flags().Bool("device", false, "with --sso, use the device-code flow instead of a browser redirect")
 	rootCmd.AddCommand(loginCmd)
 }
 
@@ -32,12 +55,45 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("central_url not configured: run 'kb config set central_url <url>'")
 	}
 
+	sso, _ := cmd.Flags().GetBool("sso")
+	device, _ := cmd.Flags().GetBool("device")
+	if device && !sso {
+		return fmt.Errorf("--device requires --sso")
+	}
+
+	client := NewCentralClient(centralURL)
+	var resp *LoginResponse
+	var err error
+	switch {
+	case device:
+		resp, err = loginWithDevice(client)
+	case sso:
+		resp, err = loginWithBrowser(client)
+	default:
+		resp, err = loginWithPassword(client)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Store tokens in config
+	viper.Set(ConfigKeyToken, resp.AccessToken)
+	viper.Set(ConfigKeyRefreshToken, resp.RefreshToken)
+	if err := saveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Println("Login successful.")
+	return nil
+}
+
+func loginWithPassword(client *CentralClient) (*LoginResponse, error) {
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Print("Email: ")
 	email, err := reader.ReadString('\n')
 	if err != nil {
-		return fmt.Errorf("reading email: %w", err)
+		return nil, fmt.Errorf("reading email: %w", err)
 	}
 	email = strings.TrimSpace(email)
 
@@ -45,23 +101,152 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 	fmt.Println()
 	if err != nil {
-		return fmt.Errorf("reading password: %w", err)
+		return nil, fmt.Errorf("reading password: %w", err)
 	}
 	password := string(passwordBytes)
 
-	client := NewCentralClient(centralURL)
-	resp, err := client.Login(email, password)
+	return client.Login(email, password)
+}
+
+// loginWithBrowser runs an authorization-code + PKCE login. The identity
+// provider redirects the browser to a one-shot listener on the loopback
+// interface; the code and PKCE verifier are then handed to central, which
+// holds the client credentials and performs the exchange.
+func loginWithBrowser(client *CentralClient) (*LoginResponse, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("starting callback listener: %w", err)
 	}
+	redirectURI := fmt.Sprintf("http://%s/callback", ln.Addr())
 
-	// Store tokens in config
-	viper.Set(ConfigKeyToken, resp.AccessToken)
-	viper.Set(ConfigKeyRefreshToken, resp.RefreshToken)
-	if err := saveConfig(); err != nil {
-		return fmt.Errorf("saving config: %w", err)
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return nil, err
 	}
+	verifier := oauth2.GenerateVerifier()
 
-	fmt.Println("Login successful.")
-	return nil
+	authURL, err := client.OIDCAuthorize(redirectURI, state, nonce, oauth2.S256ChallengeFromVerifier(verifier))
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+	srv := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+			q := r.URL.Query()
+			var res callbackResult
+			switch {
+			case q.Get("state") != state:
+				res.err = fmt.Errorf("sso login failed: state mismatch in callback")
+			case q.Get("error") != "":
+				res.err = fmt.Errorf("sso login failed: %s %s", q.Get("error"), q.Get("error_description"))
+			case q.Get("code") == "":
+				res.err = fmt.Errorf("sso login failed: callback has no code")
+			default:
+				res.code = q.Get("code")
+			}
+			if res.err != nil {
+				http.Error(w, "Login failed. Return to your terminal for details.", http.StatusBadRequest)
+			} else {
+				fmt.Fprintln(w, "Login complete. You can close this window and return to your terminal.")
+			}
+			select {
+			case results <- res:
+			default:
+			}
+		}),
+	}
+	go srv.Serve(ln) //nolint:errcheck
+	defer srv.Close()
+
+	fmt.Printf("Opening your browser to sign in. If it does not open, visit:\n\n  %s\n\n", authURL)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not open a browser: %v\n", err)
+	}
+
+	select {
+	case res := <-results:
+		if res.err != nil {
+			return nil, res.err
+		}
+		return client.OIDCToken(res.code, verifier, redirectURI, nonce)
+	case <-time.After(ssoLoginTimeout):
+		return nil, fmt.Errorf("timed out waiting for the browser login to complete")
+	}
+}
+
+// loginWithDevice runs a device-code login for hosts without a browser.
+func loginWithDevice(client *CentralClient) (*LoginResponse, error) {
+	da, err := client.OIDCDeviceStart()
+	if err != nil {
+		return nil, err
+	}
+
+	if da.VerificationURIComplete != "" {
+		fmt.Printf("To sign in, visit:\n\n  %s\n\nand confirm the code %s\n\n", da.VerificationURIComplete, da.UserCode)
+	} else {
+		fmt.Printf("To sign in, visit:\n\n  %s\n\nand enter the code %s\n\n", da.VerificationURI, da.UserCode)
+	}
+
+	interval := defaultDevicePollInterval
+	if da.Interval > 0 {
+		interval = time.Duration(da.Interval) * time.Second
+	}
+	expiresIn := 10 * time.Minute
+	if da.ExpiresIn > 0 {
+		expiresIn = time.Duration(da.ExpiresIn) * time.Second
+	}
+	deadline := time.Now().Add(expiresIn)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		resp, status, err := client.OIDCDeviceToken(da.DeviceCode)
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			return resp, nil
+		}
+		if status == "slow_down" {
+			interval += 5 * time.Second
+		}
+	}
+	return nil, fmt.Errorf("device code expired before the login was approved")
+}
+
+// openBrowser opens url in the user's default browser. It is a variable so
+// tests can stand in for the browser.
+var openBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
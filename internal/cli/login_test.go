@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 )
 
 func TestCentralClient_Login_Success(t *testing.T) {
@@ -125,3 +129,90 @@ func TestCentralClient_Unauthorized_Response(t *testing.T) {
 		t.Fatal("expected error for 401 response")
 	}
 }
+
+func TestLoginWithBrowser(t *testing.T) {
+	const code = "auth-code-1"
+	var challenge, nonce string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/auth/oidc/authorize":
+			challenge, nonce = req["code_challenge"], req["nonce"]
+			q := url.Values{"redirect_uri": {req["redirect_uri"]}, "state": {req["state"]}}
+			json.NewEncoder(w).Encode(map[string]string{"auth_url": "https://idp.test/authorize?" + q.Encode()})
+		case "/auth/oidc/token":
+			sum := sha256.Sum256([]byte(req["code_verifier"]))
+			if req["code"] != code || req["nonce"] != nonce || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"sso login failed"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "sso-access", RefreshToken: "sso-refresh"})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	// Stand in for the browser: the IdP redirects back to the loopback
+	// callback with the code and the original state.
+	orig := openBrowser
+	defer func() { openBrowser = orig }()
+	openBrowser = func(authURL string) error {
+		u, _ := url.Parse(authURL)
+		q := u.Query()
+		go http.Get(q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode())
+		return nil
+	}
+
+	resp, err := loginWithBrowser(NewCentralClient(server.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.AccessToken != "sso-access" || resp.RefreshToken != "sso-refresh" {
+		t.Errorf("unexpected tokens: %+v", resp)
+	}
+}
+
+func TestLoginWithDevice(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/oidc/device":
+			json.NewEncoder(w).Encode(DeviceAuthResponse{DeviceCode: "dev-1", UserCode: "ABCD", VerificationURI: "https://idp.test/activate"})
+		case "/auth/oidc/device/token":
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(map[string]string{"status": "authorization_pending"})
+				return
+			}
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "device-access", RefreshToken: "device-refresh"})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	orig := defaultDevicePollInterval
+	defer func() { defaultDevicePollInterval = orig }()
+	defaultDevicePollInterval = time.Millisecond
+
+	resp, err := loginWithDevice(NewCentralClient(server.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.AccessToken != "device-access" || polls != 3 {
+		t.Errorf("got %+v after %d polls", resp, polls)
+	}
+}
+
+func TestCentralClient_OIDC_NotEnabled(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := NewCentralClient(server.URL).OIDCDeviceStart(); err == nil {
+		t.Fatal("expected error when SSO is not enabled")
+	}
+}
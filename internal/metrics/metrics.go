@@ -0,0 +1,413 @@
+// Package metrics is a small instrumentation library for Prometheus: labelled
+// counters, gauges and histograms, served in the Prometheus text exposition
+// format. Central and the agent both expose it on /metrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are histogram buckets for latencies in seconds, from 5ms to 10s.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// family is one named metric and all of its label combinations.
+type family interface {
+	desc() *desc
+	samples() []sample
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+// sample is one line of exposition output. suffix and extra are used by
+// histograms for _bucket/_sum/_count and the le label.
+type sample struct {
+	suffix      string
+	labelValues []string
+	extraName   string
+	extraValue  string
+	value       float64
+}
+
+// Registry holds a set of metrics. Registering two metrics with the same name
+// panics, as it is always a programming error.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := f.desc().name
+	if _, dup := r.families[name]; dup {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.families[name] = f
+}
+
+func (r *Registry) snapshot() []family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		out = append(out, f)
+	}
+	return out
+}
+
+// vec holds the children of a labelled metric, created on first use.
+type vec[T any] struct {
+	desc
+	mu       sync.Mutex
+	children map[string]*T
+	order    map[string][]string
+	newChild func() *T
+}
+
+func (v *vec[T]) with(lvs []string) *T {
+	if len(lvs) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", v.name, len(v.labels), len(lvs)))
+	}
+	key := strings.Join(lvs, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.children[key]
+	if !ok {
+		c = v.newChild()
+		v.children[key] = c
+		v.order[key] = append([]string(nil), lvs...)
+	}
+	return c
+}
+
+// each calls fn for every child in label order.
+func (v *vec[T]) each(fn func(lvs []string, c *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type entry struct {
+		lvs []string
+		c   *T
+	}
+	entries := make([]entry, len(keys))
+	for i, k := range keys {
+		entries[i] = entry{v.order[k], v.children[k]}
+	}
+	v.mu.Unlock()
+	for _, e := range entries {
+		fn(e.lvs, e.c)
+	}
+}
+
+func newVec[T any](name, help, typ string, labels []string, newChild func() *T) *vec[T] {
+	return &vec[T]{
+		desc:     desc{name: name, help: help, typ: typ, labels: labels},
+		children: make(map[string]*T),
+		order:    make(map[string][]string),
+		newChild: newChild,
+	}
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Inc adds one.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.v += v
+	c.mu.Unlock()
+}
+
+// Value returns the current count.
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct{ v *vec[Counter] }
+
+// NewCounterVec registers a counter with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{newVec(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.register(cv)
+	return cv
+}
+
+// NewCounter registers a counter without labels.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+// WithLabelValues returns the counter for the label values, in the order the
+// labels were declared.
+func (cv *CounterVec) WithLabelValues(lvs ...string) *Counter { return cv.v.with(lvs) }
+
+func (cv *CounterVec) desc() *desc { return &cv.v.desc }
+
+func (cv *CounterVec) samples() []sample {
+	var out []sample
+	cv.v.each(func(lvs []string, c *Counter) {
+		out = append(out, sample{labelValues: lvs, value: c.Value()})
+	})
+	return out
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.v = v
+	g.mu.Unlock()
+}
+
+// Add adds v, which may be negative.
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.v += v
+	g.mu.Unlock()
+}
+
+// Inc adds one.
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec subtracts one.
+func (g *Gauge) Dec() { g.Add(-1) }
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ v *vec[Gauge] }
+
+// NewGaugeVec registers a gauge with the given label names.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	gv := &GaugeVec{newVec(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	r.register(gv)
+	return gv
+}
+
+// NewGauge registers a gauge without labels.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).WithLabelValues()
+}
+
+// WithLabelValues returns the gauge for the label values.
+func (gv *GaugeVec) WithLabelValues(lvs ...string) *Gauge { return gv.v.with(lvs) }
+
+func (gv *GaugeVec) desc() *desc { return &gv.v.desc }
+
+func (gv *GaugeVec) samples() []sample {
+	var out []sample
+	gv.v.each(func(lvs []string, g *Gauge) {
+		out = append(out, sample{labelValues: lvs, value: g.Value()})
+	})
+	return out
+}
+
+// gaugeFunc is a gauge whose values are computed at scrape time.
+type gaugeFunc struct {
+	d       desc
+	collect func(set func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc registers a gauge computed when the registry is scraped.
+// collect calls set once per label combination to report; combinations it
+// does not report are absent from that scrape.
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(set func(value float64, labelValues ...string))) {
+	r.register(&gaugeFunc{d: desc{name: name, help: help, typ: "gauge", labels: labels}, collect: collect})
+}
+
+func (g *gaugeFunc) desc() *desc { return &g.d }
+
+func (g *gaugeFunc) samples() []sample {
+	var out []sample
+	g.collect(func(value float64, lvs ...string) {
+		if len(lvs) != len(g.d.labels) {
+			panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", g.d.name, len(g.d.labels), len(lvs)))
+		}
+		out = append(out, sample{labelValues: append([]string(nil), lvs...), value: value})
+	})
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Join(out[i].labelValues, "\xff") < strings.Join(out[j].labelValues, "\xff")
+	})
+	return out
+}
+
+// Histogram counts observations into buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []uint64 // non-cumulative; the last one is +Inf
+	sum     float64
+	count   uint64
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.mu.Lock()
+	h.buckets[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct{ v *vec[Histogram] }
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds,
+// which must be sorted, and label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := append([]float64(nil), buckets...)
+	hv := &HistogramVec{newVec(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{bounds: bounds, buckets: make([]uint64, len(bounds)+1)}
+	})}
+	r.register(hv)
+	return hv
+}
+
+// WithLabelValues returns the histogram for the label values.
+func (hv *HistogramVec) WithLabelValues(lvs ...string) *Histogram { return hv.v.with(lvs) }
+
+func (hv *HistogramVec) desc() *desc { return &hv.v.desc }
+
+func (hv *HistogramVec) samples() []sample {
+	var out []sample
+	hv.v.each(func(lvs []string, h *Histogram) {
+		h.mu.Lock()
+		var cum uint64
+		for i, b := range h.bounds {
+			cum += h.buckets[i]
+			out = append(out, sample{suffix: "_bucket", labelValues: lvs, extraName: "le",
+				extraValue: formatFloat(b), value: float64(cum)})
+		}
+		out = append(out,
+			sample{suffix: "_bucket", labelValues: lvs, extraName: "le", extraValue: "+Inf", value: float64(h.count)},
+			sample{suffix: "_sum", labelValues: lvs, value: h.sum},
+			sample{suffix: "_count", labelValues: lvs, value: float64(h.count)},
+		)
+		h.mu.Unlock()
+	})
+	return out
+}
+
+// Write writes every metric in regs to w in the text exposition format,
+// sorted by name.
+func Write(w io.Writer, regs ...*Registry) error {
+	var families []family
+	for _, r := range regs {
+		families = append(families, r.snapshot()...)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].desc().name < families[j].desc().name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		d := f.desc()
+		fmt.Fprintf(bw, "# HELP %s %s\n", d.name, helpEscaper.Replace(d.help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", d.name, d.typ)
+		for _, s := range f.samples() {
+			bw.WriteString(d.name + s.suffix)
+			writeLabels(bw, d.labels, s)
+			bw.WriteByte(' ')
+			bw.WriteString(formatFloat(s.value))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics of regs.
+func Handler(regs ...*Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		Write(w, regs...) //nolint:errcheck // the client went away
+	})
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeLabels(w *bufio.Writer, names []string, s sample) {
+	if len(names) == 0 && s.extraName == "" {
+		return
+	}
+	w.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, `%s="%s"`, n, labelEscaper.Replace(s.labelValues[i]))
+	}
+	if s.extraName != "" {
+		if len(names) > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, `%s="%s"`, s.extraName, s.extraValue)
+	}
+	w.WriteByte('}')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	reqs := r.NewCounterVec("app_requests_total", "Requests handled.", "code")
+	reqs.WithLabelValues("200").Inc()
+	reqs.WithLabelValues("200").Add(2)
+	reqs.WithLabelValues(`a"b`).Inc()
+	r.NewGauge("app_up", "Whether the app is up.\nAlways 1.").Set(1)
+	lat := r.NewHistogramVec("app_latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	lat.WithLabelValues("get").Observe(0.05)
+	lat.WithLabelValues("get").Observe(0.5)
+	lat.WithLabelValues("get").Observe(3)
+	r.NewGaugeFunc("app_queue_depth", "Queued items.", []string{"queue"}, func(set func(float64, ...string)) {
+		set(4, "b")
+		set(2, "a")
+	})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP app_latency_seconds Latency.
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{op="get",le="0.1"} 1
+app_latency_seconds_bucket{op="get",le="1"} 2
+app_latency_seconds_bucket{op="get",le="+Inf"} 3
+app_latency_seconds_sum{op="get"} 3.55
+app_latency_seconds_count{op="get"} 3
+# HELP app_queue_depth Queued items.
+# TYPE app_queue_depth gauge
+app_queue_depth{queue="a"} 2
+app_queue_depth{queue="b"} 4
+# HELP app_requests_total Requests handled.
+# TYPE app_requests_total counter
+app_requests_total{code="200"} 3
+app_requests_total{code="a\"b"} 1
+# HELP app_up Whether the app is up.\nAlways 1.
+# TYPE app_up gauge
+app_up 1
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestHandlerMergesRegistries(t *testing.T) {
+	a, b := NewRegistry(), NewRegistry()
+	a.NewCounter("b_total", "B.").Inc()
+	b.NewCounter("a_total", "A.").Inc()
+
+	w := httptest.NewRecorder()
+	Handler(a, b).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Header().Get("Content-Type") != ContentType {
+		t.Errorf("content type = %q", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	if i, j := strings.Index(body, "a_total 1"), strings.Index(body, "b_total 1"); i < 0 || j < 0 || i > j {
+		t.Errorf("want both registries sorted by name:\n%s", body)
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"duplicate name", func(r *Registry) {
+			r.NewCounter("x_total", "X.")
+			r.NewGauge("x_total", "X.")
+		}},
+		{"wrong label count", func(r *Registry) {
+			r.NewCounterVec("y_total", "Y.", "a", "b").WithLabelValues("only-one")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("want a panic")
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}